@@ -0,0 +1,200 @@
+package main
+
+// api_identity.go — who is calling.
+//
+// authMiddleware matches the bearer token against the legacy
+// PAYFLUX_API_KEY(S) list, then against the key registry selected by
+// PAYFLUX_API_KEY_REGISTRY:
+//
+//	file      PAYFLUX_API_KEY_REGISTRY_FILE (default config/api_keys.json)
+//	postgres  the dashboard's workspace_api_keys table (needs DATABASE_URL)
+//
+// The matched apikeys.Identity, its CanonicalTier and its entitlement tier
+// go into the request context. Rate limiters are keyed and logs are
+// attributed by Identity.KeyID, never by the key.
+//
+// With the postgres registry, admin keys manage keys at /api/v1/api-keys
+// (apikeys.Handler); changes go to the config audit log at
+// PAYFLUX_AUDIT_LOG and reach every node through Postgres NOTIFY.
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"payment-node/internal/apikeys"
+	"payment-node/internal/config"
+	"payment-node/internal/entitlements"
+	"payment-node/internal/tier"
+)
+
+// apiKeyRegistryTTL bounds how long a revoked or edited Postgres key keeps
+// its previous behavior.
+const apiKeyRegistryTTL = 30 * time.Second
+
+var (
+	apiKeyRegistryMode string           // PAYFLUX_API_KEY_REGISTRY: "" (off), "file" or "postgres"
+	apiKeyRegistry     apikeys.Registry // nil when off
+	apiKeyStore        *apikeys.Store   // key management; postgres mode only
+	legacyKeysAdmin    bool             // PAYFLUX_LEGACY_KEYS_ADMIN: legacy keys get the admin scope
+)
+
+// loadAPIKeyRegistry builds the registry. Runs after Postgres is connected;
+// the postgres registry listens for key changes until ctx is done.
+func loadAPIKeyRegistry(ctx context.Context, dsn string) {
+	switch apiKeyRegistryMode {
+	case "":
+		return
+	case "file":
+		path := env("PAYFLUX_API_KEY_REGISTRY_FILE", "config/api_keys.json")
+		reg, err := apikeys.NewFileRegistry(path)
+		if err != nil {
+			log.Fatalf("api_key_registry_error err=%v", err)
+		}
+		apiKeyRegistry = reg
+		slog.Info("api_key_registry_loaded", "mode", "file", "path", path, "keys", reg.Len())
+	case "postgres":
+		if pgDB == nil {
+			log.Fatal("PAYFLUX_API_KEY_REGISTRY=postgres requires a reachable DATABASE_URL")
+		}
+		auditPath := env("PAYFLUX_AUDIT_LOG", "audit.log")
+		if err := config.InitAuditLogger(auditPath); err != nil {
+			log.Fatalf("api key management requires the audit log: %v", err)
+		}
+		reg := apikeys.NewPostgresRegistry(pgDB, apiKeyRegistryTTL)
+		go reg.Listen(ctx, dsn)
+		apiKeyRegistry = reg
+		apiKeyStore = apikeys.NewStore(pgDB, reg)
+		slog.Info("api_key_registry_loaded", "mode", "postgres", "cache_ttl", apiKeyRegistryTTL.String(),
+			"audit_log", auditPath)
+	default:
+		log.Fatalf("PAYFLUX_API_KEY_REGISTRY must be 'file' or 'postgres', got: %s", apiKeyRegistryMode)
+	}
+}
+
+// errKeyExpired is returned by lookupRegistryKey for a registered key past
+// its expires_at.
+var errKeyExpired = errors.New("api key expired")
+
+// lookupRegistryKey resolves token through the registry. Returns
+// apikeys.ErrUnknownKey when there is no registry or no match.
+func lookupRegistryKey(r *http.Request, token string) (*apikeys.Identity, error) {
+	if apiKeyRegistry == nil {
+		return nil, apikeys.ErrUnknownKey
+	}
+	id, err := apiKeyRegistry.Lookup(r.Context(), token)
+	if err != nil {
+		return nil, err
+	}
+	if id.Expired(time.Now()) {
+		return id, errKeyExpired
+	}
+	return id, nil
+}
+
+// withIdentity returns r carrying id, its CanonicalTier and its
+// entitlement tier.
+func withIdentity(r *http.Request, token string, id *apikeys.Identity) *http.Request {
+	t := resolveIdentityTier(r, token, id)
+	entTier := id.EntitlementTier
+	if entTier == "" {
+		entTier = t.EntitlementTier()
+	}
+	ctx := apikeys.WithIdentity(r.Context(), id)
+	ctx = tier.WithTier(ctx, t)
+	ctx = entitlements.WithTier(ctx, entTier)
+	return r.WithContext(ctx)
+}
+
+// resolveIdentityTier: the key's own tier, else its workspace's, else the
+// legacy PAYFLUX_API_KEY_TIERS / PAYFLUX_API_KEY_WORKSPACES bindings and
+// the deployment default.
+func resolveIdentityTier(r *http.Request, token string, id *apikeys.Identity) tier.CanonicalTier {
+	if id.Tier != "" {
+		return id.Tier
+	}
+	t, err := runtimeCanonicalTier, error(nil)
+	switch {
+	case requestTierResolver == nil:
+	case id.WorkspaceID != "":
+		t, err = requestTierResolver.ResolveWorkspace(r.Context(), id.WorkspaceID)
+	case id.Legacy:
+		t, err = requestTierResolver.Resolve(r.Context(), token)
+	}
+	if err != nil {
+		slog.Warn("tier_resolution_failed", "key_id", id.KeyID, "fallback", string(t), "error", err)
+	}
+	if t == "" {
+		t = tier.TierFree
+	}
+	return t
+}
+
+// requestKeyID names the caller for rate limiting and logs.
+func requestKeyID(r *http.Request) string {
+	if id := apikeys.FromContext(r.Context()); id != nil {
+		return id.KeyID
+	}
+	return "anonymous"
+}
+
+// requestWorkspaceID is the caller's workspace, "" for keys without one.
+// Ingest stamps it on every event so stored data can be attributed.
+func requestWorkspaceID(r *http.Request) string {
+	if id := apikeys.FromContext(r.Context()); id != nil {
+		return id.WorkspaceID
+	}
+	return ""
+}
+
+// statusRecorder captures the response status for audit logging.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	s.status = code
+	s.ResponseWriter.WriteHeader(code)
+}
+
+// adminMiddleware requires the admin scope and writes an api_audit log
+// line per request, attributed to the caller's key and workspace.
+func adminMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return authMiddleware(apikeys.RequireScope(apikeys.ScopeAdmin, func(w http.ResponseWriter, r *http.Request) {
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next(rec, r)
+		id := apikeys.FromContext(r.Context())
+		slog.Info("api_audit",
+			"key_id", id.KeyID,
+			"workspace_id", id.WorkspaceID,
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", rec.status,
+		)
+	}))
+}
+
+// deploymentAdminMiddleware is adminMiddleware for deployment-wide routes:
+// keys bound to a workspace are refused, since the response covers every
+// workspace.
+func deploymentAdminMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return adminMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if requestWorkspaceID(r) != "" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]string{"error": "workspace_key_not_allowed"})
+			return
+		}
+		next(w, r)
+	})
+}
+
+// scopedMiddleware authenticates and requires scope.
+func scopedMiddleware(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return authMiddleware(apikeys.RequireScope(scope, next))
+}
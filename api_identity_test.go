@@ -0,0 +1,126 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"payment-node/internal/apikeys"
+	"payment-node/internal/runtime/entitlementctx"
+	"payment-node/internal/tier"
+)
+
+func TestAuthMiddlewareIdentity(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api_keys.json")
+	registryJSON := `{"keys": [
+		{"id": "acme-ingest", "sha256": "` + apikeys.Hash("acme-secret") + `", "workspace_id": "ws-acme",
+		 "tier": "enterprise", "scopes": ["ingest"]},
+		{"id": "acme-pilot", "sha256": "` + apikeys.Hash("pilot-secret") + `", "tier": "pro",
+		 "entitlement_tier": "proof", "scopes": ["read"]},
+		{"id": "expired", "sha256": "` + apikeys.Hash("expired-secret") + `", "scopes": ["read"],
+		 "expires_at": "2020-01-01T00:00:00Z"}
+	]}`
+	if err := os.WriteFile(path, []byte(registryJSON), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	reg, err := apikeys.NewFileRegistry(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	validAPIKeys = []string{"legacy-key"}
+	revokedAPIKeys = nil
+	runtimeCanonicalTier = tier.TierFree
+	apiKeyRegistry = reg
+	defer func() { apiKeyRegistry = nil }()
+
+	type seen struct {
+		keyID, workspace, entTier string
+		tier                      tier.CanonicalTier
+	}
+	var got seen
+	record := func(w http.ResponseWriter, r *http.Request) {
+		id := apikeys.FromContext(r.Context())
+		got = seen{id.KeyID, id.WorkspaceID, entitlementctx.TierFromContext(r.Context()), tier.FromContext(r.Context())}
+	}
+
+	tests := []struct {
+		name   string
+		token  string
+		scope  string
+		status int
+		want   seen
+	}{
+		{"registry key", "acme-secret", apikeys.ScopeIngest, http.StatusOK,
+			seen{"acme-ingest", "ws-acme", "fortress", tier.TierEnterprise}},
+		{"entitlement override", "pilot-secret", apikeys.ScopeRead, http.StatusOK,
+			seen{"acme-pilot", "", "proof", tier.TierPro}},
+		{"legacy key", "legacy-key", apikeys.ScopeIngest, http.StatusOK,
+			seen{apikeys.LegacyIdentity("legacy-key", false).KeyID, "", "baseline", tier.TierFree}},
+		{"legacy key without admin", "legacy-key", apikeys.ScopeAdmin, http.StatusForbidden, seen{}},
+		{"missing scope", "acme-secret", apikeys.ScopeRead, http.StatusForbidden, seen{}},
+		{"expired key", "expired-secret", apikeys.ScopeRead, http.StatusUnauthorized, seen{}},
+		{"unknown key", "nope", apikeys.ScopeRead, http.StatusUnauthorized, seen{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got = seen{}
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			w := httptest.NewRecorder()
+			scopedMiddleware(tt.scope, record)(w, req)
+
+			if w.Code != tt.status {
+				t.Fatalf("status %d, want %d", w.Code, tt.status)
+			}
+			if got != tt.want {
+				t.Errorf("context = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	legacyKeysAdmin = true
+	defer func() { legacyKeysAdmin = false }()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer legacy-key")
+	w := httptest.NewRecorder()
+	scopedMiddleware(apikeys.ScopeAdmin, record)(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("legacy key with PAYFLUX_LEGACY_KEYS_ADMIN: status %d, want 200", w.Code)
+	}
+}
+
+func TestDeploymentAdminMiddleware(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api_keys.json")
+	registryJSON := `{"keys": [
+		{"id": "ops", "sha256": "` + apikeys.Hash("ops-secret") + `", "scopes": ["admin"]},
+		{"id": "acme-admin", "sha256": "` + apikeys.Hash("acme-secret") + `", "workspace_id": "ws-acme", "scopes": ["admin"]},
+		{"id": "reader", "sha256": "` + apikeys.Hash("read-secret") + `", "scopes": ["read"]}
+	]}`
+	if err := os.WriteFile(path, []byte(registryJSON), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	reg, err := apikeys.NewFileRegistry(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	apiKeyRegistry = reg
+	defer func() { apiKeyRegistry = nil }()
+
+	handler := deploymentAdminMiddleware(func(w http.ResponseWriter, r *http.Request) {})
+	for token, want := range map[string]int{
+		"ops-secret":  http.StatusOK,
+		"acme-secret": http.StatusForbidden,
+		"read-secret": http.StatusForbidden,
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/warning-webhooks", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		handler(w, req)
+		if w.Code != want {
+			t.Errorf("%s: status %d, want %d", token, w.Code, want)
+		}
+	}
+}
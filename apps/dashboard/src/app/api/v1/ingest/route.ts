@@ -13,7 +13,7 @@ import { recordAccountAPIActivity } from '@/lib/account-resolver';
  * 1. Resolve account tier (boundary layer)
  * 2. Map tier → numeric QuotaConfig
  * 3. Check rate limit (infra layer, no tier knowledge)
- * 4. Forward to Go backend with numeric headers
+ * 4. Forward to Go backend, which applies its own per-workspace limit
  */
 export async function POST(request: NextRequest) {
     const authResult = await requirePaidAuth();
@@ -112,19 +112,13 @@ export async function POST(request: NextRequest) {
         );
     }
 
-    // 6. Forward to Go backend with numeric headers (NO TIER SEMANTICS)
+    // 6. Forward to Go backend (it resolves the workspace and limit from the key)
     try {
         const body = await request.text();
 
         const goResponse = await fetch(`${GO_BACKEND_URL}/v1/events/payment_exhaust`, {
             method: 'POST',
             headers: {
-                // Numeric config only (no tier strings/enums)
-                'X-Payflux-Account-Id': account.id,
-                'X-Payflux-Ingest-Capacity': String(quotaConfig.capacity),
-                'X-Payflux-Ingest-Refill': String(quotaConfig.refillRate),
-                'X-Payflux-Ingest-Window': String(quotaConfig.window),
-
                 // Forward original auth for Go's validation
                 'Authorization': authHeader || '',
                 'Content-Type': 'application/json',
@@ -0,0 +1,77 @@
+-- Subscription projection v2: invoice payment outcomes and checkout links.
+--
+-- Reducer v2 folds invoice.payment_* / invoice.paid and
+-- checkout.session.completed into the subscription projection alongside
+-- customer.subscription.* events. Each event group is ordered against its
+-- own watermark so an invoice event is never rejected as late merely
+-- because a newer subscription event already landed.
+--
+-- All new columns are nullable (or defaulted) so v1 rows stay valid as-is.
+
+ALTER TABLE subscription_projection
+    ADD COLUMN IF NOT EXISTS last_payment_status text,
+    ADD COLUMN IF NOT EXISTS last_payment_failed_at timestamptz,
+    ADD COLUMN IF NOT EXISTS dunning_attempt_count integer NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS checkout_session_id text,
+    ADD COLUMN IF NOT EXISTS checkout_completed_at timestamptz,
+    ADD COLUMN IF NOT EXISTS subscription_event_occurred_at timestamptz,
+    ADD COLUMN IF NOT EXISTS payment_event_occurred_at timestamptz;
+
+-- A payment or checkout event can precede the first subscription event;
+-- status is NULL until that lands. The drift detector treats a NULL status
+-- as not yet projected, so an empty string stays an impossible status.
+ALTER TABLE subscription_projection
+    ALTER COLUMN status DROP NOT NULL;
+
+ALTER TABLE subscription_projection
+    DROP CONSTRAINT IF EXISTS subscription_projection_last_payment_status_check;
+ALTER TABLE subscription_projection
+    ADD CONSTRAINT subscription_projection_last_payment_status_check
+    CHECK (last_payment_status IS NULL OR last_payment_status IN ('succeeded', 'failed', 'action_required'));
+
+-- Each reducer version writes its own chain (the reducer scopes its
+-- current-projection lookup by reducer_version), so once v2 runs there are
+-- two chain heads per subscription. A new version must not be served while
+-- its replay is still behind: its chain would show stale state for every
+-- subscription it has reached and the old state for the rest.
+--
+-- subscription_reducer_activations records the versions that have caught
+-- up. The reducer inserts its version's row once its reducer_cursors
+-- position has no ledger rows left after it; the insert checks that
+-- itself, so a caller cannot activate a version that is still replaying.
+-- A row is never updated; deleting it rolls the view back to the previous
+-- version. v1 predates the table and is activated here.
+CREATE TABLE IF NOT EXISTS subscription_reducer_activations (
+    reducer_version text PRIMARY KEY,
+    cursor_received_at timestamptz NOT NULL,
+    activated_at timestamptz NOT NULL DEFAULT now()
+);
+
+INSERT INTO subscription_reducer_activations (reducer_version, cursor_received_at)
+VALUES ('v1', now())
+ON CONFLICT (reducer_version) DO NOTHING;
+
+-- The view serves the head of the newest activated version present for
+-- each subscription. Versions compare as (length, text) so v10 sorts
+-- after v9.
+--
+-- Recreated rather than altered: p.* is expanded when the view is
+-- created, so the new columns only appear after CREATE OR REPLACE.
+CREATE OR REPLACE VIEW subscription_current_state AS
+SELECT p.*
+FROM subscription_projection p
+JOIN subscription_reducer_activations a ON a.reducer_version = p.reducer_version
+WHERE NOT EXISTS (
+    SELECT 1 FROM subscription_projection newer
+    WHERE newer.supersedes_id = p.id
+)
+AND NOT EXISTS (
+    SELECT 1 FROM subscription_projection later
+    JOIN subscription_reducer_activations la ON la.reducer_version = later.reducer_version
+    WHERE later.stripe_subscription_id = p.stripe_subscription_id
+      AND (length(later.reducer_version), later.reducer_version)
+        > (length(p.reducer_version), p.reducer_version)
+);
+
+COMMENT ON VIEW subscription_current_state IS
+    'Semantic contract: the most recent interpretation per subscription, from the newest caught-up reducer version that has projected it. Today implemented as a regular view; may be promoted to materialized view, cache, or read replica without contract change. Treat this name as stable; treat the physical implementation as evolving.';
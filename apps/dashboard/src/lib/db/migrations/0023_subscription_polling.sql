@@ -0,0 +1,64 @@
+-- Subscription polling reconciliation worker (reducer v2).
+--
+-- The polling worker lists subscriptions from Stripe's API and appends
+-- every changed subscription to stripe_event_ledger as a synthetic
+-- customer.subscription.polled event. Those rows carry no signature, so
+-- they get their own verify_outcome: 'polling'. The ledger stays the
+-- reducer's only input, which keeps replay epochs reproducible.
+--
+-- The reducer orders polled snapshots against their own watermark
+-- (polled_at) and arbitrates per field between webhook and polling
+-- authority; see SUBSCRIPTION_REDUCER_CONTRACT.md.
+
+-- Drop the existing CHECK constraint by discovering its name (0014 named
+-- it, but older databases may still carry the auto-generated one).
+DO $$
+DECLARE
+    constraint_name text;
+BEGIN
+    SELECT con.conname INTO constraint_name
+    FROM pg_constraint con
+    JOIN pg_class rel ON rel.oid = con.conrelid
+    WHERE rel.relname = 'stripe_event_ledger'
+      AND con.contype = 'c'
+      AND pg_get_constraintdef(con.oid) LIKE '%verify_outcome%';
+
+    IF constraint_name IS NOT NULL THEN
+        EXECUTE format('ALTER TABLE stripe_event_ledger DROP CONSTRAINT %I', constraint_name);
+    END IF;
+END $$;
+
+ALTER TABLE stripe_event_ledger
+    ADD CONSTRAINT stripe_event_ledger_verify_outcome_check
+    CHECK (verify_outcome IN (
+        'primary',         -- verified against STRIPE_WEBHOOK_SECRET (platform endpoint)
+        'fallback',        -- verified against STRIPE_WEBHOOK_SECRET_FALLBACK (rotation transitional)
+        'connect',         -- verified against STRIPE_CONNECT_WEBHOOK_SECRET (Connect endpoint)
+        'per_account',     -- verified against per-merchant secret in processor_connections
+        'polling',         -- synthetic snapshot written by the polling worker (no signature)
+        'fail',            -- signature present but did not verify against any candidate
+        'no_signature',    -- request arrived without a Stripe-Signature header
+        'malformed'        -- body was not valid JSON / could not be parsed
+    ));
+
+-- Polling watermark: the poll time of the newest polled snapshot folded
+-- into the row. NULL until the first poll.
+ALTER TABLE subscription_projection
+    ADD COLUMN IF NOT EXISTS polled_at timestamptz;
+
+-- Recreated so p.* picks up the new column; definition unchanged from 0022.
+CREATE OR REPLACE VIEW subscription_current_state AS
+SELECT p.*
+FROM subscription_projection p
+JOIN subscription_reducer_activations a ON a.reducer_version = p.reducer_version
+WHERE NOT EXISTS (
+    SELECT 1 FROM subscription_projection newer
+    WHERE newer.supersedes_id = p.id
+)
+AND NOT EXISTS (
+    SELECT 1 FROM subscription_projection later
+    JOIN subscription_reducer_activations la ON la.reducer_version = later.reducer_version
+    WHERE later.stripe_subscription_id = p.stripe_subscription_id
+      AND (length(later.reducer_version), later.reducer_version)
+        > (length(p.reducer_version), p.reducer_version)
+);
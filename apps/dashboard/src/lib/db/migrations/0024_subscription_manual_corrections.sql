@@ -0,0 +1,101 @@
+-- Manual operator corrections for subscription projections (reducer v2).
+--
+-- An operator correction is appended to stripe_event_ledger as a synthetic
+-- subscription.manual_correction event with verify_outcome = 'manual' and
+-- a manual_-prefixed event id, so it reaches the projection through the
+-- reducer's merge function like every other input and replay epochs stay
+-- reproducible from the ledger alone.
+--
+-- subscription_manual_corrections is the queryable provenance record:
+-- who corrected what, why, and which ledger row carries it. Append-only —
+-- a correction is undone by submitting another correction.
+
+DO $$
+DECLARE
+    constraint_name text;
+BEGIN
+    SELECT con.conname INTO constraint_name
+    FROM pg_constraint con
+    JOIN pg_class rel ON rel.oid = con.conrelid
+    WHERE rel.relname = 'stripe_event_ledger'
+      AND con.contype = 'c'
+      AND pg_get_constraintdef(con.oid) LIKE '%verify_outcome%';
+
+    IF constraint_name IS NOT NULL THEN
+        EXECUTE format('ALTER TABLE stripe_event_ledger DROP CONSTRAINT %I', constraint_name);
+    END IF;
+END $$;
+
+ALTER TABLE stripe_event_ledger
+    ADD CONSTRAINT stripe_event_ledger_verify_outcome_check
+    CHECK (verify_outcome IN (
+        'primary',         -- verified against STRIPE_WEBHOOK_SECRET (platform endpoint)
+        'fallback',        -- verified against STRIPE_WEBHOOK_SECRET_FALLBACK (rotation transitional)
+        'connect',         -- verified against STRIPE_CONNECT_WEBHOOK_SECRET (Connect endpoint)
+        'per_account',     -- verified against per-merchant secret in processor_connections
+        'polling',         -- synthetic snapshot written by the polling worker (no signature)
+        'manual',          -- operator correction submitted through the corrections endpoint
+        'fail',            -- signature present but did not verify against any candidate
+        'no_signature',    -- request arrived without a Stripe-Signature header
+        'malformed'        -- body was not valid JSON / could not be parsed
+    ));
+
+-- Fields currently held by an operator correction, mapped to the time the
+-- correction was submitted. Mirrors field_authority = 'manual'.
+ALTER TABLE subscription_projection
+    ADD COLUMN IF NOT EXISTS manual_overrides jsonb NOT NULL DEFAULT '{}'::jsonb;
+
+CREATE TABLE IF NOT EXISTS subscription_manual_corrections (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+
+    stripe_subscription_id text NOT NULL,
+    workspace_id uuid NOT NULL,
+
+    -- The ledger row carrying the correction; its stripe_event_id is the
+    -- source_event_id of the projection row the correction produces.
+    ledger_event_id uuid NOT NULL REFERENCES stripe_event_ledger(id),
+    source_event_id text NOT NULL UNIQUE,
+
+    -- Corrected field name -> submitted JSON value.
+    fields jsonb NOT NULL,
+
+    operator text NOT NULL CHECK (operator <> ''),
+    reason text NOT NULL CHECK (reason <> ''),
+
+    submitted_at timestamptz NOT NULL DEFAULT now()
+);
+
+DROP TRIGGER IF EXISTS subscription_manual_corrections_no_update ON subscription_manual_corrections;
+CREATE TRIGGER subscription_manual_corrections_no_update
+    BEFORE UPDATE ON subscription_manual_corrections
+    FOR EACH ROW EXECUTE FUNCTION append_only_table_block_mutation();
+
+DROP TRIGGER IF EXISTS subscription_manual_corrections_no_delete ON subscription_manual_corrections;
+CREATE TRIGGER subscription_manual_corrections_no_delete
+    BEFORE DELETE ON subscription_manual_corrections
+    FOR EACH ROW EXECUTE FUNCTION append_only_table_block_mutation();
+
+DROP TRIGGER IF EXISTS subscription_manual_corrections_no_truncate ON subscription_manual_corrections;
+CREATE TRIGGER subscription_manual_corrections_no_truncate
+    BEFORE TRUNCATE ON subscription_manual_corrections
+    FOR EACH STATEMENT EXECUTE FUNCTION append_only_table_block_truncate();
+
+CREATE INDEX IF NOT EXISTS subscription_manual_corrections_entity_idx
+    ON subscription_manual_corrections (stripe_subscription_id, submitted_at DESC);
+
+-- Recreated so p.* picks up the new column; definition unchanged from 0022.
+CREATE OR REPLACE VIEW subscription_current_state AS
+SELECT p.*
+FROM subscription_projection p
+JOIN subscription_reducer_activations a ON a.reducer_version = p.reducer_version
+WHERE NOT EXISTS (
+    SELECT 1 FROM subscription_projection newer
+    WHERE newer.supersedes_id = p.id
+)
+AND NOT EXISTS (
+    SELECT 1 FROM subscription_projection later
+    JOIN subscription_reducer_activations la ON la.reducer_version = later.reducer_version
+    WHERE later.stripe_subscription_id = p.stripe_subscription_id
+      AND (length(later.reducer_version), later.reducer_version)
+        > (length(p.reducer_version), p.reducer_version)
+);
@@ -0,0 +1,20 @@
+-- Provider votes on reconciliation events (drift detector v2).
+--
+-- The detector can compare projections against several authority
+-- providers and only records a field as drift when a quorum of the
+-- providers reporting the subscription disagree with the projection.
+-- details records how each provider voted so an operator can see which
+-- sources agreed and which dissented:
+--
+--   {"quorum": {"required": 2,
+--               "providers": ["billing_subscriptions", "stripe_api"],
+--               "fields": {"status": {"agreed": [...], "disagreed": [...],
+--                                     "drift": true}}}}
+--
+-- Only fields at least one provider disagreed on appear under "fields".
+-- canonical_state on multi-provider rows is the quorum view
+-- (provider_name = 'quorum'). Rows written before this migration have
+-- details = NULL.
+
+ALTER TABLE subscription_reconciliation_events
+    ADD COLUMN IF NOT EXISTS details jsonb;
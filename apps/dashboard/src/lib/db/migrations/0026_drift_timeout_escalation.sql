@@ -0,0 +1,96 @@
+-- Drift timeout escalation (drift detector v3).
+--
+-- The detector applies a per-severity timeout policy to open drifts. When
+-- a drift has held its severity past the policy's TTL it is either
+--
+--   * escalated: a drift_escalated row is appended with the bumped
+--     severity and escalates_id pointing at the open detection. The
+--     detection row is never rewritten; its effective severity is the
+--     latest escalation's. The escalation's own TTL runs from its
+--     detected_at.
+--   * closed: a drift_resolved row is appended with
+--     resolution_mechanism = 'timeout_unresolved'. The detector does not
+--     re-open drift for the subscription until the projection head moves.
+--
+-- Escalation rows carry copies of the detection's snapshots and a details
+-- object: {"timeout": {"from": "warning", "to": "critical", "after_seconds": 21600}}.
+
+DO $$
+DECLARE
+    constraint_name text;
+BEGIN
+    SELECT con.conname INTO constraint_name
+    FROM pg_constraint con
+    JOIN pg_class rel ON rel.oid = con.conrelid
+    WHERE rel.relname = 'subscription_reconciliation_events'
+      AND con.contype = 'c'
+      AND pg_get_constraintdef(con.oid) LIKE '%event_type%'
+      AND pg_get_constraintdef(con.oid) LIKE '%drift_none%';
+
+    IF constraint_name IS NOT NULL THEN
+        EXECUTE format('ALTER TABLE subscription_reconciliation_events DROP CONSTRAINT %I', constraint_name);
+    END IF;
+END $$;
+
+ALTER TABLE subscription_reconciliation_events
+    ADD CONSTRAINT subscription_reconciliation_events_event_type_check
+    CHECK (event_type IN (
+        'drift_none',                  -- detector ran, found agreement
+        'drift_minor',                 -- semantic agreement, timestamps within tolerance
+        'drift_major',                 -- status or other authoritative field differs
+        'drift_resolved',              -- detector re-observed and a prior drift is now agreement
+        'drift_escalated',             -- an open drift outlived its severity's TTL; severity bumped
+        'projection_impossible',       -- reducer produced an invalid state (regulatory)
+        'manual_reconciliation',       -- operator manually issued a correction
+        'polling_confirmation',        -- direct Stripe poll confirmed projection state
+        'authority_correction'         -- authority policy issued a corrective delta
+    ));
+
+ALTER TABLE subscription_reconciliation_events
+    ADD COLUMN IF NOT EXISTS escalates_id uuid REFERENCES subscription_reconciliation_events(id);
+
+ALTER TABLE subscription_reconciliation_events
+    DROP CONSTRAINT IF EXISTS subscription_reconciliation_events_escalates_check;
+
+ALTER TABLE subscription_reconciliation_events
+    ADD CONSTRAINT subscription_reconciliation_events_escalates_check
+    CHECK ((event_type = 'drift_escalated') = (escalates_id IS NOT NULL));
+
+CREATE INDEX IF NOT EXISTS subscription_reconciliation_events_escalates_idx
+    ON subscription_reconciliation_events (escalates_id, detected_at DESC)
+    WHERE escalates_id IS NOT NULL;
+
+-- Open drift gauge, now by effective severity: the latest escalation's
+-- severity when the drift has been escalated. Column shape unchanged
+-- from 0021.
+CREATE OR REPLACE VIEW subscription_drift_open AS
+SELECT
+    COALESCE(e.severity, d.severity) AS severity,
+    d.event_type,
+    count(*) AS open_count,
+    min(d.detected_at) AS oldest_detected_at,
+    max(now() - d.detected_at) AS longest_age
+FROM subscription_reconciliation_events d
+LEFT JOIN LATERAL (
+    SELECT esc.severity FROM subscription_reconciliation_events esc
+    WHERE esc.escalates_id = d.id
+    ORDER BY esc.detected_at DESC
+    LIMIT 1
+) e ON true
+WHERE d.event_type IN ('drift_minor', 'drift_major', 'projection_impossible')
+  AND NOT EXISTS (
+    SELECT 1 FROM subscription_reconciliation_events r
+    WHERE r.resolves_id = d.id
+  )
+GROUP BY 1, 2
+ORDER BY
+    CASE COALESCE(e.severity, d.severity)
+        WHEN 'regulatory' THEN 1
+        WHEN 'critical' THEN 2
+        WHEN 'warning' THEN 3
+        WHEN 'informational' THEN 4
+    END,
+    d.event_type;
+
+COMMENT ON VIEW subscription_drift_open IS
+    'Currently-unresolved drift detections grouped by effective severity (after escalation) and event_type. The oldest_detected_at column is the operational signal for "this drift has been outstanding for hours/days."';
@@ -0,0 +1,53 @@
+-- Drift notification delivery log.
+--
+-- The detector pushes open drifts at or above each notifier's minimum
+-- severity to webhook, Slack and PagerDuty destinations, and tells the
+-- same destinations when those drifts resolve. One row per delivered
+-- notification:
+--
+--   * kind = 'trigger': the drift was raised to the notifier. At most one
+--     per drift and notifier — escalating an already-notified drift does
+--     not page again.
+--   * kind = 'resolve': the drift's resolution was delivered. Only sent
+--     for drifts with a trigger row.
+--
+-- A pending notification is one whose row is missing, so a failed
+-- delivery is retried on the next sweep. Append-only.
+
+CREATE TABLE IF NOT EXISTS subscription_drift_notifications (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+
+    -- The detection row (drift_minor / drift_major / projection_impossible).
+    drift_id uuid NOT NULL REFERENCES subscription_reconciliation_events(id),
+
+    -- DRIFT_NOTIFIERS name of the destination.
+    notifier text NOT NULL CHECK (notifier <> ''),
+
+    kind text NOT NULL CHECK (kind IN ('trigger', 'resolve')),
+
+    -- Effective severity at trigger time; resolves repeat it.
+    severity text NOT NULL
+        CHECK (severity IN ('informational', 'warning', 'critical', 'regulatory')),
+
+    sent_at timestamptz NOT NULL DEFAULT now(),
+
+    UNIQUE (drift_id, notifier, kind)
+);
+
+DROP TRIGGER IF EXISTS subscription_drift_notifications_no_update ON subscription_drift_notifications;
+CREATE TRIGGER subscription_drift_notifications_no_update
+    BEFORE UPDATE ON subscription_drift_notifications
+    FOR EACH ROW EXECUTE FUNCTION append_only_table_block_mutation();
+
+DROP TRIGGER IF EXISTS subscription_drift_notifications_no_delete ON subscription_drift_notifications;
+CREATE TRIGGER subscription_drift_notifications_no_delete
+    BEFORE DELETE ON subscription_drift_notifications
+    FOR EACH ROW EXECUTE FUNCTION append_only_table_block_mutation();
+
+DROP TRIGGER IF EXISTS subscription_drift_notifications_no_truncate ON subscription_drift_notifications;
+CREATE TRIGGER subscription_drift_notifications_no_truncate
+    BEFORE TRUNCATE ON subscription_drift_notifications
+    FOR EACH STATEMENT EXECUTE FUNCTION append_only_table_block_truncate();
+
+CREATE INDEX IF NOT EXISTS subscription_drift_notifications_notifier_idx
+    ON subscription_drift_notifications (notifier, kind, drift_id);
@@ -0,0 +1,116 @@
+-- Shadow replay of a candidate reducer version.
+--
+-- Before a reducer_version bump is cut over, the candidate binary replays
+-- stripe_event_ledger through its merge function without touching
+-- subscription_projection, and diffs the result against the current
+-- version's projections (cmd/reducer-replay). Each run is a replay_epochs
+-- row with reducer_name = 'subscription_shadow' and
+-- reducer_version = the candidate; its drift_summary holds the diff
+-- counts.
+--
+--   * subscription_projection_shadow: the candidate's head per
+--     subscription at the end of the run. Same interpreted columns as
+--     subscription_projection; no chain, one row per (epoch, subscription).
+--   * subscription_replay_diffs: one row per subscription whose candidate
+--     head differs from the baseline version's current projection.
+--
+-- Both tables are append-only. Shadow rows are never read by
+-- subscription_current_state or the drift detector.
+
+CREATE TABLE IF NOT EXISTS subscription_projection_shadow (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+
+    replay_epoch_id uuid NOT NULL REFERENCES replay_epochs(id),
+
+    stripe_subscription_id text NOT NULL,
+
+    -- No foreign key: a shadow run must not hold workspaces in place.
+    workspace_id uuid NOT NULL,
+
+    status text, -- NULL until a subscription event, as in subscription_projection
+    current_period_start timestamptz,
+    current_period_end timestamptz,
+    cancel_at_period_end boolean NOT NULL DEFAULT false,
+    canceled_at timestamptz,
+    trial_start timestamptz,
+    trial_end timestamptz,
+    last_payment_status text,
+    last_payment_failed_at timestamptz,
+    dunning_attempt_count integer NOT NULL DEFAULT 0,
+    checkout_session_id text,
+    checkout_completed_at timestamptz,
+    field_authority jsonb NOT NULL,
+    manual_overrides jsonb NOT NULL DEFAULT '{}'::jsonb,
+
+    -- Candidate version and the last ledger event folded into the head.
+    reducer_version text NOT NULL,
+    source_event_id text NOT NULL,
+
+    event_occurred_at timestamptz NOT NULL,
+    subscription_event_occurred_at timestamptz,
+    payment_event_occurred_at timestamptz,
+    polled_at timestamptz,
+
+    projected_at timestamptz NOT NULL DEFAULT now(),
+
+    UNIQUE (replay_epoch_id, stripe_subscription_id)
+);
+
+CREATE TABLE IF NOT EXISTS subscription_replay_diffs (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+
+    replay_epoch_id uuid NOT NULL REFERENCES replay_epochs(id),
+
+    stripe_subscription_id text NOT NULL,
+
+    -- changed:        both versions project it, with different values
+    -- only_baseline:  the candidate produced no projection
+    -- only_candidate: the baseline has no projection
+    diff_kind text NOT NULL
+        CHECK (diff_kind IN ('changed', 'only_baseline', 'only_candidate')),
+
+    baseline_version text NOT NULL,
+    candidate_version text NOT NULL,
+
+    -- {"<column>": {"baseline": ..., "candidate": ...}} for each differing
+    -- column; empty for only_baseline / only_candidate.
+    fields jsonb NOT NULL DEFAULT '{}'::jsonb,
+
+    created_at timestamptz NOT NULL DEFAULT now(),
+
+    UNIQUE (replay_epoch_id, stripe_subscription_id)
+);
+
+-- Append-only enforcement.
+DROP TRIGGER IF EXISTS subscription_projection_shadow_no_update ON subscription_projection_shadow;
+CREATE TRIGGER subscription_projection_shadow_no_update
+    BEFORE UPDATE ON subscription_projection_shadow
+    FOR EACH ROW EXECUTE FUNCTION append_only_table_block_mutation();
+
+DROP TRIGGER IF EXISTS subscription_projection_shadow_no_delete ON subscription_projection_shadow;
+CREATE TRIGGER subscription_projection_shadow_no_delete
+    BEFORE DELETE ON subscription_projection_shadow
+    FOR EACH ROW EXECUTE FUNCTION append_only_table_block_mutation();
+
+DROP TRIGGER IF EXISTS subscription_projection_shadow_no_truncate ON subscription_projection_shadow;
+CREATE TRIGGER subscription_projection_shadow_no_truncate
+    BEFORE TRUNCATE ON subscription_projection_shadow
+    FOR EACH STATEMENT EXECUTE FUNCTION append_only_table_block_truncate();
+
+DROP TRIGGER IF EXISTS subscription_replay_diffs_no_update ON subscription_replay_diffs;
+CREATE TRIGGER subscription_replay_diffs_no_update
+    BEFORE UPDATE ON subscription_replay_diffs
+    FOR EACH ROW EXECUTE FUNCTION append_only_table_block_mutation();
+
+DROP TRIGGER IF EXISTS subscription_replay_diffs_no_delete ON subscription_replay_diffs;
+CREATE TRIGGER subscription_replay_diffs_no_delete
+    BEFORE DELETE ON subscription_replay_diffs
+    FOR EACH ROW EXECUTE FUNCTION append_only_table_block_mutation();
+
+DROP TRIGGER IF EXISTS subscription_replay_diffs_no_truncate ON subscription_replay_diffs;
+CREATE TRIGGER subscription_replay_diffs_no_truncate
+    BEFORE TRUNCATE ON subscription_replay_diffs
+    FOR EACH STATEMENT EXECUTE FUNCTION append_only_table_block_truncate();
+
+CREATE INDEX IF NOT EXISTS subscription_replay_diffs_kind_idx
+    ON subscription_replay_diffs (replay_epoch_id, diff_kind);
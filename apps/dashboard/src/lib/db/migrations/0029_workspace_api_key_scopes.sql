@@ -0,0 +1,22 @@
+-- Per-key identity for the ingest node's Postgres key registry.
+--
+-- The node (PAYFLUX_API_KEY_REGISTRY=postgres) authenticates bearer tokens
+-- against workspace_api_keys.key_hash and needs, per key:
+--
+--   * tier: the key's CanonicalTier. NULL means the workspace's
+--     entitlement_tier, which is what every key created before this
+--     migration gets.
+--   * entitlement_tier: overrides the runtime entitlement tier derived from
+--     tier (free -> baseline, pro -> shield, enterprise -> fortress).
+--   * scopes: what the key may do. Existing keys keep ingest + read;
+--     admin (DLQ replay, warning outcomes, subscription corrections) must
+--     be granted explicitly.
+--   * expires_at: NULL never expires.
+
+ALTER TABLE workspace_api_keys
+    ADD COLUMN IF NOT EXISTS tier workspace_tier_enum,
+    ADD COLUMN IF NOT EXISTS entitlement_tier text
+        CHECK (entitlement_tier IN ('baseline', 'proof', 'shield', 'fortress')),
+    ADD COLUMN IF NOT EXISTS scopes text[] NOT NULL DEFAULT ARRAY['ingest', 'read']::text[]
+        CHECK (cardinality(scopes) > 0 AND scopes <@ ARRAY['ingest', 'read', 'admin']::text[]),
+    ADD COLUMN IF NOT EXISTS expires_at timestamptz;
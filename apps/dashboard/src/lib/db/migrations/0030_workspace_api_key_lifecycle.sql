@@ -0,0 +1,31 @@
+-- API key lifecycle managed by the ingest node (/api/v1/api-keys).
+--
+--   * key_salt: hex salt for keys the node creates; key_hash is then
+--     sha256(salt || key) and the row is found by key_prefix. NULL for
+--     dashboard keys, whose key_hash is the unsalted sha256 of the key.
+--   * rotated_from: the key this one replaced. The replaced key keeps
+--     working until its expires_at, set to the end of the overlap window.
+--
+-- Every update or delete notifies payflux_api_keys so each node drops its
+-- cached lookups immediately instead of waiting out the cache TTL.
+
+ALTER TABLE workspace_api_keys
+    ADD COLUMN IF NOT EXISTS key_salt text,
+    ADD COLUMN IF NOT EXISTS rotated_from uuid REFERENCES workspace_api_keys(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS workspace_api_keys_prefix_idx
+    ON workspace_api_keys (key_prefix)
+    WHERE revoked_at IS NULL;
+
+CREATE OR REPLACE FUNCTION workspace_api_keys_notify_change()
+RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('payflux_api_keys', OLD.id::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS workspace_api_keys_notify_change ON workspace_api_keys;
+CREATE TRIGGER workspace_api_keys_notify_change
+    AFTER UPDATE OR DELETE ON workspace_api_keys
+    FOR EACH ROW EXECUTE FUNCTION workspace_api_keys_notify_change();
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Batch ingestion (POST /v1/events/payment_exhaust:batch).
+//
+// Accepts either a JSON array of Event objects or NDJSON (one Event per line).
+// Every item goes through the same validateEvent + dedup:{event_id} SETNX
+// discipline as handleEvent; accepted items are enqueued with a single
+// pipelined XADD round-trip. The response carries one result per input item
+// so forwarders only resend the items that were rejected.
+
+const (
+	maxBatchItems    = 1000
+	maxBatchBodySize = 8 * 1024 * 1024 // 8MB (global httpmw cap is 10MB)
+)
+
+// Batch item statuses
+const (
+	BatchStatusAccepted  = "accepted"
+	BatchStatusDuplicate = "duplicate"
+	BatchStatusRejected  = "rejected"
+)
+
+// BatchItemResult is the per-item outcome of a batch ingest request.
+// Index is the zero-based position of the item in the request body.
+type BatchItemResult struct {
+	Index   int    `json:"index"`
+	EventID string `json:"event_id,omitempty"`
+	Status  string `json:"status"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// BatchIngestResponse is returned by the batch ingest endpoint.
+type BatchIngestResponse struct {
+	Accepted  int               `json:"accepted"`
+	Duplicate int               `json:"duplicate"`
+	Rejected  int               `json:"rejected"`
+	Results   []BatchItemResult `json:"results"`
+}
+
+// errBatchTooLarge is returned by decodeBatch when the item count exceeds maxBatchItems.
+var errBatchTooLarge = fmt.Errorf("batch exceeds %d items", maxBatchItems)
+
+// decodeBatch splits a request body into raw per-item JSON documents.
+// A body whose first non-whitespace byte is '[' is treated as a JSON array;
+// anything else is treated as NDJSON, skipping blank lines.
+// Items are not decoded into Event here so that one malformed item is
+// reported individually instead of failing the whole batch.
+func decodeBatch(body []byte) ([]json.RawMessage, error) {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 {
+		return nil, errors.New("empty batch")
+	}
+
+	if trimmed[0] == '[' {
+		var items []json.RawMessage
+		if err := json.Unmarshal(trimmed, &items); err != nil {
+			return nil, fmt.Errorf("invalid json array: %w", err)
+		}
+		if len(items) == 0 {
+			return nil, errors.New("empty batch")
+		}
+		if len(items) > maxBatchItems {
+			return nil, errBatchTooLarge
+		}
+		return items, nil
+	}
+
+	var items []json.RawMessage
+	scanner := bufio.NewScanner(bytes.NewReader(trimmed))
+	scanner.Buffer(make([]byte, 0, 64*1024), maxBodySize)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if len(items) >= maxBatchItems {
+			return nil, errBatchTooLarge
+		}
+		items = append(items, json.RawMessage(append([]byte(nil), line...)))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("invalid ndjson: %w", err)
+	}
+	return items, nil
+}
+
+// batchCandidate is an item that passed validation and awaits dedup/enqueue.
+type batchCandidate struct {
+	index int
+	event Event
+	data  []byte
+}
+
+func handleEventBatch(w http.ResponseWriter, r *http.Request) {
+	// Ingest kill switch check
+	if !ingestEnabled {
+		http.Error(w, "event ingestion temporarily disabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	start := time.Now()
+	defer func() {
+		ingestLatency.Observe(time.Since(start).Seconds())
+	}()
+
+	if r.Method != http.MethodPost {
+		ingestRejected.Inc()
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxBatchBodySize)
+	defer r.Body.Close()
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		if strings.Contains(err.Error(), "http: request body too large") {
+			http.Error(w, "payload too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "failed to read body", http.StatusBadRequest)
+		return
+	}
+
+	items, err := decodeBatch(body)
+	if err != nil {
+		if errors.Is(err, errBatchTooLarge) {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ingestBatchSize.Observe(float64(len(items)))
+
+	// Each item costs one ingest token, so a batch spends what its events
+	// would singly.
+	if !chargeRateLimit(w, r, rateLimitIngest, len(items)) {
+		return
+	}
+
+	results := make([]BatchItemResult, len(items))
+	candidates := make([]batchCandidate, 0, len(items))
+	seen := make(map[string]struct{}, len(items))
+	workspaceID := requestWorkspaceID(r)
+
+	// 1) Decode + validate each item independently
+	for i, raw := range items {
+		results[i] = BatchItemResult{Index: i}
+
+		var e Event
+		if err := json.Unmarshal(raw, &e); err != nil {
+			results[i].Status = BatchStatusRejected
+			results[i].Reason = "invalid json"
+			continue
+		}
+		results[i].EventID = e.EventID
+
+		if err := validateEvent(&e); err != nil {
+			results[i].Status = BatchStatusRejected
+			results[i].Reason = fmt.Sprintf("validation error: %v", err)
+			continue
+		}
+
+		// Same event_id twice in one batch: second copy is a duplicate
+		if _, dup := seen[e.EventID]; dup {
+			results[i].Status = BatchStatusDuplicate
+			continue
+		}
+		seen[e.EventID] = struct{}{}
+		e.WorkspaceID = workspaceID
+
+		data, err := json.Marshal(e)
+		if err != nil {
+			results[i].Status = BatchStatusRejected
+			results[i].Reason = "failed to serialize event"
+			continue
+		}
+		candidates = append(candidates, batchCandidate{index: i, event: e, data: data})
+	}
+
+	reqCtx := r.Context()
+
+	if len(candidates) > 0 {
+		// 2) Pipelined idempotency check
+		setPipe := rdb.Pipeline()
+		setCmds := make([]*redis.BoolCmd, len(candidates))
+		for i, c := range candidates {
+			setCmds[i] = setPipe.SetNX(reqCtx, fmt.Sprintf("dedup:%s", c.event.EventID), "1", dedupTTL)
+		}
+		_, _ = setPipe.Exec(reqCtx) // per-command errors inspected below
+
+		fresh := make([]batchCandidate, 0, len(candidates))
+		for i, c := range candidates {
+			wasSet, err := setCmds[i].Result()
+			if err != nil {
+				log.Printf("dedup_check_error event_id=%s err=%v", c.event.EventID, err)
+				results[c.index].Status = BatchStatusRejected
+				results[c.index].Reason = "internal error"
+				continue
+			}
+			if !wasSet {
+				results[c.index].Status = BatchStatusDuplicate
+				continue
+			}
+			fresh = append(fresh, c)
+		}
+
+		// 3) Pipelined XADD for items that won the dedup latch
+		if len(fresh) > 0 {
+			addPipe := rdb.Pipeline()
+			addCmds := make([]*redis.StringCmd, len(fresh))
+			for i, c := range fresh {
+				addCmds[i] = addPipe.XAdd(reqCtx, &redis.XAddArgs{
+					Stream: streamKey,
+					Values: map[string]any{"data": string(c.data)},
+				})
+			}
+			_, _ = addPipe.Exec(reqCtx)
+
+			var releaseKeys []string
+			for i, c := range fresh {
+				if err := addCmds[i].Err(); err != nil {
+					results[c.index].Status = BatchStatusRejected
+					results[c.index].Reason = "failed to enqueue"
+					// Release the dedup latch so a resend of this item is not
+					// swallowed as a duplicate.
+					releaseKeys = append(releaseKeys, fmt.Sprintf("dedup:%s", c.event.EventID))
+					continue
+				}
+				eventsByProcessor.WithLabelValues(c.event.Processor).Inc()
+				results[c.index].Status = BatchStatusAccepted
+			}
+			if len(releaseKeys) > 0 {
+				if err := rdb.Del(reqCtx, releaseKeys...).Err(); err != nil {
+					log.Printf("dedup_release_error count=%d err=%v", len(releaseKeys), err)
+				}
+			}
+
+			// Stream retention: trim once per batch
+			if streamMaxLen > 0 {
+				trimmed, err := rdb.XTrimMaxLenApprox(reqCtx, streamKey, streamMaxLen, 0).Result()
+				if err != nil {
+					log.Printf("stream_trim_error err=%v", err)
+				} else if trimmed > 0 {
+					streamEvictions.WithLabelValues("maxlen").Add(float64(trimmed))
+				}
+			}
+		}
+	}
+
+	resp := BatchIngestResponse{Results: results}
+	for _, res := range results {
+		switch res.Status {
+		case BatchStatusAccepted:
+			resp.Accepted++
+			ingestAccepted.Inc()
+		case BatchStatusDuplicate:
+			resp.Duplicate++
+			ingestDuplicate.Inc()
+		default:
+			resp.Rejected++
+			ingestRejected.Inc()
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	_ = json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"payment-node/internal/apikeys"
+)
+
+func batchTestEvent(id string) Event {
+	return Event{
+		EventType:           "test_batch",
+		EventTimestamp:      time.Now().UTC().Format(time.RFC3339),
+		EventID:             id,
+		Processor:           "stripe",
+		MerchantIDHash:      "test",
+		PaymentIntentIDHash: "test",
+		FailureCategory:     "test",
+		GeoBucket:           "US",
+	}
+}
+
+func TestDecodeBatch(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		wantItems int
+		wantErr   bool
+	}{
+		{"JSON array", `[{"event_id":"a"},{"event_id":"b"}]`, 2, false},
+		{"NDJSON", "{\"event_id\":\"a\"}\n{\"event_id\":\"b\"}\n{\"event_id\":\"c\"}\n", 3, false},
+		{"NDJSON skips blank lines", "{\"event_id\":\"a\"}\n\n  \n{\"event_id\":\"b\"}", 2, false},
+		{"NDJSON keeps malformed line as item", "{\"event_id\":\"a\"}\nnot-json\n", 2, false},
+		{"Empty body", "   ", 0, true},
+		{"Empty array", "[]", 0, true},
+		{"Malformed array", `[{"event_id":"a"`, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, err := decodeBatch([]byte(tt.body))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %d items", len(items))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(items) != tt.wantItems {
+				t.Errorf("expected %d items, got %d", tt.wantItems, len(items))
+			}
+		})
+	}
+}
+
+func TestDecodeBatch_TooManyItems(t *testing.T) {
+	var sb strings.Builder
+	for i := 0; i <= maxBatchItems; i++ {
+		sb.WriteString("{}\n")
+	}
+	if _, err := decodeBatch([]byte(sb.String())); err != errBatchTooLarge {
+		t.Errorf("expected errBatchTooLarge, got %v", err)
+	}
+}
+
+func TestBatchIngest_PartialFailure(t *testing.T) {
+	setupTestRedis(t)
+	defer teardownTestRedis(t)
+
+	validAPIKeys = []string{"test-key"}
+	revokedAPIKeys = []string{}
+	ingestEnabled = true
+
+	// Pre-existing event: its dedup key is already set
+	existing := batchTestEvent("bbbbbbbb-bbbb-bbbb-bbbb-bbbbbbbbbbbb")
+	testRdb.Set(testCtx, fmt.Sprintf("dedup:%s", existing.EventID), "1", dedupTTL)
+
+	invalid := batchTestEvent("not-a-uuid")
+	fresh := batchTestEvent("cccccccc-cccc-cccc-cccc-cccccccccccc")
+
+	var body bytes.Buffer
+	for _, e := range []Event{fresh, existing, invalid, fresh} {
+		line, _ := json.Marshal(e)
+		body.Write(line)
+		body.WriteByte('\n')
+	}
+	body.WriteString("{broken\n")
+
+	req := httptest.NewRequest("POST", "/v1/events/payment_exhaust:batch", &body)
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	req.Header.Set("Authorization", "Bearer test-key")
+
+	w := httptest.NewRecorder()
+	authMiddleware(handleEventBatch)(w, req)
+
+	if w.Code != 202 {
+		t.Fatalf("expected 202, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp BatchIngestResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	wantStatuses := []string{
+		BatchStatusAccepted,  // fresh
+		BatchStatusDuplicate, // dedup key already present
+		BatchStatusRejected,  // invalid UUID
+		BatchStatusDuplicate, // repeated within the batch
+		BatchStatusRejected,  // malformed JSON line
+	}
+	if len(resp.Results) != len(wantStatuses) {
+		t.Fatalf("expected %d results, got %d", len(wantStatuses), len(resp.Results))
+	}
+	for i, want := range wantStatuses {
+		if resp.Results[i].Index != i {
+			t.Errorf("result %d: expected index %d, got %d", i, i, resp.Results[i].Index)
+		}
+		if resp.Results[i].Status != want {
+			t.Errorf("result %d: expected status %q, got %q (reason=%q)", i, want, resp.Results[i].Status, resp.Results[i].Reason)
+		}
+	}
+	if resp.Accepted != 1 || resp.Duplicate != 2 || resp.Rejected != 2 {
+		t.Errorf("unexpected totals: accepted=%d duplicate=%d rejected=%d", resp.Accepted, resp.Duplicate, resp.Rejected)
+	}
+
+	streamLen, err := testRdb.XLen(testCtx, "events_stream").Result()
+	if err != nil {
+		t.Fatalf("Failed to check stream length: %v", err)
+	}
+	if streamLen != 1 {
+		t.Errorf("Expected 1 stream entry, got %d", streamLen)
+	}
+}
+
+func TestBatchIngest_ChargesPerItem(t *testing.T) {
+	setupTestRedis(t)
+	defer teardownTestRedis(t)
+	ingestEnabled = true
+
+	path := filepath.Join(t.TempDir(), "api_keys.json")
+	registryJSON := `{"keys": [
+		{"id": "a", "sha256": "` + apikeys.Hash("key-a") + `", "workspace_id": "ws-1", "tier": "free", "scopes": ["ingest"]}
+	]}`
+	if err := os.WriteFile(path, []byte(registryJSON), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	reg, err := apikeys.NewFileRegistry(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	apiKeyRegistry = reg
+	defer func() { apiKeyRegistry = nil }()
+
+	send := func(items int) int {
+		body := strings.Repeat("{}\n", items)
+		req := httptest.NewRequest("POST", "/v1/events/payment_exhaust:batch", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer key-a")
+		w := httptest.NewRecorder()
+		scopedMiddleware(apikeys.ScopeIngest, handleEventBatch)(w, req)
+		return w.Code
+	}
+
+	// baseline: 20 tokens
+	if code := send(21); code != http.StatusRequestEntityTooLarge {
+		t.Errorf("batch over capacity: status %d, want 413", code)
+	}
+	if code := send(15); code != http.StatusAccepted {
+		t.Errorf("batch of 15: status %d, want 202", code)
+	}
+	if code := send(10); code != http.StatusTooManyRequests {
+		t.Errorf("batch of 10 with 5 tokens left: status %d, want 429", code)
+	}
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+const defaultBaseURL = "http://localhost:8080"
+
+func main() {
+	if len(os.Args) < 2 {
+		printUsage()
+		os.Exit(1)
+	}
+
+	command := os.Args[1]
+	args := os.Args[2:]
+
+	switch command {
+	case "list":
+		listEntries(args)
+	case "show":
+		if len(args) < 1 {
+			fmt.Println("Error: entry ID required")
+			fmt.Println("Usage: dlqctl show <entry_id>")
+			os.Exit(1)
+		}
+		showEntry(args[0])
+	case "replay":
+		replayEntries(args)
+	case "help", "-h", "--help":
+		printUsage()
+	default:
+		fmt.Printf("Unknown command: %s\n\n", command)
+		printUsage()
+		os.Exit(1)
+	}
+}
+
+func printUsage() {
+	fmt.Println("dlqctl - PayFlux Dead Letter Queue CLI")
+	fmt.Println()
+	fmt.Println("Usage:")
+	fmt.Println("  dlqctl list [--reason R] [--since T] [--until T] [--limit N] [--cursor ID]")
+	fmt.Println("                                           List DLQ entries (times are RFC3339)")
+	fmt.Println("  dlqctl show <entry_id>                   Show one entry (event data redacted)")
+	fmt.Println("  dlqctl replay <entry_id>...              Replay specific entries")
+	fmt.Println("  dlqctl replay --reason R [--since T] [--until T] [--dry-run]")
+	fmt.Println("                                           Replay all replayable entries for a reason")
+	fmt.Println()
+	fmt.Println("Environment Variables:")
+	fmt.Println("  PAYFLUX_API_KEY   API key (required)")
+	fmt.Println("  PAYFLUX_BASE_URL  Base URL (default: http://localhost:8080)")
+}
+
+func getBaseURL() string {
+	if u := os.Getenv("PAYFLUX_BASE_URL"); u != "" {
+		return strings.TrimSuffix(u, "/")
+	}
+	return defaultBaseURL
+}
+
+func getAPIKey() string {
+	key := os.Getenv("PAYFLUX_API_KEY")
+	if key == "" {
+		fmt.Println("Error: PAYFLUX_API_KEY environment variable not set")
+		os.Exit(1)
+	}
+	return key
+}
+
+func makeRequest(method, path string, body io.Reader) ([]byte, error) {
+	req, err := http.NewRequest(method, getBaseURL()+path, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+getAPIKey())
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := (&http.Client{}).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("request failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	return respBody, nil
+}
+
+func mustRequest(method, path string, body io.Reader) []byte {
+	respBody, err := makeRequest(method, path, body)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	return respBody
+}
+
+type entry struct {
+	ID          string          `json:"id"`
+	OriginalID  string          `json:"original_id"`
+	Reason      string          `json:"reason"`
+	Timestamp   string          `json:"timestamp"`
+	ReplayCount int             `json:"replay_count"`
+	Replayable  bool            `json:"replayable"`
+	Data        json.RawMessage `json:"data"`
+}
+
+func listEntries(args []string) {
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	reason := fs.String("reason", "", "filter by DLQ reason")
+	since := fs.String("since", "", "only entries at or after this RFC3339 time")
+	until := fs.String("until", "", "only entries at or before this RFC3339 time")
+	limit := fs.Int("limit", 0, "maximum entries to return (server default 100)")
+	cursor := fs.String("cursor", "", "continue after this entry ID")
+	_ = fs.Parse(args)
+
+	q := url.Values{}
+	for k, v := range map[string]string{"reason": *reason, "since": *since, "until": *until, "cursor": *cursor} {
+		if v != "" {
+			q.Set(k, v)
+		}
+	}
+	if *limit > 0 {
+		q.Set("limit", fmt.Sprint(*limit))
+	}
+	path := "/api/v1/dlq"
+	if len(q) > 0 {
+		path += "?" + q.Encode()
+	}
+
+	var resp struct {
+		Entries    []entry `json:"entries"`
+		NextCursor string  `json:"next_cursor"`
+	}
+	if err := json.Unmarshal(mustRequest("GET", path, nil), &resp); err != nil {
+		fmt.Printf("Error parsing output: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Found %d entries:\n\n", len(resp.Entries))
+	for _, e := range resp.Entries {
+		marker := ""
+		if !e.Replayable {
+			marker = " [REPLAY LIMIT]"
+		}
+		fmt.Printf("  %s  %s  %s  replays=%d%s\n", e.ID, e.Timestamp, e.Reason, e.ReplayCount, marker)
+	}
+	if resp.NextCursor != "" {
+		fmt.Printf("\nMore entries available: --cursor %s\n", resp.NextCursor)
+	}
+}
+
+func showEntry(id string) {
+	var e entry
+	if err := json.Unmarshal(mustRequest("GET", "/api/v1/dlq/"+url.PathEscape(id), nil), &e); err != nil {
+		fmt.Printf("Error parsing output: %v\n", err)
+		os.Exit(1)
+	}
+	prettyJSON, _ := json.MarshalIndent(e, "", "  ")
+	fmt.Println(string(prettyJSON))
+}
+
+func replayEntries(args []string) {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	reason := fs.String("reason", "", "replay every replayable entry with this reason")
+	since := fs.String("since", "", "with --reason: only entries at or after this RFC3339 time")
+	until := fs.String("until", "", "with --reason: only entries at or before this RFC3339 time")
+	dryRun := fs.Bool("dry-run", false, "report what would be replayed without changing anything")
+	_ = fs.Parse(args)
+
+	req := map[string]any{"dry_run": *dryRun}
+	switch {
+	case *reason != "" && fs.NArg() == 0:
+		req["reason"] = *reason
+		req["since"] = *since
+		req["until"] = *until
+	case *reason == "" && fs.NArg() > 0:
+		req["ids"] = fs.Args()
+	default:
+		fmt.Println("Error: pass either entry IDs or --reason")
+		os.Exit(1)
+	}
+
+	body, _ := json.Marshal(req)
+	respBody := mustRequest("POST", "/api/v1/dlq/replay", bytes.NewReader(body))
+
+	var resp struct {
+		Replayed int `json:"replayed"`
+		Skipped  int `json:"skipped"`
+		Failed   int `json:"failed"`
+		Results  []struct {
+			ID          string `json:"id"`
+			Status      string `json:"status"`
+			NewID       string `json:"new_id"`
+			ReplayCount int    `json:"replay_count"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		fmt.Printf("Error parsing output: %v\n", err)
+		os.Exit(1)
+	}
+
+	for _, r := range resp.Results {
+		fmt.Printf("  %s  %s", r.ID, r.Status)
+		if r.NewID != "" {
+			fmt.Printf(" -> %s", r.NewID)
+		}
+		fmt.Printf("  replays=%d\n", r.ReplayCount)
+	}
+	fmt.Printf("\nReplayed: %d  Skipped: %d  Failed: %d\n", resp.Replayed, resp.Skipped, resp.Failed)
+	if resp.Failed > 0 {
+		os.Exit(1)
+	}
+}
@@ -1,10 +1,33 @@
 // drift-detector is the entry point for the subscription drift detector
 // service.
 //
-// In Phase 1 (now), the detector compares subscription_projection against
-// the dashboard's canonical billing_subscriptions table. When the Stripe
-// polling worker exists (Phase 2), a new authority provider is plugged in
-// and this binary is unchanged.
+// DRIFT_AUTHORITY_PROVIDER selects what subscription_projection is
+// compared against, as a comma-separated list:
+//
+//	billing (default)  the dashboard's billing_subscriptions table (Phase 1)
+//	stripe             Stripe's subscriptions API (Phase 2); requires
+//	                   STRIPE_SECRET_KEY. STRIPE_API_BASE overrides the API
+//	                   origin (a local mock in tests and staging).
+//
+// With more than one provider, a field is drift only when DRIFT_QUORUM
+// providers disagree with the projection (default: a strict majority of
+// the providers reporting the subscription).
+//
+// The stripe provider is paced at DRIFT_STRIPE_RATE_LIMIT requests per
+// second (default 20, leaving headroom under Stripe's account-wide read
+// limit for the poller and the dashboard) and caches responses for
+// DRIFT_STRIPE_CACHE_SECONDS (default 30; 0 disables).
+//
+// DRIFT_TIMEOUT_POLICY escalates or closes drifts that stay open, as
+// comma-separated severity=duration:action rules, e.g.
+// "warning=6h:escalate,critical=72h:close". Escalation and timeout counts
+// and open-drift gauges are served on DRIFT_DETECTOR_METRICS_ADDR
+// (default :9091) at /metrics.
+//
+// DRIFT_NOTIFIERS names destinations (webhook, slack or pagerduty) told
+// once about each open drift at or above their DRIFT_NOTIFIER_<NAME>_MIN_SEVERITY
+// (default critical) and again when it resolves; see package drift/notify
+// for the per-notifier variables.
 //
 // The detector NEVER corrects either side. It observes and records.
 // Reconciliation actions are separate operational decisions.
@@ -13,17 +36,24 @@ package main
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"log/slog"
+	"net/http"
 	"os"
 	"os/signal"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	_ "github.com/lib/pq"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"golang.org/x/time/rate"
 
 	"payment-node/internal/reducer/subscription/authority"
 	"payment-node/internal/reducer/subscription/drift"
+	"payment-node/internal/reducer/subscription/drift/notify"
+	"payment-node/internal/reducer/subscription/polling"
 )
 
 func main() {
@@ -65,14 +95,68 @@ func main() {
 		os.Exit(1)
 	}
 
-	provider := authority.NewBillingProvider(db)
+	providers, err := newProviders(db)
+	if err != nil {
+		logger.Error("configure authority providers", "error", err)
+		os.Exit(2)
+	}
+	quorum := 0
+	if v := os.Getenv("DRIFT_QUORUM"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > len(providers) {
+			logger.Error("DRIFT_QUORUM must be between 1 and the number of providers", "value", v)
+			os.Exit(2)
+		}
+		quorum = n
+	}
+
+	timeouts, err := drift.ParseTimeoutPolicy(os.Getenv("DRIFT_TIMEOUT_POLICY"))
+	if err != nil {
+		logger.Error("invalid DRIFT_TIMEOUT_POLICY", "error", err)
+		os.Exit(2)
+	}
+	if len(timeouts) > 0 {
+		logger.Info("drift timeout policy configured", "policy", timeouts.String())
+	}
+
+	notifyConfigs, err := notify.ParseConfigs(os.Getenv("DRIFT_NOTIFIERS"), os.Environ())
+	if err != nil {
+		logger.Error("invalid DRIFT_NOTIFIERS", "error", err)
+		os.Exit(2)
+	}
+	var notifiers []drift.Notifier
+	for _, cfg := range notifyConfigs {
+		n, err := notify.New(cfg)
+		if err != nil {
+			logger.Error("invalid drift notifier", "error", err)
+			os.Exit(2)
+		}
+		notifiers = append(notifiers, n)
+		logger.Info("drift notifier configured", "notifier", cfg.Name, "type", cfg.Type, "min_severity", cfg.MinSeverity)
+	}
+
 	detector := &drift.Detector{
-		DB:       db,
-		Provider: provider,
-		Logger:   logger,
-		Tick:     tick,
+		DB:        db,
+		Providers: providers,
+		Quorum:    quorum,
+		Timeouts:  timeouts,
+		Notifiers: notifiers,
+		Logger:    logger,
+		Tick:      tick,
 	}
 
+	metricsAddr := os.Getenv("DRIFT_DETECTOR_METRICS_ADDR")
+	if metricsAddr == "" {
+		metricsAddr = ":9091"
+	}
+	go func() {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", promhttp.Handler())
+		if err := http.ListenAndServe(metricsAddr, mux); err != nil {
+			logger.Error("metrics listener exited", "addr", metricsAddr, "error", err)
+		}
+	}()
+
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
@@ -90,3 +174,58 @@ func main() {
 	}
 	logger.Info("detector exited cleanly")
 }
+
+func newProviders(db *sql.DB) ([]authority.Provider, error) {
+	names := os.Getenv("DRIFT_AUTHORITY_PROVIDER")
+	if names == "" {
+		names = "billing"
+	}
+	var providers []authority.Provider
+	seen := map[string]bool{}
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if seen[name] {
+			return nil, fmt.Errorf("DRIFT_AUTHORITY_PROVIDER lists %q twice", name)
+		}
+		seen[name] = true
+		p, err := newProvider(db, name)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, p)
+	}
+	return providers, nil
+}
+
+func newProvider(db *sql.DB, name string) (authority.Provider, error) {
+	switch name {
+	case "billing":
+		return authority.NewBillingProvider(db), nil
+	case "stripe":
+		apiKey := os.Getenv("STRIPE_SECRET_KEY")
+		if apiKey == "" {
+			return nil, fmt.Errorf("STRIPE_SECRET_KEY is required for the stripe provider")
+		}
+		rps := 20.0
+		if v := os.Getenv("DRIFT_STRIPE_RATE_LIMIT"); v != "" {
+			n, err := strconv.ParseFloat(v, 64)
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("DRIFT_STRIPE_RATE_LIMIT must be a positive number; got %q", v)
+			}
+			rps = n
+		}
+		ttl := 30 * time.Second
+		if v := os.Getenv("DRIFT_STRIPE_CACHE_SECONDS"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("DRIFT_STRIPE_CACHE_SECONDS must be a non-negative integer; got %q", v)
+			}
+			ttl = time.Duration(n) * time.Second
+		}
+		client := polling.NewClient(os.Getenv("STRIPE_API_BASE"), apiKey)
+		client.Limiter = rate.NewLimiter(rate.Limit(rps), 1)
+		return authority.NewStripeProvider(client, ttl), nil
+	default:
+		return nil, fmt.Errorf("unknown DRIFT_AUTHORITY_PROVIDER %q (want billing or stripe)", name)
+	}
+}
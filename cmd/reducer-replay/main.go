@@ -0,0 +1,152 @@
+// reducer-replay replays the full stripe_event_ledger through this
+// binary's subscription reducer (the candidate version) into
+// subscription_projection_shadow, and reports every subscription whose
+// candidate projection differs from the baseline version's.
+//
+// Build it from the branch that bumps ReducerVersion and run it against
+// production before cutover; the report is the review artifact for the
+// version bump. It is also persisted: the run is a replay_epochs row with
+// reducer_name 'subscription_shadow', and each difference is a row in
+// subscription_replay_diffs.
+//
+// Usage:
+//
+//	reducer-replay [-baseline v1] [-json] [-limit 50]
+//
+// The baseline defaults to the newest activated reducer_version in
+// subscription_projection. Exit status is 0 when the versions agree on
+// every subscription, 3 when there are differences, 1 on error.
+//
+// The command never writes subscription_projection, conflicts, or the
+// live reducer's cursor.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+
+	_ "github.com/lib/pq"
+
+	"payment-node/internal/reducer/subscription"
+)
+
+func main() {
+	baseline := flag.String("baseline", "", "reducer_version to compare against (default: newest projected)")
+	asJSON := flag.Bool("json", false, "print the full report as JSON")
+	limit := flag.Int("limit", 50, "differing subscriptions to list in the text report (0 = all)")
+	flag.Parse()
+
+	// Progress goes to stderr; stdout carries only the report.
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	slog.SetDefault(logger)
+
+	dsn := os.Getenv("DIRECT_URL")
+	if dsn == "" {
+		dsn = os.Getenv("DATABASE_URL")
+	}
+	if dsn == "" {
+		logger.Error("DIRECT_URL or DATABASE_URL is required")
+		os.Exit(2)
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		logger.Error("open postgres", "error", err)
+		os.Exit(1)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(2)
+
+	if err := db.Ping(); err != nil {
+		logger.Error("ping postgres", "error", err)
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	report, err := subscription.ReplayShadow(ctx, db, subscription.ShadowOptions{
+		BaselineVersion: *baseline,
+		Logger:          logger,
+	})
+	if err != nil {
+		logger.Error("shadow replay failed", "error", err)
+		os.Exit(1)
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(report)
+	} else {
+		printReport(report, *limit)
+	}
+	if len(report.Diffs) > 0 {
+		os.Exit(3)
+	}
+}
+
+func printReport(r *subscription.ShadowReport, limit int) {
+	fmt.Printf("Shadow replay %s: candidate %s vs baseline %s\n", r.EpochID, r.CandidateVersion, r.BaselineVersion)
+	fmt.Printf("Ledger through %s: %d events scanned, %d applied, %d conflicts\n",
+		r.LedgerBound.Format("2006-01-02T15:04:05Z07:00"), r.EventsScanned, r.EventsApplied, r.Conflicts)
+	fmt.Printf("Subscriptions: %d total, %d unchanged, %d changed, %d only in baseline, %d only in candidate\n",
+		r.Subscriptions, r.Unchanged,
+		r.ByKind[subscription.DiffChanged], r.ByKind[subscription.DiffOnlyBaseline], r.ByKind[subscription.DiffOnlyCandidate])
+	if len(r.Diffs) == 0 {
+		return
+	}
+
+	fields := make([]string, 0, len(r.ByField))
+	for f := range r.ByField {
+		fields = append(fields, f)
+	}
+	sort.Slice(fields, func(i, j int) bool {
+		if r.ByField[fields[i]] != r.ByField[fields[j]] {
+			return r.ByField[fields[i]] > r.ByField[fields[j]]
+		}
+		return fields[i] < fields[j]
+	})
+	if len(fields) > 0 {
+		fmt.Println()
+		fmt.Println("Changed fields:")
+		for _, f := range fields {
+			fmt.Printf("  %-24s %d\n", f, r.ByField[f])
+		}
+	}
+
+	fmt.Println()
+	for i, d := range r.Diffs {
+		if limit > 0 && i == limit {
+			fmt.Printf("... %d more (see subscription_replay_diffs WHERE replay_epoch_id = '%s')\n", len(r.Diffs)-limit, r.EpochID)
+			break
+		}
+		fmt.Printf("%s  %s\n", d.StripeSubscriptionID, d.Kind)
+		for _, f := range d.Fields {
+			fmt.Printf("    %-24s %s -> %s\n", f.Field, render(f.Baseline), render(f.Candidate))
+		}
+	}
+}
+
+func render(v any) string {
+	if v == nil {
+		return "NULL"
+	}
+	if s, ok := v.(string); ok {
+		return s
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return strings.TrimSpace(string(b))
+}
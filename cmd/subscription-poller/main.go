@@ -0,0 +1,92 @@
+// subscription-poller is the entry point for the subscription polling
+// reconciliation worker.
+//
+// The worker lists subscriptions from Stripe's API on a tick and appends
+// each changed subscription to stripe_event_ledger as a synthetic
+// customer.subscription.polled event. The reducer folds those rows with
+// polling authority. The worker never writes projections directly.
+//
+// STRIPE_API_BASE overrides the API origin (a local fake in tests and
+// staging).
+package main
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	_ "github.com/lib/pq"
+
+	"payment-node/internal/reducer/subscription/polling"
+)
+
+func main() {
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	slog.SetDefault(logger)
+
+	dsn := os.Getenv("DIRECT_URL")
+	if dsn == "" {
+		dsn = os.Getenv("DATABASE_URL")
+	}
+	if dsn == "" {
+		logger.Error("DIRECT_URL or DATABASE_URL is required")
+		os.Exit(2)
+	}
+
+	apiKey := os.Getenv("STRIPE_SECRET_KEY")
+	if apiKey == "" {
+		logger.Error("STRIPE_SECRET_KEY is required")
+		os.Exit(2)
+	}
+
+	tick := 5 * time.Minute
+	if v := os.Getenv("POLLER_TICK_SECONDS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			tick = time.Duration(n) * time.Second
+		}
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		logger.Error("open postgres", "error", err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	db.SetMaxOpenConns(2)
+	db.SetMaxIdleConns(1)
+
+	if err := db.Ping(); err != nil {
+		logger.Error("ping postgres", "error", err)
+		os.Exit(1)
+	}
+
+	worker := &polling.Worker{
+		DB:     db,
+		Client: polling.NewClient(os.Getenv("STRIPE_API_BASE"), apiKey),
+		Logger: logger,
+		Tick:   tick,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		sigCh := make(chan os.Signal, 1)
+		signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+		sig := <-sigCh
+		logger.Info("received signal; canceling poller", "signal", sig.String())
+		cancel()
+	}()
+
+	if err := worker.Run(ctx); err != nil {
+		logger.Error("poller exited with error", "error", err)
+		os.Exit(1)
+	}
+	logger.Info("poller exited cleanly")
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+)
+
+const (
+	defaultBaseURL  = "http://localhost:8080"
+	correctionsPath = "/api/v1/subscriptions/corrections"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		printUsage()
+		os.Exit(1)
+	}
+
+	command := os.Args[1]
+	args := os.Args[2:]
+
+	switch command {
+	case "correct":
+		submitCorrection(args)
+	case "corrections":
+		listCorrections(args)
+	case "help", "-h", "--help":
+		printUsage()
+	default:
+		fmt.Printf("Unknown command: %s\n\n", command)
+		printUsage()
+		os.Exit(1)
+	}
+}
+
+func printUsage() {
+	fmt.Println("subscriptionctl - PayFlux subscription projection CLI")
+	fmt.Println()
+	fmt.Println("Usage:")
+	fmt.Println("  subscriptionctl correct --set field=value [--set ...] --reason R <subscription_id>")
+	fmt.Println("                                           Record a manual correction")
+	fmt.Println("  subscriptionctl corrections [--limit N] <subscription_id>")
+	fmt.Println("                                           Show a subscription's corrections, newest first")
+	fmt.Println()
+	fmt.Println("Correctable fields: status, current_period_start, current_period_end,")
+	fmt.Println("cancel_at_period_end, canceled_at, trial_start, trial_end,")
+	fmt.Println("last_payment_status, dunning_attempt_count. Values are read as JSON when")
+	fmt.Println("they parse (true, 3, null) and as strings otherwise; timestamps are RFC3339.")
+	fmt.Println()
+	fmt.Println("Environment Variables:")
+	fmt.Println("  PAYFLUX_API_KEY   API key (required)")
+	fmt.Println("  PAYFLUX_BASE_URL  Base URL (default: http://localhost:8080)")
+	fmt.Println()
+	fmt.Println("Corrections are attributed to the API key's id.")
+}
+
+func getBaseURL() string {
+	if u := os.Getenv("PAYFLUX_BASE_URL"); u != "" {
+		return strings.TrimSuffix(u, "/")
+	}
+	return defaultBaseURL
+}
+
+func getAPIKey() string {
+	key := os.Getenv("PAYFLUX_API_KEY")
+	if key == "" {
+		fmt.Println("Error: PAYFLUX_API_KEY environment variable not set")
+		os.Exit(1)
+	}
+	return key
+}
+
+func makeRequest(method, path string, body io.Reader) ([]byte, error) {
+	req, err := http.NewRequest(method, getBaseURL()+path, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+getAPIKey())
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := (&http.Client{}).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("request failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	return respBody, nil
+}
+
+func mustRequest(method, path string, body io.Reader) []byte {
+	respBody, err := makeRequest(method, path, body)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	return respBody
+}
+
+// setFlags collects repeated --set field=value flags.
+type setFlags map[string]json.RawMessage
+
+func (s setFlags) String() string { return "" }
+
+func (s setFlags) Set(v string) error {
+	field, value, ok := strings.Cut(v, "=")
+	if !ok || field == "" {
+		return fmt.Errorf("expected field=value, got %q", v)
+	}
+	if json.Valid([]byte(value)) {
+		s[field] = json.RawMessage(value)
+		return nil
+	}
+	quoted, _ := json.Marshal(value)
+	s[field] = quoted
+	return nil
+}
+
+func submitCorrection(args []string) {
+	fields := setFlags{}
+	fs := flag.NewFlagSet("correct", flag.ExitOnError)
+	fs.Var(fields, "set", "field=value to correct (repeatable)")
+	reason := fs.String("reason", "", "why the correction is needed, e.g. an incident reference (required)")
+	_ = fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fmt.Println("Error: exactly one subscription ID required")
+		os.Exit(1)
+	}
+	if len(fields) == 0 || *reason == "" {
+		fmt.Println("Error: --set and --reason are required")
+		os.Exit(1)
+	}
+
+	body, _ := json.Marshal(map[string]any{
+		"subscription": fs.Arg(0),
+		"fields":       fields,
+		"reason":       *reason,
+	})
+	var receipt struct {
+		ID            string `json:"id"`
+		SourceEventID string `json:"source_event_id"`
+		SubmittedAt   string `json:"submitted_at"`
+	}
+	if err := json.Unmarshal(mustRequest("POST", correctionsPath, bytes.NewReader(body)), &receipt); err != nil {
+		fmt.Printf("Error parsing output: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Correction %s recorded at %s\n", receipt.ID, receipt.SubmittedAt)
+	fmt.Printf("Ledger event: %s (applied when the reducer processes it)\n", receipt.SourceEventID)
+}
+
+func listCorrections(args []string) {
+	fs := flag.NewFlagSet("corrections", flag.ExitOnError)
+	limit := fs.Int("limit", 0, "maximum corrections to return (server default 50)")
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 {
+		fmt.Println("Error: exactly one subscription ID required")
+		os.Exit(1)
+	}
+
+	q := url.Values{"subscription": {fs.Arg(0)}}
+	if *limit > 0 {
+		q.Set("limit", fmt.Sprint(*limit))
+	}
+	var resp struct {
+		Corrections []struct {
+			ID          string                     `json:"id"`
+			SubmittedAt string                     `json:"submitted_at"`
+			Operator    string                     `json:"operator"`
+			Reason      string                     `json:"reason"`
+			Fields      map[string]json.RawMessage `json:"fields"`
+			ProjectedAt *string                    `json:"projected_at"`
+		} `json:"corrections"`
+	}
+	if err := json.Unmarshal(mustRequest("GET", correctionsPath+"?"+q.Encode(), nil), &resp); err != nil {
+		fmt.Printf("Error parsing output: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Found %d corrections:\n\n", len(resp.Corrections))
+	for _, c := range resp.Corrections {
+		projected := "not projected"
+		if c.ProjectedAt != nil {
+			projected = "projected " + *c.ProjectedAt
+		}
+		fmt.Printf("  %s  %s  by %s  [%s]\n", c.ID, c.SubmittedAt, c.Operator, projected)
+		names := make([]string, 0, len(c.Fields))
+		for name := range c.Fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Printf("      %s = %s\n", name, c.Fields[name])
+		}
+		fmt.Printf("      reason: %s\n", c.Reason)
+	}
+}
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Consumer worker pool.
+//
+// consumeEvents reads a batch (XAUTOCLAIM or XREADGROUP) and hands it to
+// consumerPool.processBatch, which:
+//
+//  1. fetches every message's delivery count in one pipelined XPENDING round-trip
+//  2. decodes and validates messages, sending rejects to the DLQ
+//  3. checks every idempotency latch in one pipelined EXISTS round-trip
+//  4. exports on PAYFLUX_CONSUMER_WORKERS goroutines, sharded by
+//     PaymentIntentIDHash so events for one payment intent are exported in
+//     stream order by a single worker, setting each message's latch right
+//     after its export succeeds
+//  5. ACKs every exported (or duplicate) message with one multi-ID XACK
+//
+// At-least-once is preserved: a message is latched and ACKed only after its
+// export succeeds, so a process that dies mid-batch leaves the unexported
+// messages unlatched and their redelivery is exported, not skipped. When an
+// export fails, later messages for the same payment intent in the batch are
+// held back unexported (and un-ACKed) so redelivery keeps their order.
+//
+// The batch is a barrier: the next read starts only after every worker has
+// finished, so ordering also holds across batches.
+
+const (
+	defaultConsumerWorkers   = 1
+	defaultConsumerBatchSize = 50
+)
+
+var (
+	consumerWorkers   = defaultConsumerWorkers   // PAYFLUX_CONSUMER_WORKERS
+	consumerBatchSize = defaultConsumerBatchSize // PAYFLUX_CONSUMER_BATCH_SIZE
+)
+
+// consumerPool processes stream batches. export is exportEvent in production
+// and a stub in tests.
+type consumerPool struct {
+	workers int
+	export  func(Event, string) error
+}
+
+func newConsumerPool(workers int) *consumerPool {
+	if workers < 1 {
+		workers = 1
+	}
+	return &consumerPool{workers: workers, export: exportEvent}
+}
+
+// batchMessage is a decoded message awaiting export.
+type batchMessage struct {
+	msg      redis.XMessage
+	event    Event
+	latchKey string
+}
+
+// orderingKey groups messages that must be exported in stream order. Events
+// without a payment intent have no ordering constraint and are spread by ID.
+func (m batchMessage) orderingKey() string {
+	if m.event.PaymentIntentIDHash != "" {
+		return m.event.PaymentIntentIDHash
+	}
+	return m.msg.ID
+}
+
+// processBatch exports msgs and ACKs the ones that succeeded. msgs must be
+// in stream order.
+func (p *consumerPool) processBatch(ctx context.Context, msgs []redis.XMessage) {
+	if len(msgs) == 0 {
+		return
+	}
+	consumerBatchSizeHist.Observe(float64(len(msgs)))
+
+	retries := fetchRetryCounts(ctx, msgs)
+
+	valid := make([]batchMessage, 0, len(msgs))
+	for _, msg := range msgs {
+		if n := retries[msg.ID]; n > maxRetries {
+			log.Printf("dlq_max_retries id=%s retries=%d", msg.ID, n)
+			_ = sendToDlq(ctx, msg, "max_retries_exceeded")
+			continue
+		}
+		event, reason := decodeStreamMessage(msg)
+		if reason != "" {
+			_ = sendToDlq(ctx, msg, reason)
+			continue
+		}
+		log.Printf("event_processed id=%s type=%s processor=%s",
+			msg.ID, event.EventType, event.Processor)
+		consumerProcessed.Inc()
+		valid = append(valid, batchMessage{
+			msg:      msg,
+			event:    event,
+			latchKey: fmt.Sprintf("processed:%s:%s", streamKey, msg.ID),
+		})
+	}
+
+	fresh, ackIDs := skipDuplicates(ctx, valid)
+
+	shards := make([][]batchMessage, p.workers)
+	for _, m := range fresh {
+		h := fnv.New32a()
+		h.Write([]byte(m.orderingKey()))
+		i := int(h.Sum32() % uint32(p.workers))
+		shards[i] = append(shards[i], m)
+	}
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for _, shard := range shards {
+		if len(shard) == 0 {
+			continue
+		}
+		wg.Add(1)
+		go func(shard []batchMessage) {
+			defer wg.Done()
+			exported := p.exportShard(ctx, shard)
+			mu.Lock()
+			ackIDs = append(ackIDs, exported...)
+			mu.Unlock()
+		}(shard)
+	}
+	wg.Wait()
+
+	ackMessages(ackIDs)
+}
+
+// exportShard exports one worker's messages in order and latches each one as
+// soon as its export succeeds. Returns the IDs to ACK; messages after a
+// failure for the same ordering key are left pending.
+func (p *consumerPool) exportShard(ctx context.Context, shard []batchMessage) (exported []string) {
+	blocked := make(map[string]bool)
+	for _, m := range shard {
+		key := m.orderingKey()
+		if blocked[key] {
+			continue
+		}
+		ok, dlqd := p.safeExport(ctx, m)
+		switch {
+		case ok:
+			setLatch(m)
+			exported = append(exported, m.msg.ID)
+		case dlqd:
+			// Removed from the stream; does not block its successors.
+		default:
+			blocked[key] = true
+		}
+	}
+	return exported
+}
+
+// safeExport runs the export with panic recovery. A message that panics
+// repeatedly is sent to the DLQ (which ACKs it) and reported as dlqd.
+func (p *consumerPool) safeExport(ctx context.Context, m batchMessage) (ok, dlqd bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("consumer_panic_recovered message_id=%s panic=%v", m.msg.ID, r)
+			ok = false
+
+			v, _ := poisonCounts.LoadOrStore(m.msg.ID, 0)
+			n := v.(int) + 1
+			if n >= 5 {
+				log.Printf("poison_message_detected message_id=%s failures=%d", m.msg.ID, n)
+				dlqd = sendToDlq(ctx, m.msg, "poison_message_repeated_panics") == nil
+				poisonCounts.Delete(m.msg.ID)
+				return
+			}
+			poisonCounts.Store(m.msg.ID, n)
+		}
+	}()
+
+	if err := p.export(m.event, m.msg.ID); err != nil {
+		log.Printf("export_failed event_id=%s reason=%v", m.event.EventID, err)
+		exportFailStreak.Add(1)
+		return false, false
+	}
+	exportFailStreak.Store(0)
+	return true, false
+}
+
+// decodeStreamMessage extracts the event from a stream message. A non-empty
+// reason means the message is malformed and belongs in the DLQ.
+func decodeStreamMessage(msg redis.XMessage) (Event, string) {
+	dataAny, ok := msg.Values["data"]
+	if !ok {
+		log.Printf("message_missing_data id=%s", msg.ID)
+		return Event{}, "missing_data_field"
+	}
+	dataStr, ok := dataAny.(string)
+	if !ok {
+		log.Printf("message_data_not_string id=%s", msg.ID)
+		return Event{}, "invalid_data_type"
+	}
+	var event Event
+	if err := json.Unmarshal([]byte(dataStr), &event); err != nil {
+		log.Printf("event_unmarshal_error id=%s err=%v", msg.ID, err)
+		return Event{}, "unmarshal_failed"
+	}
+	return event, ""
+}
+
+// fetchRetryCounts returns each message's delivery count from XPENDING in a
+// single pipelined round-trip. Messages missing from the result (or all of
+// them, on error) are treated as first deliveries.
+func fetchRetryCounts(ctx context.Context, msgs []redis.XMessage) map[string]int64 {
+	counts := make(map[string]int64, len(msgs))
+	pipe := rdb.Pipeline()
+	cmds := make([]*redis.XPendingExtCmd, len(msgs))
+	for i, msg := range msgs {
+		cmds[i] = pipe.XPendingExt(ctx, &redis.XPendingExtArgs{
+			Stream: streamKey,
+			Group:  groupName,
+			Start:  msg.ID,
+			End:    msg.ID,
+			Count:  1,
+		})
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("xpending_pipeline_error count=%d err=%v", len(msgs), err)
+	}
+	for i, msg := range msgs {
+		counts[msg.ID] = 1
+		if pending, err := cmds[i].Result(); err == nil && len(pending) == 1 {
+			counts[msg.ID] = pending[0].RetryCount
+		}
+	}
+	return counts
+}
+
+// skipDuplicates checks every message's idempotency latch in one pipelined
+// EXISTS round-trip. A set latch means an earlier delivery was exported but
+// not ACKed: those IDs are returned for ACK. If the pipeline fails every
+// message is treated as fresh (fail-open) so no event is dropped.
+func skipDuplicates(ctx context.Context, msgs []batchMessage) (fresh []batchMessage, duplicates []string) {
+	if len(msgs) == 0 {
+		return nil, nil
+	}
+	rctx, cancel := context.WithTimeout(ctx, redisWriteTimeout)
+	defer cancel()
+
+	pipe := rdb.Pipeline()
+	cmds := make([]*redis.IntCmd, len(msgs))
+	for i, m := range msgs {
+		cmds[i] = pipe.Exists(rctx, m.latchKey)
+	}
+	_, _ = pipe.Exec(rctx)
+
+	for i, m := range msgs {
+		n, err := cmds[i].Result()
+		if err == nil && n > 0 {
+			log.Printf("duplicate_skipped message_id=%s", m.msg.ID)
+			duplicates = append(duplicates, m.msg.ID)
+			continue
+		}
+		fresh = append(fresh, m)
+	}
+	return fresh, duplicates
+}
+
+// setLatch records that m was exported, so a redelivery before its ACK lands
+// is ACKed without re-export. It runs on a fresh context: the export has
+// already happened even if the consumer is shutting down. A failed write only
+// risks a duplicate export.
+func setLatch(m batchMessage) {
+	wctx, cancel := context.WithTimeout(context.Background(), redisWriteTimeout)
+	defer cancel()
+	if err := rdb.Set(wctx, m.latchKey, "1", idempotencyTTL).Err(); err != nil {
+		log.Printf("latch_set_error message_id=%s err=%v", m.msg.ID, err)
+	}
+}
+
+// ackMessages ACKs ids with a single multi-ID XACK, retrying briefly. It
+// runs on a fresh context so exports finished during shutdown are still
+// ACKed after the consumer context is cancelled.
+func ackMessages(ids []string) {
+	if len(ids) == 0 {
+		return
+	}
+	ackCtx, cancel := context.WithTimeout(context.Background(), ackTimeout)
+	defer cancel()
+	var ackErr error
+	for i := 0; i < 3; i++ {
+		ackErr = rdb.XAck(ackCtx, streamKey, groupName, ids...).Err()
+		if ackErr == nil {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	log.Printf("xack_error count=%d first_id=%s retries=3 err=%v", len(ids), ids[0], ackErr)
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// readBatch adds events to the test stream and reads them back through the
+// consumer group, so they are pending exactly as in production.
+func readBatch(t *testing.T, events []Event, raw ...string) []redis.XMessage {
+	t.Helper()
+	for _, ev := range events {
+		data, _ := json.Marshal(ev)
+		testRdb.XAdd(testCtx, &redis.XAddArgs{Stream: streamKey, Values: map[string]any{"data": string(data)}})
+	}
+	for _, r := range raw {
+		testRdb.XAdd(testCtx, &redis.XAddArgs{Stream: streamKey, Values: map[string]any{"data": r}})
+	}
+	streams, err := testRdb.XReadGroup(testCtx, &redis.XReadGroupArgs{
+		Group: groupName, Consumer: "test-consumer", Streams: []string{streamKey, ">"}, Count: 100,
+	}).Result()
+	if err != nil {
+		t.Fatalf("xreadgroup: %v", err)
+	}
+	return streams[0].Messages
+}
+
+func groupPendingCount(t *testing.T) int64 {
+	t.Helper()
+	p, err := testRdb.XPending(testCtx, streamKey, groupName).Result()
+	if err != nil {
+		t.Fatalf("xpending: %v", err)
+	}
+	return p.Count
+}
+
+func TestConsumerPool_PerKeyOrdering(t *testing.T) {
+	setupTestRedis(t)
+	defer teardownTestRedis(t)
+
+	var events []Event
+	for i := 0; i < 40; i++ {
+		events = append(events, Event{EventID: fmt.Sprintf("e%02d", i), PaymentIntentIDHash: fmt.Sprintf("pi_%d", i%4)})
+	}
+	msgs := readBatch(t, events)
+
+	var mu sync.Mutex
+	seen := make(map[string][]string)
+	pool := newConsumerPool(8)
+	pool.export = func(ev Event, _ string) error {
+		mu.Lock()
+		seen[ev.PaymentIntentIDHash] = append(seen[ev.PaymentIntentIDHash], ev.EventID)
+		mu.Unlock()
+		return nil
+	}
+	pool.processBatch(testCtx, msgs)
+
+	for key, ids := range seen {
+		for i := 1; i < len(ids); i++ {
+			if ids[i-1] >= ids[i] {
+				t.Errorf("%s exported out of order: %v", key, ids)
+				break
+			}
+		}
+	}
+	if n := groupPendingCount(t); n != 0 {
+		t.Errorf("expected all messages ACKed, %d pending", n)
+	}
+
+	// Redelivery of already-exported messages is ACKed without re-export.
+	calls := 0
+	pool.export = func(Event, string) error { calls++; return nil }
+	pool.processBatch(testCtx, msgs)
+	if calls != 0 {
+		t.Errorf("duplicates should not be re-exported, got %d exports", calls)
+	}
+}
+
+func TestConsumerPool_FailureHoldsKeyWithoutLatch(t *testing.T) {
+	setupTestRedis(t)
+	defer teardownTestRedis(t)
+
+	msgs := readBatch(t, []Event{
+		{EventID: "a1", PaymentIntentIDHash: "pi_a"},
+		{EventID: "a2", PaymentIntentIDHash: "pi_a"},
+		{EventID: "b1", PaymentIntentIDHash: "pi_b"},
+	})
+
+	var exported []string
+	pool := newConsumerPool(1)
+	pool.export = func(ev Event, _ string) error {
+		if ev.EventID == "a1" {
+			return errors.New("sink down")
+		}
+		exported = append(exported, ev.EventID)
+		return nil
+	}
+	pool.processBatch(testCtx, msgs)
+
+	if len(exported) != 1 || exported[0] != "b1" {
+		t.Errorf("expected only b1 exported while pi_a is blocked, got %v", exported)
+	}
+	if n := groupPendingCount(t); n != 2 {
+		t.Errorf("expected a1 and a2 pending, got %d", n)
+	}
+	for _, m := range msgs[:2] {
+		key := fmt.Sprintf("processed:%s:%s", streamKey, m.ID)
+		if n, _ := testRdb.Exists(testCtx, key).Result(); n != 0 {
+			t.Errorf("latch %s should not be set before export succeeds", key)
+		}
+	}
+
+	// Retry succeeds in order.
+	exported = nil
+	pool.export = func(ev Event, _ string) error { exported = append(exported, ev.EventID); return nil }
+	pool.processBatch(testCtx, msgs[:2])
+	if len(exported) != 2 || exported[0] != "a1" || exported[1] != "a2" {
+		t.Errorf("expected a1, a2 on retry, got %v", exported)
+	}
+	if n := groupPendingCount(t); n != 0 {
+		t.Errorf("expected nothing pending after retry, got %d", n)
+	}
+}
+
+// A batch finishing after shutdown still latches and ACKs what it exported.
+func TestConsumerPool_CancelledContextStillAcks(t *testing.T) {
+	setupTestRedis(t)
+	defer teardownTestRedis(t)
+
+	msgs := readBatch(t, []Event{{EventID: "c1", PaymentIntentIDHash: "pi_c"}})
+
+	ctx, cancel := context.WithCancel(testCtx)
+	cancel()
+	pool := newConsumerPool(1)
+	pool.export = func(Event, string) error { return nil }
+	pool.processBatch(ctx, msgs)
+
+	if n := groupPendingCount(t); n != 0 {
+		t.Errorf("expected the exported message ACKed, %d pending", n)
+	}
+	key := fmt.Sprintf("processed:%s:%s", streamKey, msgs[0].ID)
+	if n, _ := testRdb.Exists(testCtx, key).Result(); n != 1 {
+		t.Errorf("latch %s should be set after export", key)
+	}
+}
+
+func TestConsumerPool_MalformedToDlq(t *testing.T) {
+	setupTestRedis(t)
+	defer teardownTestRedis(t)
+
+	msgs := readBatch(t, []Event{{EventID: "ok", PaymentIntentIDHash: "pi"}}, "{not json")
+
+	pool := newConsumerPool(2)
+	pool.export = func(Event, string) error { return nil }
+	pool.processBatch(testCtx, msgs)
+
+	dlq, err := testRdb.XRange(testCtx, dlqKey, "-", "+").Result()
+	if err != nil {
+		t.Fatalf("xrange dlq: %v", err)
+	}
+	if len(dlq) != 1 || dlq[0].Values["reason"] != "unmarshal_failed" {
+		t.Fatalf("expected one unmarshal_failed DLQ entry, got %v", dlq)
+	}
+	if n := groupPendingCount(t); n != 0 {
+		t.Errorf("expected nothing pending, got %d", n)
+	}
+}
@@ -0,0 +1,389 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"payment-node/internal/logsafe"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DLQ inspection and replay.
+//
+// Entries written by sendToDlq carry data, original_id, reason, timestamp and
+// replay_count. Replaying an entry re-enqueues its data onto events_stream
+// with replay_count+1 and removes it from the DLQ. If the replayed message
+// fails again, sendToDlq copies replay_count back into the new DLQ entry, so
+// an entry that keeps failing stops being replayable once it reaches
+// maxDlqReplays instead of looping between the two streams forever.
+//
+// Callers whose key belongs to a workspace see and replay only entries whose
+// event carries that workspace; other entries read as not found.
+
+const (
+	maxDlqReplays     = 3
+	dlqDefaultLimit   = 100
+	dlqMaxLimit       = 1000
+	dlqReplayMaxBatch = 1000
+)
+
+// DlqEntry is the API view of a single DLQ stream entry. Data is the
+// redacted payload; raw payloads never leave the process.
+type DlqEntry struct {
+	ID          string          `json:"id"`
+	OriginalID  string          `json:"original_id"`
+	Reason      string          `json:"reason"`
+	Timestamp   time.Time       `json:"timestamp"`
+	ReplayCount int             `json:"replay_count"`
+	Replayable  bool            `json:"replayable"`
+	Data        json.RawMessage `json:"data"`
+}
+
+// DlqListResponse is returned by GET /api/v1/dlq.
+type DlqListResponse struct {
+	Entries    []DlqEntry `json:"entries"`
+	NextCursor string     `json:"next_cursor,omitempty"`
+}
+
+// DlqReplayRequest selects entries to replay: explicit IDs, or every entry
+// with the given reason (optionally bounded by since/until).
+type DlqReplayRequest struct {
+	IDs    []string `json:"ids,omitempty"`
+	Reason string   `json:"reason,omitempty"`
+	Since  string   `json:"since,omitempty"`
+	Until  string   `json:"until,omitempty"`
+	DryRun bool     `json:"dry_run,omitempty"`
+}
+
+// DLQ replay outcomes
+const (
+	DlqReplayReplayed    = "replayed"
+	DlqReplayWouldReplay = "would_replay"
+	DlqReplayExhausted   = "replay_limit_reached"
+	DlqReplayNotFound    = "not_found"
+	DlqReplayFailed      = "failed"
+)
+
+// DlqReplayResult reports what happened to one selected entry.
+type DlqReplayResult struct {
+	ID          string `json:"id"`
+	Status      string `json:"status"`
+	NewID       string `json:"new_id,omitempty"`
+	ReplayCount int    `json:"replay_count"`
+}
+
+// DlqReplayResponse is returned by POST /api/v1/dlq/replay.
+type DlqReplayResponse struct {
+	Replayed int               `json:"replayed"`
+	Skipped  int               `json:"skipped"`
+	Failed   int               `json:"failed"`
+	Results  []DlqReplayResult `json:"results"`
+}
+
+// dlqFilter narrows a DLQ scan. Zero times and an empty WorkspaceID are
+// unbounded.
+type dlqFilter struct {
+	Reason         string
+	WorkspaceID    string
+	Since          time.Time
+	Until          time.Time
+	ReplayableOnly bool
+}
+
+// dlqVisibleTo reports whether a caller confined to workspaceID may see msg.
+func dlqVisibleTo(msg redis.XMessage, workspaceID string) bool {
+	return workspaceID == "" || dlqWorkspaceID(msg) == workspaceID
+}
+
+// streamBound converts a time into an XRANGE bound. Stream IDs are
+// <unix-millis>-<seq>, so time filtering is done by Redis, not in Go.
+func streamBound(t time.Time, open string) string {
+	if t.IsZero() {
+		return open
+	}
+	return strconv.FormatInt(t.UnixMilli(), 10)
+}
+
+// replayCountOf reads the replay_count field from a stream entry (0 if absent).
+func replayCountOf(values map[string]any) int {
+	if v, ok := values["replay_count"]; ok {
+		if s, ok := v.(string); ok {
+			if n, err := strconv.Atoi(s); err == nil {
+				return n
+			}
+		}
+	}
+	return 0
+}
+
+func dlqEntryFromMessage(msg redis.XMessage) DlqEntry {
+	e := DlqEntry{ID: msg.ID}
+	if s, ok := msg.Values["original_id"].(string); ok {
+		e.OriginalID = s
+	}
+	if s, ok := msg.Values["reason"].(string); ok {
+		e.Reason = s
+	}
+	if s, ok := msg.Values["timestamp"].(string); ok {
+		if ts, err := strconv.ParseInt(s, 10, 64); err == nil {
+			e.Timestamp = time.Unix(ts, 0).UTC()
+		}
+	}
+	e.ReplayCount = replayCountOf(msg.Values)
+	e.Replayable = e.ReplayCount < maxDlqReplays
+	raw, _ := msg.Values["data"].(string)
+	e.Data = json.RawMessage(logsafe.RedactJSON([]byte(raw)))
+	return e
+}
+
+// scanDlq returns up to limit entries matching f, oldest first, starting
+// after cursor (an exclusive stream ID). The returned cursor is non-empty
+// when more entries may remain.
+func scanDlq(ctx context.Context, f dlqFilter, cursor string, limit int) ([]redis.XMessage, string, error) {
+	start := streamBound(f.Since, "-")
+	if cursor != "" {
+		start = "(" + cursor
+	}
+	end := "+"
+	if !f.Until.IsZero() {
+		end = strconv.FormatInt(f.Until.UnixMilli(), 10)
+	}
+
+	var out []redis.XMessage
+	for len(out) < limit {
+		page, err := rdb.XRangeN(ctx, dlqKey, start, end, int64(limit)).Result()
+		if err != nil {
+			return nil, "", err
+		}
+		for _, msg := range page {
+			start = "(" + msg.ID
+			if f.Reason != "" {
+				if reason, _ := msg.Values["reason"].(string); reason != f.Reason {
+					continue
+				}
+			}
+			if f.ReplayableOnly && replayCountOf(msg.Values) >= maxDlqReplays {
+				continue
+			}
+			if !dlqVisibleTo(msg, f.WorkspaceID) {
+				continue
+			}
+			out = append(out, msg)
+			if len(out) == limit {
+				return out, msg.ID, nil
+			}
+		}
+		if len(page) < limit {
+			return out, "", nil
+		}
+	}
+	return out, "", nil
+}
+
+// replayDlqMessage re-enqueues msg onto the main stream with an incremented
+// replay counter and deletes it from the DLQ.
+func replayDlqMessage(ctx context.Context, msg redis.XMessage, dryRun bool) DlqReplayResult {
+	count := replayCountOf(msg.Values)
+	res := DlqReplayResult{ID: msg.ID, ReplayCount: count}
+	if count >= maxDlqReplays {
+		res.Status = DlqReplayExhausted
+		dlqReplayed.WithLabelValues(res.Status).Inc()
+		return res
+	}
+	if dryRun {
+		res.Status = DlqReplayWouldReplay
+		return res
+	}
+
+	raw, _ := msg.Values["data"].(string)
+	wctx, cancel := context.WithTimeout(ctx, redisWriteTimeout)
+	defer cancel()
+
+	newID, err := rdb.XAdd(wctx, &redis.XAddArgs{
+		Stream: streamKey,
+		Values: map[string]any{"data": raw, "replay_count": count + 1},
+	}).Result()
+	if err != nil {
+		log.Printf("dlq_replay_error id=%s err=%v", msg.ID, err)
+		res.Status = DlqReplayFailed
+		dlqReplayed.WithLabelValues(res.Status).Inc()
+		return res
+	}
+	// Once re-enqueued the entry must leave the DLQ, otherwise a second
+	// replay would enqueue it twice. A failed XDEL is logged, not retried:
+	// the consumer-side latch is keyed by stream ID, so a duplicate would
+	// still be exported once more.
+	if err := rdb.XDel(wctx, dlqKey, msg.ID).Err(); err != nil {
+		log.Printf("dlq_replay_xdel_error id=%s err=%v", msg.ID, err)
+	}
+
+	res.Status = DlqReplayReplayed
+	res.NewID = newID
+	res.ReplayCount = count + 1
+	dlqReplayed.WithLabelValues(res.Status).Inc()
+	log.Printf("dlq_replayed id=%s new_id=%s replay_count=%d", msg.ID, newID, count+1)
+	return res
+}
+
+func parseDlqTime(raw string) (time.Time, error) {
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, raw)
+}
+
+func writeDlqError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
+
+// handleDlqList serves GET /api/v1/dlq?reason=&since=&until=&limit=&cursor=
+func handleDlqList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	q := r.URL.Query()
+
+	since, err := parseDlqTime(q.Get("since"))
+	if err != nil {
+		writeDlqError(w, http.StatusBadRequest, "since must be RFC3339")
+		return
+	}
+	until, err := parseDlqTime(q.Get("until"))
+	if err != nil {
+		writeDlqError(w, http.StatusBadRequest, "until must be RFC3339")
+		return
+	}
+	limit := dlqDefaultLimit
+	if raw := q.Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			writeDlqError(w, http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+		limit = min(n, dlqMaxLimit)
+	}
+
+	filter := dlqFilter{Reason: q.Get("reason"), WorkspaceID: requestWorkspaceID(r), Since: since, Until: until}
+	msgs, next, err := scanDlq(r.Context(), filter, q.Get("cursor"), limit)
+	if err != nil {
+		log.Printf("dlq_list_error err=%v", err)
+		writeDlqError(w, http.StatusServiceUnavailable, "dlq unavailable")
+		return
+	}
+
+	resp := DlqListResponse{Entries: make([]DlqEntry, 0, len(msgs)), NextCursor: next}
+	for _, msg := range msgs {
+		resp.Entries = append(resp.Entries, dlqEntryFromMessage(msg))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// handleDlqEntry serves GET /api/v1/dlq/{id}
+func handleDlqEntry(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id := strings.TrimPrefix(r.URL.Path, "/api/v1/dlq/")
+	msgs, err := rdb.XRangeN(r.Context(), dlqKey, id, id, 1).Result()
+	if err != nil {
+		if strings.Contains(err.Error(), "Invalid stream ID") {
+			writeDlqError(w, http.StatusBadRequest, "invalid entry id")
+			return
+		}
+		log.Printf("dlq_get_error id=%s err=%v", id, err)
+		writeDlqError(w, http.StatusServiceUnavailable, "dlq unavailable")
+		return
+	}
+	if len(msgs) == 0 || !dlqVisibleTo(msgs[0], requestWorkspaceID(r)) {
+		writeDlqError(w, http.StatusNotFound, "dlq entry not found")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(dlqEntryFromMessage(msgs[0]))
+}
+
+// handleDlqReplay serves POST /api/v1/dlq/replay
+func handleDlqReplay(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req DlqReplayRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDlqError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	if (len(req.IDs) == 0) == (req.Reason == "") {
+		writeDlqError(w, http.StatusBadRequest, "exactly one of ids or reason is required")
+		return
+	}
+	if len(req.IDs) > dlqReplayMaxBatch {
+		writeDlqError(w, http.StatusRequestEntityTooLarge, "too many ids")
+		return
+	}
+	since, err := parseDlqTime(req.Since)
+	if err != nil {
+		writeDlqError(w, http.StatusBadRequest, "since must be RFC3339")
+		return
+	}
+	until, err := parseDlqTime(req.Until)
+	if err != nil {
+		writeDlqError(w, http.StatusBadRequest, "until must be RFC3339")
+		return
+	}
+
+	ctx := r.Context()
+	workspaceID := requestWorkspaceID(r)
+	resp := DlqReplayResponse{Results: []DlqReplayResult{}}
+	record := func(res DlqReplayResult) {
+		switch res.Status {
+		case DlqReplayReplayed, DlqReplayWouldReplay:
+			resp.Replayed++
+		case DlqReplayFailed:
+			resp.Failed++
+		default:
+			resp.Skipped++
+		}
+		resp.Results = append(resp.Results, res)
+	}
+
+	if len(req.IDs) > 0 {
+		for _, id := range req.IDs {
+			msgs, err := rdb.XRangeN(ctx, dlqKey, id, id, 1).Result()
+			if err != nil || len(msgs) == 0 || !dlqVisibleTo(msgs[0], workspaceID) {
+				record(DlqReplayResult{ID: id, Status: DlqReplayNotFound})
+				continue
+			}
+			record(replayDlqMessage(ctx, msgs[0], req.DryRun))
+		}
+	} else {
+		// Bounded per request so a large DLQ cannot hold the handler past
+		// the server's write timeout; callers repeat until replayed == 0.
+		// Exhausted entries are skipped by the scan so repeated calls make
+		// progress.
+		filter := dlqFilter{Reason: req.Reason, WorkspaceID: workspaceID, Since: since, Until: until, ReplayableOnly: true}
+		msgs, _, err := scanDlq(ctx, filter, "", dlqReplayMaxBatch)
+		if err != nil {
+			log.Printf("dlq_replay_scan_error reason=%s err=%v", req.Reason, err)
+			writeDlqError(w, http.StatusServiceUnavailable, "dlq unavailable")
+			return
+		}
+		for _, msg := range msgs {
+			record(replayDlqMessage(ctx, msg, req.DryRun))
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
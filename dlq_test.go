@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"payment-node/internal/apikeys"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func addDlqEntry(t *testing.T, reason, data string, replayCount int) string {
+	t.Helper()
+	id, err := testRdb.XAdd(testCtx, &redis.XAddArgs{
+		Stream: dlqKey,
+		Values: map[string]any{
+			"data":         data,
+			"original_id":  "0-1",
+			"reason":       reason,
+			"timestamp":    1700000000,
+			"replay_count": replayCount,
+		},
+	}).Result()
+	if err != nil {
+		t.Fatalf("xadd dlq: %v", err)
+	}
+	return id
+}
+
+func TestDlqList_FilterAndRedact(t *testing.T) {
+	setupTestRedis(t)
+	defer teardownTestRedis(t)
+
+	addDlqEntry(t, "unmarshal_failed", `{"event_id":"e1","processor":"stripe","email":"a@b.c"}`, 0)
+	addDlqEntry(t, "max_retries_exceeded", `{"event_id":"e2"}`, 0)
+	addDlqEntry(t, "unmarshal_failed", `not json`, 0)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/dlq?reason=unmarshal_failed", nil)
+	rr := httptest.NewRecorder()
+	handleDlqList(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var resp DlqListResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(resp.Entries) != 2 {
+		t.Fatalf("expected 2 unmarshal_failed entries, got %d", len(resp.Entries))
+	}
+	if strings.Contains(string(resp.Entries[0].Data), "a@b.c") {
+		t.Errorf("payload not redacted: %s", resp.Entries[0].Data)
+	}
+	if !strings.Contains(string(resp.Entries[0].Data), `"processor":"stripe"`) {
+		t.Errorf("safe fields should survive redaction: %s", resp.Entries[0].Data)
+	}
+	if !resp.Entries[0].Replayable {
+		t.Error("fresh entry should be replayable")
+	}
+
+	// Pagination
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/dlq?limit=1", nil)
+	rr = httptest.NewRecorder()
+	handleDlqList(rr, req)
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	if len(resp.Entries) != 1 || resp.NextCursor == "" {
+		t.Fatalf("expected 1 entry and a cursor, got %d cursor=%q", len(resp.Entries), resp.NextCursor)
+	}
+}
+
+func TestDlqReplay_ReplayCounterStopsLoops(t *testing.T) {
+	setupTestRedis(t)
+	defer teardownTestRedis(t)
+
+	fresh := addDlqEntry(t, "unmarshal_failed", `{"event_id":"e1"}`, 0)
+	exhausted := addDlqEntry(t, "unmarshal_failed", `{"event_id":"e2"}`, maxDlqReplays)
+	addDlqEntry(t, "max_retries_exceeded", `{"event_id":"e3"}`, 0)
+
+	body, _ := json.Marshal(DlqReplayRequest{Reason: "unmarshal_failed"})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/dlq/replay", bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+	handleDlqReplay(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var resp DlqReplayResponse
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	if resp.Replayed != 1 || len(resp.Results) != 1 || resp.Results[0].ID != fresh {
+		t.Fatalf("expected only the fresh entry replayed, got %+v", resp)
+	}
+
+	// Replayed entry is on the main stream with an incremented counter
+	msgs, _ := testRdb.XRange(testCtx, streamKey, "-", "+").Result()
+	if len(msgs) != 1 || replayCountOf(msgs[0].Values) != 1 {
+		t.Fatalf("expected one replayed message with replay_count=1, got %+v", msgs)
+	}
+	if n, _ := testRdb.XLen(testCtx, dlqKey).Result(); n != 2 {
+		t.Errorf("replayed entry should be removed from DLQ, len=%d", n)
+	}
+
+	// Explicit replay of an exhausted entry is refused
+	body, _ = json.Marshal(DlqReplayRequest{IDs: []string{exhausted, "1-1"}})
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/dlq/replay", bytes.NewReader(body))
+	rr = httptest.NewRecorder()
+	handleDlqReplay(rr, req)
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	if resp.Results[0].Status != DlqReplayExhausted || resp.Results[1].Status != DlqReplayNotFound {
+		t.Errorf("unexpected results: %+v", resp.Results)
+	}
+
+	// Failing again carries the counter back into the DLQ
+	if err := sendToDlq(testCtx, msgs[0], "unmarshal_failed"); err != nil {
+		t.Fatalf("sendToDlq: %v", err)
+	}
+	last, _ := testRdb.XRevRangeN(testCtx, dlqKey, "+", "-", 1).Result()
+	if replayCountOf(last[0].Values) != 1 {
+		t.Errorf("replay_count not carried into DLQ: %+v", last[0].Values)
+	}
+}
+
+func TestDlqReplay_RequiresSelector(t *testing.T) {
+	for _, body := range []string{`{}`, `{"ids":["1-1"],"reason":"x"}`, `nope`} {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/dlq/replay", strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		handleDlqReplay(rr, req)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("body %s: expected 400, got %d", body, rr.Code)
+		}
+	}
+}
+
+func TestDlq_WorkspaceKeySeesOwnEntries(t *testing.T) {
+	setupTestRedis(t)
+	defer teardownTestRedis(t)
+
+	own := addDlqEntry(t, "unmarshal_failed", `{"event_id":"e1","workspace_id":"ws-acme"}`, 0)
+	other := addDlqEntry(t, "unmarshal_failed", `{"event_id":"e2","workspace_id":"ws-other"}`, 0)
+	addDlqEntry(t, "unmarshal_failed", `not json`, 0)
+
+	asAcme := func(req *http.Request) *http.Request {
+		id := &apikeys.Identity{KeyID: "acme-admin", WorkspaceID: "ws-acme", Scopes: []string{apikeys.ScopeAdmin}}
+		return req.WithContext(apikeys.WithIdentity(req.Context(), id))
+	}
+
+	rr := httptest.NewRecorder()
+	handleDlqList(rr, asAcme(httptest.NewRequest(http.MethodGet, "/api/v1/dlq", nil)))
+	var list DlqListResponse
+	json.Unmarshal(rr.Body.Bytes(), &list)
+	if len(list.Entries) != 1 || list.Entries[0].ID != own {
+		t.Fatalf("workspace list = %+v, want only %s", list.Entries, own)
+	}
+
+	rr = httptest.NewRecorder()
+	handleDlqEntry(rr, asAcme(httptest.NewRequest(http.MethodGet, "/api/v1/dlq/"+other, nil)))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("other workspace's entry: status %d, want 404", rr.Code)
+	}
+
+	body, _ := json.Marshal(DlqReplayRequest{IDs: []string{other}, DryRun: true})
+	rr = httptest.NewRecorder()
+	handleDlqReplay(rr, asAcme(httptest.NewRequest(http.MethodPost, "/api/v1/dlq/replay", bytes.NewReader(body))))
+	var replay DlqReplayResponse
+	json.Unmarshal(rr.Body.Bytes(), &replay)
+	if len(replay.Results) != 1 || replay.Results[0].Status != DlqReplayNotFound {
+		t.Errorf("replay of other workspace's entry = %+v, want not_found", replay.Results)
+	}
+}
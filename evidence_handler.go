@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"os"
 	"payment-node/internal/evidence"
+	"payment-node/internal/tier"
 	"strconv"
 	"sync/atomic"
 	"time"
@@ -92,18 +93,27 @@ func handleEvidence(w http.ResponseWriter, r *http.Request) {
 		SourceStatus: "OK",
 	}
 
+	// Raw warning records are evidence export; without it artifacts carry
+	// only their summary fields.
+	gate := tier.NewGate(r)
+	exportData := gate.Allow(tier.FeatureEvidenceExport)
+
 	if warningStore != nil {
-		allWarnings := warningStore.List(5000, "")
+		allWarnings := warningStore.List(5000, "", requestWorkspaceID(r))
 		for _, rw := range allWarnings {
 			sev := mapSeverity(rw.RiskBand)
 
 			// Artifact
+			var data interface{}
+			if exportData {
+				data = gateWarning(gate, rw)
+			}
 			artifacts = append(artifacts, evidence.ArtifactSource{
 				ID:        rw.WarningID,
 				Timestamp: rw.ProcessedAt.UTC().Format(time.RFC3339),
 				Entity:    rw.MerchantIDHash,
 				Severity:  sev,
-				Data:      rw,
+				Data:      data,
 			})
 
 			// Narrative
@@ -134,6 +144,7 @@ func handleEvidence(w http.ResponseWriter, r *http.Request) {
 	env := evidence.GenerateEnvelope(merchants, artifacts, narratives, sys, meta)
 
 	// 5. Emit
+	gate.SetHeaders(w.Header())
 	w.Header().Set(hdrContentType, valApplicationJSON)
 	w.Header().Set(hdrCacheControl, valNoStore)
 	json.NewEncoder(w).Encode(env)
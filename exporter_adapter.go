@@ -5,6 +5,8 @@ package main
 // This file wires the live globals in main.go to the internal/exporter pipeline
 // by implementing exporter.ExportWriter, exporter.HealthTracker,
 // exporter.ExportMetrics, exporter.RiskScorer, and exporter.WarningStore.
+// Pluggable sinks (exporter.Sink) are built from PAYFLUX_EXPORT_SINKS in
+// setupExport() and passed through unchanged.
 //
 // None of these types escape this file: they are constructed in buildExporter()
 // and stored in exporterInstance; all access goes through the interface values
@@ -20,6 +22,7 @@ package main
 import (
 	"bufio"
 	"os"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -43,7 +46,7 @@ func buildExporter() *exporter.Exporter {
 	// the riskScorer == nil guard in exporter.scoreRisk.
 	var rs exporter.RiskScorer
 	if riskScorer != nil {
-		rs = &riskScorerAdapter{s: riskScorer}
+		rs = &riskScorerAdapter{s: riskScorer, shadow: shadowScorer, cmp: shadowComparator}
 	}
 
 	// WarningStore adapter is nil when pilot mode is disabled, which matches
@@ -53,6 +56,13 @@ func buildExporter() *exporter.Exporter {
 		ws = &warningSinkAdapter{s: warningStore}
 	}
 
+	// Assigned only when non-nil so the interface stays nil when push
+	// delivery is off (avoids a typed-nil notifier).
+	var wn exporter.WarningNotifier
+	if warningDispatcher != nil {
+		wn = warningDispatcher
+	}
+
 	return exporter.New(exporter.Config{
 		// Step 2
 		ConsumerName: consumerNameGlobal,
@@ -72,6 +82,10 @@ func buildExporter() *exporter.Exporter {
 		Health: &healthAdapter{},
 		// Step 7
 		Metrics: &metricsAdapter{},
+		// Pluggable sinks (built in setupExport)
+		Sinks: exportSinks,
+		// Warning push (built in setupWarningWebhooks)
+		WarningNotifier: wn,
 	})
 }
 
@@ -82,10 +96,16 @@ func buildExporter() *exporter.Exporter {
 // WriteFile delegates to the live *bufio.Writer and flushes after each write
 // (matching the Write + Flush pair in exportEvent, collapsing the two error
 // paths into one as documented in output.go).
+//
+// WriteFile is serialized by exportFileMu: consumer workers export
+// concurrently and *bufio.Writer is not safe for concurrent use. Retention
+// holds the same lock while it rewrites the file (pruneExportFile).
 type exportWriterAdapter struct {
 	writer *bufio.Writer // nil when exportMode == "stdout"
 }
 
+var exportFileMu sync.Mutex
+
 func (a *exportWriterAdapter) WriteStdout(data []byte) (int, error) {
 	return os.Stdout.Write(data)
 }
@@ -94,6 +114,8 @@ func (a *exportWriterAdapter) WriteFile(data []byte) (int, error) {
 	if a.writer == nil {
 		return 0, nil // defensive: caller guards on ExportMode before calling writeFile
 	}
+	exportFileMu.Lock()
+	defer exportFileMu.Unlock()
 	n, err := a.writer.Write(data)
 	if err != nil {
 		return n, err
@@ -113,8 +135,25 @@ func (a *exportWriterAdapter) Mode() string { return exportMode }
 // of exportEvent() (main.go:1844–1847, 1872–1875).
 // MarkFailure mirrors the "Record error timestamp" blocks (main.go:1838–1840,
 // 1856–1858, 1866–1868).
+//
+// Pluggable sinks (any other dest) are tracked by name in sinkHealth so
+// /export/health can report them alongside stdout and file.
 type healthAdapter struct{}
 
+// sinkHealthState holds the unix timestamps for one pluggable sink.
+type sinkHealthState struct {
+	lastSuccess atomic.Int64
+	lastError   atomic.Int64
+}
+
+// sinkHealth maps sink name -> *sinkHealthState.
+var sinkHealth sync.Map
+
+func sinkHealthFor(dest string) *sinkHealthState {
+	v, _ := sinkHealth.LoadOrStore(dest, &sinkHealthState{})
+	return v.(*sinkHealthState)
+}
+
 func (h *healthAdapter) MarkSuccess(dest string) {
 	now := time.Now().Unix()
 	switch dest {
@@ -122,6 +161,8 @@ func (h *healthAdapter) MarkSuccess(dest string) {
 		atomic.StoreInt64(&exportLastSuccessStdout, now)
 	case "file":
 		atomic.StoreInt64(&exportLastSuccessFile, now)
+	default:
+		sinkHealthFor(dest).lastSuccess.Store(now)
 	}
 	exportLastSuccess.WithLabelValues(dest).Set(float64(now))
 }
@@ -133,6 +174,8 @@ func (h *healthAdapter) MarkFailure(dest, reason string) {
 		atomic.StoreInt64(&exportLastErrorStdout, now)
 	case "file":
 		atomic.StoreInt64(&exportLastErrorFile, now)
+	default:
+		sinkHealthFor(dest).lastError.Store(now)
 	}
 	exportLastErrorReason.Store(dest, reason)
 }
@@ -163,6 +206,10 @@ func (m *metricsAdapter) SetRiskScoreLast(processor string, score float64) {
 	riskScoreLast.WithLabelValues(processor).Set(score)
 }
 
+func (m *metricsAdapter) IncMerchantRiskEvent(band string) {
+	merchantRiskEventsTotal.WithLabelValues(band).Inc()
+}
+
 func (m *metricsAdapter) IncTier2Context()  { tier2ContextEmitted.Inc() }
 func (m *metricsAdapter) IncTier2Trajectory() { tier2TrajectoryEmitted.Inc() }
 
@@ -186,12 +233,21 @@ func (m *metricsAdapter) ObserveExportDuration(dest string, seconds float64) {
 // main.RiskResult → exporter.RiskResult: json tags differ (main carries
 // "processor_risk_score" etc.; exporter.RiskResult has no json tags), so a
 // direct conversion is not valid. An explicit field-by-field copy is used.
+//
+// When shadow scoring is configured the event is also recorded in the shadow
+// scorer and both results go to the comparator; only the primary result is
+// returned to the pipeline.
 type riskScorerAdapter struct {
-	s *RiskScorer
+	s      *RiskScorer
+	shadow *RiskScorer       // nil unless shadow scoring is enabled
+	cmp    *ShadowComparator // nil unless shadow scoring is enabled
 }
 
 func (a *riskScorerAdapter) RecordEvent(ev exporter.Event) exporter.RiskResult {
 	res := a.s.RecordEvent(Event(ev)) // Event(ev): direct conversion (same layout)
+	if a.shadow != nil && a.cmp != nil {
+		a.cmp.Observe(ev.Processor, res, a.shadow.RecordEvent(Event(ev)))
+	}
 	return exporter.RiskResult{
 		Score:                res.Score,
 		Band:                 res.Band,
@@ -201,6 +257,11 @@ func (a *riskScorerAdapter) RecordEvent(ev exporter.Event) exporter.RiskResult {
 		TrajectoryWindowSec:  res.TrajectoryWindowSec,
 		CurrentFailureRate:   res.CurrentFailureRate,
 		BaselineFailureRate:  res.BaselineFailureRate,
+		ModelVersion:         res.ModelVersion,
+		MerchantScored:       res.MerchantScored,
+		MerchantScore:        res.MerchantScore,
+		MerchantBand:         res.MerchantBand,
+		MerchantDrivers:      res.MerchantDrivers,
 	}
 }
 
@@ -211,7 +272,7 @@ func (a *riskScorerAdapter) RecordEvent(ev exporter.Event) exporter.RiskResult {
 // *exporter.Warning → *main.Warning: both types have identical field names,
 // types, json tags, and order; direct pointer conversion is valid.
 type warningSinkAdapter struct {
-	s *WarningStore
+	s WarningStorage
 }
 
 func (a *warningSinkAdapter) Add(w *exporter.Warning) {
@@ -0,0 +1,215 @@
+// Package apikeys identifies API callers.
+//
+// A Registry maps a bearer token to an Identity: a stable key id, the
+// workspace the key belongs to, its CanonicalTier, the scopes it may use
+// and when it expires. Registries never hold plaintext keys; entries are
+// looked up by the SHA-256 of the token (see Hash) or, for keys created by
+// Store, by prefix and verified against a salted hash.
+//
+// Two registries exist: a JSON file (NewFileRegistry) and the dashboard's
+// workspace_api_keys table (NewPostgresRegistry). authMiddleware stores the resolved
+// Identity in the request context (WithIdentity), where rate limiting,
+// entitlement enforcement and logging read it.
+//
+// Keys in workspace_api_keys can also be created, rotated and revoked over
+// HTTP (Store, Handler). Those keys are stored as salted hashes.
+package apikeys
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"payment-node/internal/entitlements"
+	"payment-node/internal/tier"
+)
+
+// Scopes a key may be granted.
+const (
+	ScopeIngest = "ingest" // submit payment events and checkouts
+	ScopeRead   = "read"   // read risk, evidence and warnings
+	ScopeAdmin  = "admin"  // DLQ replay, warning outcomes, subscription corrections
+)
+
+var validScopes = map[string]bool{ScopeIngest: true, ScopeRead: true, ScopeAdmin: true}
+
+// ErrUnknownKey is returned by Registry.Lookup for a token no entry matches.
+var ErrUnknownKey = errors.New("unknown api key")
+
+// Identity is who is calling.
+type Identity struct {
+	// KeyID names the key in logs, metrics and rate limiters. Never the
+	// key itself.
+	KeyID string `json:"id"`
+
+	WorkspaceID string `json:"workspace_id,omitempty"`
+
+	// Tier is the key's CanonicalTier. Empty means: the workspace's
+	// entitlement tier, else the deployment default.
+	Tier tier.CanonicalTier `json:"tier,omitempty"`
+
+	// EntitlementTier overrides Tier.EntitlementTier() for
+	// internal/entitlements (baseline, proof, shield, fortress).
+	EntitlementTier string `json:"entitlement_tier,omitempty"`
+
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+
+	// Legacy marks keys from PAYFLUX_API_KEY(S), which predate the
+	// registry (see LegacyIdentity for their scopes).
+	Legacy bool `json:"-"`
+}
+
+// HasScope reports whether the key may use scope.
+func (id *Identity) HasScope(scope string) bool {
+	for _, s := range id.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Expired reports whether the key has expired at now.
+func (id *Identity) Expired(now time.Time) bool {
+	return id.ExpiresAt != nil && !now.Before(*id.ExpiresAt)
+}
+
+func (id *Identity) validate() error {
+	if id.KeyID == "" {
+		return errors.New("id is required")
+	}
+	if err := validateGrant(id.Tier, id.EntitlementTier, id.Scopes); err != nil {
+		return fmt.Errorf("key %s: %w", id.KeyID, err)
+	}
+	return nil
+}
+
+// validateGrant checks what a key is granted: its tier, entitlement tier
+// override and scopes.
+func validateGrant(t tier.CanonicalTier, entitlementTier string, scopes []string) error {
+	if t != "" {
+		if _, ok := tier.Parse(string(t)); !ok {
+			return fmt.Errorf("unknown tier %q", t)
+		}
+	}
+	switch entitlementTier {
+	case "", "baseline", "proof", "shield", "fortress":
+	default:
+		return fmt.Errorf("unknown entitlement_tier %q", entitlementTier)
+	}
+	if len(scopes) == 0 {
+		return errors.New("at least one scope is required")
+	}
+	for _, s := range scopes {
+		if !validScopes[s] {
+			return fmt.Errorf("unknown scope %q", s)
+		}
+	}
+	return nil
+}
+
+// ErrGrantExceedsCaller is returned when a workspace-scoped caller asks for
+// a key with a higher tier or entitlement tier than its own.
+var ErrGrantExceedsCaller = errors.New("grant exceeds the caller's key")
+
+var (
+	tierRank            = map[tier.CanonicalTier]int{tier.TierFree: 0, tier.TierPro: 1, tier.TierEnterprise: 2}
+	entitlementTierRank = map[string]int{"baseline": 0, "proof": 1, "shield": 2, "fortress": 3}
+)
+
+// checkCallerGrant checks that the caller in ctx may issue a key with tier
+// t and entitlement tier entitlementTier (empty: derived as at request
+// time). Callers without a workspace may issue any key. A workspace-scoped
+// caller is capped at the tier and entitlement tier authentication resolved
+// for its own key.
+func checkCallerGrant(ctx context.Context, t tier.CanonicalTier, entitlementTier string) error {
+	caller := FromContext(ctx)
+	if caller == nil || caller.WorkspaceID == "" {
+		return nil
+	}
+	callerTier := tier.FromContext(ctx)
+	callerEntitlementTier, _ := ctx.Value(entitlements.TierContextKey).(string)
+	if callerEntitlementTier == "" {
+		callerEntitlementTier = "baseline"
+	}
+
+	if t == "" {
+		// The key would take the workspace's tier, which is the caller's
+		// only when the caller's key does not fix its own.
+		if caller.Tier != "" {
+			return fmt.Errorf("%w: tier is required because the caller's key has a fixed tier", ErrGrantExceedsCaller)
+		}
+		t = callerTier
+	}
+	if tierRank[t] > tierRank[callerTier] {
+		return fmt.Errorf("%w: tier %s is above %s", ErrGrantExceedsCaller, t, callerTier)
+	}
+	if entitlementTier == "" {
+		entitlementTier = t.EntitlementTier()
+	}
+	if entitlementTierRank[entitlementTier] > entitlementTierRank[callerEntitlementTier] {
+		return fmt.Errorf("%w: entitlement_tier %s is above %s", ErrGrantExceedsCaller, entitlementTier, callerEntitlementTier)
+	}
+	return nil
+}
+
+// Registry resolves bearer tokens to identities.
+type Registry interface {
+	// Lookup returns the identity for token, ErrUnknownKey when no entry
+	// matches, or another error when the registry cannot be read. Expiry
+	// is the caller's check (Identity.Expired). The identity may be
+	// shared between requests and must not be modified.
+	Lookup(ctx context.Context, token string) (*Identity, error)
+}
+
+// Hash returns the hex SHA-256 of token, the form registries store.
+func Hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// LegacyIdentity returns the identity of a PAYFLUX_API_KEY(S) key. Its id
+// is derived from the key's hash, so it is stable and safe to log. Legacy
+// keys may ingest and read; they get the admin scope only when the
+// deployment opts in (admin).
+func LegacyIdentity(token string, admin bool) *Identity {
+	scopes := []string{ScopeIngest, ScopeRead}
+	if admin {
+		scopes = append(scopes, ScopeAdmin)
+	}
+	return &Identity{KeyID: "legacy-" + Hash(token)[:12], Scopes: scopes, Legacy: true}
+}
+
+type contextKey struct{}
+
+// WithIdentity returns a copy of ctx carrying id.
+func WithIdentity(ctx context.Context, id *Identity) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request's identity, or nil for requests that did
+// not pass authentication.
+func FromContext(ctx context.Context) *Identity {
+	id, _ := ctx.Value(contextKey{}).(*Identity)
+	return id
+}
+
+// RequireScope wraps next so it only runs for identities holding scope;
+// others get 403 naming the scope. Mount it inside authentication.
+func RequireScope(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if id := FromContext(r.Context()); id == nil || !id.HasScope(scope) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]string{"error": "insufficient_scope", "scope": scope})
+			return
+		}
+		next(w, r)
+	}
+}
@@ -0,0 +1,136 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"payment-node/internal/tier"
+)
+
+func writeRegistry(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "api_keys.json")
+	if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestFileRegistryLookup(t *testing.T) {
+	path := writeRegistry(t, `{"keys": [
+		{"id": "acme-prod", "sha256": "`+Hash("secret-acme")+`", "workspace_id": "ws-acme",
+		 "tier": "pro", "scopes": ["ingest", "read"]},
+		{"id": "old", "sha256": "`+Hash("secret-old")+`", "scopes": ["read"],
+		 "expires_at": "2020-01-01T00:00:00Z"}
+	]}`)
+	reg, err := NewFileRegistry(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := reg.Lookup(context.Background(), "secret-acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id.KeyID != "acme-prod" || id.WorkspaceID != "ws-acme" || id.Tier != tier.TierPro {
+		t.Errorf("identity = %+v", id)
+	}
+	if !id.HasScope(ScopeIngest) || id.HasScope(ScopeAdmin) {
+		t.Errorf("scopes = %v", id.Scopes)
+	}
+	if id.Expired(time.Now()) {
+		t.Error("key without expires_at expired")
+	}
+
+	old, err := reg.Lookup(context.Background(), "secret-old")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !old.Expired(time.Now()) {
+		t.Error("key past expires_at not expired")
+	}
+
+	if _, err := reg.Lookup(context.Background(), "secret-unknown"); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("unknown key: err = %v", err)
+	}
+}
+
+func TestFileRegistryValidation(t *testing.T) {
+	h := Hash("k")
+	tests := []struct {
+		name, body, want string
+	}{
+		{"no id", `{"keys":[{"sha256":"` + h + `","scopes":["read"]}]}`, "id is required"},
+		{"bad hash", `{"keys":[{"id":"a","sha256":"K","scopes":["read"]}]}`, "64 lowercase hex"},
+		{"no scopes", `{"keys":[{"id":"a","sha256":"` + h + `"}]}`, "at least one scope"},
+		{"bad scope", `{"keys":[{"id":"a","sha256":"` + h + `","scopes":["root"]}]}`, `unknown scope "root"`},
+		{"bad tier", `{"keys":[{"id":"a","sha256":"` + h + `","tier":"gold","scopes":["read"]}]}`, `unknown tier "gold"`},
+		{"bad entitlement", `{"keys":[{"id":"a","sha256":"` + h + `","entitlement_tier":"gold","scopes":["read"]}]}`, "unknown entitlement_tier"},
+		{"duplicate id", `{"keys":[{"id":"a","sha256":"` + h + `","scopes":["read"]},{"id":"a","sha256":"` + Hash("j") + `","scopes":["read"]}]}`, "duplicate id a"},
+		{"duplicate hash", `{"keys":[{"id":"a","sha256":"` + h + `","scopes":["read"]},{"id":"b","sha256":"` + h + `","scopes":["read"]}]}`, "already registered"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewFileRegistry(writeRegistry(t, tt.body))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLegacyIdentity(t *testing.T) {
+	id := LegacyIdentity("legacy-secret-key", false)
+	if strings.Contains(id.KeyID, "legacy-secret") {
+		t.Errorf("KeyID %q leaks the key", id.KeyID)
+	}
+	if id.KeyID != LegacyIdentity("legacy-secret-key", true).KeyID {
+		t.Error("KeyID not stable")
+	}
+	for _, s := range []string{ScopeIngest, ScopeRead} {
+		if !id.HasScope(s) {
+			t.Errorf("legacy key lacks %s", s)
+		}
+	}
+	if id.HasScope(ScopeAdmin) {
+		t.Error("legacy key has admin without opting in")
+	}
+	if !LegacyIdentity("legacy-secret-key", true).HasScope(ScopeAdmin) {
+		t.Error("opted-in legacy key lacks admin")
+	}
+}
+
+func TestRequireScope(t *testing.T) {
+	handler := RequireScope(ScopeAdmin, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	for _, tt := range []struct {
+		name string
+		id   *Identity
+		want int
+	}{
+		{"unauthenticated", nil, http.StatusForbidden},
+		{"missing scope", &Identity{KeyID: "k", Scopes: []string{ScopeRead}}, http.StatusForbidden},
+		{"granted", &Identity{KeyID: "k", Scopes: []string{ScopeRead, ScopeAdmin}}, http.StatusNoContent},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		if tt.id != nil {
+			req = req.WithContext(WithIdentity(req.Context(), tt.id))
+		}
+		w := httptest.NewRecorder()
+		handler(w, req)
+		if w.Code != tt.want {
+			t.Errorf("%s: status %d, want %d", tt.name, w.Code, tt.want)
+		}
+		if w.Code == http.StatusForbidden && !strings.Contains(w.Body.String(), `"scope":"admin"`) {
+			t.Errorf("%s: 403 body %q does not name the scope", tt.name, w.Body)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package apikeys
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+)
+
+// FileRegistry serves identities from a JSON file loaded at startup:
+//
+//	{"keys": [{"id": "acme-prod", "sha256": "<hex sha256 of the key>",
+//	           "workspace_id": "…", "tier": "pro",
+//	           "scopes": ["ingest", "read"], "expires_at": "2027-01-01T00:00:00Z"}]}
+type FileRegistry struct {
+	byHash map[string]*Identity
+}
+
+type fileEntry struct {
+	Identity
+	SHA256 string `json:"sha256"`
+}
+
+var sha256Hex = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// NewFileRegistry loads and validates path. Ids and hashes must be unique.
+func NewFileRegistry(path string) (*FileRegistry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read api key registry: %w", err)
+	}
+	var doc struct {
+		Keys []fileEntry `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parse api key registry: %w", err)
+	}
+
+	r := &FileRegistry{byHash: make(map[string]*Identity, len(doc.Keys))}
+	ids := map[string]bool{}
+	for i := range doc.Keys {
+		e := doc.Keys[i]
+		if err := e.validate(); err != nil {
+			return nil, fmt.Errorf("api key registry entry %d: %w", i+1, err)
+		}
+		if !sha256Hex.MatchString(e.SHA256) {
+			return nil, fmt.Errorf("api key registry: key %s: sha256 must be 64 lowercase hex characters", e.KeyID)
+		}
+		if ids[e.KeyID] {
+			return nil, fmt.Errorf("api key registry: duplicate id %s", e.KeyID)
+		}
+		if _, dup := r.byHash[e.SHA256]; dup {
+			return nil, fmt.Errorf("api key registry: key %s: sha256 already registered", e.KeyID)
+		}
+		ids[e.KeyID] = true
+		id := e.Identity
+		r.byHash[e.SHA256] = &id
+	}
+	return r, nil
+}
+
+// Len returns the number of registered keys.
+func (r *FileRegistry) Len() int { return len(r.byHash) }
+
+// Lookup implements Registry.
+func (r *FileRegistry) Lookup(_ context.Context, token string) (*Identity, error) {
+	id, ok := r.byHash[Hash(token)]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	return id, nil
+}
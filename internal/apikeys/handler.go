@@ -0,0 +1,192 @@
+package apikeys
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// maxRequestBytes bounds a key management request body.
+const maxRequestBytes = 16 << 10
+
+const basePath = "/api/v1/api-keys"
+
+type rotateRequest struct {
+	OverlapSeconds *int64     `json:"overlap_seconds,omitempty"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+}
+
+// Handler serves key management. Mount it behind authentication and the
+// admin scope. Callers whose key belongs to a workspace only see and
+// change that workspace's keys, and cannot create or rotate a key with a
+// higher tier or entitlement tier than their own.
+//
+//	POST   /api/v1/api-keys               KeySpec → 201 {"key": "pf_live_…", "api_key": KeyRecord}
+//	GET    /api/v1/api-keys[?workspace_id=…&prefix=…&include_revoked=true]
+//	                                      → 200 {"api_keys": [KeyRecord…]}, newest first
+//	POST   /api/v1/api-keys/{id}/rotate   {"overlap_seconds": N, "expires_at": …}
+//	                                      → 201 {"key": …, "api_key": KeyRecord, "previous": KeyRecord}
+//	DELETE /api/v1/api-keys/{id}          → 200 {"api_key": KeyRecord}
+//
+// The key is returned once, on create and rotate; only its prefix and a
+// salted hash are stored.
+func Handler(s *Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rest := strings.Trim(strings.TrimPrefix(r.URL.Path, basePath), "/")
+		id, action, _ := strings.Cut(rest, "/")
+		switch {
+		case id == "" && r.Method == http.MethodPost:
+			handleCreate(w, r, s)
+		case id == "" && r.Method == http.MethodGet:
+			handleList(w, r, s)
+		case id != "" && action == "rotate" && r.Method == http.MethodPost:
+			handleRotate(w, r, s, id)
+		case id != "" && action == "" && r.Method == http.MethodDelete:
+			handleRevoke(w, r, s, id)
+		case id != "" && action != "" && action != "rotate":
+			writeError(w, http.StatusNotFound, "not_found", "unknown api key action")
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", r.Method+" not allowed")
+		}
+	}
+}
+
+// caller returns the operator name for the audit log and the workspace the
+// caller is restricted to ("" for none).
+func caller(r *http.Request) (operator, workspaceID string) {
+	id := FromContext(r.Context())
+	if id == nil {
+		return "unknown", ""
+	}
+	return "key:" + id.KeyID, id.WorkspaceID
+}
+
+func handleCreate(w http.ResponseWriter, r *http.Request, s *Store) {
+	var spec KeySpec
+	if !decode(w, r, &spec) {
+		return
+	}
+	operator, scope := caller(r)
+	if scope != "" && spec.WorkspaceID != scope {
+		writeError(w, http.StatusForbidden, "forbidden", "keys can only be created for the caller's workspace")
+		return
+	}
+	if err := spec.Validate(time.Now()); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", err.Error())
+		return
+	}
+	if err := checkCallerGrant(r.Context(), spec.Tier, spec.EntitlementTier); err != nil {
+		writeError(w, http.StatusForbidden, "forbidden", err.Error())
+		return
+	}
+	token, rec, err := s.Create(r.Context(), operator, spec)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, map[string]any{"key": token, "api_key": rec})
+}
+
+func handleList(w http.ResponseWriter, r *http.Request, s *Store) {
+	q := r.URL.Query()
+	_, scope := caller(r)
+	workspaceID := q.Get("workspace_id")
+	if scope != "" {
+		if workspaceID != "" && workspaceID != scope {
+			writeError(w, http.StatusForbidden, "forbidden", "keys can only be listed for the caller's workspace")
+			return
+		}
+		workspaceID = scope
+	}
+	records, err := s.List(r.Context(), workspaceID, q.Get("prefix"), q.Get("include_revoked") == "true")
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"api_keys": records})
+}
+
+func handleRotate(w http.ResponseWriter, r *http.Request, s *Store, id string) {
+	if _, err := uuid.Parse(id); err != nil {
+		writeStoreError(w, ErrKeyNotFound)
+		return
+	}
+	var req rotateRequest
+	if r.ContentLength != 0 && !decode(w, r, &req) {
+		return
+	}
+	overlap := DefaultRotationOverlap
+	if req.OverlapSeconds != nil {
+		overlap = time.Duration(*req.OverlapSeconds) * time.Second
+		if *req.OverlapSeconds < 0 || overlap > MaxRotationOverlap {
+			writeError(w, http.StatusBadRequest, "bad_request", "overlap_seconds must be between 0 and 2592000")
+			return
+		}
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		writeError(w, http.StatusBadRequest, "bad_request", "expires_at must be in the future")
+		return
+	}
+	operator, scope := caller(r)
+	token, rec, previous, err := s.Rotate(r.Context(), operator, scope, id, overlap, req.ExpiresAt)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, map[string]any{"key": token, "api_key": rec, "previous": previous})
+}
+
+func handleRevoke(w http.ResponseWriter, r *http.Request, s *Store, id string) {
+	if _, err := uuid.Parse(id); err != nil {
+		writeStoreError(w, ErrKeyNotFound)
+		return
+	}
+	operator, scope := caller(r)
+	rec, err := s.Revoke(r.Context(), operator, scope, id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"api_key": rec})
+}
+
+func decode(w http.ResponseWriter, r *http.Request, v any) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "invalid JSON: "+err.Error())
+		return false
+	}
+	return true
+}
+
+func writeStoreError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrKeyNotFound):
+		writeError(w, http.StatusNotFound, "not_found", "no active api key with that id")
+	case errors.Is(err, ErrGrantExceedsCaller):
+		writeError(w, http.StatusForbidden, "forbidden", err.Error())
+	case errors.Is(err, ErrUnknownWorkspace):
+		writeError(w, http.StatusNotFound, "not_found", "workspace not found")
+	case errors.Is(err, errStoreNotAvailable):
+		writeError(w, http.StatusServiceUnavailable, "unavailable", "api key store not available")
+	default:
+		slog.Error("api_key_store_error", "error", err)
+		writeError(w, http.StatusInternalServerError, "internal_error", "api key store error")
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, map[string]string{"error": code, "message": message})
+}
@@ -0,0 +1,176 @@
+package apikeys
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+
+	"payment-node/internal/tier"
+)
+
+// maxCachedLookups bounds the Postgres registry cache. Unknown tokens are
+// cached too, so a caller cycling random tokens cannot grow it without
+// limit; when full the cache is dropped and refilled.
+const maxCachedLookups = 10000
+
+// NotifyChannel is the Postgres channel workspace_api_keys changes are
+// announced on (migration 0030); the payload is the key's id.
+const NotifyChannel = "payflux_api_keys"
+
+// PostgresRegistry serves identities from the dashboard's
+// workspace_api_keys table (scopes, tier override and expiry added by
+// migration 0029). A key without its own tier gets its workspace's
+// entitlement_tier. Lookups, including misses, are cached for ttl. Listen
+// drops cached keys as soon as their row changes; without it, a revoked or
+// edited key takes up to ttl to change behavior. Read errors are not
+// cached.
+type PostgresRegistry struct {
+	db  *sql.DB
+	ttl time.Duration
+
+	mu    sync.Mutex
+	cache map[string]cachedLookup
+}
+
+type cachedLookup struct {
+	id      *Identity // nil: unknown key
+	expires time.Time
+}
+
+// NewPostgresRegistry returns a registry reading db.
+func NewPostgresRegistry(db *sql.DB, ttl time.Duration) *PostgresRegistry {
+	return &PostgresRegistry{db: db, ttl: ttl, cache: make(map[string]cachedLookup)}
+}
+
+// Lookup implements Registry.
+func (r *PostgresRegistry) Lookup(ctx context.Context, token string) (*Identity, error) {
+	hash := Hash(token)
+	now := time.Now()
+
+	r.mu.Lock()
+	c, ok := r.cache[hash]
+	r.mu.Unlock()
+	if ok && now.Before(c.expires) {
+		if c.id == nil {
+			return nil, ErrUnknownKey
+		}
+		return c.id, nil
+	}
+
+	id, err := r.query(ctx, token)
+	if err != nil && !errors.Is(err, ErrUnknownKey) {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	if len(r.cache) >= maxCachedLookups {
+		r.cache = make(map[string]cachedLookup)
+	}
+	r.cache[hash] = cachedLookup{id: id, expires: now.Add(r.ttl)}
+	r.mu.Unlock()
+	return id, err
+}
+
+// Invalidate drops the cached lookup of key id, or every cached lookup
+// when id is empty.
+func (r *PostgresRegistry) Invalidate(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if id == "" {
+		r.cache = make(map[string]cachedLookup)
+		return
+	}
+	for hash, c := range r.cache {
+		if c.id != nil && c.id.KeyID == id {
+			delete(r.cache, hash)
+		}
+	}
+}
+
+// Listen invalidates cached lookups on every NotifyChannel notification
+// until ctx is done, so revocations reach every node within seconds. The
+// whole cache is dropped after a reconnect, since notifications may have
+// been missed.
+func (r *PostgresRegistry) Listen(ctx context.Context, dsn string) {
+	l := pq.NewListener(dsn, time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			slog.Warn("api_key_listener_error", "event", int(ev), "error", err)
+		}
+	})
+	defer l.Close()
+	if err := l.Listen(NotifyChannel); err != nil {
+		slog.Error("api_key_listener_failed", "error", err)
+		return
+	}
+
+	ping := time.NewTicker(90 * time.Second)
+	defer ping.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case n := <-l.Notify:
+			if n == nil { // reconnected
+				r.Invalidate("")
+				continue
+			}
+			r.Invalidate(n.Extra)
+		case <-ping.C:
+			go l.Ping()
+		}
+	}
+}
+
+// query finds the active key matching token: dashboard keys by unsalted
+// hash, Store keys by prefix and then salted hash.
+func (r *PostgresRegistry) query(ctx context.Context, token string) (*Identity, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT k.id::text, k.workspace_id::text, COALESCE(k.tier, w.entitlement_tier)::text,
+		       k.entitlement_tier, k.scopes, k.expires_at, k.key_salt, k.key_hash
+		FROM workspace_api_keys k
+		JOIN workspaces w ON w.id = k.workspace_id
+		WHERE k.revoked_at IS NULL AND w.deleted_at IS NULL
+		  AND ((k.key_salt IS NULL AND k.key_hash = $1)
+		    OR (k.key_salt IS NOT NULL AND k.key_prefix = $2))
+	`, Hash(token), Prefix(token))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			id              Identity
+			workspaceID     sql.NullString
+			canonical       sql.NullString
+			entitlementTier sql.NullString
+			expiresAt       sql.NullTime
+			salt            sql.NullString
+			hash            string
+		)
+		if err := rows.Scan(&id.KeyID, &workspaceID, &canonical, &entitlementTier,
+			pq.Array(&id.Scopes), &expiresAt, &salt, &hash); err != nil {
+			return nil, err
+		}
+		if salt.Valid && !matchesSalted(salt.String, hash, token) {
+			continue
+		}
+		id.WorkspaceID = workspaceID.String
+		id.Tier = tier.CanonicalTier(canonical.String)
+		id.EntitlementTier = entitlementTier.String
+		if expiresAt.Valid {
+			t := expiresAt.Time
+			id.ExpiresAt = &t
+		}
+		return &id, nil
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return nil, ErrUnknownKey
+}
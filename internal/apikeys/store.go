@@ -0,0 +1,362 @@
+package apikeys
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"payment-node/internal/config"
+	"payment-node/internal/tier"
+)
+
+// Rotation overlap: how long a rotated key keeps working next to its
+// replacement.
+const (
+	DefaultRotationOverlap = 24 * time.Hour
+	MaxRotationOverlap     = 30 * 24 * time.Hour
+)
+
+// prefixLen matches the dashboard's workspaceApiKeyPrefix: "pf_live_" and
+// the first 8 hex characters of the secret.
+const prefixLen = 16
+
+// listLimit bounds List.
+const listLimit = 500
+
+var (
+	ErrKeyNotFound       = errors.New("api key not found")
+	ErrUnknownWorkspace  = errors.New("workspace not found")
+	errStoreNotAvailable = errors.New("api key store not available")
+)
+
+// Generate returns a new key in the dashboard's format.
+func Generate() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "pf_live_" + hex.EncodeToString(b), nil
+}
+
+// Prefix returns the part of token that is stored in the clear and shown
+// in listings. It identifies a key; it does not authenticate it.
+func Prefix(token string) string {
+	if len(token) < prefixLen {
+		return token
+	}
+	return token[:prefixLen]
+}
+
+// SaltedHash returns the hex SHA-256 of salt followed by token.
+func SaltedHash(salt []byte, token string) string {
+	h := sha256.New()
+	h.Write(salt)
+	h.Write([]byte(token))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func matchesSalted(saltHex, hash, token string) bool {
+	salt, err := hex.DecodeString(saltHex)
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(SaltedHash(salt, token)), []byte(hash)) == 1
+}
+
+// KeySpec is what a new key is granted.
+type KeySpec struct {
+	WorkspaceID     string             `json:"workspace_id"`
+	Label           string             `json:"label"`
+	Tier            tier.CanonicalTier `json:"tier,omitempty"`
+	EntitlementTier string             `json:"entitlement_tier,omitempty"`
+	Scopes          []string           `json:"scopes"`
+	ExpiresAt       *time.Time         `json:"expires_at,omitempty"`
+}
+
+// Validate checks s and fills defaults: label "API key", scopes ingest and
+// read (the table's default).
+func (s *KeySpec) Validate(now time.Time) error {
+	if _, err := uuid.Parse(s.WorkspaceID); err != nil {
+		return errors.New("workspace_id must be a workspace uuid")
+	}
+	if s.Label == "" {
+		s.Label = "API key"
+	}
+	if len(s.Scopes) == 0 {
+		s.Scopes = []string{ScopeIngest, ScopeRead}
+	}
+	if err := validateGrant(s.Tier, s.EntitlementTier, s.Scopes); err != nil {
+		return err
+	}
+	if s.ExpiresAt != nil && !s.ExpiresAt.After(now) {
+		return errors.New("expires_at must be in the future")
+	}
+	return nil
+}
+
+// KeyRecord is a stored key as listed to operators. It never carries the
+// key or its hash.
+type KeyRecord struct {
+	ID              string             `json:"id"`
+	WorkspaceID     string             `json:"workspace_id"`
+	Label           string             `json:"label"`
+	Prefix          string             `json:"prefix"`
+	Tier            tier.CanonicalTier `json:"tier,omitempty"`
+	EntitlementTier string             `json:"entitlement_tier,omitempty"`
+	Scopes          []string           `json:"scopes"`
+	ExpiresAt       *time.Time         `json:"expires_at,omitempty"`
+	RevokedAt       *time.Time         `json:"revoked_at,omitempty"`
+	RotatedFrom     string             `json:"rotated_from,omitempty"`
+	CreatedAt       time.Time          `json:"created_at"`
+}
+
+const recordColumns = `id::text, workspace_id::text, label, key_prefix, tier::text,
+	entitlement_tier, scopes, expires_at, revoked_at, rotated_from::text, created_at`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanRecord(row rowScanner) (KeyRecord, error) {
+	var (
+		rec             KeyRecord
+		canonical       sql.NullString
+		entitlementTier sql.NullString
+		expiresAt       sql.NullTime
+		revokedAt       sql.NullTime
+		rotatedFrom     sql.NullString
+	)
+	err := row.Scan(&rec.ID, &rec.WorkspaceID, &rec.Label, &rec.Prefix, &canonical,
+		&entitlementTier, pq.Array(&rec.Scopes), &expiresAt, &revokedAt, &rotatedFrom, &rec.CreatedAt)
+	if err != nil {
+		return KeyRecord{}, err
+	}
+	rec.Tier = tier.CanonicalTier(canonical.String)
+	rec.EntitlementTier = entitlementTier.String
+	rec.RotatedFrom = rotatedFrom.String
+	if expiresAt.Valid {
+		t := expiresAt.Time
+		rec.ExpiresAt = &t
+	}
+	if revokedAt.Valid {
+		t := revokedAt.Time
+		rec.RevokedAt = &t
+	}
+	return rec, nil
+}
+
+// Store manages keys in workspace_api_keys. Every change is written to the
+// config audit log and dropped from the local registry cache; other nodes
+// drop it on the row's NotifyChannel notification.
+type Store struct {
+	db       *sql.DB
+	registry *PostgresRegistry // may be nil
+}
+
+// NewStore returns a store writing db. registry, when set, is invalidated
+// on every change.
+func NewStore(db *sql.DB, registry *PostgresRegistry) *Store {
+	return &Store{db: db, registry: registry}
+}
+
+func (s *Store) invalidate(id string) {
+	if s.registry != nil {
+		s.registry.Invalidate(id)
+	}
+}
+
+// Create stores a new key for spec, which must have passed Validate, and
+// returns the key. The key is not stored and cannot be shown again.
+func (s *Store) Create(ctx context.Context, operator string, spec KeySpec) (string, KeyRecord, error) {
+	if s.db == nil {
+		return "", KeyRecord{}, errStoreNotAvailable
+	}
+	token, rec, err := insertKey(ctx, s.db, spec, "")
+	if err != nil {
+		return "", KeyRecord{}, err
+	}
+	config.LogAPIKeyChange(operator, rec.ID, "create", recordMetadata(rec))
+	return token, rec, nil
+}
+
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func insertKey(ctx context.Context, q queryRower, spec KeySpec, rotatedFrom string) (string, KeyRecord, error) {
+	token, err := Generate()
+	if err != nil {
+		return "", KeyRecord{}, err
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return "", KeyRecord{}, err
+	}
+	// Selecting from workspaces rejects deleted workspaces as well as
+	// unknown ones.
+	rec, err := scanRecord(q.QueryRowContext(ctx, `
+		INSERT INTO workspace_api_keys (
+			workspace_id, label, key_prefix, key_hash, key_salt,
+			tier, entitlement_tier, scopes, expires_at, rotated_from
+		)
+		SELECT w.id, $2, $3, $4, $5, $6::workspace_tier_enum, $7, $8, $9, $10::uuid
+		FROM workspaces w
+		WHERE w.id = $1 AND w.deleted_at IS NULL
+		RETURNING `+recordColumns,
+		spec.WorkspaceID, spec.Label, Prefix(token), SaltedHash(salt, token), hex.EncodeToString(salt),
+		nullString(string(spec.Tier)), nullString(spec.EntitlementTier), pq.Array(spec.Scopes),
+		spec.ExpiresAt, nullString(rotatedFrom)))
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", KeyRecord{}, ErrUnknownWorkspace
+	}
+	if err != nil {
+		return "", KeyRecord{}, fmt.Errorf("insert api key: %w", err)
+	}
+	return token, rec, nil
+}
+
+// List returns keys newest first, optionally limited to a workspace and to
+// keys whose prefix starts with prefix. Revoked keys are included only
+// when includeRevoked is set.
+func (s *Store) List(ctx context.Context, workspaceID, prefix string, includeRevoked bool) ([]KeyRecord, error) {
+	if s.db == nil {
+		return nil, errStoreNotAvailable
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+recordColumns+`
+		FROM workspace_api_keys
+		WHERE ($1 = '' OR workspace_id::text = $1)
+		  AND left(key_prefix, length($2)) = $2
+		  AND ($3 OR revoked_at IS NULL)
+		ORDER BY created_at DESC
+		LIMIT $4
+	`, workspaceID, prefix, includeRevoked, listLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := []KeyRecord{}
+	for rows.Next() {
+		rec, err := scanRecord(rows)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, rec)
+	}
+	return records, rows.Err()
+}
+
+// Rotate replaces active key id with a new key carrying the same
+// workspace, label, tier and scopes, expiring at expiresAt (nil: never).
+// The old key keeps working for overlap, or until its own expiry if that
+// is sooner. workspaceID, when set, restricts the operation to that
+// workspace's keys. A workspace-scoped caller in ctx may only rotate keys
+// whose tier does not exceed its own (ErrGrantExceedsCaller).
+func (s *Store) Rotate(ctx context.Context, operator, workspaceID, id string, overlap time.Duration, expiresAt *time.Time) (string, KeyRecord, KeyRecord, error) {
+	if s.db == nil {
+		return "", KeyRecord{}, KeyRecord{}, errStoreNotAvailable
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", KeyRecord{}, KeyRecord{}, err
+	}
+	defer tx.Rollback()
+
+	old, err := scanRecord(tx.QueryRowContext(ctx, `
+		SELECT `+recordColumns+`
+		FROM workspace_api_keys
+		WHERE id::text = $1 AND ($2 = '' OR workspace_id::text = $2)
+		  AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > now())
+		FOR UPDATE
+	`, id, workspaceID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", KeyRecord{}, KeyRecord{}, ErrKeyNotFound
+	}
+	if err != nil {
+		return "", KeyRecord{}, KeyRecord{}, err
+	}
+	if err := checkCallerGrant(ctx, old.Tier, old.EntitlementTier); err != nil {
+		return "", KeyRecord{}, KeyRecord{}, err
+	}
+
+	token, rec, err := insertKey(ctx, tx, KeySpec{
+		WorkspaceID:     old.WorkspaceID,
+		Label:           old.Label,
+		Tier:            old.Tier,
+		EntitlementTier: old.EntitlementTier,
+		Scopes:          old.Scopes,
+		ExpiresAt:       expiresAt,
+	}, old.ID)
+	if err != nil {
+		return "", KeyRecord{}, KeyRecord{}, err
+	}
+
+	old, err = scanRecord(tx.QueryRowContext(ctx, `
+		UPDATE workspace_api_keys
+		SET expires_at = LEAST(COALESCE(expires_at, 'infinity'), now() + make_interval(secs => $2)),
+		    updated_at = now()
+		WHERE id::text = $1
+		RETURNING `+recordColumns,
+		old.ID, overlap.Seconds()))
+	if err != nil {
+		return "", KeyRecord{}, KeyRecord{}, fmt.Errorf("expire rotated api key: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return "", KeyRecord{}, KeyRecord{}, err
+	}
+	s.invalidate(old.ID)
+
+	meta := recordMetadata(rec)
+	meta["rotated_from"] = old.ID
+	meta["previous_expires_at"] = old.ExpiresAt
+	config.LogAPIKeyChange(operator, rec.ID, "rotate", meta)
+	return token, rec, old, nil
+}
+
+// Revoke revokes active key id. workspaceID restricts as in Rotate.
+func (s *Store) Revoke(ctx context.Context, operator, workspaceID, id string) (KeyRecord, error) {
+	if s.db == nil {
+		return KeyRecord{}, errStoreNotAvailable
+	}
+	rec, err := scanRecord(s.db.QueryRowContext(ctx, `
+		UPDATE workspace_api_keys
+		SET revoked_at = now(), updated_at = now()
+		WHERE id::text = $1 AND ($2 = '' OR workspace_id::text = $2) AND revoked_at IS NULL
+		RETURNING `+recordColumns,
+		id, workspaceID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return KeyRecord{}, ErrKeyNotFound
+	}
+	if err != nil {
+		return KeyRecord{}, err
+	}
+	s.invalidate(rec.ID)
+	config.LogAPIKeyChange(operator, rec.ID, "revoke", recordMetadata(rec))
+	return rec, nil
+}
+
+// recordMetadata is what the audit log records about a key.
+func recordMetadata(rec KeyRecord) map[string]interface{} {
+	return map[string]interface{}{
+		"workspace_id":     rec.WorkspaceID,
+		"prefix":           rec.Prefix,
+		"tier":             string(rec.Tier),
+		"entitlement_tier": rec.EntitlementTier,
+		"scopes":           rec.Scopes,
+		"expires_at":       rec.ExpiresAt,
+	}
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
@@ -0,0 +1,184 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"payment-node/internal/entitlements"
+	"payment-node/internal/tier"
+)
+
+const testWorkspace = "6f1c2a9e-3b7d-4c1e-9a58-2d0f4b7e8c13"
+
+func TestGenerateAndSaltedHash(t *testing.T) {
+	token, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(token, "pf_live_") || len(token) != 56 {
+		t.Fatalf("token %q not in the dashboard's format", token)
+	}
+	if p := Prefix(token); p != token[:16] {
+		t.Errorf("Prefix = %q", p)
+	}
+
+	salt := []byte("0123456789abcdef")
+	hash := SaltedHash(salt, token)
+	if hash == Hash(token) {
+		t.Error("salted hash equals unsalted hash")
+	}
+	if !matchesSalted("30313233343536373839616263646566", hash, token) {
+		t.Error("key does not match its salted hash")
+	}
+	if matchesSalted("30313233343536373839616263646566", hash, token+"x") {
+		t.Error("other key matches salted hash")
+	}
+	if matchesSalted("not hex", hash, token) {
+		t.Error("malformed salt matches")
+	}
+}
+
+func TestKeySpecValidate(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-time.Hour)
+
+	spec := KeySpec{WorkspaceID: testWorkspace}
+	if err := spec.Validate(now); err != nil {
+		t.Fatal(err)
+	}
+	if spec.Label != "API key" || len(spec.Scopes) != 2 {
+		t.Errorf("defaults not applied: %+v", spec)
+	}
+
+	for _, tt := range []struct {
+		name string
+		spec KeySpec
+		want string
+	}{
+		{"workspace", KeySpec{WorkspaceID: "acme"}, "workspace uuid"},
+		{"scope", KeySpec{WorkspaceID: testWorkspace, Scopes: []string{"root"}}, `unknown scope "root"`},
+		{"tier", KeySpec{WorkspaceID: testWorkspace, Tier: "gold"}, `unknown tier "gold"`},
+		{"expired", KeySpec{WorkspaceID: testWorkspace, ExpiresAt: &past}, "in the future"},
+	} {
+		if err := tt.spec.Validate(now); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestPostgresRegistryInvalidate(t *testing.T) {
+	reg := NewPostgresRegistry(nil, time.Minute)
+	expires := time.Now().Add(time.Minute)
+	reg.cache["h1"] = cachedLookup{id: &Identity{KeyID: "k1"}, expires: expires}
+	reg.cache["h2"] = cachedLookup{id: &Identity{KeyID: "k2"}, expires: expires}
+	reg.cache["h3"] = cachedLookup{expires: expires}
+
+	reg.Invalidate("k1")
+	if _, ok := reg.cache["h1"]; ok || len(reg.cache) != 2 {
+		t.Errorf("Invalidate(k1) left %v", reg.cache)
+	}
+	reg.Invalidate("")
+	if len(reg.cache) != 0 {
+		t.Errorf("Invalidate(\"\") left %v", reg.cache)
+	}
+}
+
+// The handler validates before touching the database, so every rejection
+// path runs against a store without one; requests that pass validation
+// get 503.
+func TestHandlerRejectsInvalidRequests(t *testing.T) {
+	h := Handler(NewStore(nil, nil))
+	other := "0b0e8f0c-1d7a-4c55-8f2e-55c3a1f0e9d4"
+	cases := []struct {
+		name      string
+		method    string
+		target    string
+		body      string
+		workspace string // caller's workspace
+		want      int
+		substr    string
+	}{
+		{"method", http.MethodPut, "/api/v1/api-keys", "", "", http.StatusMethodNotAllowed, "method_not_allowed"},
+		{"action", http.MethodPost, "/api/v1/api-keys/" + testWorkspace + "/renew", "", "", http.StatusNotFound, "unknown api key action"},
+		{"bad json", http.MethodPost, "/api/v1/api-keys", `{`, "", http.StatusBadRequest, "invalid JSON"},
+		{"unknown field", http.MethodPost, "/api/v1/api-keys", `{"workspace_id":"` + testWorkspace + `","key":"x"}`, "", http.StatusBadRequest, "invalid JSON"},
+		{"bad scope", http.MethodPost, "/api/v1/api-keys", `{"workspace_id":"` + testWorkspace + `","scopes":["root"]}`, "", http.StatusBadRequest, "unknown scope"},
+		{"other workspace", http.MethodPost, "/api/v1/api-keys", `{"workspace_id":"` + other + `"}`, testWorkspace, http.StatusForbidden, "caller's workspace"},
+		{"list other workspace", http.MethodGet, "/api/v1/api-keys?workspace_id=" + other, "", testWorkspace, http.StatusForbidden, "caller's workspace"},
+		{"rotate bad id", http.MethodPost, "/api/v1/api-keys/nope/rotate", "", "", http.StatusNotFound, "no active api key"},
+		{"rotate overlap", http.MethodPost, "/api/v1/api-keys/" + testWorkspace + "/rotate", `{"overlap_seconds":-1}`, "", http.StatusBadRequest, "overlap_seconds"},
+		{"rotate long overlap", http.MethodPost, "/api/v1/api-keys/" + testWorkspace + "/rotate", `{"overlap_seconds":2592001}`, "", http.StatusBadRequest, "overlap_seconds"},
+		{"revoke bad id", http.MethodDelete, "/api/v1/api-keys/nope", "", "", http.StatusNotFound, "no active api key"},
+		{"create valid", http.MethodPost, "/api/v1/api-keys", `{"workspace_id":"` + testWorkspace + `"}`, testWorkspace, http.StatusServiceUnavailable, "unavailable"},
+		{"rotate valid", http.MethodPost, "/api/v1/api-keys/" + testWorkspace + "/rotate", "", "", http.StatusServiceUnavailable, "unavailable"},
+		{"list", http.MethodGet, "/api/v1/api-keys?prefix=pf_live_ab", "", "", http.StatusServiceUnavailable, "unavailable"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body))
+			req = req.WithContext(WithIdentity(context.Background(),
+				&Identity{KeyID: "ops", WorkspaceID: tc.workspace, Scopes: []string{ScopeAdmin}}))
+			rec := httptest.NewRecorder()
+			h(rec, req)
+			if rec.Code != tc.want {
+				t.Fatalf("status = %d, want %d (%s)", rec.Code, tc.want, strings.TrimSpace(rec.Body.String()))
+			}
+			if !strings.Contains(rec.Body.String(), tc.substr) {
+				t.Fatalf("body %q does not mention %q", rec.Body.String(), tc.substr)
+			}
+		})
+	}
+}
+
+func TestCheckCallerGrant(t *testing.T) {
+	// ctxFor is a request authenticated with id, resolved to t and et.
+	ctxFor := func(id *Identity, t tier.CanonicalTier, et string) context.Context {
+		ctx := WithIdentity(context.Background(), id)
+		ctx = tier.WithTier(ctx, t)
+		return entitlements.WithTier(ctx, et)
+	}
+	scoped := &Identity{KeyID: "ops", WorkspaceID: testWorkspace, Scopes: []string{ScopeAdmin}}
+	pinned := &Identity{KeyID: "ops", WorkspaceID: testWorkspace, Tier: tier.TierPro, Scopes: []string{ScopeAdmin}}
+	global := &Identity{KeyID: "root", Scopes: []string{ScopeAdmin}}
+
+	for _, tt := range []struct {
+		name  string
+		ctx   context.Context
+		tier  tier.CanonicalTier
+		ent   string
+		allow bool
+	}{
+		{"global enterprise", ctxFor(global, tier.TierFree, "baseline"), tier.TierEnterprise, "fortress", true},
+		{"same tier", ctxFor(scoped, tier.TierPro, "shield"), tier.TierPro, "", true},
+		{"lower tier", ctxFor(scoped, tier.TierPro, "shield"), tier.TierFree, "proof", true},
+		{"workspace tier", ctxFor(scoped, tier.TierPro, "shield"), "", "", true},
+		{"higher tier", ctxFor(scoped, tier.TierFree, "baseline"), tier.TierEnterprise, "", false},
+		{"higher entitlement", ctxFor(scoped, tier.TierFree, "baseline"), tier.TierFree, "fortress", false},
+		{"derived entitlement", ctxFor(scoped, tier.TierPro, "proof"), tier.TierPro, "", false},
+		{"unpinned under pinned caller", ctxFor(pinned, tier.TierPro, "shield"), "", "", false},
+	} {
+		err := checkCallerGrant(tt.ctx, tt.tier, tt.ent)
+		if (err == nil) != tt.allow || (err != nil && !errors.Is(err, ErrGrantExceedsCaller)) {
+			t.Errorf("%s: err = %v, want allowed=%t", tt.name, err, tt.allow)
+		}
+	}
+}
+
+func TestHandlerRejectsTierEscalation(t *testing.T) {
+	h := Handler(NewStore(nil, nil))
+	body := `{"workspace_id":"` + testWorkspace + `","tier":"enterprise","entitlement_tier":"fortress"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/api-keys", strings.NewReader(body))
+	ctx := WithIdentity(context.Background(),
+		&Identity{KeyID: "ops", WorkspaceID: testWorkspace, Tier: tier.TierFree, Scopes: []string{ScopeAdmin}})
+	ctx = entitlements.WithTier(tier.WithTier(ctx, tier.TierFree), "baseline")
+	rec := httptest.NewRecorder()
+	h(rec, req.WithContext(ctx))
+	if rec.Code != http.StatusForbidden || !strings.Contains(rec.Body.String(), "above") {
+		t.Fatalf("status = %d (%s), want 403", rec.Code, strings.TrimSpace(rec.Body.String()))
+	}
+}
@@ -12,8 +12,9 @@ import (
 type AuditEntry struct {
 	Timestamp time.Time              `json:"timestamp"`
 	Operator  string                 `json:"operator"`
-	SignalID  string                 `json:"signal_id"`
-	Action    string                 `json:"action"` // "set", "delete", "expire"
+	SignalID  string                 `json:"signal_id,omitempty"`
+	KeyID     string                 `json:"key_id,omitempty"` // API key changes
+	Action    string                 `json:"action"`           // "set", "delete", "expire"; keys: "create", "rotate", "revoke"
 	OldValue  *SignalOverride        `json:"old_value,omitempty"`
 	NewValue  *SignalOverride        `json:"new_value,omitempty"`
 	Metadata  map[string]interface{} `json:"metadata,omitempty"`
@@ -64,7 +65,26 @@ func LogOverrideChange(operator, signalID, action string, oldValue, newValue *Si
 		NewValue:  newValue,
 		Metadata:  metadata,
 	}
+	writeAuditEntry(entry)
+}
+
+// LogAPIKeyChange logs an API key lifecycle change to the audit log.
+// metadata must never contain the key itself.
+func LogAPIKeyChange(operator, keyID, action string, metadata map[string]interface{}) {
+	if globalAuditLogger == nil {
+		return // Audit logging not initialized
+	}
+
+	writeAuditEntry(AuditEntry{
+		Timestamp: time.Now(),
+		Operator:  operator,
+		KeyID:     keyID,
+		Action:    action,
+		Metadata:  metadata,
+	})
+}
 
+func writeAuditEntry(entry AuditEntry) {
 	globalAuditLogger.mu.Lock()
 	defer globalAuditLogger.mu.Unlock()
 
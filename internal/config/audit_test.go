@@ -284,3 +284,34 @@ func TestExpirationAuditLogging(t *testing.T) {
 		t.Errorf("Expected operator=system, got %s", entry.Operator)
 	}
 }
+
+func TestAPIKeyAuditLogging(t *testing.T) {
+	tmpFile, err := os.CreateTemp("", "audit_test_*.log")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmpFile.Name())
+	tmpFile.Close()
+
+	if err := InitAuditLogger(tmpFile.Name()); err != nil {
+		t.Fatalf("Failed to init audit logger: %v", err)
+	}
+	defer CloseAuditLogger()
+
+	LogAPIKeyChange("key:ops", "key-123", "revoke", map[string]interface{}{"prefix": "pf_live_0123abcd"})
+
+	data, err := os.ReadFile(tmpFile.Name())
+	if err != nil {
+		t.Fatalf("Failed to read audit log: %v", err)
+	}
+	var entry map[string]interface{}
+	if err := json.Unmarshal(data, &entry); err != nil {
+		t.Fatalf("Failed to parse audit entry: %v", err)
+	}
+	if entry["key_id"] != "key-123" || entry["action"] != "revoke" || entry["operator"] != "key:ops" {
+		t.Errorf("entry = %v", entry)
+	}
+	if _, ok := entry["signal_id"]; ok {
+		t.Error("API key entry carries signal_id")
+	}
+}
@@ -14,6 +14,23 @@ type Entitlements struct {
 	SLAResponseTimeMs     int      `json:"sla_response_time_ms"`
 	MaxConcurrentRequests int      `json:"max_concurrent_requests"`
 	ExportFormats         []string `json:"export_formats"`
+
+	// Per-workspace token buckets for the ingest (events, checkout) and
+	// pilot outcome routes, shared by all replicas through Redis.
+	IngestRateLimit  RateLimit `json:"ingest_rate_limit"`
+	OutcomeRateLimit RateLimit `json:"outcome_rate_limit"`
+}
+
+// RateLimit is a token bucket: up to Capacity requests in a burst,
+// refilled at RefillPerSecond. The zero value means not configured.
+type RateLimit struct {
+	Capacity        int `json:"capacity"`
+	RefillPerSecond int `json:"refill_per_second"`
+}
+
+// IsZero reports whether the limit is not configured.
+func (rl RateLimit) IsZero() bool {
+	return rl == RateLimit{}
 }
 
 // EntitlementsConfig represents the full entitlements configuration
@@ -72,6 +89,8 @@ func (er *EntitlementsRegistry) GetEntitlements(tier string) (Entitlements, erro
 			SLAResponseTimeMs:     30000,
 			MaxConcurrentRequests: 1,
 			ExportFormats:         []string{"json"},
+			IngestRateLimit:       RateLimit{Capacity: 1, RefillPerSecond: 1},
+			OutcomeRateLimit:      RateLimit{Capacity: 1, RefillPerSecond: 1},
 		}, fmt.Errorf("unknown tier: %s", tier)
 	}
 
@@ -113,5 +132,27 @@ func validateEntitlements(tier string, ent Entitlements) error {
 		}
 	}
 
+	if err := validateRateLimit(ent.IngestRateLimit); err != nil {
+		return fmt.Errorf("ingest_rate_limit: %w", err)
+	}
+	if err := validateRateLimit(ent.OutcomeRateLimit); err != nil {
+		return fmt.Errorf("outcome_rate_limit: %w", err)
+	}
+
+	return nil
+}
+
+// validateRateLimit checks a configured limit; the zero value is allowed
+// here and rejected by callers that need one.
+func validateRateLimit(rl RateLimit) error {
+	if rl.IsZero() {
+		return nil
+	}
+	if rl.Capacity < 1 || rl.Capacity > 1000000 {
+		return fmt.Errorf("capacity must be between 1 and 1000000, got %d", rl.Capacity)
+	}
+	if rl.RefillPerSecond < 1 || rl.RefillPerSecond > 100000 {
+		return fmt.Errorf("refill_per_second must be between 1 and 100000, got %d", rl.RefillPerSecond)
+	}
 	return nil
 }
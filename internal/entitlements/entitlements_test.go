@@ -2,6 +2,8 @@ package entitlements
 
 import (
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -149,6 +151,31 @@ func TestValidateEntitlements(t *testing.T) {
 			},
 			expectError: true,
 		},
+		{
+			name: "valid rate limits",
+			tier: "test",
+			ent: Entitlements{
+				RetentionDays:         30,
+				SLAResponseTimeMs:     1000,
+				MaxConcurrentRequests: 100,
+				ExportFormats:         []string{"json"},
+				IngestRateLimit:       RateLimit{Capacity: 200, RefillPerSecond: 100},
+				OutcomeRateLimit:      RateLimit{Capacity: 20, RefillPerSecond: 10},
+			},
+			expectError: false,
+		},
+		{
+			name: "invalid ingest rate limit - no capacity",
+			tier: "test",
+			ent: Entitlements{
+				RetentionDays:         30,
+				SLAResponseTimeMs:     1000,
+				MaxConcurrentRequests: 100,
+				ExportFormats:         []string{"json"},
+				IngestRateLimit:       RateLimit{RefillPerSecond: 100},
+			},
+			expectError: true,
+		},
 	}
 
 	for _, tc := range tests {
@@ -195,6 +222,29 @@ func TestLoadEntitlementsRegistry_MissingTier(t *testing.T) {
 	}
 }
 
+func TestValidateEntitlementsConfig_RuntimeConfig(t *testing.T) {
+	configPath, _ := filepath.Abs("../../config/tier_entitlements.runtime.json")
+	schemaPath, _ := filepath.Abs("../specs/tier-entitlements-schema.v1.json")
+
+	if err := ValidateEntitlementsConfig(configPath, schemaPath); err != nil {
+		t.Fatalf("shipped config does not match the schema: %v", err)
+	}
+
+	// The schema still rejects malformed rate limits
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bad := strings.Replace(string(data), `"refill_per_second": 10`, `"refill_per_second": 10, "burst": 5`, 1)
+	badPath := filepath.Join(t.TempDir(), "entitlements.json")
+	if err := os.WriteFile(badPath, []byte(bad), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateEntitlementsConfig(badPath, schemaPath); err == nil {
+		t.Error("expected schema error for unknown rate limit field")
+	}
+}
+
 func BenchmarkGetEntitlements(b *testing.B) {
 	registry, err := LoadEntitlementsRegistry("../../config/tier_entitlements.runtime.json")
 	if err != nil {
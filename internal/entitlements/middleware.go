@@ -16,6 +16,12 @@ const (
 	TierContextKey contextKey = "tier"
 )
 
+// WithTier returns a copy of ctx carrying the caller's entitlement tier,
+// read by EnforcementMiddleware.
+func WithTier(ctx context.Context, tier string) context.Context {
+	return context.WithValue(ctx, TierContextKey, tier)
+}
+
 // EnforcementMiddleware wraps HTTP handlers with tier enforcement
 type EnforcementMiddleware struct {
 	registry *EntitlementsRegistry
@@ -68,5 +68,17 @@ func ValidateEntitlementsConfig(configPath, schemaPath string) error {
 		return fmt.Errorf("max_concurrent_requests must increase across tiers")
 	}
 
+	// Rate limits should not decrease
+	if !(baseline.IngestRateLimit.RefillPerSecond <= proof.IngestRateLimit.RefillPerSecond &&
+		proof.IngestRateLimit.RefillPerSecond <= shield.IngestRateLimit.RefillPerSecond &&
+		shield.IngestRateLimit.RefillPerSecond <= fortress.IngestRateLimit.RefillPerSecond) {
+		return fmt.Errorf("ingest_rate_limit.refill_per_second must increase across tiers")
+	}
+	if !(baseline.OutcomeRateLimit.RefillPerSecond <= proof.OutcomeRateLimit.RefillPerSecond &&
+		proof.OutcomeRateLimit.RefillPerSecond <= shield.OutcomeRateLimit.RefillPerSecond &&
+		shield.OutcomeRateLimit.RefillPerSecond <= fortress.OutcomeRateLimit.RefillPerSecond) {
+		return fmt.Errorf("outcome_rate_limit.refill_per_second must increase across tiers")
+	}
+
 	return nil
 }
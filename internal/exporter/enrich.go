@@ -12,7 +12,7 @@ import (
 //   addTierContext  — populate ProcessorPlaybookContext when exportTier=="tier2"
 //   addTrajectory   — populate RiskTrajectory when exportTier=="tier2"
 //   addWarnings     — create a Warning in WarningStore when pilotMode is active
-//                     and risk band is above "low"
+//                     and the processor or merchant risk band is above "low"
 //
 // addWarnings reads ProcessorPlaybookContext and RiskTrajectory from the
 // record, so it MUST be called after addTierContext and addTrajectory.
@@ -73,9 +73,11 @@ func (e *Exporter) addTrajectory(record *ExportedEvent, res RiskResult) {
 //   warningLatency   → e.cfg.Metrics.ObserveWarningLatency
 //   warningsSuppressed → e.cfg.Metrics.IncWarningsSuppressed
 func (e *Exporter) addWarnings(event Event, record *ExportedEvent, res RiskResult, messageID string) {
-	// Pilot mode: Create warning record for elevated+ risk bands (v0.2.3+)
-	// Respects warningsEnabled kill switch
-	if e.cfg.PilotModeEnabled && e.cfg.WarningStore != nil && res.Band != "low" {
+	// Pilot mode: Create warning record for elevated+ risk bands (v0.2.3+).
+	// A merchant in trouble raises a warning even while its processor as a
+	// whole is still "low". Respects warningsEnabled kill switch.
+	merchantElevated := res.MerchantScored && res.MerchantBand != "low"
+	if e.cfg.PilotModeEnabled && e.cfg.WarningStore != nil && (res.Band != "low" || merchantElevated) {
 		if e.cfg.WarningsEnabled {
 			// Parse event timestamp for latency measurement
 			var eventTimestamp time.Time
@@ -91,6 +93,7 @@ func (e *Exporter) addWarnings(event Event, record *ExportedEvent, res RiskResul
 				EventID:         event.EventID,
 				Processor:       event.Processor,
 				MerchantIDHash:  event.MerchantIDHash,
+				WorkspaceID:     event.WorkspaceID,
 				ProcessedAt:     processedAt,
 				EventTimestamp:  eventTimestamp,
 				RiskScore:       res.Score,
@@ -98,6 +101,13 @@ func (e *Exporter) addWarnings(event Event, record *ExportedEvent, res RiskResul
 				RiskDrivers:     res.Drivers,
 				PlaybookContext: record.ProcessorPlaybookContext,
 				RiskTrajectory:  record.RiskTrajectory,
+
+				RiskModelVersion: res.ModelVersion,
+			}
+			if res.MerchantScored {
+				warning.MerchantRiskScore = res.MerchantScore
+				warning.MerchantRiskBand = res.MerchantBand
+				warning.MerchantRiskDrivers = res.MerchantDrivers
 			}
 
 			// Observe warning latency if we have a valid event timestamp
@@ -109,6 +119,9 @@ func (e *Exporter) addWarnings(event Event, record *ExportedEvent, res RiskResul
 			}
 
 			e.cfg.WarningStore.Add(warning)
+			if e.cfg.WarningNotifier != nil {
+				e.cfg.WarningNotifier.Notify(warning)
+			}
 		} else {
 			// Warnings disabled, increment suppression counter
 			e.cfg.Metrics.IncWarningsSuppressed()
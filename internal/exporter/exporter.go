@@ -5,13 +5,14 @@
 //  1. transform — build the ExportedEvent record from the raw event (transform.go)
 //  2. risk      — apply the risk scorer and annotate score/band/drivers (risk.go)
 //  3. enrich    — add tier context, risk trajectory, and pilot warnings (enrich.go)
-//  4. output    — marshal to JSON and write to all configured destinations (output.go)
+//  4. output    — marshal to JSON and write to all configured destinations
+//                 (output.go for stdout/file, sink.go for pluggable sinks)
 //  5. health    — record per-destination success or failure timestamps (health.go)
 //
 // The Exporter type (this file) is the sole orchestration point.
 // Each concern is isolated in its own file and does not call the others.
 //
-// ACK discipline: the caller (handleMessageWithDlq in main.go) ACKs only
+// ACK discipline: the caller (consumerPool.processBatch in main) ACKs only
 // after Export returns nil. Export returning an error means no destination
 // received the event, so no ACK occurs, preserving at-least-once delivery.
 package exporter
@@ -26,7 +27,8 @@ import (
 // All dependencies are supplied at construction time via Config,
 // replacing the package-level globals currently in main.go.
 //
-// Exporter is safe for concurrent use: all mutable state it touches
+// Exporter is safe for concurrent use (the consumer worker pool calls Export
+// from several goroutines): all mutable state it touches
 // is either owned by Config fields that are themselves safe, or is
 // accessed through the atomics and sync types inherited from main.go.
 type Exporter struct {
@@ -69,6 +71,9 @@ func (e *Exporter) Export(event Event, messageID string) error {
 		if e.cfg.Metrics != nil {
 			e.cfg.Metrics.IncRiskEvent(event.Processor, res.Band)
 			e.cfg.Metrics.SetRiskScoreLast(event.Processor, res.Score)
+			if res.MerchantScored {
+				e.cfg.Metrics.IncMerchantRiskEvent(res.MerchantBand)
+			}
 		}
 
 		e.addWarnings(event, &record, res, messageID)
@@ -102,6 +107,11 @@ func (e *Exporter) Export(event Event, messageID string) error {
 			exportErr = err
 		}
 	}
+	// Pluggable sinks (sink.go) follow the same attempt-all rule; best-effort
+	// sinks never contribute to exportErr.
+	if err := e.writeSinks(data, event.EventID); err != nil {
+		exportErr = err
+	}
 
 	return exportErr
 }
@@ -136,4 +146,10 @@ type Config struct {
 
 	// Step 7: metrics
 	Metrics ExportMetrics // Prometheus counters/gauges (interface; concrete in main.go)
+
+	// Pluggable sinks: PAYFLUX_EXPORT_SINKS (built via BuildSinks in main.go)
+	Sinks []ConfiguredSink
+
+	// Warning push: PAYFLUX_WARNING_WEBHOOKS (nil when not configured or not entitled)
+	WarningNotifier WarningNotifier
 }
@@ -37,6 +37,15 @@ type RiskResult struct {
 	TrajectoryWindowSec  int
 	CurrentFailureRate   float64
 	BaselineFailureRate  float64
+
+	// ModelVersion identifies the scoring model that produced the result.
+	ModelVersion string
+
+	// Merchant-level fields — set only when MerchantScored is true.
+	MerchantScored  bool
+	MerchantScore   float64
+	MerchantBand    string
+	MerchantDrivers []string
 }
 
 // WarningStore is the in-memory LRU cache for pilot warnings.
@@ -46,6 +55,13 @@ type WarningStore interface {
 	Add(w *Warning)
 }
 
+// WarningNotifier receives each warning right after it is stored.
+// Satisfied by *WarningDispatcher (warning_webhook.go). Notify must not
+// block the export path.
+type WarningNotifier interface {
+	Notify(w *Warning)
+}
+
 // Warning mirrors main.Warning exactly.
 // Defined here so that enrich.go can construct warnings without
 // importing package main. Field names, types, and json tags are
@@ -55,6 +71,7 @@ type Warning struct {
 	EventID        string    `json:"event_id"`
 	Processor      string    `json:"processor"`
 	MerchantIDHash string    `json:"merchant_id_hash,omitempty"`
+	WorkspaceID    string    `json:"workspace_id,omitempty"`
 	ProcessedAt    time.Time `json:"processed_at"`
 	EventTimestamp time.Time `json:"event_timestamp"` // upstream event time for latency measurement
 
@@ -63,6 +80,14 @@ type Warning struct {
 	RiskBand    string   `json:"processor_risk_band"`
 	RiskDrivers []string `json:"processor_risk_drivers"`
 
+	// Merchant risk data (when merchant scoring is enabled)
+	MerchantRiskScore   float64  `json:"merchant_risk_score,omitempty"`
+	MerchantRiskBand    string   `json:"merchant_risk_band,omitempty"`
+	MerchantRiskDrivers []string `json:"merchant_risk_drivers,omitempty"`
+
+	// Scoring model that produced the risk fields above
+	RiskModelVersion string `json:"risk_model_version,omitempty"`
+
 	// Tier 2 context (if present)
 	PlaybookContext string `json:"processor_playbook_context,omitempty"`
 	RiskTrajectory  string `json:"risk_trajectory,omitempty"`
@@ -113,6 +138,8 @@ type ExportMetrics interface {
 	IncRiskEvent(processor, band string)
 	// SetRiskScoreLast sets the last risk score gauge for the processor.
 	SetRiskScoreLast(processor string, score float64)
+	// IncMerchantRiskEvent increments the merchant risk event counter for band.
+	IncMerchantRiskEvent(band string)
 	// IncTier2Context increments the tier2 context emitted counter.
 	IncTier2Context()
 	// IncTier2Trajectory increments the tier2 trajectory emitted counter.
@@ -3,8 +3,9 @@ package exporter
 // risk.go — Stage 2 of the export pipeline: risk scoring and annotation.
 //
 // Responsibility: apply the RiskScorer to the event and annotate the
-// ExportedEvent record with the resulting score, band, and driver fields.
-// No enrichment, no I/O.
+// ExportedEvent record with the resulting score, band, and driver fields —
+// processor-level always, merchant-level when the scorer tracked the event's
+// MerchantIDHash. No enrichment, no I/O.
 //
 // Returns (RiskResult, true) when scoring was applied.
 // Returns (zero, false) when riskScoreEnabled is false or RiskScorer is nil;
@@ -37,6 +38,14 @@ func (e *Exporter) scoreRisk(event Event, record *ExportedEvent) (RiskResult, bo
 	record.ProcessorRiskScore = res.Score
 	record.ProcessorRiskBand = res.Band
 	record.ProcessorRiskDrivers = res.Drivers
+	record.RiskModelVersion = res.ModelVersion
+
+	if res.MerchantScored {
+		record.MerchantIDHash = event.MerchantIDHash
+		record.MerchantRiskScore = res.MerchantScore
+		record.MerchantRiskBand = res.MerchantBand
+		record.MerchantRiskDrivers = res.MerchantDrivers
+	}
 
 	// Tier 1 only: Add upgrade hint (v0.2.3+)
 	if e.cfg.ExportTier == "tier1" && res.Band != "low" {
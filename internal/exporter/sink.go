@@ -0,0 +1,292 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sink.go — pluggable export destinations beyond stdout and file.
+//
+// A Sink receives the same newline-delimited JSON record that writeStdout and
+// writeFile receive. Sinks are constructed by name from a registry of kinds
+// (see sinks_builtin.go for "webhook", "kafka_rest" and "redis_stream"), so
+// new destinations can be added with RegisterSinkKind without touching the
+// Export orchestrator.
+//
+// Health and metrics for each sink are reported through the same
+// HealthTracker / ExportMetrics surfaces as the built-in destinations, using
+// the sink's configured name as the destination label.
+//
+// ACK discipline: a failing sink makes Export return an error (no ACK, the
+// message is redelivered) unless the sink was configured as best-effort.
+
+// sinkWriteTimeout bounds a single Sink.Write call so one slow destination
+// cannot stall the consumer loop indefinitely.
+const sinkWriteTimeout = 5 * time.Second
+
+// Sink is a named export destination.
+type Sink interface {
+	// Name returns the configured destination name (used as metric/health label).
+	Name() string
+	// Write delivers one serialized record (newline-terminated JSON).
+	Write(ctx context.Context, data []byte) error
+	// Close releases any resources held by the sink.
+	Close() error
+}
+
+// SinkConfig describes one configured sink instance.
+type SinkConfig struct {
+	Name       string            // destination label, e.g. "warehouse"
+	Kind       string            // registered kind, e.g. "webhook"
+	BestEffort bool              // failures do not block the stream ACK
+	Options    map[string]string // kind-specific options (upper-case keys)
+}
+
+// Option returns the named option or fallback when unset.
+func (c SinkConfig) Option(key, fallback string) string {
+	if v := strings.TrimSpace(c.Options[key]); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// SinkFactory builds a Sink from its configuration.
+type SinkFactory func(cfg SinkConfig) (Sink, error)
+
+// sinkKind is a registered kind: its factory and the option names it reads.
+type sinkKind struct {
+	factory SinkFactory
+	options []string
+}
+
+var (
+	sinkKindsMu sync.RWMutex
+	sinkKinds   = map[string]sinkKind{}
+)
+
+// commonSinkOptions are read for every sink, whatever its kind.
+var commonSinkOptions = []string{"BEST_EFFORT"}
+
+// RegisterSinkKind makes a sink kind available to NewSink. options lists the
+// upper-case option names the kind reads; ParseSinkSpecs resolves only
+// those (plus commonSinkOptions). Registering the same kind twice replaces
+// the earlier registration.
+func RegisterSinkKind(kind string, factory SinkFactory, options ...string) {
+	sinkKindsMu.Lock()
+	defer sinkKindsMu.Unlock()
+	sinkKinds[kind] = sinkKind{factory: factory, options: options}
+}
+
+// sinkOptionNames returns the option names resolved for a sink of kind. An
+// unregistered kind gets only the common options; NewSink rejects it.
+func sinkOptionNames(kind string) []string {
+	sinkKindsMu.RLock()
+	defer sinkKindsMu.RUnlock()
+	return append(append([]string{}, commonSinkOptions...), sinkKinds[kind].options...)
+}
+
+// SinkKinds returns the registered kind names in sorted order.
+func SinkKinds() []string {
+	sinkKindsMu.RLock()
+	defer sinkKindsMu.RUnlock()
+	kinds := make([]string, 0, len(sinkKinds))
+	for k := range sinkKinds {
+		kinds = append(kinds, k)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// NewSink constructs a sink of cfg.Kind from the registry.
+func NewSink(cfg SinkConfig) (Sink, error) {
+	sinkKindsMu.RLock()
+	k, ok := sinkKinds[cfg.Kind]
+	sinkKindsMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown sink kind %q for sink %q (registered: %s)",
+			cfg.Kind, cfg.Name, strings.Join(SinkKinds(), ", "))
+	}
+	return k.factory(cfg)
+}
+
+// reservedSinkNames cannot be used as sink names because they collide with
+// the built-in destination labels.
+var reservedSinkNames = map[string]bool{"stdout": true, "file": true, "both": true}
+
+// ParseSinkSpecs parses PAYFLUX_EXPORT_SINKS ("name=kind,name2=kind2") and
+// resolves each sink's options from PAYFLUX_SINK_<NAME>_<OPTION> entries in
+// environ (normally os.Environ()). Only the options the kind registered are
+// read, by exact name. Option keys are returned upper-case.
+//
+// Two sinks whose <NAME> segments are equal ("my-sink", "my_sink") or where
+// one extends the other ("a", "a_b": PAYFLUX_SINK_A_B_URL would read as
+// either) are rejected.
+//
+// Recognized per-sink variables common to every kind:
+//
+//	PAYFLUX_SINK_<NAME>_BEST_EFFORT=true   failures do not block ACK
+//
+// Kind-specific options are documented in sinks_builtin.go.
+func ParseSinkSpecs(spec string, environ []string) ([]SinkConfig, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, nil
+	}
+
+	env := make(map[string]string, len(environ))
+	for _, kv := range environ {
+		if k, v, found := strings.Cut(kv, "="); found {
+			env[k] = v
+		}
+	}
+
+	var configs []SinkConfig
+	seen := map[string]bool{}
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, kind, ok := strings.Cut(part, "=")
+		name, kind = strings.TrimSpace(name), strings.TrimSpace(kind)
+		if !ok || name == "" || kind == "" {
+			return nil, fmt.Errorf("invalid sink spec %q (expected name=kind)", part)
+		}
+		if !validSinkName(name) {
+			return nil, fmt.Errorf("invalid sink name %q (use letters, digits, '_' or '-')", name)
+		}
+		if reservedSinkNames[name] {
+			return nil, fmt.Errorf("sink name %q is reserved", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate sink name %q", name)
+		}
+		seen[name] = true
+
+		envName := sinkEnvName(name)
+		for _, other := range configs {
+			otherEnv := sinkEnvName(other.Name)
+			if strings.HasPrefix(envName+"_", otherEnv+"_") || strings.HasPrefix(otherEnv+"_", envName+"_") {
+				return nil, fmt.Errorf("sink names %q and %q collide in PAYFLUX_SINK_%s_* / PAYFLUX_SINK_%s_*",
+					other.Name, name, otherEnv, envName)
+			}
+		}
+
+		prefix := "PAYFLUX_SINK_" + envName + "_"
+		opts := map[string]string{}
+		for _, opt := range sinkOptionNames(kind) {
+			if v, found := env[prefix+opt]; found {
+				opts[opt] = v
+			}
+		}
+
+		configs = append(configs, SinkConfig{
+			Name:       name,
+			Kind:       kind,
+			BestEffort: strings.EqualFold(opts["BEST_EFFORT"], "true"),
+			Options:    opts,
+		})
+	}
+	return configs, nil
+}
+
+func validSinkName(name string) bool {
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// sinkEnvName maps a sink name to its env var segment ("my-sink" → "MY_SINK").
+func sinkEnvName(name string) string {
+	return strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// ConfiguredSink pairs a Sink with its delivery policy.
+type ConfiguredSink struct {
+	Sink       Sink
+	BestEffort bool // failures are recorded but do not fail Export
+}
+
+// writeSinks delivers data to every configured sink. Every sink is always
+// attempted; the returned error is the last failure from a sink that is not
+// best-effort, matching the "attempt all, return last" rule for stdout/file.
+func (e *Exporter) writeSinks(data []byte, eventID string) error {
+	var sinkErr error
+	for _, cs := range e.cfg.Sinks {
+		if err := e.writeSink(cs.Sink, data, eventID); err != nil && !cs.BestEffort {
+			sinkErr = err
+		}
+	}
+	return sinkErr
+}
+
+// writeSink writes data to a single sink and records health/metrics under
+// the sink's name. Mirrors writeStdout / writeFile in output.go.
+func (e *Exporter) writeSink(sink Sink, data []byte, eventID string) error {
+	dest := sink.Name()
+	ctx, cancel := context.WithTimeout(context.Background(), sinkWriteTimeout)
+	defer cancel()
+
+	start := time.Now()
+	if err := sink.Write(ctx, data); err != nil {
+		log.Printf("export_sink_error sink=%s event_id=%s err=%v", dest, eventID, err)
+		if e.cfg.Metrics != nil {
+			e.cfg.Metrics.IncExportError(dest, "write")
+		}
+		e.markFailure(dest, "write")
+		return fmt.Errorf("%s: %w", dest, err)
+	}
+	if e.cfg.Metrics != nil {
+		e.cfg.Metrics.ObserveExportDuration(dest, time.Since(start).Seconds())
+		e.cfg.Metrics.IncExported(dest)
+	}
+	e.markSuccess(dest)
+	return nil
+}
+
+// Close releases resources held by configured sinks.
+func (e *Exporter) Close() error {
+	var firstErr error
+	for _, cs := range e.cfg.Sinks {
+		if err := cs.Sink.Close(); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("%s: %w", cs.Sink.Name(), err)
+		}
+	}
+	return firstErr
+}
+
+// SinkNames returns the names of configured sinks in configuration order.
+func (e *Exporter) SinkNames() []string {
+	names := make([]string, 0, len(e.cfg.Sinks))
+	for _, cs := range e.cfg.Sinks {
+		names = append(names, cs.Sink.Name())
+	}
+	return names
+}
+
+// BuildSinks constructs every sink in configs. On error, sinks built so far
+// are closed and the error names the offending sink.
+func BuildSinks(configs []SinkConfig) ([]ConfiguredSink, error) {
+	sinks := make([]ConfiguredSink, 0, len(configs))
+	for _, c := range configs {
+		s, err := NewSink(c)
+		if err != nil {
+			for _, built := range sinks {
+				_ = built.Sink.Close()
+			}
+			return nil, fmt.Errorf("sink %q: %w", c.Name, err)
+		}
+		sinks = append(sinks, ConfiguredSink{Sink: s, BestEffort: c.BestEffort})
+	}
+	return sinks, nil
+}
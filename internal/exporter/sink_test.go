@@ -0,0 +1,218 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseSinkSpecs(t *testing.T) {
+	environ := []string{
+		"PAYFLUX_SINK_WAREHOUSE_URL=https://example.invalid/hook",
+		"PAYFLUX_SINK_WAREHOUSE_SECRET=s3cret=with=equals",
+		"PAYFLUX_SINK_AUDIT_LOG_STREAM=audit",
+		"PAYFLUX_SINK_AUDIT_LOG_BEST_EFFORT=true",
+		"UNRELATED=1",
+	}
+
+	configs, err := ParseSinkSpecs(" warehouse=webhook , audit-log=redis_stream ", environ)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(configs) != 2 {
+		t.Fatalf("expected 2 configs, got %d", len(configs))
+	}
+
+	wh := configs[0]
+	if wh.Name != "warehouse" || wh.Kind != "webhook" || wh.BestEffort {
+		t.Errorf("unexpected warehouse config: %+v", wh)
+	}
+	if wh.Option("SECRET", "") != "s3cret=with=equals" {
+		t.Errorf("SECRET not parsed correctly: %q", wh.Option("SECRET", ""))
+	}
+
+	audit := configs[1]
+	if audit.Name != "audit-log" || !audit.BestEffort || audit.Option("STREAM", "") != "audit" {
+		t.Errorf("unexpected audit-log config: %+v", audit)
+	}
+}
+
+func TestParseSinkSpecs_ExactOptions(t *testing.T) {
+	environ := []string{
+		"PAYFLUX_SINK_WAREHOUSE_URL=https://example.invalid/hook",
+		"PAYFLUX_SINK_WAREHOUSE_EU_URL=https://eu.example.invalid/hook",
+		"PAYFLUX_SINK_WAREHOUSE_TOPIC=ignored",
+	}
+	configs, err := ParseSinkSpecs("warehouse=webhook", environ)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string]string{"URL": "https://example.invalid/hook"}
+	if len(configs[0].Options) != len(want) || configs[0].Options["URL"] != want["URL"] {
+		t.Errorf("options = %v, want %v", configs[0].Options, want)
+	}
+}
+
+func TestParseSinkSpecs_Invalid(t *testing.T) {
+	for _, spec := range []string{
+		"warehouse",
+		"=webhook",
+		"stdout=webhook",
+		"a=webhook,a=kafka_rest",
+		"bad name=webhook",
+		"my-sink=webhook,my_sink=webhook",
+		"a=webhook,a_b=webhook",
+		"a_b=webhook,a=webhook",
+	} {
+		if _, err := ParseSinkSpecs(spec, nil); err == nil {
+			t.Errorf("expected error for spec %q", spec)
+		}
+	}
+}
+
+func TestNewSink_UnknownKind(t *testing.T) {
+	_, err := NewSink(SinkConfig{Name: "x", Kind: "carrier_pigeon"})
+	if err == nil || !strings.Contains(err.Error(), "carrier_pigeon") {
+		t.Fatalf("expected unknown kind error, got %v", err)
+	}
+}
+
+func TestWebhookSink_SignsPayload(t *testing.T) {
+	var gotBody, gotSig string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		gotBody = string(b)
+		gotSig = r.Header.Get(SignatureHeader)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	sink, err := NewSink(SinkConfig{
+		Name:    "warehouse",
+		Kind:    "webhook",
+		Options: map[string]string{"URL": srv.URL, "SECRET": "whsec_test"},
+	})
+	if err != nil {
+		t.Fatalf("NewSink: %v", err)
+	}
+	defer sink.Close()
+
+	ws := sink.(*webhookSink)
+	ws.now = func() time.Time { return time.Unix(1700000000, 0) }
+
+	if err := sink.Write(context.Background(), []byte(`{"event_id":"e1"}`+"\n")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if gotBody != `{"event_id":"e1"}` {
+		t.Errorf("unexpected body %q", gotBody)
+	}
+	want := SignPayload([]byte("whsec_test"), 1700000000, []byte(`{"event_id":"e1"}`))
+	if gotSig != want {
+		t.Errorf("signature mismatch: got %q want %q", gotSig, want)
+	}
+}
+
+func TestKafkaRESTSink_Envelope(t *testing.T) {
+	var gotPath, gotBody, gotType string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		gotPath, gotBody, gotType = r.URL.Path, string(b), r.Header.Get("Content-Type")
+	}))
+	defer srv.Close()
+
+	sink, err := NewSink(SinkConfig{
+		Name:    "kafka",
+		Kind:    "kafka_rest",
+		Options: map[string]string{"URL": srv.URL + "/", "TOPIC": "payflux.events"},
+	})
+	if err != nil {
+		t.Fatalf("NewSink: %v", err)
+	}
+	defer sink.Close()
+
+	if err := sink.Write(context.Background(), []byte(`{"event_id":"e1"}`+"\n")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if gotPath != "/topics/payflux.events" {
+		t.Errorf("unexpected path %q", gotPath)
+	}
+	if gotType != "application/vnd.kafka.json.v2+json" {
+		t.Errorf("unexpected content type %q", gotType)
+	}
+	if gotBody != `{"records":[{"value":{"event_id":"e1"}}]}` {
+		t.Errorf("unexpected body %q", gotBody)
+	}
+}
+
+// fakeSink records writes and optionally fails.
+type fakeSink struct {
+	name   string
+	fail   bool
+	writes int
+}
+
+func (f *fakeSink) Name() string { return f.name }
+func (f *fakeSink) Write(ctx context.Context, data []byte) error {
+	f.writes++
+	if f.fail {
+		return errors.New("boom")
+	}
+	return nil
+}
+func (f *fakeSink) Close() error { return nil }
+
+// recordingHealth captures MarkSuccess / MarkFailure calls by destination.
+type recordingHealth struct {
+	mu       sync.Mutex
+	success  map[string]int
+	failures map[string]int
+}
+
+func (h *recordingHealth) MarkSuccess(dest string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.success[dest]++
+}
+
+func (h *recordingHealth) MarkFailure(dest, reason string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.failures[dest]++
+}
+
+func TestWriteSinks_BestEffortDoesNotFail(t *testing.T) {
+	ok := &fakeSink{name: "ok"}
+	flaky := &fakeSink{name: "flaky", fail: true}
+	health := &recordingHealth{success: map[string]int{}, failures: map[string]int{}}
+
+	e := New(Config{
+		Health: health,
+		Sinks: []ConfiguredSink{
+			{Sink: ok},
+			{Sink: flaky, BestEffort: true},
+		},
+	})
+	if err := e.writeSinks([]byte("{}\n"), "e1"); err != nil {
+		t.Fatalf("best-effort failure should not fail writeSinks: %v", err)
+	}
+	if ok.writes != 1 || flaky.writes != 1 {
+		t.Errorf("expected both sinks attempted, got ok=%d flaky=%d", ok.writes, flaky.writes)
+	}
+	if health.success["ok"] != 1 || health.failures["flaky"] != 1 {
+		t.Errorf("unexpected health: success=%v failures=%v", health.success, health.failures)
+	}
+
+	e = New(Config{Health: health, Sinks: []ConfiguredSink{{Sink: flaky}, {Sink: ok}}})
+	if err := e.writeSinks([]byte("{}\n"), "e2"); err == nil {
+		t.Fatal("required sink failure should fail writeSinks")
+	}
+	if ok.writes != 2 {
+		t.Errorf("sinks after a failure must still be attempted, got ok=%d", ok.writes)
+	}
+}
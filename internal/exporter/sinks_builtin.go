@@ -0,0 +1,257 @@
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// sinks_builtin.go — sink kinds registered at package init.
+//
+//	webhook       HTTP POST of each record, HMAC-SHA256 signed.
+//	              URL (required), SECRET (required), TIMEOUT_MS (default 3000)
+//	kafka_rest    Kafka-compatible producer via the Confluent REST Proxy v2 API.
+//	              URL (required, proxy base), TOPIC (required), AUTH_HEADER (optional),
+//	              TIMEOUT_MS (default 3000)
+//	redis_stream  XADD of each record onto another Redis stream.
+//	              ADDR (required), STREAM (required), PASSWORD, TLS=true,
+//	              MAXLEN (default 0 = no trimming)
+//
+// Options are read from PAYFLUX_SINK_<NAME>_<OPTION>; see ParseSinkSpecs.
+
+// SignatureHeader carries the HMAC signature on outbound webhook requests.
+const SignatureHeader = "X-Payflux-Signature"
+
+func init() {
+	RegisterSinkKind("webhook", newWebhookSink, "URL", "SECRET", "TIMEOUT_MS")
+	RegisterSinkKind("kafka_rest", newKafkaRESTSink, "URL", "TOPIC", "AUTH_HEADER", "TIMEOUT_MS")
+	RegisterSinkKind("redis_stream", newRedisStreamSink, "ADDR", "STREAM", "PASSWORD", "TLS", "MAXLEN")
+}
+
+// SignPayload returns the signature header value for body sent at ts:
+//
+//	t=<unix seconds>,v1=<hex HMAC-SHA256(secret, "<t>.<body>")>
+//
+// Receivers recompute the HMAC over the same "<t>.<body>" string and should
+// reject timestamps outside their replay tolerance.
+func SignPayload(secret []byte, ts int64, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(strconv.FormatInt(ts, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return fmt.Sprintf("t=%d,v1=%s", ts, hex.EncodeToString(mac.Sum(nil)))
+}
+
+func sinkTimeout(cfg SinkConfig) (time.Duration, error) {
+	raw := cfg.Option("TIMEOUT_MS", "3000")
+	ms, err := strconv.Atoi(raw)
+	if err != nil || ms <= 0 {
+		return 0, fmt.Errorf("TIMEOUT_MS=%q must be a positive integer", raw)
+	}
+	return time.Duration(ms) * time.Millisecond, nil
+}
+
+// drainAndCheck consumes resp and returns an error for non-2xx statuses.
+func drainAndCheck(resp *http.Response) error {
+	defer resp.Body.Close()
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(snippet)))
+	}
+	return nil
+}
+
+// ── webhook ──────────────────────────────────────────────────────────────────
+
+type webhookSink struct {
+	name   string
+	url    string
+	secret []byte
+	client *http.Client
+	now    func() time.Time
+}
+
+func newWebhookSink(cfg SinkConfig) (Sink, error) {
+	url := cfg.Option("URL", "")
+	if url == "" {
+		return nil, fmt.Errorf("URL is required")
+	}
+	secret := cfg.Option("SECRET", "")
+	if secret == "" {
+		return nil, fmt.Errorf("SECRET is required")
+	}
+	timeout, err := sinkTimeout(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &webhookSink{
+		name:   cfg.Name,
+		url:    url,
+		secret: []byte(secret),
+		client: &http.Client{Timeout: timeout},
+		now:    time.Now,
+	}, nil
+}
+
+func (s *webhookSink) Name() string { return s.name }
+
+func (s *webhookSink) Write(ctx context.Context, data []byte) error {
+	body := bytes.TrimRight(data, "\n")
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, SignPayload(s.secret, s.now().Unix(), body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	return drainAndCheck(resp)
+}
+
+func (s *webhookSink) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
+
+// ── kafka_rest ───────────────────────────────────────────────────────────────
+
+// kafkaRESTEnvelope is the REST Proxy v2 produce body: {"records":[{"value":...}]}.
+type kafkaRESTEnvelope struct {
+	Records []kafkaRESTRecord `json:"records"`
+}
+
+type kafkaRESTRecord struct {
+	Value json.RawMessage `json:"value"`
+}
+
+type kafkaRESTSink struct {
+	name       string
+	endpoint   string
+	authHeader string
+	client     *http.Client
+}
+
+func newKafkaRESTSink(cfg SinkConfig) (Sink, error) {
+	base := strings.TrimRight(cfg.Option("URL", ""), "/")
+	if base == "" {
+		return nil, fmt.Errorf("URL is required")
+	}
+	topic := cfg.Option("TOPIC", "")
+	if topic == "" {
+		return nil, fmt.Errorf("TOPIC is required")
+	}
+	timeout, err := sinkTimeout(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &kafkaRESTSink{
+		name:       cfg.Name,
+		endpoint:   base + "/topics/" + topic,
+		authHeader: cfg.Option("AUTH_HEADER", ""),
+		client:     &http.Client{Timeout: timeout},
+	}, nil
+}
+
+func (s *kafkaRESTSink) Name() string { return s.name }
+
+func (s *kafkaRESTSink) Write(ctx context.Context, data []byte) error {
+	body, err := json.Marshal(kafkaRESTEnvelope{
+		Records: []kafkaRESTRecord{{Value: json.RawMessage(bytes.TrimRight(data, "\n"))}},
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/vnd.kafka.json.v2+json")
+	if s.authHeader != "" {
+		req.Header.Set("Authorization", s.authHeader)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	return drainAndCheck(resp)
+}
+
+func (s *kafkaRESTSink) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
+
+// ── redis_stream ─────────────────────────────────────────────────────────────
+
+type redisStreamSink struct {
+	name   string
+	stream string
+	maxLen int64
+	client *redis.Client
+}
+
+func newRedisStreamSink(cfg SinkConfig) (Sink, error) {
+	addr := cfg.Option("ADDR", "")
+	if addr == "" {
+		return nil, fmt.Errorf("ADDR is required")
+	}
+	stream := cfg.Option("STREAM", "")
+	if stream == "" {
+		return nil, fmt.Errorf("STREAM is required")
+	}
+	maxLen, err := strconv.ParseInt(cfg.Option("MAXLEN", "0"), 10, 64)
+	if err != nil || maxLen < 0 {
+		return nil, fmt.Errorf("MAXLEN=%q must be a non-negative integer", cfg.Option("MAXLEN", ""))
+	}
+
+	opts := &redis.Options{
+		Addr:         addr,
+		Password:     cfg.Option("PASSWORD", ""),
+		DialTimeout:  2 * time.Second,
+		ReadTimeout:  2 * time.Second,
+		WriteTimeout: 2 * time.Second,
+	}
+	if strings.EqualFold(cfg.Option("TLS", ""), "true") {
+		opts.TLSConfig = &tls.Config{}
+	}
+	return &redisStreamSink{
+		name:   cfg.Name,
+		stream: stream,
+		maxLen: maxLen,
+		client: redis.NewClient(opts),
+	}, nil
+}
+
+func (s *redisStreamSink) Name() string { return s.name }
+
+func (s *redisStreamSink) Write(ctx context.Context, data []byte) error {
+	args := &redis.XAddArgs{
+		Stream: s.stream,
+		Values: map[string]any{"data": string(bytes.TrimRight(data, "\n"))},
+	}
+	if s.maxLen > 0 {
+		args.MaxLen = s.maxLen
+		args.Approx = true
+	}
+	return s.client.XAdd(ctx, args).Err()
+}
+
+func (s *redisStreamSink) Close() error {
+	return s.client.Close()
+}
@@ -29,6 +29,8 @@ type Event struct {
 	Channel             string `json:"channel"`
 	RetryResult         string `json:"retry_result"`
 	FailureOrigin       string `json:"failure_origin"`
+
+	WorkspaceID string `json:"workspace_id,omitempty"`
 }
 
 // ExportedEvent mirrors main.ExportedEvent. Defined here alongside Event
@@ -43,11 +45,19 @@ type ExportedEvent struct {
 	StreamMessageID string `json:"stream_message_id"`
 	ConsumerName    string `json:"consumer_name"`
 	ProcessedAt     string `json:"processed_at"`
+	WorkspaceID     string `json:"workspace_id,omitempty"`
 
 	// Risk signals (v0.2.1+)
 	ProcessorRiskScore   float64  `json:"processor_risk_score,omitempty"`
 	ProcessorRiskBand    string   `json:"processor_risk_band,omitempty"`
 	ProcessorRiskDrivers []string `json:"processor_risk_drivers,omitempty"`
+	RiskModelVersion     string   `json:"risk_model_version,omitempty"` // scoring model that produced the risk fields
+
+	// Merchant-scoped risk signals, from the event's MerchantIDHash window
+	MerchantIDHash      string   `json:"merchant_id_hash,omitempty"`
+	MerchantRiskScore   float64  `json:"merchant_risk_score,omitempty"`
+	MerchantRiskBand    string   `json:"merchant_risk_band,omitempty"`
+	MerchantRiskDrivers []string `json:"merchant_risk_drivers,omitempty"`
 
 	// Tier 1 only: Upgrade hint (v0.2.3+)
 	UpgradeHint string `json:"upgrade_hint,omitempty"`
@@ -72,5 +82,6 @@ func (e *Exporter) buildExportRecord(event Event, messageID string) ExportedEven
 		StreamMessageID: messageID,
 		ConsumerName:    e.cfg.ConsumerName,
 		ProcessedAt:     time.Now().UTC().Format(time.RFC3339),
+		WorkspaceID:     event.WorkspaceID,
 	}
 }
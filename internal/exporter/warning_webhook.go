@@ -0,0 +1,399 @@
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// warning_webhook.go — push delivery of pilot warnings.
+//
+// addWarnings hands every new warning to Config.WarningNotifier. The
+// WarningDispatcher implementation fans it out to each configured endpoint
+// whose min band the warning meets, signs the body with SignPayload, and
+// retries failed deliveries with exponential backoff on background workers,
+// so a slow receiver never stalls the consumer.
+//
+// Endpoints are configured like sinks:
+//
+//	PAYFLUX_WARNING_WEBHOOKS=ops,risk-team
+//	PAYFLUX_WARNING_WEBHOOK_<NAME>_URL         (required)
+//	PAYFLUX_WARNING_WEBHOOK_<NAME>_SECRET      (required, HMAC-SHA256 key)
+//	PAYFLUX_WARNING_WEBHOOK_<NAME>_MIN_BAND    (default elevated)
+//	PAYFLUX_WARNING_WEBHOOK_<NAME>_TIMEOUT_MS  (default 3000)
+//
+// Each endpoint keeps a bounded in-memory delivery log for inspection.
+
+// WarningEventType is the "type" field of every warning webhook body.
+const WarningEventType = "warning.created"
+
+// Delivery results, used in the delivery log and as the metric label.
+const (
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+	DeliveryDropped   = "dropped"
+)
+
+const (
+	defaultWarningHookAttempts  = 5
+	defaultWarningHookBackoff   = 500 * time.Millisecond
+	maxWarningHookBackoff       = 30 * time.Second
+	defaultWarningHookQueueSize = 1000
+	defaultWarningHookLogSize   = 100
+)
+
+// bandRank orders risk bands for min-band filtering.
+var bandRank = map[string]int{"low": 0, "elevated": 1, "high": 2, "critical": 3}
+
+// ValidBand reports whether band is a known risk band.
+func ValidBand(band string) bool {
+	_, ok := bandRank[band]
+	return ok
+}
+
+// WarningWebhookConfig describes one warning webhook endpoint.
+type WarningWebhookConfig struct {
+	Name    string
+	URL     string
+	Secret  string
+	MinBand string
+	Timeout time.Duration
+}
+
+// ParseWarningWebhookSpecs parses PAYFLUX_WARNING_WEBHOOKS ("name1,name2")
+// and resolves each endpoint's options from PAYFLUX_WARNING_WEBHOOK_<NAME>_*
+// entries in environ (normally os.Environ()).
+func ParseWarningWebhookSpecs(spec string, environ []string) ([]WarningWebhookConfig, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, nil
+	}
+
+	var configs []WarningWebhookConfig
+	seen := map[string]bool{}
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !validSinkName(name) {
+			return nil, fmt.Errorf("invalid webhook name %q (use letters, digits, '_' or '-')", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate webhook name %q", name)
+		}
+		seen[name] = true
+
+		prefix := "PAYFLUX_WARNING_WEBHOOK_" + sinkEnvName(name) + "_"
+		opts := map[string]string{}
+		for _, kv := range environ {
+			k, v, found := strings.Cut(kv, "=")
+			if found && strings.HasPrefix(k, prefix) {
+				opts[strings.TrimPrefix(k, prefix)] = strings.TrimSpace(v)
+			}
+		}
+
+		cfg := WarningWebhookConfig{
+			Name:    name,
+			URL:     opts["URL"],
+			Secret:  opts["SECRET"],
+			MinBand: opts["MIN_BAND"],
+			Timeout: 3 * time.Second,
+		}
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("webhook %q: URL is required", name)
+		}
+		if cfg.Secret == "" {
+			return nil, fmt.Errorf("webhook %q: SECRET is required", name)
+		}
+		if cfg.MinBand == "" {
+			cfg.MinBand = "elevated"
+		}
+		if !ValidBand(cfg.MinBand) {
+			return nil, fmt.Errorf("webhook %q: MIN_BAND=%q must be low, elevated, high or critical", name, cfg.MinBand)
+		}
+		if raw := opts["TIMEOUT_MS"]; raw != "" {
+			ms, err := strconv.Atoi(raw)
+			if err != nil || ms <= 0 {
+				return nil, fmt.Errorf("webhook %q: TIMEOUT_MS=%q must be a positive integer", name, raw)
+			}
+			cfg.Timeout = time.Duration(ms) * time.Millisecond
+		}
+		configs = append(configs, cfg)
+	}
+	return configs, nil
+}
+
+// DeliveryRecord is one entry in an endpoint's delivery log.
+type DeliveryRecord struct {
+	WarningID  string    `json:"warning_id"`
+	Result     string    `json:"result"`
+	Attempts   int       `json:"attempts"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	At         time.Time `json:"at"`
+}
+
+// WebhookEndpointStatus summarizes one endpoint for the inspection API.
+// The secret is never included.
+type WebhookEndpointStatus struct {
+	Name      string          `json:"name"`
+	URL       string          `json:"url"`
+	MinBand   string          `json:"min_band"`
+	Delivered int64           `json:"delivered"`
+	Failed    int64           `json:"failed"`
+	Dropped   int64           `json:"dropped"`
+	Last      *DeliveryRecord `json:"last_delivery,omitempty"`
+}
+
+type webhookEndpoint struct {
+	cfg    WarningWebhookConfig
+	client *http.Client
+
+	mu        sync.Mutex
+	log       []DeliveryRecord // ring buffer; next is the slot after the newest entry
+	next      int
+	delivered int64
+	failed    int64
+	dropped   int64
+}
+
+func (ep *webhookEndpoint) record(rec DeliveryRecord, logSize int) {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+	switch rec.Result {
+	case DeliveryDelivered:
+		ep.delivered++
+	case DeliveryFailed:
+		ep.failed++
+	case DeliveryDropped:
+		ep.dropped++
+	}
+	if len(ep.log) < logSize {
+		ep.log = append(ep.log, rec)
+	} else {
+		ep.log[ep.next] = rec
+	}
+	ep.next = (ep.next + 1) % logSize
+}
+
+// recent returns up to limit records, newest first.
+func (ep *webhookEndpoint) recent(limit int) []DeliveryRecord {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+	n := len(ep.log)
+	if limit <= 0 || limit > n {
+		limit = n
+	}
+	out := make([]DeliveryRecord, 0, limit)
+	for i := 0; i < limit; i++ {
+		out = append(out, ep.log[(ep.next-1-i+n)%n])
+	}
+	return out
+}
+
+type warningDelivery struct {
+	ep        *webhookEndpoint
+	warningID string
+	body      []byte
+}
+
+// WarningDispatcher delivers warnings to webhook endpoints. Safe for
+// concurrent use; Notify never blocks.
+type WarningDispatcher struct {
+	endpoints []*webhookEndpoint
+	byName    map[string]*webhookEndpoint
+	queue     chan warningDelivery
+
+	// Tunables; set before Run.
+	Workers     int
+	MaxAttempts int
+	Backoff     time.Duration
+	LogSize     int
+	// OnResult is called once per finished delivery (metrics hook).
+	OnResult func(endpoint, result string)
+
+	now func() time.Time
+}
+
+// NewWarningDispatcher builds a dispatcher for configs. Call Run to start
+// delivering.
+func NewWarningDispatcher(configs []WarningWebhookConfig) *WarningDispatcher {
+	d := &WarningDispatcher{
+		byName:      make(map[string]*webhookEndpoint, len(configs)),
+		queue:       make(chan warningDelivery, defaultWarningHookQueueSize),
+		Workers:     2,
+		MaxAttempts: defaultWarningHookAttempts,
+		Backoff:     defaultWarningHookBackoff,
+		LogSize:     defaultWarningHookLogSize,
+		now:         time.Now,
+	}
+	for _, c := range configs {
+		ep := &webhookEndpoint{cfg: c, client: &http.Client{Timeout: c.Timeout}}
+		d.endpoints = append(d.endpoints, ep)
+		d.byName[c.Name] = ep
+	}
+	return d
+}
+
+// warningBand is the higher of the processor and merchant bands: a warning
+// raised by a merchant window should pass a filter its processor band would not.
+func warningBand(w *Warning) string {
+	if bandRank[w.MerchantRiskBand] > bandRank[w.RiskBand] {
+		return w.MerchantRiskBand
+	}
+	return w.RiskBand
+}
+
+// Notify enqueues w for every endpoint whose min band it meets. When the
+// queue is full the delivery is dropped and recorded in the endpoint's log.
+func (d *WarningDispatcher) Notify(w *Warning) {
+	band := warningBand(w)
+	var body []byte
+	for _, ep := range d.endpoints {
+		if bandRank[band] < bandRank[ep.cfg.MinBand] {
+			continue
+		}
+		if body == nil {
+			var err error
+			body, err = json.Marshal(struct {
+				Type    string   `json:"type"`
+				Warning *Warning `json:"warning"`
+			}{WarningEventType, w})
+			if err != nil {
+				log.Printf("warning_webhook_marshal_error warning_id=%s err=%v", w.WarningID, err)
+				return
+			}
+		}
+		select {
+		case d.queue <- warningDelivery{ep: ep, warningID: w.WarningID, body: body}:
+		default:
+			log.Printf("warning_webhook_queue_full endpoint=%s warning_id=%s", ep.cfg.Name, w.WarningID)
+			d.finish(ep, DeliveryRecord{WarningID: w.WarningID, Result: DeliveryDropped, Error: "queue full", At: d.now().UTC()})
+		}
+	}
+}
+
+// Run delivers queued warnings until ctx is cancelled.
+func (d *WarningDispatcher) Run(ctx context.Context) {
+	workers := d.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case job := <-d.queue:
+					d.deliver(ctx, job)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// errPermanent marks a response that retrying will not fix (4xx other than 408/429).
+var errPermanent = errors.New("permanent failure")
+
+func (d *WarningDispatcher) deliver(ctx context.Context, job warningDelivery) {
+	rec := DeliveryRecord{WarningID: job.warningID}
+	backoff := d.Backoff
+retry:
+	for attempt := 1; attempt <= d.MaxAttempts; attempt++ {
+		rec.Attempts = attempt
+		status, err := d.post(ctx, job, attempt)
+		rec.StatusCode = status
+		if err == nil {
+			rec.Result, rec.Error = DeliveryDelivered, ""
+			break
+		}
+		rec.Result, rec.Error = DeliveryFailed, err.Error()
+		if errors.Is(err, errPermanent) || attempt == d.MaxAttempts {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			rec.Error = "shutdown: " + rec.Error
+			break retry
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > maxWarningHookBackoff {
+			backoff = maxWarningHookBackoff
+		}
+	}
+	rec.At = d.now().UTC()
+	if rec.Result == DeliveryFailed {
+		log.Printf("warning_webhook_failed endpoint=%s warning_id=%s attempts=%d err=%s",
+			job.ep.cfg.Name, job.warningID, rec.Attempts, rec.Error)
+	}
+	d.finish(job.ep, rec)
+}
+
+func (d *WarningDispatcher) post(ctx context.Context, job warningDelivery, attempt int) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, job.ep.cfg.URL, bytes.NewReader(job.body))
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", errPermanent, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, SignPayload([]byte(job.ep.cfg.Secret), d.now().Unix(), job.body))
+	req.Header.Set("X-Payflux-Warning-Id", job.warningID)
+	req.Header.Set("X-Payflux-Delivery-Attempt", strconv.Itoa(attempt))
+
+	resp, err := job.ep.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	err = drainAndCheck(resp)
+	if err != nil && resp.StatusCode >= 400 && resp.StatusCode < 500 &&
+		resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests {
+		err = fmt.Errorf("%w: %v", errPermanent, err)
+	}
+	return resp.StatusCode, err
+}
+
+func (d *WarningDispatcher) finish(ep *webhookEndpoint, rec DeliveryRecord) {
+	ep.record(rec, d.LogSize)
+	if d.OnResult != nil {
+		d.OnResult(ep.cfg.Name, rec.Result)
+	}
+}
+
+// Endpoints returns the status of every endpoint in configuration order.
+func (d *WarningDispatcher) Endpoints() []WebhookEndpointStatus {
+	out := make([]WebhookEndpointStatus, 0, len(d.endpoints))
+	for _, ep := range d.endpoints {
+		st := WebhookEndpointStatus{Name: ep.cfg.Name, URL: ep.cfg.URL, MinBand: ep.cfg.MinBand}
+		if last := ep.recent(1); len(last) == 1 {
+			st.Last = &last[0]
+		}
+		ep.mu.Lock()
+		st.Delivered, st.Failed, st.Dropped = ep.delivered, ep.failed, ep.dropped
+		ep.mu.Unlock()
+		out = append(out, st)
+	}
+	return out
+}
+
+// Deliveries returns up to limit log entries for the named endpoint, newest
+// first. ok is false for an unknown endpoint.
+func (d *WarningDispatcher) Deliveries(name string, limit int) (records []DeliveryRecord, ok bool) {
+	ep, ok := d.byName[name]
+	if !ok {
+		return nil, false
+	}
+	return ep.recent(limit), true
+}
@@ -0,0 +1,190 @@
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+type hookReceiver struct {
+	mu       sync.Mutex
+	statuses []int // response status per request; 200 once exhausted
+	bodies   [][]byte
+	sigs     []string
+}
+
+func (h *hookReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	h.mu.Lock()
+	h.bodies = append(h.bodies, body)
+	h.sigs = append(h.sigs, r.Header.Get(SignatureHeader))
+	status := http.StatusOK
+	if len(h.statuses) > 0 {
+		status, h.statuses = h.statuses[0], h.statuses[1:]
+	}
+	h.mu.Unlock()
+	w.WriteHeader(status)
+}
+
+func (h *hookReceiver) count() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.bodies)
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for delivery")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func newTestDispatcher(t *testing.T, configs []WarningWebhookConfig) *WarningDispatcher {
+	t.Helper()
+	d := NewWarningDispatcher(configs)
+	d.Backoff = time.Millisecond
+	d.now = func() time.Time { return time.Unix(1700000000, 0) }
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go d.Run(ctx)
+	return d
+}
+
+func TestWarningDispatcher_SignsAndRetries(t *testing.T) {
+	recv := &hookReceiver{statuses: []int{http.StatusInternalServerError, http.StatusTooManyRequests}}
+	srv := httptest.NewServer(recv)
+	defer srv.Close()
+
+	var results []string
+	var mu sync.Mutex
+	d := newTestDispatcher(t, []WarningWebhookConfig{{Name: "ops", URL: srv.URL, Secret: "whsec_test", MinBand: "elevated", Timeout: time.Second}})
+	d.OnResult = func(_, result string) { mu.Lock(); results = append(results, result); mu.Unlock() }
+
+	d.Notify(&Warning{WarningID: "1-0", Processor: "stripe", RiskBand: "high"})
+	waitFor(t, func() bool { recs, _ := d.Deliveries("ops", 10); return len(recs) == 1 })
+
+	if got := recv.count(); got != 3 {
+		t.Fatalf("expected 3 attempts (500, 429, 200), got %d", got)
+	}
+	recs, _ := d.Deliveries("ops", 10)
+	if recs[0].Result != DeliveryDelivered || recs[0].Attempts != 3 || recs[0].StatusCode != 200 {
+		t.Errorf("unexpected delivery record: %+v", recs[0])
+	}
+	if want := SignPayload([]byte("whsec_test"), 1700000000, recv.bodies[2]); recv.sigs[2] != want {
+		t.Errorf("signature mismatch: got %s, want %s", recv.sigs[2], want)
+	}
+	var body struct {
+		Type    string  `json:"type"`
+		Warning Warning `json:"warning"`
+	}
+	if err := json.Unmarshal(recv.bodies[2], &body); err != nil || body.Type != WarningEventType || body.Warning.WarningID != "1-0" {
+		t.Errorf("unexpected body %s (err %v)", recv.bodies[2], err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(results) != 1 || results[0] != DeliveryDelivered {
+		t.Errorf("expected one delivered result, got %v", results)
+	}
+}
+
+func TestWarningDispatcher_PermanentFailureNotRetried(t *testing.T) {
+	recv := &hookReceiver{statuses: []int{http.StatusBadRequest}}
+	srv := httptest.NewServer(recv)
+	defer srv.Close()
+
+	d := newTestDispatcher(t, []WarningWebhookConfig{{Name: "ops", URL: srv.URL, Secret: "s", MinBand: "low", Timeout: time.Second}})
+	d.Notify(&Warning{WarningID: "1-0", RiskBand: "elevated"})
+	waitFor(t, func() bool { recs, _ := d.Deliveries("ops", 10); return len(recs) == 1 })
+
+	recs, _ := d.Deliveries("ops", 10)
+	if recs[0].Result != DeliveryFailed || recs[0].Attempts != 1 || recs[0].StatusCode != http.StatusBadRequest {
+		t.Errorf("expected single failed attempt, got %+v", recs[0])
+	}
+	if st := d.Endpoints()[0]; st.Failed != 1 || st.Delivered != 0 {
+		t.Errorf("unexpected endpoint counts: %+v", st)
+	}
+}
+
+func TestWarningDispatcher_MinBandFilter(t *testing.T) {
+	recvHigh, recvAll := &hookReceiver{}, &hookReceiver{}
+	srvHigh, srvAll := httptest.NewServer(recvHigh), httptest.NewServer(recvAll)
+	defer srvHigh.Close()
+	defer srvAll.Close()
+
+	d := newTestDispatcher(t, []WarningWebhookConfig{
+		{Name: "pager", URL: srvHigh.URL, Secret: "s", MinBand: "high", Timeout: time.Second},
+		{Name: "log", URL: srvAll.URL, Secret: "s", MinBand: "elevated", Timeout: time.Second},
+	})
+
+	d.Notify(&Warning{WarningID: "1-0", RiskBand: "elevated"})
+	d.Notify(&Warning{WarningID: "2-0", RiskBand: "low", MerchantRiskBand: "critical"})
+	waitFor(t, func() bool { return recvAll.count() == 2 && recvHigh.count() == 1 })
+
+	recs, _ := d.Deliveries("pager", 10)
+	if len(recs) != 1 || recs[0].WarningID != "2-0" {
+		t.Errorf("pager should only receive the merchant-critical warning, got %+v", recs)
+	}
+	recs, _ = d.Deliveries("log", 10)
+	if len(recs) != 2 || recs[0].WarningID != "2-0" || recs[1].WarningID != "1-0" {
+		t.Errorf("expected both warnings newest first, got %+v", recs)
+	}
+	if _, ok := d.Deliveries("missing", 10); ok {
+		t.Error("unknown endpoint should report ok=false")
+	}
+}
+
+func TestWebhookEndpoint_LogWraps(t *testing.T) {
+	ep := &webhookEndpoint{}
+	for i := 0; i < 5; i++ {
+		ep.record(DeliveryRecord{WarningID: string(rune('a' + i)), Result: DeliveryDelivered}, 3)
+	}
+	recs := ep.recent(0)
+	if len(recs) != 3 || recs[0].WarningID != "e" || recs[2].WarningID != "c" {
+		t.Errorf("expected e,d,c, got %+v", recs)
+	}
+	if ep.delivered != 5 {
+		t.Errorf("expected 5 delivered, got %d", ep.delivered)
+	}
+}
+
+func TestParseWarningWebhookSpecs(t *testing.T) {
+	env := []string{
+		"PAYFLUX_WARNING_WEBHOOK_OPS_URL=https://example.test/hook",
+		"PAYFLUX_WARNING_WEBHOOK_OPS_SECRET=whsec",
+		"PAYFLUX_WARNING_WEBHOOK_RISK_TEAM_URL=https://example.test/risk",
+		"PAYFLUX_WARNING_WEBHOOK_RISK_TEAM_SECRET=whsec2",
+		"PAYFLUX_WARNING_WEBHOOK_RISK_TEAM_MIN_BAND=critical",
+		"PAYFLUX_WARNING_WEBHOOK_RISK_TEAM_TIMEOUT_MS=500",
+	}
+	configs, err := ParseWarningWebhookSpecs("ops, risk-team", env)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(configs) != 2 || configs[0].MinBand != "elevated" || configs[1].MinBand != "critical" || configs[1].Timeout != 500*time.Millisecond {
+		t.Errorf("unexpected configs: %+v", configs)
+	}
+
+	bad := []struct{ spec, env string }{
+		{"ops", "PAYFLUX_WARNING_WEBHOOK_OPS_URL=https://x"},     // no secret
+		{"ops", "PAYFLUX_WARNING_WEBHOOK_OPS_SECRET=s"},          // no URL
+		{"ops,ops", "PAYFLUX_WARNING_WEBHOOK_OPS_URL=https://x"}, // duplicate
+		{"bad name", ""}, // invalid name
+	}
+	for _, b := range bad {
+		if _, err := ParseWarningWebhookSpecs(b.spec, []string{b.env}); err == nil {
+			t.Errorf("expected error for spec %q env %q", b.spec, b.env)
+		}
+	}
+	if _, err := ParseWarningWebhookSpecs("ops", append(env[:2:2], "PAYFLUX_WARNING_WEBHOOK_OPS_MIN_BAND=severe")); err == nil {
+		t.Error("expected error for unknown min band")
+	}
+}
@@ -0,0 +1,78 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// DriftEscalationsTotal tracks open drifts escalated past their TTL
+	DriftEscalationsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "payflux_drift_escalations_total",
+			Help: "Total number of open subscription drifts escalated after outliving their severity's TTL",
+		},
+		[]string{"from", "to"},
+	)
+
+	// DriftTimeoutsTotal tracks open drifts closed as timeout_unresolved
+	DriftTimeoutsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "payflux_drift_timeouts_total",
+			Help: "Total number of open subscription drifts closed as timeout_unresolved",
+		},
+		[]string{"severity"},
+	)
+
+	// DriftOpen tracks open drifts by effective severity
+	DriftOpen = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "payflux_drift_open",
+			Help: "Current number of open subscription drifts by effective severity",
+		},
+		[]string{"severity"},
+	)
+
+	// DriftOldestOpenSeconds tracks the age of the oldest open drift by effective severity
+	DriftOldestOpenSeconds = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "payflux_drift_oldest_open_seconds",
+			Help: "Age in seconds of the oldest open subscription drift by effective severity",
+		},
+		[]string{"severity"},
+	)
+
+	// DriftNotificationsTotal tracks drift notification deliveries
+	DriftNotificationsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "payflux_drift_notifications_total",
+			Help: "Total number of subscription drift notifications by notifier, kind and result",
+		},
+		[]string{"notifier", "kind", "result"},
+	)
+)
+
+// RecordDriftEscalation records an escalated drift
+func RecordDriftEscalation(from, to string) {
+	DriftEscalationsTotal.WithLabelValues(from, to).Inc()
+}
+
+// RecordDriftTimeout records a drift closed as timeout_unresolved
+func RecordDriftTimeout(severity string) {
+	DriftTimeoutsTotal.WithLabelValues(severity).Inc()
+}
+
+// UpdateDriftOpen sets the open drift gauges for one severity
+func UpdateDriftOpen(severity string, count int, oldestSeconds float64) {
+	DriftOpen.WithLabelValues(severity).Set(float64(count))
+	DriftOldestOpenSeconds.WithLabelValues(severity).Set(oldestSeconds)
+}
+
+// RecordDriftNotification records a drift notification delivery attempt
+func RecordDriftNotification(notifier, kind string, delivered bool) {
+	result := "delivered"
+	if !delivered {
+		result = "failed"
+	}
+	DriftNotificationsTotal.WithLabelValues(notifier, kind, result).Inc()
+}
@@ -38,14 +38,19 @@ func NewLimiter(redisClient *redis.Client) *Limiter {
 	return &Limiter{redis: redisClient}
 }
 
-// Check performs atomic rate limit check using Lua script
-// This is identical to the TypeScript implementation for consistency
-func (l *Limiter) Check(ctx context.Context, accountID string, cfg Config) (*Result, error) {
+// Check performs atomic rate limit check using Lua script, spending cost
+// tokens when the bucket holds that many. A request is otherwise denied
+// without spending anything. Cost 1 is identical to the TypeScript
+// implementation.
+func (l *Limiter) Check(ctx context.Context, accountID string, cfg Config, cost int) (*Result, error) {
 	// Validate config
 	if cfg.Capacity <= 0 || cfg.RefillRate <= 0 || cfg.Window <= 0 {
 		return nil, fmt.Errorf("invalid rate limit config: capacity=%d refill=%d window=%d",
 			cfg.Capacity, cfg.RefillRate, cfg.Window)
 	}
+	if cost <= 0 || cost > cfg.Capacity {
+		return nil, fmt.Errorf("invalid rate limit cost %d for capacity %d", cost, cfg.Capacity)
+	}
 
 	// Key by accountId ONLY (not tier, not API key)
 	key := fmt.Sprintf("rl:account:%s", accountID)
@@ -58,6 +63,7 @@ func (l *Limiter) Check(ctx context.Context, accountID string, cfg Config) (*Res
 		local capacity = tonumber(ARGV[2])
 		local refillRate = tonumber(ARGV[3])
 		local ttl = tonumber(ARGV[4])
+		local cost = tonumber(ARGV[5])
 		
 		-- Get current state or initialize
 		local stateJson = redis.call('GET', key)
@@ -82,14 +88,14 @@ func (l *Limiter) Check(ctx context.Context, accountID string, cfg Config) (*Res
 		local allowed = 0
 		local reset = 0
 		
-		if tokens >= 1 then
+		if tokens >= cost then
 			allowed = 1
-			tokens = tokens - 1
+			tokens = tokens - cost
 			if tokens < 1 then
 				reset = math.ceil((1 - tokens) / refillRate)
 			end
 		else
-			reset = math.ceil((1 - tokens) / refillRate)
+			reset = math.ceil((cost - tokens) / refillRate)
 		end
 		
 		-- Save updated state
@@ -103,7 +109,7 @@ func (l *Limiter) Check(ctx context.Context, accountID string, cfg Config) (*Res
 	// Execute Lua script
 	result, err := l.redis.Eval(ctx, script,
 		[]string{key},
-		now, cfg.Capacity, cfg.RefillRate, cfg.Window,
+		now, cfg.Capacity, cfg.RefillRate, cfg.Window, cost,
 	).Result()
 
 	if err != nil {
@@ -0,0 +1,193 @@
+package rac
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// MigrationPolicy declares how reducer vN+1's final state may differ from
+// vN's over the same events. Every field not named here must fold to the
+// same value under both versions.
+type MigrationPolicy[S any] struct {
+	// Name identifies the migration in failure messages, e.g. "v1 -> v2".
+	Name string
+
+	// Fields breaks a state into named field values, compared with
+	// reflect.DeepEqual. Values should be normalized (times in UTC or
+	// formatted, nil pointers as nil) so equal states yield equal maps.
+	// A field present under only one version is reported as absent under
+	// the other. Required.
+	Fields func(s *S) map[string]any
+
+	// Changed lists fields vN+1 may report differently without a rule
+	// for how, including fields it adds or drops.
+	Changed []string
+
+	// Transforms derives, from vN's final state, the value vN+1 must
+	// report for a field. Use it for renamed, re-encoded, or newly
+	// derived fields.
+	Transforms map[string]func(prev *S) any
+}
+
+// VersionDiff is one field on which vN+1 departs from the policy.
+type VersionDiff struct {
+	Field string
+
+	// Prev and Next are the field's values under vN and vN+1; Absent
+	// when the version does not report the field.
+	Prev, Next any
+
+	// Want is the value a transform expected. Nil for fields compared
+	// for equality.
+	Want        any
+	Transformed bool
+}
+
+// Absent stands in for a field a version does not report.
+var Absent = absent{}
+
+type absent struct{}
+
+func (absent) String() string { return "<absent>" }
+
+func (d VersionDiff) String() string {
+	if d.Transformed {
+		return fmt.Sprintf("%s: vN %s, vN+1 %s, transform wants %s",
+			d.Field, formatValue(d.Prev), formatValue(d.Next), formatValue(d.Want))
+	}
+	return fmt.Sprintf("%s: vN %s, vN+1 %s", d.Field, formatValue(d.Prev), formatValue(d.Next))
+}
+
+func formatValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "<nil>"
+	case absent:
+		return v.String()
+	case string:
+		return fmt.Sprintf("%q", v)
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// Validate rejects a policy without Fields or with a field both changed
+// and transformed.
+func (p MigrationPolicy[S]) Validate() error {
+	if p.Fields == nil {
+		return fmt.Errorf("migration policy %q: Fields is required", p.Name)
+	}
+	for _, f := range p.Changed {
+		if _, ok := p.Transforms[f]; ok {
+			return fmt.Errorf("migration policy %q: %s is both changed and transformed", p.Name, f)
+		}
+	}
+	return nil
+}
+
+// CompareVersions folds events through prev and next and returns every
+// field on which next's final state departs from policy, sorted by
+// field. Policy entries naming a field neither version reports are an
+// error: a stale policy would otherwise excuse nothing silently.
+func CompareVersions[S any, E any](prev, next Reducer[S, E], policy MigrationPolicy[S], events []E) ([]VersionDiff, error) {
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	prevState := FoldEvents(prev, events)
+	nextState := FoldEvents(next, events)
+
+	prevFields, nextFields := map[string]any{}, map[string]any{}
+	if prevState != nil {
+		prevFields = policy.Fields(prevState)
+	}
+	if nextState != nil {
+		nextFields = policy.Fields(nextState)
+	}
+
+	known := map[string]bool{}
+	for f := range prevFields {
+		known[f] = true
+	}
+	for f := range nextFields {
+		known[f] = true
+	}
+	declared := map[string]bool{}
+	for _, f := range policy.Changed {
+		declared[f] = true
+	}
+	for f := range policy.Transforms {
+		declared[f] = true
+	}
+	var unknown []string
+	for f := range declared {
+		if !known[f] {
+			unknown = append(unknown, f)
+		}
+	}
+	// With no state at all there are no fields to check the policy
+	// against.
+	if len(unknown) > 0 && len(known) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("migration policy %q names unknown fields: %s", policy.Name, strings.Join(unknown, ", "))
+	}
+
+	lookup := func(fields map[string]any, f string) any {
+		if v, ok := fields[f]; ok {
+			return v
+		}
+		return Absent
+	}
+	names := make([]string, 0, len(known))
+	for f := range known {
+		names = append(names, f)
+	}
+	sort.Strings(names)
+
+	var diffs []VersionDiff
+	for _, f := range names {
+		p, n := lookup(prevFields, f), lookup(nextFields, f)
+		if transform, ok := policy.Transforms[f]; ok {
+			if prevState == nil {
+				continue
+			}
+			want := transform(prevState)
+			if !reflect.DeepEqual(want, n) {
+				diffs = append(diffs, VersionDiff{Field: f, Prev: p, Next: n, Want: want, Transformed: true})
+			}
+			continue
+		}
+		if declared[f] {
+			continue
+		}
+		if !reflect.DeepEqual(p, n) {
+			diffs = append(diffs, VersionDiff{Field: f, Prev: p, Next: n})
+		}
+	}
+	return diffs, nil
+}
+
+// AssertFutureVersionCompatibility folds the vN event corpus through prev
+// (vN) and next (vN+1) and fails with a per-field diff when next's final
+// state differs from prev's in any way policy does not declare. Running
+// prev against itself with an empty policy is the identity check a
+// reducer with a single version uses until its successor exists.
+func AssertFutureVersionCompatibility[S any, E any](t *testing.T, prev, next Reducer[S, E], policy MigrationPolicy[S], events []E) {
+	t.Helper()
+	diffs, err := CompareVersions(prev, next, policy, events)
+	if err != nil {
+		t.Fatalf("future-version compatibility: %v", err)
+	}
+	if len(diffs) == 0 {
+		return
+	}
+	lines := make([]string, len(diffs))
+	for i, d := range diffs {
+		lines[i] = "  " + d.String()
+	}
+	t.Errorf("future-version compatibility (%s) failed: %d undeclared field difference(s)\n%s\n  vN:   %s\n  vN+1: %s",
+		policy.Name, len(diffs), strings.Join(lines, "\n"),
+		safeDescribe(prev, FoldEvents(prev, events)),
+		safeDescribe(next, FoldEvents(next, events)))
+}
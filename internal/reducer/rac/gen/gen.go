@@ -0,0 +1,330 @@
+// Package gen synthesizes Stripe subscription lifecycles for the RAC
+// harness and shrinks failing cases to a minimal event list.
+//
+// A lifecycle is one subscription's webhook stream as Stripe would
+// deliver it: an optional checkout, creation (trialing or active), trial
+// conversion, monthly renewals with invoice outcomes, dunning through
+// past_due into recovery or cancellation, and cancel-at-period-end. On
+// top of the clean stream the generator layers the pathologies the
+// reducer must absorb — duplicate deliveries, late deliveries, and events
+// without created — at configurable rates.
+//
+// Events are reducer-agnostic: each carries a Stripe-shaped payload
+// (Event.JSON) that a reducer's test adapter parses exactly as the
+// reducer parses stripe_event_ledger rows. Everything is a pure function
+// of the seed, so a failing seed reproduces.
+package gen
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// Event is one generated webhook delivery.
+type Event struct {
+	ID   string
+	Type string
+
+	// Created is event.created in unix seconds; 0 when the generator
+	// dropped it (the payload then omits the field).
+	Created int64
+
+	// Object is data.object.
+	Object map[string]any
+}
+
+// APIVersion is the api_version stamped on generated events.
+const APIVersion = "2025-02-24.acacia"
+
+// JSON renders the event as Stripe's webhook payload.
+func (e Event) JSON() []byte {
+	payload := map[string]any{
+		"id":          e.ID,
+		"object":      "event",
+		"type":        e.Type,
+		"api_version": APIVersion,
+		"data":        map[string]any{"object": e.Object},
+	}
+	if e.Created != 0 {
+		payload["created"] = e.Created
+	}
+	b, _ := json.Marshal(payload)
+	return b
+}
+
+// String summarizes the event for failure reports.
+func (e Event) String() string {
+	created := "<missing>"
+	if e.Created != 0 {
+		created = time.Unix(e.Created, 0).UTC().Format(time.RFC3339)
+	}
+	s := fmt.Sprintf("%s %s created=%s", e.ID, e.Type, created)
+	if status, ok := e.Object["status"]; ok {
+		s += fmt.Sprintf(" status=%v", status)
+	}
+	if attempts, ok := e.Object["attempt_count"]; ok {
+		s += fmt.Sprintf(" attempt_count=%v", attempts)
+	}
+	if cancel, ok := e.Object["cancel_at_period_end"]; ok && cancel == true {
+		s += " cancel_at_period_end"
+	}
+	return s
+}
+
+// Options shapes a generated lifecycle.
+type Options struct {
+	// SubscriptionID names the subscription. Default "sub_gen".
+	SubscriptionID string
+
+	// Start is when the subscription is created. Default 2026-01-01 UTC.
+	Start time.Time
+
+	// MaxPeriods bounds the number of billing periods. Default 6.
+	MaxPeriods int
+
+	// DuplicateRate is the chance each event is delivered twice.
+	DuplicateRate float64
+
+	// LateRate is the chance each event is delivered after a later one.
+	LateRate float64
+
+	// MissingCreatedRate is the chance an event loses created.
+	MissingCreatedRate float64
+}
+
+// DefaultOptions are the rates the harness runs with: enough
+// pathology that most lifecycles exercise each one.
+func DefaultOptions() Options {
+	return Options{
+		DuplicateRate:      0.1,
+		LateRate:           0.2,
+		MissingCreatedRate: 0.03,
+	}
+}
+
+func (o Options) withDefaults() Options {
+	if o.SubscriptionID == "" {
+		o.SubscriptionID = "sub_gen"
+	}
+	if o.Start.IsZero() {
+		o.Start = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	if o.MaxPeriods <= 0 {
+		o.MaxPeriods = 6
+	}
+	return o
+}
+
+// Lifecycle generates one subscription's delivered event stream for
+// seed.
+func Lifecycle(seed int64, opts Options) []Event {
+	opts = opts.withDefaults()
+	rng := rand.New(rand.NewSource(seed))
+	events := cleanLifecycle(rng, opts)
+	return deliver(rng, events, opts)
+}
+
+// lifecycle tracks the subscription while its events are generated.
+type lifecycle struct {
+	rng    *rand.Rand
+	subID  string
+	now    time.Time
+	seq    int
+	events []Event
+
+	status            string
+	periodStart       time.Time
+	periodEnd         time.Time
+	cancelAtPeriodEnd bool
+	canceledAt        *time.Time
+	trialStart        *time.Time
+	trialEnd          *time.Time
+}
+
+// advance moves the clock forward by at least d, plus up to an hour of
+// jitter, so every generated event has a distinct created second.
+func (l *lifecycle) advance(d time.Duration) {
+	l.now = l.now.Add(d + time.Duration(l.rng.Intn(3600)+1)*time.Second)
+}
+
+func (l *lifecycle) emit(eventType string, object map[string]any) {
+	l.seq++
+	l.events = append(l.events, Event{
+		ID:      fmt.Sprintf("evt_%s_%03d", l.subID, l.seq),
+		Type:    eventType,
+		Created: l.now.Unix(),
+		Object:  object,
+	})
+}
+
+func unixPtr(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.Unix()
+}
+
+func (l *lifecycle) subscription(eventType string) {
+	l.emit(eventType, map[string]any{
+		"id":                   l.subID,
+		"object":               "subscription",
+		"status":               l.status,
+		"current_period_start": l.periodStart.Unix(),
+		"current_period_end":   l.periodEnd.Unix(),
+		"cancel_at_period_end": l.cancelAtPeriodEnd,
+		"canceled_at":          unixPtr(l.canceledAt),
+		"trial_start":          unixPtr(l.trialStart),
+		"trial_end":            unixPtr(l.trialEnd),
+		"customer":             "cus_" + l.subID,
+	})
+}
+
+func (l *lifecycle) invoice(eventType string, attempt int) {
+	l.emit(eventType, map[string]any{
+		"id":            fmt.Sprintf("in_%s_%s", l.subID, l.periodStart.Format("200601")),
+		"object":        "invoice",
+		"subscription":  l.subID,
+		"attempt_count": attempt,
+		"customer":      "cus_" + l.subID,
+	})
+}
+
+func (l *lifecycle) cancel() {
+	at := l.now
+	l.status, l.canceledAt = "canceled", &at
+	l.subscription("customer.subscription.deleted")
+}
+
+func cleanLifecycle(rng *rand.Rand, opts Options) []Event {
+	l := &lifecycle{rng: rng, subID: opts.SubscriptionID, now: opts.Start}
+
+	if rng.Intn(2) == 0 {
+		l.emit("checkout.session.completed", map[string]any{
+			"id":                  "cs_" + l.subID,
+			"object":              "checkout.session",
+			"mode":                "subscription",
+			"subscription":        l.subID,
+			"client_reference_id": "ws_" + l.subID,
+		})
+		l.advance(0)
+	}
+
+	l.periodStart = l.now
+	if rng.Intn(2) == 0 {
+		start, end := l.now, l.now.AddDate(0, 0, 14)
+		l.status, l.trialStart, l.trialEnd = "trialing", &start, &end
+		l.periodEnd = end
+		l.subscription("customer.subscription.created")
+		l.now = end
+		l.periodStart = end
+		l.status = "active"
+	} else {
+		l.status = "active"
+		l.subscription("customer.subscription.created")
+	}
+	l.periodEnd = l.periodStart.AddDate(0, 1, 0)
+	l.advance(0)
+	l.invoice("invoice.paid", 1)
+	l.advance(0)
+	l.subscription("customer.subscription.updated")
+
+	periods := 1 + rng.Intn(opts.MaxPeriods)
+	for p := 0; p < periods; p++ {
+		// Mid-period: the customer may schedule cancellation (and
+		// sometimes think better of it).
+		if rng.Intn(5) == 0 {
+			l.advance(time.Duration(rng.Intn(10)+1) * 24 * time.Hour)
+			l.cancelAtPeriodEnd = true
+			l.subscription("customer.subscription.updated")
+			if rng.Intn(3) == 0 {
+				l.advance(24 * time.Hour)
+				l.cancelAtPeriodEnd = false
+				l.subscription("customer.subscription.updated")
+			}
+		}
+
+		// Renewal at period end.
+		l.now = l.periodEnd
+		l.advance(0)
+		if l.cancelAtPeriodEnd {
+			l.cancel()
+			return l.events
+		}
+		l.periodStart, l.periodEnd = l.periodEnd, l.periodEnd.AddDate(0, 1, 0)
+
+		if rng.Intn(3) != 0 {
+			l.invoice("invoice.paid", 1)
+			l.advance(0)
+			l.subscription("customer.subscription.updated")
+			continue
+		}
+
+		// Dunning: up to four attempts, a few days apart.
+		l.invoice("invoice.payment_failed", 1)
+		l.advance(0)
+		l.status = "past_due"
+		l.subscription("customer.subscription.updated")
+		recovered := false
+		for attempt := 2; attempt <= 4; attempt++ {
+			l.advance(time.Duration(rng.Intn(3)+2) * 24 * time.Hour)
+			switch rng.Intn(4) {
+			case 0:
+				l.invoice("invoice.payment_succeeded", attempt)
+				recovered = true
+			case 1:
+				l.invoice("invoice.payment_action_required", attempt)
+			default:
+				l.invoice("invoice.payment_failed", attempt)
+			}
+			if recovered {
+				break
+			}
+		}
+		l.advance(0)
+		if !recovered {
+			l.cancel()
+			return l.events
+		}
+		l.status = "active"
+		l.subscription("customer.subscription.updated")
+	}
+
+	// Survivors may still be canceled outright.
+	if rng.Intn(4) == 0 {
+		l.advance(time.Duration(rng.Intn(20)+1) * 24 * time.Hour)
+		l.cancel()
+	}
+	return l.events
+}
+
+// deliver turns the clean, created-ordered stream into a delivery
+// order: some events lose created, some are delivered twice, some are
+// delivered after events created later.
+func deliver(rng *rand.Rand, events []Event, opts Options) []Event {
+	out := make([]Event, 0, len(events)+len(events)/4)
+	for _, e := range events {
+		if rng.Float64() < opts.MissingCreatedRate {
+			e.Created = 0
+		}
+		out = append(out, e)
+		if rng.Float64() < opts.DuplicateRate {
+			out = append(out, e)
+		}
+	}
+	for i := len(out) - 2; i >= 0; i-- {
+		if rng.Float64() >= opts.LateRate {
+			continue
+		}
+		// Deliver out[i] up to five positions later.
+		j := i + 1 + rng.Intn(5)
+		if j >= len(out) {
+			j = len(out) - 1
+		}
+		late := out[i]
+		copy(out[i:j], out[i+1:j+1])
+		out[j] = late
+	}
+	return out
+}
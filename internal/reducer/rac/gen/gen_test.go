@@ -0,0 +1,101 @@
+package gen
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestLifecycleIsAFunctionOfSeed(t *testing.T) {
+	a := Lifecycle(42, DefaultOptions())
+	b := Lifecycle(42, DefaultOptions())
+	if !reflect.DeepEqual(a, b) {
+		t.Fatal("same seed produced different lifecycles")
+	}
+	if reflect.DeepEqual(a, Lifecycle(43, DefaultOptions())) {
+		t.Fatal("different seeds produced the same lifecycle")
+	}
+}
+
+func TestCleanLifecycleIsOrderedAndComplete(t *testing.T) {
+	statuses := map[any]bool{}
+	for seed := int64(1); seed <= 200; seed++ {
+		events := Lifecycle(seed, Options{})
+		var last int64
+		for _, e := range events {
+			if e.Created <= last {
+				t.Fatalf("seed %d: %s not after the previous event", seed, e)
+			}
+			last = e.Created
+			statuses[e.Object["status"]] = true
+		}
+	}
+	for _, want := range []string{"trialing", "active", "past_due", "canceled"} {
+		if !statuses[want] {
+			t.Errorf("no lifecycle reached %s", want)
+		}
+	}
+}
+
+func TestDeliveryPathologies(t *testing.T) {
+	var duplicates, late, missing int
+	for seed := int64(1); seed <= 100; seed++ {
+		seen := map[string]bool{}
+		var last int64
+		for _, e := range Lifecycle(seed, DefaultOptions()) {
+			if seen[e.ID] {
+				duplicates++
+			}
+			seen[e.ID] = true
+			switch {
+			case e.Created == 0:
+				missing++
+			case e.Created < last:
+				late++
+			default:
+				last = e.Created
+			}
+		}
+	}
+	if duplicates == 0 || late == 0 || missing == 0 {
+		t.Fatalf("duplicates=%d late=%d missing_created=%d; want all non-zero", duplicates, late, missing)
+	}
+}
+
+func TestEventJSON(t *testing.T) {
+	e := Event{ID: "evt_1", Type: "customer.subscription.updated", Object: map[string]any{"id": "sub_1"}}
+	var payload map[string]any
+	if err := json.Unmarshal(e.JSON(), &payload); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if _, ok := payload["created"]; ok {
+		t.Error("created present on an event without it")
+	}
+	e.Created = 1700000000
+	if err := json.Unmarshal(e.JSON(), &payload); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if payload["created"] != float64(1700000000) || payload["data"].(map[string]any)["object"].(map[string]any)["id"] != "sub_1" {
+		t.Errorf("payload = %v", payload)
+	}
+}
+
+func TestShrink(t *testing.T) {
+	// Fails whenever 3 precedes 7.
+	fails := func(xs []int) bool {
+		seen3 := false
+		for _, x := range xs {
+			if x == 3 {
+				seen3 = true
+			}
+			if x == 7 && seen3 {
+				return true
+			}
+		}
+		return false
+	}
+	got := Shrink([]int{9, 3, 1, 4, 1, 5, 9, 2, 6, 5, 3, 5, 7, 9}, fails)
+	if !reflect.DeepEqual(got, []int{3, 7}) {
+		t.Fatalf("Shrink = %v, want [3 7]", got)
+	}
+}
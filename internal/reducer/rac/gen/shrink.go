@@ -0,0 +1,54 @@
+package gen
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// Shrink returns a minimal sub-sequence of events for which fails still
+// reports true: removing any single remaining event makes it pass.
+// Order is preserved. fails must be deterministic and must hold for
+// events.
+//
+// The search is delta debugging: drop ever smaller chunks while the
+// failure persists, then single events until none can go.
+func Shrink[E any](events []E, fails func([]E) bool) []E {
+	current := append([]E(nil), events...)
+	for chunk := len(current) / 2; chunk >= 1; chunk /= 2 {
+		for start := 0; start+chunk <= len(current); {
+			candidate := make([]E, 0, len(current)-chunk)
+			candidate = append(candidate, current[:start]...)
+			candidate = append(candidate, current[start+chunk:]...)
+			if len(candidate) > 0 && fails(candidate) {
+				current = candidate
+				continue
+			}
+			start += chunk
+		}
+	}
+	return current
+}
+
+// Check runs property over cases generated lifecycles, seeds 1..cases.
+// A failing case is shrunk to a minimal event list and reported with its
+// seed, so it can be replayed with generate(seed) and turned into a
+// fixture.
+func Check[E any](t *testing.T, cases int, generate func(seed int64) []E, property func([]E) error) {
+	t.Helper()
+	for seed := int64(1); seed <= int64(cases); seed++ {
+		events := generate(seed)
+		err := property(events)
+		if err == nil {
+			continue
+		}
+		minimal := Shrink(events, func(candidate []E) bool { return property(candidate) != nil })
+		lines := make([]string, len(minimal))
+		for i, e := range minimal {
+			lines[i] = fmt.Sprintf("  %2d. %v", i+1, e)
+		}
+		t.Errorf("seed %d: %v\nshrunk from %d to %d events:\n%s\nminimal case fails with: %v",
+			seed, err, len(events), len(minimal), strings.Join(lines, "\n"), property(minimal))
+		return
+	}
+}
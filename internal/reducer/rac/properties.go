@@ -1,6 +1,7 @@
 package rac
 
 import (
+	"errors"
 	"fmt"
 	"math/rand"
 	"testing"
@@ -21,12 +22,20 @@ import (
 // must converge.
 func AssertPermutationInvariance[S any, E any](t *testing.T, r Reducer[S, E], events []E, trials int) {
 	t.Helper()
+	if err := CheckPermutationInvariance(r, events, trials); err != nil {
+		t.Error(err)
+	}
+}
+
+// CheckPermutationInvariance is AssertPermutationInvariance as a
+// predicate: it returns the first violation, or nil.
+func CheckPermutationInvariance[S any, E any](r Reducer[S, E], events []E, trials int) error {
 	if trials < 2 {
-		t.Fatalf("permutation invariance requires at least 2 trials, got %d", trials)
+		return fmt.Errorf("permutation invariance requires at least 2 trials, got %d", trials)
 	}
 	if len(events) < 2 {
 		// Trivially satisfied; nothing to permute.
-		return
+		return nil
 	}
 
 	baseline := FoldEvents(r, events)
@@ -43,12 +52,13 @@ func AssertPermutationInvariance[S any, E any](t *testing.T, r Reducer[S, E], ev
 
 		result := FoldEvents(r, shuffled)
 		if !r.Equal(baseline, result) {
-			t.Errorf("permutation invariance failed at trial %d\n  baseline:    %s\n  permutation: %s",
+			return fmt.Errorf("permutation invariance failed at trial %d\n  baseline:    %s\n  permutation: %s",
 				trial,
 				safeDescribe(r, baseline),
 				safeDescribe(r, result))
 		}
 	}
+	return nil
 }
 
 // AssertIdempotency verifies that applying the same event twice in
@@ -59,8 +69,15 @@ func AssertPermutationInvariance[S any, E any](t *testing.T, r Reducer[S, E], ev
 // fails to dedup.
 func AssertIdempotency[S any, E any](t *testing.T, r Reducer[S, E], events []E) {
 	t.Helper()
+	if err := CheckIdempotency(r, events); err != nil {
+		t.Error(err)
+	}
+}
+
+// CheckIdempotency is AssertIdempotency as a predicate.
+func CheckIdempotency[S any, E any](r Reducer[S, E], events []E) error {
 	if len(events) == 0 {
-		return
+		return nil
 	}
 
 	once := FoldEvents(r, events)
@@ -73,10 +90,11 @@ func AssertIdempotency[S any, E any](t *testing.T, r Reducer[S, E], events []E)
 	twiceResult := FoldEvents(r, twice)
 
 	if !r.Equal(once, twiceResult) {
-		t.Errorf("idempotency failed: same events applied twice produced different result\n  once:  %s\n  twice: %s",
+		return fmt.Errorf("idempotency failed: same events applied twice produced different result\n  once:  %s\n  twice: %s",
 			safeDescribe(r, once),
 			safeDescribe(r, twiceResult))
 	}
+	return nil
 }
 
 // AssertDeterminism verifies that running the reducer over the same input
@@ -84,20 +102,28 @@ func AssertIdempotency[S any, E any](t *testing.T, r Reducer[S, E], events []E)
 // on wall-clock time, process state, or other non-deterministic inputs.
 func AssertDeterminism[S any, E any](t *testing.T, r Reducer[S, E], events []E, repeats int) {
 	t.Helper()
+	if err := CheckDeterminism(r, events, repeats); err != nil {
+		t.Error(err)
+	}
+}
+
+// CheckDeterminism is AssertDeterminism as a predicate.
+func CheckDeterminism[S any, E any](r Reducer[S, E], events []E, repeats int) error {
 	if repeats < 2 {
-		t.Fatalf("determinism requires at least 2 repeats, got %d", repeats)
+		return fmt.Errorf("determinism requires at least 2 repeats, got %d", repeats)
 	}
 
 	first := FoldEvents(r, events)
 	for i := 1; i < repeats; i++ {
 		result := FoldEvents(r, events)
 		if !r.Equal(first, result) {
-			t.Errorf("determinism failed at repeat %d\n  first:  %s\n  repeat: %s",
+			return fmt.Errorf("determinism failed at repeat %d\n  first:  %s\n  repeat: %s",
 				i,
 				safeDescribe(r, first),
 				safeDescribe(r, result))
 		}
 	}
+	return nil
 }
 
 // AssertPartialReplayEquivalence verifies that processing a prefix of the
@@ -111,8 +137,16 @@ func AssertDeterminism[S any, E any](t *testing.T, r Reducer[S, E], events []E,
 // equivalent to a reducer that has processed every prior event.
 func AssertPartialReplayEquivalence[S any, E any](t *testing.T, r Reducer[S, E], events []E, splits int) {
 	t.Helper()
+	if err := CheckPartialReplayEquivalence(r, events, splits); err != nil {
+		t.Error(err)
+	}
+}
+
+// CheckPartialReplayEquivalence is AssertPartialReplayEquivalence as a
+// predicate.
+func CheckPartialReplayEquivalence[S any, E any](r Reducer[S, E], events []E, splits int) error {
 	if len(events) < 2 {
-		return
+		return nil
 	}
 
 	whole := FoldEvents(r, events)
@@ -135,24 +169,25 @@ func AssertPartialReplayEquivalence[S any, E any](t *testing.T, r Reducer[S, E],
 		}
 
 		if !r.Equal(whole, continued) {
-			t.Errorf("partial replay equivalence failed at split %d (%d/%d events)\n  whole:    %s\n  split:    %s",
+			return fmt.Errorf("partial replay equivalence failed at split %d (%d/%d events)\n  whole:    %s\n  split:    %s",
 				i, splitPoint, len(events),
 				safeDescribe(r, whole),
 				safeDescribe(r, continued))
 		}
 	}
+	return nil
 }
 
-// AssertFutureVersionCompatibility is a placeholder for the future-reducer
-// compatibility test. Once reducer v2 exists, the test will run v1 and v2
-// over the same event set and either assert identical output or invoke a
-// documented migration policy to explain the difference.
-//
-// For now this is a no-op so tests that include it compile and document
-// the intent.
-func AssertFutureVersionCompatibility[S any, E any](t *testing.T, r Reducer[S, E], events []E) {
-	t.Helper()
-	t.Log("future-version compatibility: only one reducer version exists today; placeholder satisfied trivially")
+// CheckAll runs every single-version property with the trial counts the
+// reducers' hand-written RAC tests use. It is the property generated
+// lifecycles (package gen) are checked against.
+func CheckAll[S any, E any](r Reducer[S, E], events []E) error {
+	return errors.Join(
+		CheckPermutationInvariance(r, events, 10),
+		CheckIdempotency(r, events),
+		CheckDeterminism(r, events, 5),
+		CheckPartialReplayEquivalence(r, events, 5),
+	)
 }
 
 func safeDescribe[S any, E any](r Reducer[S, E], s *S) string {
@@ -13,13 +13,14 @@
 //   - Partial replay equivalence: processing the first half of events then
 //     all events produces the same final state as processing all events
 //     directly.
-//   - Future-version compatibility (placeholder): when reducer vN+1 exists,
-//     its output over the vN event set must either be identical to vN's
-//     output or differ in ways documented by a migration policy.
+//   - Future-version compatibility: reducer vN+1's output over the vN
+//     event set must either be identical to vN's output or differ in ways
+//     declared by a migration policy.
 //
 // Reducers expose themselves to the harness via the Reducer interface
 // below. Concrete tests live alongside each reducer's package — this
-// package only defines the contracts.
+// package only defines the contracts. Subpackage gen generates seeded
+// event streams to check them against and shrinks failures.
 package rac
 
 // Reducer is the minimal surface every reducer must expose to the RAC
@@ -1,53 +1,66 @@
 package subscription
 
-// AuthorityPolicy is the field-level authority arbitration table for the
-// subscription reducer. For each interpreted field on the projection, the
-// policy declares which source is authoritative.
+// Field authority is a provenance tag, not a policy: there is no per-field
+// table ranking sources. Arbitration is by time (see mergeSubscription).
+// Within an event group the newest observation wins, whatever its source,
+// and writes every field of the group, so a row never mixes the status of
+// one observation with the cancellation of another. At a tie the webhook
+// wins. The field_authority tag records which source wrote each value.
 //
-// This is the v1 policy: every field's authority is "webhook" because the
-// polling reconciliation worker doesn't exist yet. When polling lands, the
-// policy gets updated and the reducer version is bumped — that's a logic
-// change that should be exercised through the RAC test harness before any
-// production change.
+// Webhooks are the only source of the payment and checkout fields. The
+// polling reconciliation worker observes the subscription fields (status
+// through trial_end): it reads Stripe's API at rest, so a polled snapshot
+// fills gaps before the first webhook and corrects webhooks that were
+// missed.
 //
-// The policy here MUST be kept in sync with the version table in
-// SUBSCRIPTION_REDUCER_CONTRACT.md.
-var AuthorityPolicy = map[string]AuthoritySource{
-	"status":                 AuthorityWebhook,
-	"current_period_start":   AuthorityWebhook,
-	"current_period_end":     AuthorityWebhook,
-	"cancel_at_period_end":   AuthorityWebhook,
-	"canceled_at":            AuthorityWebhook,
-	"trial_start":            AuthorityWebhook,
-	"trial_end":              AuthorityWebhook,
-}
-
-// AuthorityFor returns the authority source for a given interpreted field.
-// Returns AuthorityWebhook (the v1 default) for unknown fields — but
-// callers should never pass an unknown field; the merge function operates
-// on a closed set.
-func AuthorityFor(field string) AuthoritySource {
-	if src, ok := AuthorityPolicy[field]; ok {
-		return src
-	}
-	return AuthorityWebhook
-}
+// Operator corrections (AuthorityManual) hold until a source reports
+// something newer than the correction (see mergeManual).
+//
+// These rules MUST be kept in sync with SUBSCRIPTION_REDUCER_CONTRACT.md.
 
 // EventSource maps a reducer-side observation source to the authority tag.
-// During projection-only mode, the reducer only consumes webhook events,
-// so this always returns AuthorityWebhook. When polling and manual
-// reconciliation come online, this mapping grows.
+// Webhook deliveries, polled snapshots, and operator corrections all
+// arrive through stripe_event_ledger; the reducer tells them apart by
+// verify_outcome (see EventSourceForOutcome).
 type EventSource string
 
 const (
 	EventSourceWebhook EventSource = "webhook"
-	// EventSourcePolling and EventSourceManual reserved for future use.
+	EventSourcePolling EventSource = "polling"
+	EventSourceManual  EventSource = "manual"
 )
 
 func (es EventSource) Authority() AuthoritySource {
 	switch es {
 	case EventSourceWebhook:
 		return AuthorityWebhook
+	case EventSourcePolling:
+		return AuthorityPolling
+	case EventSourceManual:
+		return AuthorityManual
 	}
 	return AuthorityWebhook
 }
+
+// PollingVerifyOutcome is the stripe_event_ledger.verify_outcome the
+// polling worker writes. Polled rows carry no signature; they are
+// trusted because the poller fetched them over an authenticated API call.
+const PollingVerifyOutcome = "polling"
+
+// ManualVerifyOutcome is the stripe_event_ledger.verify_outcome of an
+// operator correction. Like polled rows, they carry no signature; they
+// are trusted because they were submitted through the authenticated
+// corrections endpoint.
+const ManualVerifyOutcome = "manual"
+
+// EventSourceForOutcome returns the event source for a ledger row's
+// verify_outcome.
+func EventSourceForOutcome(verifyOutcome string) EventSource {
+	switch verifyOutcome {
+	case PollingVerifyOutcome:
+		return EventSourcePolling
+	case ManualVerifyOutcome:
+		return EventSourceManual
+	}
+	return EventSourceWebhook
+}
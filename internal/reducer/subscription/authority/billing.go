@@ -15,10 +15,9 @@ const BillingProviderVersion = "v1"
 // billing_subscriptions table.
 //
 // This is Phase 1. The intent is comparison against incumbent operational
-// truth as the reducer accumulates its parallel interpretation. When the
-// Stripe polling worker exists (Phase 2), a new provider takes over and
-// this one is deprecated. The detector code is unchanged across the
-// transition.
+// truth as the reducer accumulates its parallel interpretation.
+// StripeProvider (Phase 2) takes over once it is deployed, and this one is
+// deprecated. The detector code is unchanged across the transition.
 type BillingProvider struct {
 	db *sql.DB
 }
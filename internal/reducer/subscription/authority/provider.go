@@ -6,13 +6,14 @@
 // billing_subscriptions table. This is the "compare reducer's
 // interpretation against the incumbent operational truth" stage.
 //
-// Phase 2 (future, post-polling-worker): a stripe provider polls Stripe's
-// API directly. At that point billing_subscriptions becomes a legacy
+// Phase 2: the stripe provider reads Stripe's subscriptions API directly.
+// Once it is the deployed provider, billing_subscriptions becomes a legacy
 // artifact and the detector compares projection against the only
 // genuinely authoritative source — Stripe's API at-rest.
 //
 // The detector code never changes between phases. Only the registered
-// provider does.
+// provider does; cmd/drift-detector selects it with
+// DRIFT_AUTHORITY_PROVIDER.
 package authority
 
 import (
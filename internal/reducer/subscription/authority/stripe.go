@@ -0,0 +1,159 @@
+package authority
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"payment-node/internal/reducer/subscription"
+	"payment-node/internal/reducer/subscription/polling"
+)
+
+// StripeProviderVersion identifies this implementation. Bump on any
+// behavior change to how Stripe's subscription object is interpreted.
+const StripeProviderVersion = "v1"
+
+// StripeProvider implements Provider by reading Stripe's subscriptions API
+// directly — Phase 2. It shares polling.Client with the polling worker, so
+// pagination, rate limiting and 429 retries behave identically; point the
+// client at a local server to test against a mock.
+//
+// Responses are cached for ttl. FetchAll refreshes the whole cache;
+// FetchState answers from it while fresh and otherwise retrieves the one
+// subscription. Unknown ids are cached too, so a detector re-checking a
+// subscription Stripe does not have costs one request per ttl. Returned
+// States are shared with the cache and must not be modified.
+type StripeProvider struct {
+	client *polling.Client
+	ttl    time.Duration
+	now    func() time.Time
+
+	mu    sync.Mutex
+	all   []*State
+	allAt time.Time
+	byID  map[string]cachedState
+}
+
+type cachedState struct {
+	state *State // nil: Stripe has no such subscription
+	at    time.Time
+}
+
+// NewStripeProvider returns a provider reading through client. A ttl of
+// zero disables caching.
+func NewStripeProvider(client *polling.Client, ttl time.Duration) *StripeProvider {
+	return &StripeProvider{
+		client: client,
+		ttl:    ttl,
+		now:    time.Now,
+		byID:   make(map[string]cachedState),
+	}
+}
+
+func (p *StripeProvider) Name() string    { return "stripe_api" }
+func (p *StripeProvider) Version() string { return StripeProviderVersion }
+
+func (p *StripeProvider) fresh(at time.Time) bool {
+	return !at.IsZero() && p.now().Sub(at) < p.ttl
+}
+
+func (p *StripeProvider) FetchState(ctx context.Context, id string) (*State, error) {
+	p.mu.Lock()
+	if c, ok := p.byID[id]; ok && p.fresh(c.at) {
+		p.mu.Unlock()
+		return c.state, nil
+	}
+	p.mu.Unlock()
+
+	sub, err := p.client.GetSubscription(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("stripe: %w", err)
+	}
+	var s *State
+	if sub != nil {
+		if s, err = stripeState(sub.Raw); err != nil {
+			return nil, err
+		}
+	}
+
+	p.mu.Lock()
+	p.byID[id] = cachedState{state: s, at: p.now()}
+	p.mu.Unlock()
+	return s, nil
+}
+
+func (p *StripeProvider) FetchAll(ctx context.Context) ([]*State, error) {
+	p.mu.Lock()
+	if p.fresh(p.allAt) {
+		all := p.all
+		p.mu.Unlock()
+		return all, nil
+	}
+	p.mu.Unlock()
+
+	var out []*State
+	err := p.client.ListSubscriptions(ctx, func(sub polling.Subscription) error {
+		s, err := stripeState(sub.Raw)
+		if err != nil {
+			return err
+		}
+		out = append(out, s)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("stripe: %w", err)
+	}
+
+	at := p.now()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	// A full listing supersedes every per-id entry, including negative
+	// ones for subscriptions created since.
+	p.byID = make(map[string]cachedState, len(out))
+	for _, s := range out {
+		p.byID[s.StripeSubscriptionID] = cachedState{state: s, at: at}
+	}
+	p.all, p.allAt = out, at
+	return out, nil
+}
+
+// stripeState interprets a Stripe subscription object the way the
+// reducer's merge function does: zero period timestamps are unknown.
+func stripeState(raw json.RawMessage) (*State, error) {
+	var sub subscription.StripeSubscription
+	if err := json.Unmarshal(raw, &sub); err != nil {
+		return nil, fmt.Errorf("stripe: decode subscription: %w", err)
+	}
+	if sub.ID == "" {
+		return nil, fmt.Errorf("stripe: subscription object without id")
+	}
+	return &State{
+		StripeSubscriptionID: sub.ID,
+		Status:               sub.Status,
+		CurrentPeriodStart:   epochTime(sub.CurrentPeriodStart),
+		CurrentPeriodEnd:     epochTime(sub.CurrentPeriodEnd),
+		CancelAtPeriodEnd:    sub.CancelAtPeriodEnd,
+		CanceledAt:           epochPtrTime(sub.CanceledAt),
+		TrialStart:           epochPtrTime(sub.TrialStart),
+		TrialEnd:             epochPtrTime(sub.TrialEnd),
+		ProviderName:         "stripe_api",
+		ProviderVersion:      StripeProviderVersion,
+	}, nil
+}
+
+func epochTime(secs int64) *time.Time {
+	if secs == 0 {
+		return nil
+	}
+	t := time.Unix(secs, 0).UTC()
+	return &t
+}
+
+func epochPtrTime(secs *int64) *time.Time {
+	if secs == nil {
+		return nil
+	}
+	return epochTime(*secs)
+}
@@ -0,0 +1,233 @@
+package authority
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"payment-node/internal/reducer/subscription/polling"
+)
+
+// mockStripe serves GET /v1/subscriptions (starting_after pagination)
+// and GET /v1/subscriptions/{id} from an in-memory list.
+type mockStripe struct {
+	mu       sync.Mutex
+	subs     []map[string]any
+	requests int
+}
+
+func (m *mockStripe) add(sub map[string]any) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.subs = append(m.subs, sub)
+}
+
+func (m *mockStripe) count() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.requests
+}
+
+func (m *mockStripe) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests++
+
+	if r.Header.Get("Authorization") != "Bearer sk_test_mock" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if id, ok := strings.CutPrefix(r.URL.Path, "/v1/subscriptions/"); ok {
+		for _, s := range m.subs {
+			if s["id"] == id {
+				_ = json.NewEncoder(w).Encode(s)
+				return
+			}
+		}
+		http.NotFound(w, r)
+		return
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	start := 0
+	if after := r.URL.Query().Get("starting_after"); after != "" {
+		for i, s := range m.subs {
+			if s["id"] == after {
+				start = i + 1
+			}
+		}
+	}
+	end := min(start+limit, len(m.subs))
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"object":   "list",
+		"data":     m.subs[start:end],
+		"has_more": end < len(m.subs),
+	})
+}
+
+func mockSub(id, status string) map[string]any {
+	return map[string]any{
+		"id":                   id,
+		"object":               "subscription",
+		"status":               status,
+		"current_period_start": 1767268800,
+		"current_period_end":   1769947200,
+		"cancel_at_period_end": false,
+		"canceled_at":          nil,
+		"trial_start":          nil,
+		"trial_end":            nil,
+	}
+}
+
+// newTestStripeProvider returns a provider against mock with a
+// controllable clock.
+func newTestStripeProvider(t *testing.T, mock *mockStripe, ttl time.Duration) (*StripeProvider, *time.Time) {
+	t.Helper()
+	srv := httptest.NewServer(mock)
+	t.Cleanup(srv.Close)
+	client := polling.NewClient(srv.URL, "sk_test_mock")
+	client.PageSize = 2
+	p := NewStripeProvider(client, ttl)
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	p.now = func() time.Time { return now }
+	return p, &now
+}
+
+func TestStripeProviderFetchAll(t *testing.T) {
+	mock := &mockStripe{}
+	mock.add(mockSub("sub_1", "active"))
+	mock.add(mockSub("sub_2", "past_due"))
+	canceled := mockSub("sub_3", "canceled")
+	canceled["canceled_at"] = 1768000000
+	canceled["cancel_at_period_end"] = true
+	mock.add(canceled)
+	p, _ := newTestStripeProvider(t, mock, time.Minute)
+
+	states, err := p.FetchAll(context.Background())
+	if err != nil {
+		t.Fatalf("FetchAll: %v", err)
+	}
+	if len(states) != 3 || mock.count() != 2 {
+		t.Fatalf("got %d states in %d requests; want 3 in 2 pages", len(states), mock.count())
+	}
+	s := states[2]
+	if s.StripeSubscriptionID != "sub_3" || s.Status != "canceled" || !s.CancelAtPeriodEnd {
+		t.Fatalf("unexpected state %+v", s)
+	}
+	if s.CanceledAt == nil || !s.CanceledAt.Equal(time.Unix(1768000000, 0)) {
+		t.Fatalf("CanceledAt = %v", s.CanceledAt)
+	}
+	if s.CurrentPeriodEnd == nil || !s.CurrentPeriodEnd.Equal(time.Unix(1769947200, 0)) {
+		t.Fatalf("CurrentPeriodEnd = %v", s.CurrentPeriodEnd)
+	}
+	if s.TrialStart != nil || s.TrialEnd != nil {
+		t.Fatalf("null trial fields should be nil; got %v, %v", s.TrialStart, s.TrialEnd)
+	}
+	if s.ProviderName != "stripe_api" || s.ProviderVersion != StripeProviderVersion {
+		t.Fatalf("provider attribution = %s/%s", s.ProviderName, s.ProviderVersion)
+	}
+}
+
+func TestStripeProviderCaches(t *testing.T) {
+	mock := &mockStripe{}
+	mock.add(mockSub("sub_1", "active"))
+	p, now := newTestStripeProvider(t, mock, time.Minute)
+	ctx := context.Background()
+
+	if _, err := p.FetchAll(ctx); err != nil {
+		t.Fatalf("FetchAll: %v", err)
+	}
+	if _, err := p.FetchAll(ctx); err != nil {
+		t.Fatalf("FetchAll: %v", err)
+	}
+	if s, err := p.FetchState(ctx, "sub_1"); err != nil || s == nil || s.Status != "active" {
+		t.Fatalf("FetchState from cache: %+v, %v", s, err)
+	}
+	if mock.count() != 1 {
+		t.Fatalf("expected 1 request while the cache is fresh; got %d", mock.count())
+	}
+
+	// A miss retrieves the one subscription, and unknown ids are cached.
+	for range 2 {
+		if s, err := p.FetchState(ctx, "sub_missing"); err != nil || s != nil {
+			t.Fatalf("unknown id: got %+v, %v; want nil, nil", s, err)
+		}
+	}
+	if mock.count() != 2 {
+		t.Fatalf("expected one retrieve for the unknown id; got %d requests", mock.count())
+	}
+
+	// After the ttl everything is fetched again.
+	mock.mu.Lock()
+	mock.subs[0]["status"] = "past_due"
+	mock.mu.Unlock()
+	*now = now.Add(time.Minute)
+	if s, err := p.FetchState(ctx, "sub_1"); err != nil || s == nil || s.Status != "past_due" {
+		t.Fatalf("FetchState after ttl: %+v, %v", s, err)
+	}
+	states, err := p.FetchAll(ctx)
+	if err != nil || len(states) != 1 || states[0].Status != "past_due" {
+		t.Fatalf("FetchAll after ttl: %+v, %v", states, err)
+	}
+	if mock.count() != 4 {
+		t.Fatalf("expected 4 requests; got %d", mock.count())
+	}
+}
+
+func TestStripeProviderZeroTTLDisablesCache(t *testing.T) {
+	mock := &mockStripe{}
+	mock.add(mockSub("sub_1", "active"))
+	p, _ := newTestStripeProvider(t, mock, 0)
+	ctx := context.Background()
+
+	for range 2 {
+		if _, err := p.FetchAll(ctx); err != nil {
+			t.Fatalf("FetchAll: %v", err)
+		}
+		if _, err := p.FetchState(ctx, "sub_1"); err != nil {
+			t.Fatalf("FetchState: %v", err)
+		}
+	}
+	if mock.count() != 4 {
+		t.Fatalf("expected every call to reach the API; got %d requests", mock.count())
+	}
+}
+
+func TestStripeProviderRateLimited(t *testing.T) {
+	mock := &mockStripe{}
+	for _, id := range []string{"sub_1", "sub_2", "sub_3", "sub_4", "sub_5"} {
+		mock.add(mockSub(id, "active"))
+	}
+	p, _ := newTestStripeProvider(t, mock, 0)
+	p.client.Limiter = rate.NewLimiter(rate.Every(20*time.Millisecond), 1)
+
+	start := time.Now()
+	if _, err := p.FetchAll(context.Background()); err != nil {
+		t.Fatalf("FetchAll: %v", err)
+	}
+	// Three pages at one request per 20ms: the second and third wait.
+	if elapsed := time.Since(start); elapsed < 35*time.Millisecond {
+		t.Fatalf("3 paced requests took %v; limiter not applied", elapsed)
+	}
+}
+
+func TestStripeProviderPropagatesErrors(t *testing.T) {
+	mock := &mockStripe{}
+	srv := httptest.NewServer(mock)
+	defer srv.Close()
+	p := NewStripeProvider(polling.NewClient(srv.URL, "sk_test_wrong"), time.Minute)
+
+	if _, err := p.FetchAll(context.Background()); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Fatalf("FetchAll: expected 401 error; got %v", err)
+	}
+	if _, err := p.FetchState(context.Background(), "sub_1"); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Fatalf("FetchState: expected 401 error; got %v", err)
+	}
+}
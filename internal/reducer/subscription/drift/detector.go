@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"strings"
 	"time"
 
 	"payment-node/internal/reducer/subscription"
@@ -16,35 +17,66 @@ import (
 // DetectorVersion identifies this detector implementation. Used for the
 // reconciliation_events.detector_version column. Bump on any classification
 // or comparison logic change.
-const DetectorVersion = "v1"
+const DetectorVersion = "v3"
 
 // DetectorName is the canonical identifier for the subscription drift
 // detector.
 const DetectorName = "subscription_drift"
 
-// Detector compares the reducer's projection against an authority provider
-// on a periodic schedule. Designed to be reducer-version-aware and
-// provider-pluggable.
+// Detector compares the reducer's projection against one or more
+// authority providers on a periodic schedule. Designed to be
+// reducer-version-aware and provider-pluggable.
 type Detector struct {
-	DB       *sql.DB
+	DB *sql.DB
+
+	// Provider is the single authority; ignored when Providers is set.
 	Provider authority.Provider
-	Logger   *slog.Logger
+
+	// Providers are compared as a quorum: a field drifts only when Quorum
+	// of the providers reporting a subscription disagree with the
+	// projection. A sweep needs a majority of Providers to answer.
+	Providers []authority.Provider
+
+	// Quorum is the number of dissenting providers that makes a field
+	// drift. Zero means a strict majority of the reporting providers.
+	Quorum int
+
+	// Timeouts escalates or closes drifts that stay open too long. Nil
+	// leaves every drift open until agreement.
+	Timeouts TimeoutPolicy
+
+	// Notifiers are told about open drifts at or above their minimum
+	// severity, once per drift, and about those drifts' resolutions.
+	// Deliveries run inline at the end of each sweep.
+	Notifiers []Notifier
+
+	Logger *slog.Logger
 
 	// Tick is how often the detector sweeps. Default 60s if zero.
 	Tick time.Duration
 }
 
+func (d *Detector) providers() []authority.Provider {
+	if len(d.Providers) > 0 {
+		return d.Providers
+	}
+	return []authority.Provider{d.Provider}
+}
+
 // Run drives the detector until the context is canceled. Each tick the
 // detector:
 //
-//  1. Loads the full set of authoritative states from the provider.
-//  2. For each, loads the current projection (if any).
-//  3. Compares per-field.
+//  1. Loads the full set of authoritative states from every provider.
+//  2. For each subscription, loads the current projection (if any).
+//  3. Compares per-field against the providers' quorum.
 //  4. Emits the appropriate reconciliation event:
 //       - If open drift exists and now agrees: emit drift_resolved.
 //       - If disagreement and no open drift: emit drift_minor/major or
 //         projection_impossible.
 //       - If agreement and no open drift: silent (no row for steady state).
+//  5. Escalates or closes open drifts that outlived their severity's TTL
+//     under Timeouts, and refreshes the open-drift metrics.
+//  6. Sends pending trigger and resolve notifications to Notifiers.
 //
 // The detector never modifies projections or canonical state. It only
 // observes and records.
@@ -52,11 +84,16 @@ func (d *Detector) Run(ctx context.Context) error {
 	if d.Logger == nil {
 		d.Logger = slog.Default()
 	}
+	var names, versions []string
+	for _, p := range d.providers() {
+		names = append(names, p.Name())
+		versions = append(versions, p.Version())
+	}
 	d.Logger = d.Logger.With(
 		"detector", DetectorName,
 		"detector_version", DetectorVersion,
-		"provider", d.Provider.Name(),
-		"provider_version", d.Provider.Version(),
+		"provider", strings.Join(names, ","),
+		"provider_version", strings.Join(versions, ","),
 	)
 	tick := d.Tick
 	if tick == 0 {
@@ -77,27 +114,55 @@ func (d *Detector) Run(ctx context.Context) error {
 	}
 }
 
-// sweep runs one full pass over the authority provider's states. Emits
+// collect loads every provider's states. A failing provider is logged and
+// abstains for the sweep, but fewer than a majority of answering
+// providers fails the sweep: the quorum would otherwise shrink to
+// whichever providers happen to be up.
+func (d *Detector) collect(ctx context.Context) (*providerStates, error) {
+	providers := d.providers()
+	ps := &providerStates{}
+	answered := 0
+	var lastErr error
+	for _, p := range providers {
+		states, err := p.FetchAll(ctx)
+		if err != nil {
+			d.Logger.Warn("provider FetchAll", "provider", p.Name(), "error", err)
+			lastErr = fmt.Errorf("provider %s FetchAll: %w", p.Name(), err)
+			continue
+		}
+		answered++
+		for _, s := range states {
+			ps.add(s)
+		}
+	}
+	if answered < len(providers)/2+1 {
+		return nil, fmt.Errorf("%d of %d providers answered: %w", answered, len(providers), lastErr)
+	}
+	return ps, nil
+}
+
+// sweep runs one full pass over the authority providers' states. Emits
 // rows for any state transitions detected (new drifts, resolutions).
 func (d *Detector) sweep(ctx context.Context) error {
-	states, err := d.Provider.FetchAll(ctx)
+	ps, err := d.collect(ctx)
 	if err != nil {
-		return fmt.Errorf("provider FetchAll: %w", err)
+		return err
 	}
 
 	emittedDetections := 0
 	emittedResolutions := 0
+	outvoted := 0
 
-	for _, auth := range states {
+	for _, subID := range ps.order {
 		select {
 		case <-ctx.Done():
 			return nil
 		default:
 		}
 
-		projection, err := loadCurrentProjection(ctx, d.DB, auth.StripeSubscriptionID)
+		projection, err := loadCurrentProjection(ctx, d.DB, subID)
 		if err != nil {
-			d.Logger.Warn("load projection", "subscription_id", auth.StripeSubscriptionID, "error", err)
+			d.Logger.Warn("load projection", "subscription_id", subID, "error", err)
 			continue
 		}
 
@@ -109,18 +174,24 @@ func (d *Detector) sweep(ctx context.Context) error {
 			continue
 		}
 
-		c := compare(projection, auth)
+		q := compareQuorum(projection, ps.bySub[subID], d.Quorum)
+		c := q.Comparison
+		if len(q.Fields) > len(c.DisagreeingFields) {
+			// A minority of providers disagreed on some field; the
+			// others contradict it, so it is not drift.
+			outvoted++
+		}
 
-		openID, err := findOpenDrift(ctx, d.DB, auth.StripeSubscriptionID)
+		openID, err := findOpenDrift(ctx, d.DB, subID)
 		if err != nil {
-			d.Logger.Warn("find open drift", "subscription_id", auth.StripeSubscriptionID, "error", err)
+			d.Logger.Warn("find open drift", "subscription_id", subID, "error", err)
 			continue
 		}
 
 		if c.Agreed {
 			// Agreement now. If there was open drift, this is a resolution.
 			if openID != "" {
-				if err := emitResolution(ctx, d.DB, d.Provider, projection, auth, openID); err != nil {
+				if err := emitResolution(ctx, d.DB, projection, q, openID); err != nil {
 					d.Logger.Warn("emit resolution", "open_drift_id", openID, "error", err)
 					continue
 				}
@@ -137,18 +208,38 @@ func (d *Detector) sweep(ctx context.Context) error {
 			continue
 		}
 
+		// A drift the timeout policy closed stays closed until the
+		// projection moves.
+		if timedOut, err := timedOutAtHead(ctx, d.DB, subID); err != nil {
+			d.Logger.Warn("check timed-out drift", "subscription_id", subID, "error", err)
+			continue
+		} else if timedOut {
+			continue
+		}
+
 		// New drift detection.
-		if err := emitDetection(ctx, d.DB, d.Provider, projection, auth, c); err != nil {
-			d.Logger.Warn("emit detection", "subscription_id", auth.StripeSubscriptionID, "error", err)
+		if err := emitDetection(ctx, d.DB, projection, q); err != nil {
+			d.Logger.Warn("emit detection", "subscription_id", subID, "error", err)
 			continue
 		}
 		emittedDetections++
 	}
 
+	escalated, closed, err := d.enforceTimeouts(ctx)
+	if err != nil {
+		d.Logger.Warn("enforce drift timeouts", "error", err)
+	}
+
+	notified := d.notify(ctx)
+
 	d.Logger.Info("sweep complete",
-		"authority_count", len(states),
+		"authority_count", len(ps.order),
 		"detections_emitted", emittedDetections,
 		"resolutions_emitted", emittedResolutions,
+		"outvoted_disagreements", outvoted,
+		"escalations_emitted", escalated,
+		"timeouts_emitted", closed,
+		"notifications_sent", notified,
 	)
 	return nil
 }
@@ -156,8 +247,8 @@ func (d *Detector) sweep(ctx context.Context) error {
 // compare runs the field-by-field comparison between a projection and an
 // authoritative state, returning a structured Comparison.
 //
-// Pure function (no I/O). Used by both the detector's sweep loop and the
-// detector tests.
+// Pure function (no I/O). compareQuorum with a single reporting provider
+// produces the same Comparison.
 func compare(projection *subscription.State, auth *authority.State) Comparison {
 	c := Comparison{Agreed: true}
 
@@ -171,26 +262,11 @@ func compare(projection *subscription.State, auth *authority.State) Comparison {
 		return c
 	}
 
-	if projection.Status != auth.Status {
-		c.DisagreeingFields = append(c.DisagreeingFields, "status")
-	}
-	if !timestampsAgree(projection.CurrentPeriodStart, auth.CurrentPeriodStart) {
-		c.DisagreeingFields = append(c.DisagreeingFields, "current_period_start")
-	}
-	if !timestampsAgree(projection.CurrentPeriodEnd, auth.CurrentPeriodEnd) {
-		c.DisagreeingFields = append(c.DisagreeingFields, "current_period_end")
-	}
-	if projection.CancelAtPeriodEnd != auth.CancelAtPeriodEnd {
-		c.DisagreeingFields = append(c.DisagreeingFields, "cancel_at_period_end")
-	}
-	if !timestampsAgree(projection.CanceledAt, auth.CanceledAt) {
-		c.DisagreeingFields = append(c.DisagreeingFields, "canceled_at")
-	}
-	if !timestampsAgree(projection.TrialStart, auth.TrialStart) {
-		c.DisagreeingFields = append(c.DisagreeingFields, "trial_start")
-	}
-	if !timestampsAgree(projection.TrialEnd, auth.TrialEnd) {
-		c.DisagreeingFields = append(c.DisagreeingFields, "trial_end")
+	proj := asAuthority(projection)
+	for _, check := range fieldChecks {
+		if !check.agree(proj, auth) {
+			c.DisagreeingFields = append(c.DisagreeingFields, check.name)
+		}
 	}
 
 	c.Agreed = len(c.DisagreeingFields) == 0
@@ -210,8 +286,13 @@ func timestampsAgree(a, b *time.Time) bool {
 	return delta <= TimestampTolerance
 }
 
+// loadCurrentProjection reads the projection head the detector compares.
+// A head with a NULL status has only seen invoice or checkout events for
+// the subscription so far; it is treated like a missing projection. Any
+// other status, including an empty one, is compared as-is.
 func loadCurrentProjection(ctx context.Context, db *sql.DB, subID string) (*subscription.State, error) {
 	var s subscription.State
+	var status sql.NullString
 	var authorityJSON []byte
 	err := db.QueryRowContext(ctx, `
 		SELECT
@@ -223,16 +304,17 @@ func loadCurrentProjection(ctx context.Context, db *sql.DB, subID string) (*subs
 		WHERE stripe_subscription_id = $1
 	`, subID).Scan(
 		&s.StripeSubscriptionID, &s.WorkspaceID,
-		&s.Status, &s.CurrentPeriodStart, &s.CurrentPeriodEnd,
+		&status, &s.CurrentPeriodStart, &s.CurrentPeriodEnd,
 		&s.CancelAtPeriodEnd, &s.CanceledAt, &s.TrialStart, &s.TrialEnd,
 		&authorityJSON, &s.EventOccurredAt,
 	)
-	if errors.Is(err, sql.ErrNoRows) {
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && !status.Valid) {
 		return nil, nil
 	}
 	if err != nil {
 		return nil, err
 	}
+	s.Status = status.String
 	return &s, nil
 }
 
@@ -257,37 +339,37 @@ func findOpenDrift(ctx context.Context, db *sql.DB, subID string) (string, error
 	return id, err
 }
 
+// emitDetection records a drift. canonical_state is the quorum view;
+// details records how each provider voted.
 func emitDetection(
 	ctx context.Context,
 	db *sql.DB,
-	provider authority.Provider,
 	projection *subscription.State,
-	auth *authority.State,
-	c Comparison,
+	q Quorum,
 ) error {
-	severity := ClassifySeverity(c)
-	eventType := ClassifyEventType(c)
+	severity := ClassifySeverity(q.Comparison)
+	eventType := ClassifyEventType(q.Comparison)
 
 	reducerSnap := snapshotProjection(projection)
-	canonicalSnap := snapshotAuthority(auth)
+	canonicalSnap := snapshotAuthority(q.View)
 
 	_, err := db.ExecContext(ctx, `
 		INSERT INTO subscription_reconciliation_events (
 			stripe_subscription_id, event_type, severity,
 			projection_id_at_detection, reducer_state, canonical_state,
-			detector_name, detector_version, reducer_version
+			detector_name, detector_version, reducer_version, details
 		) VALUES (
 			$1, $2, $3,
 			(SELECT id FROM subscription_current_state WHERE stripe_subscription_id = $1),
 			$4, $5,
-			$6, $7, $8
+			$6, $7, $8, $9
 		)
 	`,
-		auth.StripeSubscriptionID,
+		q.View.StripeSubscriptionID,
 		string(eventType),
 		string(severity),
 		reducerSnap, canonicalSnap,
-		DetectorName, DetectorVersion, subscription.ReducerVersion,
+		DetectorName, DetectorVersion, subscription.ReducerVersion, q.details(),
 	)
 	return err
 }
@@ -295,38 +377,60 @@ func emitDetection(
 func emitResolution(
 	ctx context.Context,
 	db *sql.DB,
-	provider authority.Provider,
 	projection *subscription.State,
-	auth *authority.State,
+	q Quorum,
 	resolvesID string,
 ) error {
 	reducerSnap := snapshotProjection(projection)
-	canonicalSnap := snapshotAuthority(auth)
+	canonicalSnap := snapshotAuthority(q.View)
 
-	_, err := db.ExecContext(ctx, `
+	var headSourceEventID string
+	err := db.QueryRowContext(ctx, `
+		SELECT source_event_id FROM subscription_current_state
+		WHERE stripe_subscription_id = $1
+	`, q.View.StripeSubscriptionID).Scan(&headSourceEventID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("load projection head source: %w", err)
+	}
+
+	_, err = db.ExecContext(ctx, `
 		INSERT INTO subscription_reconciliation_events (
 			stripe_subscription_id, event_type, severity,
 			projection_id_at_detection, reducer_state, canonical_state,
 			detector_name, detector_version, reducer_version,
-			resolves_id, resolution_mechanism
+			resolves_id, resolution_mechanism, details
 		) VALUES (
 			$1, $2, $3,
 			(SELECT id FROM subscription_current_state WHERE stripe_subscription_id = $1),
 			$4, $5,
 			$6, $7, $8,
-			$9::uuid, $10
+			$9::uuid, $10, $11
 		)
 	`,
-		auth.StripeSubscriptionID,
+		q.View.StripeSubscriptionID,
 		string(EventDriftResolved),
 		string(SeverityInformational),
 		reducerSnap, canonicalSnap,
 		DetectorName, DetectorVersion, subscription.ReducerVersion,
-		resolvesID, string(ResolutionAutoProviderAgreed),
+		resolvesID, string(resolutionMechanism(headSourceEventID)), q.details(),
 	)
 	return err
 }
 
+// resolutionMechanism attributes a resolution to the polling worker or to
+// an operator when the projection head that now agrees was produced by a
+// polled snapshot or a manual correction; otherwise the projection and
+// provider simply converged.
+func resolutionMechanism(headSourceEventID string) ResolutionMechanism {
+	switch {
+	case strings.HasPrefix(headSourceEventID, subscription.PolledEventIDPrefix):
+		return ResolutionPollingSupersede
+	case strings.HasPrefix(headSourceEventID, subscription.ManualEventIDPrefix):
+		return ResolutionManualOperator
+	}
+	return ResolutionAutoProviderAgreed
+}
+
 // snapshotProjection serializes the projection state into the jsonb shape
 // stored on reconciliation rows.
 func snapshotProjection(p *subscription.State) []byte {
@@ -1,6 +1,13 @@
 package drift
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -188,6 +195,266 @@ func TestClassifyEventType_MinorWhenOnlyWarningFieldDisagrees(t *testing.T) {
 	}
 }
 
+// TestResolutionMechanism_PollingHead verifies resolutions are attributed
+// to polling only when the agreeing projection head came from a poll.
+func TestResolutionMechanism_PollingHead(t *testing.T) {
+	if got := resolutionMechanism("poll_sub_test_0123456789abcdef"); got != ResolutionPollingSupersede {
+		t.Fatalf("polled head: got %s", got)
+	}
+	if got := resolutionMechanism("evt_1PqR"); got != ResolutionAutoProviderAgreed {
+		t.Fatalf("webhook head: got %s", got)
+	}
+}
+
+// TestResolutionMechanism_ManualHead verifies resolutions are attributed
+// to the operator when the agreeing projection head is a manual correction.
+func TestResolutionMechanism_ManualHead(t *testing.T) {
+	if got := resolutionMechanism("manual_sub_test_0123456789abcdef"); got != ResolutionManualOperator {
+		t.Fatalf("manual head: got %s", got)
+	}
+}
+
+// providerState returns basicAuthority(status) attributed to name.
+func providerState(name, status string) *authority.State {
+	a := basicAuthority(status)
+	a.ProviderName = name
+	return a
+}
+
+// TestQuorum_SingleProviderMatchesCompare verifies a lone provider yields
+// exactly compare's result and its own state as the view.
+func TestQuorum_SingleProviderMatchesCompare(t *testing.T) {
+	p := basicProjection("active")
+	a := basicAuthority("past_due")
+	a.TrialEnd = ts(2026, 5, 15)
+
+	q := compareQuorum(p, []*authority.State{a}, 0)
+	c := compare(p, a)
+	if q.Agreed != c.Agreed || strings.Join(q.DisagreeingFields, ",") != strings.Join(c.DisagreeingFields, ",") {
+		t.Fatalf("quorum %+v differs from compare %+v", q.Comparison, c)
+	}
+	if *q.View != *a {
+		t.Fatalf("view = %+v, want the provider's state", q.View)
+	}
+	if q.Required != 1 || !q.Fields["status"].Drift {
+		t.Fatalf("required=%d fields=%+v", q.Required, q.Fields)
+	}
+}
+
+// TestQuorum_LaggingProviderOutvoted verifies one dissenting provider
+// contradicted by the others produces no drift but is still recorded.
+func TestQuorum_LaggingProviderOutvoted(t *testing.T) {
+	p := basicProjection("active")
+	states := []*authority.State{
+		providerState("billing_subscriptions", "past_due"),
+		providerState("stripe_api", "active"),
+		providerState("ledger_replay", "active"),
+	}
+
+	q := compareQuorum(p, states, 0)
+	if !q.Agreed {
+		t.Fatalf("expected agreement; got drift on %v", q.DisagreeingFields)
+	}
+	vote, ok := q.Fields["status"]
+	if !ok || vote.Drift || strings.Join(vote.Disagreed, ",") != "billing_subscriptions" ||
+		strings.Join(vote.Agreed, ",") != "stripe_api,ledger_replay" {
+		t.Fatalf("status vote = %+v", vote)
+	}
+	if q.View.Status != "active" || q.View.ProviderName != QuorumProviderName {
+		t.Fatalf("view status=%s provider=%s", q.View.Status, q.View.ProviderName)
+	}
+}
+
+// TestQuorum_MajorityDrift verifies a majority of dissenters produces
+// drift, and the view takes the value most dissenters report.
+func TestQuorum_MajorityDrift(t *testing.T) {
+	p := basicProjection("active")
+	states := []*authority.State{
+		providerState("a", "canceled"),
+		providerState("b", "past_due"),
+		providerState("c", "past_due"),
+	}
+
+	q := compareQuorum(p, states, 0)
+	if q.Agreed || !contains(q.DisagreeingFields, "status") {
+		t.Fatalf("expected status drift; got %+v", q.Comparison)
+	}
+	if q.View.Status != "past_due" {
+		t.Fatalf("view status = %s, want the plurality past_due", q.View.Status)
+	}
+	if ClassifyEventType(q.Comparison) != EventDriftMajor {
+		t.Fatalf("event type = %s", ClassifyEventType(q.Comparison))
+	}
+}
+
+// TestQuorum_TwoProvidersRequireBoth verifies the default majority of two
+// is two, and that an explicit quorum overrides it.
+func TestQuorum_TwoProvidersRequireBoth(t *testing.T) {
+	p := basicProjection("active")
+	states := []*authority.State{
+		providerState("billing_subscriptions", "past_due"),
+		providerState("stripe_api", "active"),
+	}
+	if q := compareQuorum(p, states, 0); !q.Agreed || q.Required != 2 {
+		t.Fatalf("default quorum: agreed=%v required=%d", q.Agreed, q.Required)
+	}
+	if q := compareQuorum(p, states, 1); q.Agreed {
+		t.Fatal("quorum 1: expected drift")
+	}
+}
+
+// TestQuorum_ProjectionImpossibleIgnoresProviders verifies an invalid
+// projection status is regulatory whatever the providers say.
+func TestQuorum_ProjectionImpossibleIgnoresProviders(t *testing.T) {
+	p := basicProjection("weird_status")
+	states := []*authority.State{providerState("a", "active"), providerState("b", "active")}
+	q := compareQuorum(p, states, 0)
+	if !q.ProjectionImpossible || ClassifySeverity(q.Comparison) != SeverityRegulatory {
+		t.Fatalf("expected projection_impossible; got %+v", q.Comparison)
+	}
+}
+
+// TestQuorum_DetailsShape pins the details jsonb written on
+// reconciliation rows.
+func TestQuorum_DetailsShape(t *testing.T) {
+	p := basicProjection("active")
+	states := []*authority.State{
+		providerState("billing_subscriptions", "past_due"),
+		providerState("stripe_api", "past_due"),
+	}
+	var got map[string]any
+	if err := json.Unmarshal(compareQuorum(p, states, 0).details(), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{"quorum": map[string]any{
+		"required":  2.0,
+		"providers": []any{"billing_subscriptions", "stripe_api"},
+		"fields": map[string]any{"status": map[string]any{
+			"agreed":    []any{},
+			"disagreed": []any{"billing_subscriptions", "stripe_api"},
+			"drift":     true,
+		}},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("details = %v\nwant %v", got, want)
+	}
+}
+
+// staticProvider serves fixed states, or err.
+type staticProvider struct {
+	name   string
+	states []*authority.State
+	err    error
+}
+
+func (p *staticProvider) Name() string    { return p.name }
+func (p *staticProvider) Version() string { return "test" }
+func (p *staticProvider) FetchState(context.Context, string) (*authority.State, error) {
+	return nil, p.err
+}
+func (p *staticProvider) FetchAll(context.Context) ([]*authority.State, error) {
+	return p.states, p.err
+}
+
+// TestCollect_UnionAndAbstention verifies the sweep input covers every
+// subscription any provider reports, providers without a subscription
+// abstain, and a sweep needs a majority of providers to answer.
+func TestCollect_UnionAndAbstention(t *testing.T) {
+	withID := func(s *authority.State, id string) *authority.State {
+		s.StripeSubscriptionID = id
+		return s
+	}
+	a := &staticProvider{name: "a", states: []*authority.State{
+		withID(providerState("a", "active"), "sub_1"),
+		withID(providerState("a", "active"), "sub_2"),
+	}}
+	b := &staticProvider{name: "b", states: []*authority.State{
+		withID(providerState("b", "active"), "sub_3"),
+		withID(providerState("b", "active"), "sub_1"),
+	}}
+	down := &staticProvider{name: "down", err: errors.New("unavailable")}
+
+	d := &Detector{Providers: []authority.Provider{a, b, down}, Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	ps, err := d.collect(context.Background())
+	if err != nil {
+		t.Fatalf("collect with 2 of 3 up: %v", err)
+	}
+	if got := strings.Join(ps.order, ","); got != "sub_1,sub_2,sub_3" {
+		t.Fatalf("order = %s", got)
+	}
+	if n := len(ps.bySub["sub_1"]); n != 2 {
+		t.Fatalf("sub_1 reported by %d providers, want 2", n)
+	}
+	if n := len(ps.bySub["sub_2"]); n != 1 {
+		t.Fatalf("sub_2 reported by %d providers, want 1", n)
+	}
+
+	d.Providers = []authority.Provider{a, down}
+	if _, err := d.collect(context.Background()); err == nil {
+		t.Fatal("collect with 1 of 2 up: expected error")
+	}
+}
+
+// TestParseTimeoutPolicy verifies the DRIFT_TIMEOUT_POLICY format and
+// its validation.
+func TestParseTimeoutPolicy(t *testing.T) {
+	p, err := ParseTimeoutPolicy(" warning=6h:escalate, critical=72h:close ")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	want := TimeoutPolicy{
+		SeverityWarning:  {After: 6 * time.Hour, Action: TimeoutEscalate},
+		SeverityCritical: {After: 72 * time.Hour, Action: TimeoutClose},
+	}
+	if !reflect.DeepEqual(p, want) {
+		t.Fatalf("policy = %v", p)
+	}
+	if got := p.String(); got != "warning=6h0m0s:escalate,critical=72h0m0s:close" {
+		t.Fatalf("String() = %s", got)
+	}
+	if p, err := ParseTimeoutPolicy(""); err != nil || len(p) != 0 {
+		t.Fatalf("empty policy: %v, %v", p, err)
+	}
+
+	for _, bad := range []string{
+		"warning=6h",             // no action
+		"warning:6h:escalate",    // no =
+		"warning=soon:escalate",  // bad duration
+		"warning=0s:close",       // non-positive TTL
+		"severe=1h:close",        // unknown severity
+		"warning=1h:page",        // unknown action
+		"critical=1h:escalate",   // nothing above critical
+		"regulatory=1h:escalate", // nor above regulatory
+		"warning=1h:close,warning=2h:close",
+	} {
+		if _, err := ParseTimeoutPolicy(bad); err == nil {
+			t.Errorf("%q: expected error", bad)
+		}
+	}
+}
+
+// TestTimeoutPolicyDue verifies the TTL runs from when the effective
+// severity took effect, and escalation moves one level.
+func TestTimeoutPolicyDue(t *testing.T) {
+	p := TimeoutPolicy{SeverityWarning: {After: time.Hour, Action: TimeoutEscalate}}
+	since := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	o := openDrift{Severity: SeverityWarning, Since: since, DetectedAt: since.Add(-24 * time.Hour)}
+
+	if _, ok := p.due(o, since.Add(59*time.Minute)); ok {
+		t.Fatal("due before the TTL elapsed")
+	}
+	rule, ok := p.due(o, since.Add(time.Hour))
+	if !ok || rule.Action != TimeoutEscalate {
+		t.Fatalf("expected escalation at the TTL; got %+v, %v", rule, ok)
+	}
+	if _, ok := p.due(openDrift{Severity: SeverityCritical, Since: since}, since.Add(48*time.Hour)); ok {
+		t.Fatal("severity without a rule should never be due")
+	}
+	if to, ok := escalatedSeverity(SeverityWarning); !ok || to != SeverityCritical {
+		t.Fatalf("warning escalates to %s", to)
+	}
+}
+
 func contains(s []string, v string) bool {
 	for _, e := range s {
 		if e == v {
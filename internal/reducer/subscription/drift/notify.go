@@ -0,0 +1,191 @@
+package drift
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"payment-node/internal/metrics"
+)
+
+// NotificationKind distinguishes a drift being raised from its
+// resolution.
+type NotificationKind string
+
+const (
+	NotificationTrigger NotificationKind = "trigger"
+	NotificationResolve NotificationKind = "resolve"
+)
+
+// Notification describes an open drift (trigger) or the resolution of a
+// drift a notifier was triggered for (resolve).
+type Notification struct {
+	Kind           NotificationKind `json:"kind"`
+	DriftID        string           `json:"drift_id"`
+	SubscriptionID string           `json:"stripe_subscription_id"`
+	EventType      EventType        `json:"event_type"`
+
+	// Severity is the drift's effective severity when triggered; a
+	// resolve carries the severity that was notified.
+	Severity   Severity  `json:"severity"`
+	DetectedAt time.Time `json:"detected_at"`
+
+	// Snapshots from the detection row, or from the resolution row for a
+	// resolve.
+	ReducerState   json.RawMessage `json:"reducer_state"`
+	CanonicalState json.RawMessage `json:"canonical_state"`
+	Details        json.RawMessage `json:"details,omitempty"`
+
+	ResolvedAt          *time.Time          `json:"resolved_at,omitempty"`
+	ResolutionMechanism ResolutionMechanism `json:"resolution_mechanism,omitempty"`
+}
+
+// Notifier delivers drift notifications to one destination.
+// Implementations live in package drift/notify.
+type Notifier interface {
+	// Name identifies the destination in subscription_drift_notifications;
+	// renaming a notifier re-sends every open drift to it.
+	Name() string
+
+	// MinSeverity is the lowest effective severity that triggers.
+	MinSeverity() Severity
+
+	Notify(ctx context.Context, n Notification) error
+}
+
+// notify sends every pending notification for every notifier. Deliveries
+// are recorded in subscription_drift_notifications, which is both the
+// dedup — one trigger per open drift per notifier, however often it
+// escalates — and the retry queue: a failed delivery is pending again on
+// the next sweep. Runs after timeouts, so a drift escalated this sweep
+// triggers this sweep.
+func (d *Detector) notify(ctx context.Context) (sent int) {
+	for _, n := range d.Notifiers {
+		triggers, err := pendingTriggers(ctx, d.DB, n)
+		if err != nil {
+			d.Logger.Warn("load pending drift triggers", "notifier", n.Name(), "error", err)
+			continue
+		}
+		resolves, err := pendingResolves(ctx, d.DB, n.Name())
+		if err != nil {
+			d.Logger.Warn("load pending drift resolves", "notifier", n.Name(), "error", err)
+			continue
+		}
+		for _, note := range append(triggers, resolves...) {
+			if err := n.Notify(ctx, note); err != nil {
+				metrics.RecordDriftNotification(n.Name(), string(note.Kind), false)
+				d.Logger.Warn("drift notification failed",
+					"notifier", n.Name(), "kind", note.Kind, "drift_id", note.DriftID, "error", err)
+				continue
+			}
+			if err := recordNotification(ctx, d.DB, n.Name(), note); err != nil {
+				// Delivered but unrecorded: the next sweep re-sends.
+				// Receivers dedup on drift_id (PagerDuty on dedup_key).
+				d.Logger.Warn("record drift notification", "notifier", n.Name(), "drift_id", note.DriftID, "error", err)
+			}
+			metrics.RecordDriftNotification(n.Name(), string(note.Kind), true)
+			sent++
+		}
+	}
+	return sent
+}
+
+// pendingTriggers returns open drifts at or above n's minimum severity
+// that n has not been triggered for.
+func pendingTriggers(ctx context.Context, db *sql.DB, n Notifier) ([]Notification, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT d.id::text, d.stripe_subscription_id, d.event_type,
+			COALESCE(e.severity, d.severity), d.detected_at,
+			d.reducer_state, d.canonical_state, d.details
+		FROM subscription_reconciliation_events d
+		LEFT JOIN LATERAL (
+			SELECT esc.severity FROM subscription_reconciliation_events esc
+			WHERE esc.escalates_id = d.id
+			ORDER BY esc.detected_at DESC
+			LIMIT 1
+		) e ON true
+		WHERE d.event_type IN ('drift_minor', 'drift_major', 'projection_impossible')
+		  AND NOT EXISTS (
+			SELECT 1 FROM subscription_reconciliation_events r
+			WHERE r.resolves_id = d.id
+		  )
+		  AND NOT EXISTS (
+			SELECT 1 FROM subscription_drift_notifications sent
+			WHERE sent.drift_id = d.id AND sent.notifier = $1 AND sent.kind = 'trigger'
+		  )
+		ORDER BY d.detected_at
+	`, n.Name())
+	if err != nil {
+		return nil, fmt.Errorf("query pending triggers: %w", err)
+	}
+	defer rows.Close()
+
+	var out []Notification
+	for rows.Next() {
+		note := Notification{Kind: NotificationTrigger}
+		var eventType, severity string
+		var reducerState, canonicalState, details []byte
+		if err := rows.Scan(&note.DriftID, &note.SubscriptionID, &eventType, &severity, &note.DetectedAt,
+			&reducerState, &canonicalState, &details); err != nil {
+			return nil, fmt.Errorf("scan pending trigger: %w", err)
+		}
+		note.EventType, note.Severity = EventType(eventType), Severity(severity)
+		if severityRank(note.Severity) < severityRank(n.MinSeverity()) {
+			continue
+		}
+		note.ReducerState, note.CanonicalState, note.Details = reducerState, canonicalState, details
+		out = append(out, note)
+	}
+	return out, rows.Err()
+}
+
+// pendingResolves returns resolved drifts the notifier was triggered for
+// and has not been told about.
+func pendingResolves(ctx context.Context, db *sql.DB, notifier string) ([]Notification, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT d.id::text, d.stripe_subscription_id, d.event_type, sent.severity, d.detected_at,
+			r.reducer_state, r.canonical_state, r.details,
+			r.detected_at, r.resolution_mechanism
+		FROM subscription_drift_notifications sent
+		JOIN subscription_reconciliation_events d ON d.id = sent.drift_id
+		JOIN subscription_reconciliation_events r ON r.resolves_id = d.id
+		WHERE sent.notifier = $1 AND sent.kind = 'trigger'
+		  AND NOT EXISTS (
+			SELECT 1 FROM subscription_drift_notifications done
+			WHERE done.drift_id = sent.drift_id AND done.notifier = $1 AND done.kind = 'resolve'
+		  )
+		ORDER BY r.detected_at
+	`, notifier)
+	if err != nil {
+		return nil, fmt.Errorf("query pending resolves: %w", err)
+	}
+	defer rows.Close()
+
+	var out []Notification
+	for rows.Next() {
+		note := Notification{Kind: NotificationResolve}
+		var eventType, severity, mechanism string
+		var resolvedAt time.Time
+		var reducerState, canonicalState, details []byte
+		if err := rows.Scan(&note.DriftID, &note.SubscriptionID, &eventType, &severity, &note.DetectedAt,
+			&reducerState, &canonicalState, &details, &resolvedAt, &mechanism); err != nil {
+			return nil, fmt.Errorf("scan pending resolve: %w", err)
+		}
+		note.EventType, note.Severity = EventType(eventType), Severity(severity)
+		note.ReducerState, note.CanonicalState, note.Details = reducerState, canonicalState, details
+		note.ResolvedAt, note.ResolutionMechanism = &resolvedAt, ResolutionMechanism(mechanism)
+		out = append(out, note)
+	}
+	return out, rows.Err()
+}
+
+func recordNotification(ctx context.Context, db *sql.DB, notifier string, note Notification) error {
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO subscription_drift_notifications (drift_id, notifier, kind, severity)
+		VALUES ($1::uuid, $2, $3, $4)
+		ON CONFLICT (drift_id, notifier, kind) DO NOTHING
+	`, note.DriftID, notifier, string(note.Kind), string(note.Severity))
+	return err
+}
@@ -0,0 +1,384 @@
+// Package notify implements drift.Notifier destinations for the
+// subscription drift detector:
+//
+//	webhook    JSON POST of the drift.Notification, HMAC-SHA256 signed
+//	           like the exporter's webhooks. URL and SECRET required.
+//	slack      Slack incoming-webhook message. URL required.
+//	pagerduty  PagerDuty Events API v2 trigger/resolve, dedup_key per
+//	           drift. ROUTING_KEY required; URL defaults to the public
+//	           events endpoint.
+//
+// Destinations are configured like the exporter's sinks:
+//
+//	DRIFT_NOTIFIERS=ops,oncall
+//	DRIFT_NOTIFIER_<NAME>_TYPE          webhook, slack or pagerduty (required)
+//	DRIFT_NOTIFIER_<NAME>_URL
+//	DRIFT_NOTIFIER_<NAME>_SECRET        webhook only
+//	DRIFT_NOTIFIER_<NAME>_ROUTING_KEY   pagerduty only
+//	DRIFT_NOTIFIER_<NAME>_MIN_SEVERITY  default critical
+//	DRIFT_NOTIFIER_<NAME>_TIMEOUT_MS    default 5000
+//
+// Delivery bookkeeping (dedup, retries, resolution notices) is the
+// detector's; a Notifier makes one attempt per call.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"payment-node/internal/reducer/subscription/drift"
+)
+
+// Notifier types.
+const (
+	TypeWebhook   = "webhook"
+	TypeSlack     = "slack"
+	TypePagerDuty = "pagerduty"
+)
+
+// DefaultPagerDutyURL is the PagerDuty Events API v2 enqueue endpoint.
+const DefaultPagerDutyURL = "https://events.pagerduty.com/v2/enqueue"
+
+// SignatureHeader carries the webhook signature; same scheme and header
+// as the exporter's signed webhooks.
+const SignatureHeader = "X-Payflux-Signature"
+
+// Config describes one notifier.
+type Config struct {
+	Name        string
+	Type        string
+	URL         string
+	Secret      string
+	RoutingKey  string
+	MinSeverity drift.Severity
+	Timeout     time.Duration
+}
+
+var severities = map[drift.Severity]bool{
+	drift.SeverityInformational: true,
+	drift.SeverityWarning:       true,
+	drift.SeverityCritical:      true,
+	drift.SeverityRegulatory:    true,
+}
+
+// ParseConfigs parses DRIFT_NOTIFIERS ("name1,name2") and resolves each
+// notifier's options from DRIFT_NOTIFIER_<NAME>_* entries in environ
+// (normally os.Environ()).
+func ParseConfigs(spec string, environ []string) ([]Config, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, nil
+	}
+
+	var configs []Config
+	seen := map[string]bool{}
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !validName(name) {
+			return nil, fmt.Errorf("invalid notifier name %q (use letters, digits, '_' or '-')", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate notifier name %q", name)
+		}
+		seen[name] = true
+
+		prefix := "DRIFT_NOTIFIER_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		opts := map[string]string{}
+		for _, kv := range environ {
+			k, v, found := strings.Cut(kv, "=")
+			if found && strings.HasPrefix(k, prefix) {
+				opts[strings.TrimPrefix(k, prefix)] = strings.TrimSpace(v)
+			}
+		}
+
+		cfg := Config{
+			Name:        name,
+			Type:        opts["TYPE"],
+			URL:         opts["URL"],
+			Secret:      opts["SECRET"],
+			RoutingKey:  opts["ROUTING_KEY"],
+			MinSeverity: drift.Severity(opts["MIN_SEVERITY"]),
+			Timeout:     5 * time.Second,
+		}
+		switch cfg.Type {
+		case TypeWebhook:
+			if cfg.URL == "" || cfg.Secret == "" {
+				return nil, fmt.Errorf("notifier %q: URL and SECRET are required", name)
+			}
+		case TypeSlack:
+			if cfg.URL == "" {
+				return nil, fmt.Errorf("notifier %q: URL is required", name)
+			}
+		case TypePagerDuty:
+			if cfg.RoutingKey == "" {
+				return nil, fmt.Errorf("notifier %q: ROUTING_KEY is required", name)
+			}
+			if cfg.URL == "" {
+				cfg.URL = DefaultPagerDutyURL
+			}
+		default:
+			return nil, fmt.Errorf("notifier %q: TYPE=%q must be webhook, slack or pagerduty", name, cfg.Type)
+		}
+		if cfg.MinSeverity == "" {
+			cfg.MinSeverity = drift.SeverityCritical
+		}
+		if !severities[cfg.MinSeverity] {
+			return nil, fmt.Errorf("notifier %q: MIN_SEVERITY=%q must be informational, warning, critical or regulatory", name, cfg.MinSeverity)
+		}
+		if raw := opts["TIMEOUT_MS"]; raw != "" {
+			ms, err := strconv.Atoi(raw)
+			if err != nil || ms <= 0 {
+				return nil, fmt.Errorf("notifier %q: TIMEOUT_MS=%q must be a positive integer", name, raw)
+			}
+			cfg.Timeout = time.Duration(ms) * time.Millisecond
+		}
+		configs = append(configs, cfg)
+	}
+	return configs, nil
+}
+
+func validName(name string) bool {
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// New builds the notifier cfg describes.
+func New(cfg Config) (drift.Notifier, error) {
+	base := notifier{cfg: cfg, client: &http.Client{Timeout: cfg.Timeout}, now: time.Now}
+	switch cfg.Type {
+	case TypeWebhook:
+		return &Webhook{base}, nil
+	case TypeSlack:
+		return &Slack{base}, nil
+	case TypePagerDuty:
+		return &PagerDuty{base}, nil
+	}
+	return nil, fmt.Errorf("notifier %q: unknown type %q", cfg.Name, cfg.Type)
+}
+
+// notifier holds what every destination shares.
+type notifier struct {
+	cfg    Config
+	client *http.Client
+	now    func() time.Time
+}
+
+func (n *notifier) Name() string                { return n.cfg.Name }
+func (n *notifier) MinSeverity() drift.Severity { return n.cfg.MinSeverity }
+
+// post sends msg as JSON and fails on any non-2xx status.
+func (n *notifier) post(ctx context.Context, msg []byte, header http.Header) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.cfg.URL, bytes.NewReader(msg))
+	if err != nil {
+		return fmt.Errorf("notifier %s: %w", n.cfg.Name, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("notifier %s: %w", n.cfg.Name, err)
+	}
+	defer resp.Body.Close()
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("notifier %s: status %d: %s", n.cfg.Name, resp.StatusCode, strings.TrimSpace(string(snippet)))
+	}
+	return nil
+}
+
+// EventType is the "type" field of webhook deliveries, by kind.
+func EventType(kind drift.NotificationKind) string {
+	return "subscription_drift." + string(kind)
+}
+
+// Webhook posts the notification as JSON, signed with the configured
+// secret.
+type Webhook struct{ notifier }
+
+func (w *Webhook) Notify(ctx context.Context, n drift.Notification) error {
+	msg, err := json.Marshal(struct {
+		Type  string             `json:"type"`
+		Drift drift.Notification `json:"drift"`
+	}{EventType(n.Kind), n})
+	if err != nil {
+		return fmt.Errorf("notifier %s: marshal: %w", w.cfg.Name, err)
+	}
+	h := http.Header{}
+	h.Set(SignatureHeader, Sign([]byte(w.cfg.Secret), w.now().Unix(), msg))
+	h.Set("X-Payflux-Drift-Id", n.DriftID)
+	return w.post(ctx, msg, h)
+}
+
+// Sign returns the signature header value for msg sent at ts:
+//
+//	t=<unix seconds>,v1=<hex HMAC-SHA256(secret, "<t>.<msg>")>
+func Sign(secret []byte, ts int64, msg []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(strconv.FormatInt(ts, 10)))
+	mac.Write([]byte("."))
+	mac.Write(msg)
+	return fmt.Sprintf("t=%d,v1=%s", ts, hex.EncodeToString(mac.Sum(nil)))
+}
+
+// Slack posts an incoming-webhook message with an attachment per drift.
+type Slack struct{ notifier }
+
+type slackMessage struct {
+	Text        string            `json:"text"`
+	Attachments []slackAttachment `json:"attachments"`
+}
+
+type slackAttachment struct {
+	Color  string       `json:"color"`
+	Fields []slackField `json:"fields"`
+}
+
+type slackField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
+
+func (s *Slack) Notify(ctx context.Context, n drift.Notification) error {
+	text := fmt.Sprintf(":rotating_light: Subscription drift (%s): %s", n.Severity, n.SubscriptionID)
+	color := "#FFA500"
+	switch n.Severity {
+	case drift.SeverityCritical:
+		color = "#FF6B6B"
+	case drift.SeverityRegulatory:
+		color = "#DC3545"
+	}
+	fields := []slackField{
+		{Title: "Subscription", Value: n.SubscriptionID, Short: true},
+		{Title: "Severity", Value: string(n.Severity), Short: true},
+		{Title: "Event type", Value: string(n.EventType), Short: true},
+		{Title: "Detected at", Value: n.DetectedAt.UTC().Format(time.RFC3339), Short: true},
+	}
+	if fieldsList := disagreeingFields(n); fieldsList != "" {
+		fields = append(fields, slackField{Title: "Disagreeing fields", Value: fieldsList})
+	}
+	if n.Kind == drift.NotificationResolve {
+		text = fmt.Sprintf(":white_check_mark: Subscription drift resolved (%s): %s", n.ResolutionMechanism, n.SubscriptionID)
+		color = "#36A64F"
+		fields = append(fields, slackField{Title: "Resolution", Value: string(n.ResolutionMechanism), Short: true})
+	}
+	fields = append(fields, slackField{Title: "Drift ID", Value: n.DriftID})
+
+	msg, err := json.Marshal(slackMessage{Text: text, Attachments: []slackAttachment{{Color: color, Fields: fields}}})
+	if err != nil {
+		return fmt.Errorf("notifier %s: marshal: %w", s.cfg.Name, err)
+	}
+	return s.post(ctx, msg, nil)
+}
+
+// PagerDuty sends Events API v2 events. Every notification for a drift
+// shares the dedup_key "payflux-drift-<drift id>", so PagerDuty itself
+// also dedups re-sent triggers and a resolve closes the incident.
+type PagerDuty struct{ notifier }
+
+type pagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key"`
+	Payload     *pagerDutyDetails `json:"payload,omitempty"`
+}
+
+type pagerDutyDetails struct {
+	Summary       string         `json:"summary"`
+	Source        string         `json:"source"`
+	Severity      string         `json:"severity"`
+	Timestamp     string         `json:"timestamp"`
+	Component     string         `json:"component"`
+	Class         string         `json:"class"`
+	CustomDetails map[string]any `json:"custom_details"`
+}
+
+// DedupKey is the PagerDuty dedup_key for a drift.
+func DedupKey(driftID string) string { return "payflux-drift-" + driftID }
+
+func (p *PagerDuty) Notify(ctx context.Context, n drift.Notification) error {
+	ev := pagerDutyEvent{RoutingKey: p.cfg.RoutingKey, EventAction: string(n.Kind), DedupKey: DedupKey(n.DriftID)}
+	if n.Kind == drift.NotificationTrigger {
+		summary := fmt.Sprintf("Subscription drift (%s) on %s", n.EventType, n.SubscriptionID)
+		if f := disagreeingFields(n); f != "" {
+			summary += ": " + f
+		}
+		ev.Payload = &pagerDutyDetails{
+			Summary:   summary,
+			Source:    drift.DetectorName,
+			Severity:  pagerDutySeverity(n.Severity),
+			Timestamp: n.DetectedAt.UTC().Format(time.RFC3339),
+			Component: "subscription_reducer",
+			Class:     string(n.EventType),
+			CustomDetails: map[string]any{
+				"drift_id":               n.DriftID,
+				"stripe_subscription_id": n.SubscriptionID,
+				"severity":               n.Severity,
+				"reducer_state":          n.ReducerState,
+				"canonical_state":        n.CanonicalState,
+				"details":                n.Details,
+			},
+		}
+	}
+	msg, err := json.Marshal(ev)
+	if err != nil {
+		return fmt.Errorf("notifier %s: marshal: %w", p.cfg.Name, err)
+	}
+	return p.post(ctx, msg, nil)
+}
+
+// pagerDutySeverity maps drift severity onto PagerDuty's scale.
+func pagerDutySeverity(s drift.Severity) string {
+	switch s {
+	case drift.SeverityCritical, drift.SeverityRegulatory:
+		return "critical"
+	case drift.SeverityWarning:
+		return "warning"
+	}
+	return "info"
+}
+
+// disagreeingFields lists the fields the detection's quorum found in
+// drift, from its details, or "" when the row has none.
+func disagreeingFields(n drift.Notification) string {
+	var d struct {
+		Quorum struct {
+			Fields map[string]struct {
+				Drift bool `json:"drift"`
+			} `json:"fields"`
+		} `json:"quorum"`
+	}
+	if len(n.Details) == 0 || json.Unmarshal(n.Details, &d) != nil {
+		return ""
+	}
+	var names []string
+	for name, vote := range d.Quorum.Fields {
+		if vote.Drift {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
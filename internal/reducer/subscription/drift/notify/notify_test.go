@@ -0,0 +1,224 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"payment-node/internal/reducer/subscription/drift"
+)
+
+func TestParseConfigs(t *testing.T) {
+	environ := []string{
+		"DRIFT_NOTIFIER_OPS_TYPE=webhook",
+		"DRIFT_NOTIFIER_OPS_URL=https://ops.example.com/drift",
+		"DRIFT_NOTIFIER_OPS_SECRET=s3cret",
+		"DRIFT_NOTIFIER_OPS_MIN_SEVERITY=warning",
+		"DRIFT_NOTIFIER_ON_CALL_TYPE=pagerduty",
+		"DRIFT_NOTIFIER_ON_CALL_ROUTING_KEY=rk",
+		"DRIFT_NOTIFIER_ON_CALL_TIMEOUT_MS=1500",
+	}
+	configs, err := ParseConfigs("ops, on-call", environ)
+	if err != nil {
+		t.Fatalf("ParseConfigs: %v", err)
+	}
+	if len(configs) != 2 {
+		t.Fatalf("got %d configs, want 2", len(configs))
+	}
+	ops, oncall := configs[0], configs[1]
+	if ops.Type != TypeWebhook || ops.Secret != "s3cret" || ops.MinSeverity != drift.SeverityWarning || ops.Timeout != 5*time.Second {
+		t.Fatalf("ops = %+v", ops)
+	}
+	if oncall.URL != DefaultPagerDutyURL || oncall.MinSeverity != drift.SeverityCritical || oncall.Timeout != 1500*time.Millisecond {
+		t.Fatalf("on-call = %+v", oncall)
+	}
+
+	if configs, err := ParseConfigs("", environ); err != nil || configs != nil {
+		t.Fatalf("empty spec = %v, %v", configs, err)
+	}
+	bad := []struct {
+		spec    string
+		environ []string
+		substr  string
+	}{
+		{"a b", nil, "invalid notifier name"},
+		{"ops,ops", environ, "duplicate"},
+		{"x", nil, "TYPE"},
+		{"x", []string{"DRIFT_NOTIFIER_X_TYPE=webhook", "DRIFT_NOTIFIER_X_URL=http://x"}, "SECRET"},
+		{"x", []string{"DRIFT_NOTIFIER_X_TYPE=slack"}, "URL"},
+		{"x", []string{"DRIFT_NOTIFIER_X_TYPE=pagerduty"}, "ROUTING_KEY"},
+		{"x", []string{"DRIFT_NOTIFIER_X_TYPE=slack", "DRIFT_NOTIFIER_X_URL=http://x", "DRIFT_NOTIFIER_X_MIN_SEVERITY=major"}, "MIN_SEVERITY"},
+		{"x", []string{"DRIFT_NOTIFIER_X_TYPE=slack", "DRIFT_NOTIFIER_X_URL=http://x", "DRIFT_NOTIFIER_X_TIMEOUT_MS=0"}, "TIMEOUT_MS"},
+	}
+	for _, tc := range bad {
+		if _, err := ParseConfigs(tc.spec, tc.environ); err == nil || !strings.Contains(err.Error(), tc.substr) {
+			t.Errorf("ParseConfigs(%q) error = %v, want mention of %q", tc.spec, err, tc.substr)
+		}
+	}
+}
+
+// capture records the last delivery to an httptest server.
+type capture struct {
+	header http.Header
+	msg    []byte
+	status int
+}
+
+func (c *capture) server(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.header = r.Header.Clone()
+		c.msg, _ = io.ReadAll(r.Body)
+		if c.status != 0 {
+			w.WriteHeader(c.status)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func testNotification(kind drift.NotificationKind) drift.Notification {
+	n := drift.Notification{
+		Kind:           kind,
+		DriftID:        "9b2f6c1e-0000-4000-8000-000000000001",
+		SubscriptionID: "sub_123",
+		EventType:      drift.EventDriftMajor,
+		Severity:       drift.SeverityCritical,
+		DetectedAt:     time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC),
+		ReducerState:   json.RawMessage(`{"status":"active"}`),
+		CanonicalState: json.RawMessage(`{"status":"canceled"}`),
+		Details:        json.RawMessage(`{"quorum":{"required":1,"providers":["billing"],"fields":{"status":{"agreed":[],"disagreed":["billing"],"drift":true}}}}`),
+	}
+	if kind == drift.NotificationResolve {
+		resolved := n.DetectedAt.Add(time.Hour)
+		n.ResolvedAt, n.ResolutionMechanism = &resolved, drift.ResolutionMechanism("polling")
+	}
+	return n
+}
+
+func newNotifier(t *testing.T, cfg Config) drift.Notifier {
+	t.Helper()
+	cfg.Name, cfg.MinSeverity, cfg.Timeout = "test", drift.SeverityCritical, time.Second
+	n, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return n
+}
+
+func TestWebhookSignsNotification(t *testing.T) {
+	var c capture
+	srv := c.server(t)
+	n := newNotifier(t, Config{Type: TypeWebhook, URL: srv.URL, Secret: "s3cret"})
+	n.(*Webhook).now = func() time.Time { return time.Unix(1700000000, 0) }
+
+	if err := n.Notify(context.Background(), testNotification(drift.NotificationTrigger)); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	if got, want := c.header.Get(SignatureHeader), Sign([]byte("s3cret"), 1700000000, c.msg); got != want {
+		t.Fatalf("signature = %q, want %q", got, want)
+	}
+	var got struct {
+		Type  string             `json:"type"`
+		Drift drift.Notification `json:"drift"`
+	}
+	if err := json.Unmarshal(c.msg, &got); err != nil {
+		t.Fatalf("decode delivery: %v", err)
+	}
+	if got.Type != "subscription_drift.trigger" || got.Drift.DriftID != testNotification(drift.NotificationTrigger).DriftID {
+		t.Fatalf("delivery = %+v", got)
+	}
+}
+
+func TestSign(t *testing.T) {
+	// Same scheme as the exporter: HMAC-SHA256 over "<t>.<msg>".
+	got := Sign([]byte("secret"), 1, []byte("{}"))
+	want := "t=1,v1=1122767b193110cfec322b6f199b599edbf608ed087f2d27afb0b97d99523908"
+	if got != want {
+		t.Fatalf("Sign = %q, want %q", got, want)
+	}
+}
+
+func TestSlackMessage(t *testing.T) {
+	var c capture
+	srv := c.server(t)
+	n := newNotifier(t, Config{Type: TypeSlack, URL: srv.URL})
+
+	if err := n.Notify(context.Background(), testNotification(drift.NotificationResolve)); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	var msg slackMessage
+	if err := json.Unmarshal(c.msg, &msg); err != nil {
+		t.Fatalf("decode delivery: %v", err)
+	}
+	if !strings.Contains(msg.Text, "resolved") || !strings.Contains(msg.Text, "sub_123") {
+		t.Fatalf("text = %q", msg.Text)
+	}
+	fields := map[string]string{}
+	for _, f := range msg.Attachments[0].Fields {
+		fields[f.Title] = f.Value
+	}
+	if fields["Resolution"] != "polling" || fields["Disagreeing fields"] != "status" {
+		t.Fatalf("fields = %v", fields)
+	}
+}
+
+func TestPagerDutyEvents(t *testing.T) {
+	var c capture
+	srv := c.server(t)
+	n := newNotifier(t, Config{Type: TypePagerDuty, URL: srv.URL, RoutingKey: "rk"})
+
+	if err := n.Notify(context.Background(), testNotification(drift.NotificationTrigger)); err != nil {
+		t.Fatalf("Notify trigger: %v", err)
+	}
+	var trigger pagerDutyEvent
+	if err := json.Unmarshal(c.msg, &trigger); err != nil {
+		t.Fatalf("decode trigger: %v", err)
+	}
+	dedup := DedupKey(testNotification(drift.NotificationTrigger).DriftID)
+	if trigger.EventAction != "trigger" || trigger.RoutingKey != "rk" || trigger.DedupKey != dedup {
+		t.Fatalf("trigger = %+v", trigger)
+	}
+	if p := trigger.Payload; p == nil || p.Severity != "critical" || p.Class != "drift_major" || !strings.HasSuffix(p.Summary, ": status") {
+		t.Fatalf("trigger payload = %+v", trigger.Payload)
+	}
+
+	if err := n.Notify(context.Background(), testNotification(drift.NotificationResolve)); err != nil {
+		t.Fatalf("Notify resolve: %v", err)
+	}
+	var resolve pagerDutyEvent
+	if err := json.Unmarshal(c.msg, &resolve); err != nil {
+		t.Fatalf("decode resolve: %v", err)
+	}
+	if resolve.EventAction != "resolve" || resolve.DedupKey != dedup || resolve.Payload != nil {
+		t.Fatalf("resolve = %+v", resolve)
+	}
+}
+
+func TestNotifyFailsOnErrorStatus(t *testing.T) {
+	c := capture{status: http.StatusServiceUnavailable}
+	srv := c.server(t)
+	n := newNotifier(t, Config{Type: TypeSlack, URL: srv.URL})
+	err := n.Notify(context.Background(), testNotification(drift.NotificationTrigger))
+	if err == nil || !strings.Contains(err.Error(), "status 503") {
+		t.Fatalf("Notify error = %v, want status 503", err)
+	}
+}
+
+func TestPagerDutySeverity(t *testing.T) {
+	for sev, want := range map[drift.Severity]string{
+		drift.SeverityRegulatory:    "critical",
+		drift.SeverityCritical:      "critical",
+		drift.SeverityWarning:       "warning",
+		drift.SeverityInformational: "info",
+	} {
+		if got := pagerDutySeverity(sev); got != want {
+			t.Errorf("pagerDutySeverity(%s) = %s, want %s", sev, got, want)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package drift
+
+import (
+	"encoding/json"
+
+	"payment-node/internal/reducer/subscription"
+	"payment-node/internal/reducer/subscription/authority"
+)
+
+// QuorumProviderName is the provider_name on the canonical_state snapshot
+// when more than one provider reported a subscription: the snapshot is
+// then the quorum view, not any single provider's state.
+const QuorumProviderName = "quorum"
+
+// fieldCheck compares and copies one interpreted field. Checks run in the
+// order DisagreeingFields are reported.
+type fieldCheck struct {
+	name  string
+	agree func(a, b *authority.State) bool
+	copy  func(dst, src *authority.State)
+}
+
+var fieldChecks = []fieldCheck{
+	{"status",
+		func(a, b *authority.State) bool { return a.Status == b.Status },
+		func(dst, src *authority.State) { dst.Status = src.Status }},
+	{"current_period_start",
+		func(a, b *authority.State) bool { return timestampsAgree(a.CurrentPeriodStart, b.CurrentPeriodStart) },
+		func(dst, src *authority.State) { dst.CurrentPeriodStart = src.CurrentPeriodStart }},
+	{"current_period_end",
+		func(a, b *authority.State) bool { return timestampsAgree(a.CurrentPeriodEnd, b.CurrentPeriodEnd) },
+		func(dst, src *authority.State) { dst.CurrentPeriodEnd = src.CurrentPeriodEnd }},
+	{"cancel_at_period_end",
+		func(a, b *authority.State) bool { return a.CancelAtPeriodEnd == b.CancelAtPeriodEnd },
+		func(dst, src *authority.State) { dst.CancelAtPeriodEnd = src.CancelAtPeriodEnd }},
+	{"canceled_at",
+		func(a, b *authority.State) bool { return timestampsAgree(a.CanceledAt, b.CanceledAt) },
+		func(dst, src *authority.State) { dst.CanceledAt = src.CanceledAt }},
+	{"trial_start",
+		func(a, b *authority.State) bool { return timestampsAgree(a.TrialStart, b.TrialStart) },
+		func(dst, src *authority.State) { dst.TrialStart = src.TrialStart }},
+	{"trial_end",
+		func(a, b *authority.State) bool { return timestampsAgree(a.TrialEnd, b.TrialEnd) },
+		func(dst, src *authority.State) { dst.TrialEnd = src.TrialEnd }},
+}
+
+// asAuthority restates a projection in the provider shape so fieldChecks
+// apply to both sides.
+func asAuthority(p *subscription.State) *authority.State {
+	return &authority.State{
+		StripeSubscriptionID: p.StripeSubscriptionID,
+		Status:               p.Status,
+		CurrentPeriodStart:   p.CurrentPeriodStart,
+		CurrentPeriodEnd:     p.CurrentPeriodEnd,
+		CancelAtPeriodEnd:    p.CancelAtPeriodEnd,
+		CanceledAt:           p.CanceledAt,
+		TrialStart:           p.TrialStart,
+		TrialEnd:             p.TrialEnd,
+	}
+}
+
+// FieldVote records how the reporting providers voted on one field.
+// Agreed and Disagreed hold provider names; Drift is whether Disagreed
+// reached the quorum.
+type FieldVote struct {
+	Agreed    []string `json:"agreed"`
+	Disagreed []string `json:"disagreed"`
+	Drift     bool     `json:"drift"`
+}
+
+// Quorum is the result of comparing a projection against every provider
+// that reported the subscription. Comparison.DisagreeingFields holds only
+// the fields at least Required providers disagree on, so one lagging
+// provider contradicted by the others does not produce a detection.
+type Quorum struct {
+	Comparison
+
+	// Required is the number of dissenting providers that makes a field
+	// drift.
+	Required int
+
+	// Providers are the providers that reported the subscription, in
+	// configured order. Providers without the subscription abstain.
+	Providers []string
+
+	// Fields holds a vote for every field at least one provider
+	// disagreed on, whether or not it reached the quorum.
+	Fields map[string]FieldVote
+
+	// View is the quorum's interpretation: for a drifting field, the
+	// value held by the largest group of dissenting providers; otherwise
+	// the first agreeing provider's value. A lone provider's State is
+	// the view unchanged.
+	View *authority.State
+}
+
+// requiredQuorum resolves a configured quorum against the number of
+// reporting providers. Zero means a strict majority.
+func requiredQuorum(configured, reporting int) int {
+	if configured > 0 {
+		return configured
+	}
+	return reporting/2 + 1
+}
+
+// compareQuorum compares projection against states, one per reporting
+// provider. Pure function (no I/O); states must be non-empty.
+func compareQuorum(projection *subscription.State, states []*authority.State, quorum int) Quorum {
+	q := Quorum{
+		Required: requiredQuorum(quorum, len(states)),
+		Fields:   map[string]FieldVote{},
+	}
+	for _, s := range states {
+		q.Providers = append(q.Providers, s.ProviderName)
+	}
+
+	view := *states[0]
+	if len(states) > 1 {
+		view.ProviderName = QuorumProviderName
+		view.ProviderVersion = DetectorVersion
+	}
+
+	// Projection-impossible is independent of the providers, as in
+	// compare.
+	if !ValidStripeStatuses[projection.Status] {
+		q.Comparison = Comparison{ProjectionImpossible: true, DisagreeingFields: []string{"status"}}
+		q.View = &view
+		return q
+	}
+
+	proj := asAuthority(projection)
+	for _, check := range fieldChecks {
+		vote := FieldVote{Agreed: []string{}, Disagreed: []string{}}
+		var agreeing, dissenting []*authority.State
+		for _, s := range states {
+			if check.agree(proj, s) {
+				vote.Agreed = append(vote.Agreed, s.ProviderName)
+				agreeing = append(agreeing, s)
+			} else {
+				vote.Disagreed = append(vote.Disagreed, s.ProviderName)
+				dissenting = append(dissenting, s)
+			}
+		}
+		if len(dissenting) == 0 {
+			check.copy(&view, agreeing[0])
+			continue
+		}
+		vote.Drift = len(dissenting) >= q.Required
+		q.Fields[check.name] = vote
+		switch {
+		case vote.Drift:
+			q.DisagreeingFields = append(q.DisagreeingFields, check.name)
+			check.copy(&view, plurality(check, dissenting))
+		case len(agreeing) > 0:
+			check.copy(&view, agreeing[0])
+		}
+	}
+	q.Agreed = len(q.DisagreeingFields) == 0
+	q.View = &view
+	return q
+}
+
+// plurality returns a state holding the value most of states agree on for
+// check's field; ties go to the earliest configured provider.
+func plurality(check fieldCheck, states []*authority.State) *authority.State {
+	best, bestCount := states[0], 0
+	for _, s := range states {
+		n := 0
+		for _, other := range states {
+			if check.agree(s, other) {
+				n++
+			}
+		}
+		if n > bestCount {
+			best, bestCount = s, n
+		}
+	}
+	return best
+}
+
+// details serializes the votes into the jsonb stored on reconciliation
+// rows.
+func (q Quorum) details() []byte {
+	b, _ := json.Marshal(map[string]any{
+		"quorum": map[string]any{
+			"required":  q.Required,
+			"providers": q.Providers,
+			"fields":    q.Fields,
+		},
+	})
+	return b
+}
+
+// providerStates is one sweep's authority input: every subscription any
+// provider reported, in first-seen order, with the reporting providers'
+// states in configured order.
+type providerStates struct {
+	order []string
+	bySub map[string][]*authority.State
+}
+
+func (ps *providerStates) add(s *authority.State) {
+	if ps.bySub == nil {
+		ps.bySub = map[string][]*authority.State{}
+	}
+	if _, ok := ps.bySub[s.StripeSubscriptionID]; !ok {
+		ps.order = append(ps.order, s.StripeSubscriptionID)
+	}
+	ps.bySub[s.StripeSubscriptionID] = append(ps.bySub[s.StripeSubscriptionID], s)
+}
@@ -37,6 +37,10 @@ const (
 	EventDriftMinor             EventType = "drift_minor"
 	EventDriftMajor             EventType = "drift_major"
 	EventDriftResolved          EventType = "drift_resolved"
+	// EventDriftEscalated is appended when an open drift outlives its
+	// severity's TTL and the timeout policy escalates it. Its severity is
+	// the drift's effective severity from then on.
+	EventDriftEscalated         EventType = "drift_escalated"
 	EventProjectionImpossible   EventType = "projection_impossible"
 )
 
@@ -46,7 +50,7 @@ type ResolutionMechanism string
 
 const (
 	ResolutionAutoProviderAgreed ResolutionMechanism = "auto_provider_agreed"
-	ResolutionPollingSupersede   ResolutionMechanism = "polling_supersede" // Phase 2
+	ResolutionPollingSupersede   ResolutionMechanism = "polling_supersede"
 	ResolutionReplayCorrection   ResolutionMechanism = "replay_correction"
 	ResolutionManualOperator     ResolutionMechanism = "manual_operator"
 	ResolutionTimeoutUnresolved  ResolutionMechanism = "timeout_unresolved"
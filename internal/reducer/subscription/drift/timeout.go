@@ -0,0 +1,329 @@
+package drift
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"payment-node/internal/metrics"
+)
+
+// TimeoutAction is what happens to an open drift that has held its
+// effective severity for longer than the rule's TTL.
+type TimeoutAction string
+
+const (
+	// TimeoutEscalate bumps the severity one level and fires an alert.
+	TimeoutEscalate TimeoutAction = "escalate"
+
+	// TimeoutClose resolves the drift as timeout_unresolved. Drift is not
+	// re-opened for the subscription until its projection head moves.
+	TimeoutClose TimeoutAction = "close"
+)
+
+// TimeoutRule applies to open drifts at one effective severity.
+type TimeoutRule struct {
+	After  time.Duration
+	Action TimeoutAction
+}
+
+// TimeoutPolicy maps effective severity to its rule. Severities without a
+// rule stay open until the providers and projection agree.
+type TimeoutPolicy map[Severity]TimeoutRule
+
+// escalatedSeverity is the severity an escalation moves to. Critical is
+// the ceiling: regulatory is reserved for impossible projections.
+func escalatedSeverity(s Severity) (Severity, bool) {
+	switch s {
+	case SeverityInformational:
+		return SeverityWarning, true
+	case SeverityWarning:
+		return SeverityCritical, true
+	}
+	return "", false
+}
+
+// Validate rejects rules for unknown severities, non-positive TTLs, and
+// escalations past critical.
+func (p TimeoutPolicy) Validate() error {
+	for sev, rule := range p {
+		if severityRank(sev) == 0 {
+			return fmt.Errorf("timeout policy: unknown severity %q", sev)
+		}
+		if rule.After <= 0 {
+			return fmt.Errorf("timeout policy: %s: TTL must be positive", sev)
+		}
+		switch rule.Action {
+		case TimeoutClose:
+		case TimeoutEscalate:
+			if _, ok := escalatedSeverity(sev); !ok {
+				return fmt.Errorf("timeout policy: %s drift cannot be escalated", sev)
+			}
+		default:
+			return fmt.Errorf("timeout policy: %s: unknown action %q", sev, rule.Action)
+		}
+	}
+	return nil
+}
+
+// ParseTimeoutPolicy parses a comma-separated list of
+// severity=duration:action rules, e.g.
+// "warning=6h:escalate,critical=72h:close". An empty string is an empty
+// policy.
+func ParseTimeoutPolicy(s string) (TimeoutPolicy, error) {
+	p := TimeoutPolicy{}
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		sev, rest, ok := strings.Cut(entry, "=")
+		after, action, ok2 := strings.Cut(rest, ":")
+		if !ok || !ok2 {
+			return nil, fmt.Errorf("timeout policy: %q: want severity=duration:action", entry)
+		}
+		d, err := time.ParseDuration(after)
+		if err != nil {
+			return nil, fmt.Errorf("timeout policy: %q: %w", entry, err)
+		}
+		if _, dup := p[Severity(sev)]; dup {
+			return nil, fmt.Errorf("timeout policy: %s listed twice", sev)
+		}
+		p[Severity(sev)] = TimeoutRule{After: d, Action: TimeoutAction(action)}
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// openDrift is an unresolved detection with its effective severity.
+type openDrift struct {
+	ID             string
+	SubscriptionID string
+	DetectedAt     time.Time
+
+	// Severity is the latest escalation's severity, or the detection's.
+	// Since is when it took effect.
+	Severity Severity
+	Since    time.Time
+}
+
+// due returns the rule whose TTL o has outlived at now.
+func (p TimeoutPolicy) due(o openDrift, now time.Time) (TimeoutRule, bool) {
+	rule, ok := p[o.Severity]
+	if !ok || now.Sub(o.Since) < rule.After {
+		return TimeoutRule{}, false
+	}
+	return rule, true
+}
+
+// loadOpenDrifts returns every unresolved detection with its effective
+// severity.
+func loadOpenDrifts(ctx context.Context, db *sql.DB) ([]openDrift, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT d.id::text, d.stripe_subscription_id, d.detected_at,
+			COALESCE(e.severity, d.severity), COALESCE(e.detected_at, d.detected_at)
+		FROM subscription_reconciliation_events d
+		LEFT JOIN LATERAL (
+			SELECT esc.severity, esc.detected_at FROM subscription_reconciliation_events esc
+			WHERE esc.escalates_id = d.id
+			ORDER BY esc.detected_at DESC
+			LIMIT 1
+		) e ON true
+		WHERE d.event_type IN ('drift_minor', 'drift_major', 'projection_impossible')
+		  AND NOT EXISTS (
+			SELECT 1 FROM subscription_reconciliation_events r
+			WHERE r.resolves_id = d.id
+		  )
+		ORDER BY d.detected_at
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("query open drifts: %w", err)
+	}
+	defer rows.Close()
+
+	var out []openDrift
+	for rows.Next() {
+		var o openDrift
+		var sev string
+		if err := rows.Scan(&o.ID, &o.SubscriptionID, &o.DetectedAt, &sev, &o.Since); err != nil {
+			return nil, fmt.Errorf("scan open drift: %w", err)
+		}
+		o.Severity = Severity(sev)
+		out = append(out, o)
+	}
+	return out, rows.Err()
+}
+
+// enforceTimeouts applies d.Timeouts to every open drift, then refreshes
+// the open-drift gauges. Runs after the comparison pass, so a drift that
+// agrees this sweep is resolved as agreement rather than timed out.
+func (d *Detector) enforceTimeouts(ctx context.Context) (escalated, closed int, err error) {
+	open, err := loadOpenDrifts(ctx, d.DB)
+	if err != nil {
+		return 0, 0, err
+	}
+	now := time.Now()
+
+	stillOpen := open[:0]
+	for _, o := range open {
+		rule, ok := d.Timeouts.due(o, now)
+		if !ok {
+			stillOpen = append(stillOpen, o)
+			continue
+		}
+		switch rule.Action {
+		case TimeoutEscalate:
+			to, _ := escalatedSeverity(o.Severity)
+			if err := emitEscalation(ctx, d.DB, o, to, rule); err != nil {
+				d.Logger.Warn("emit escalation", "drift_id", o.ID, "error", err)
+				stillOpen = append(stillOpen, o)
+				continue
+			}
+			metrics.RecordDriftEscalation(string(o.Severity), string(to))
+			d.Logger.Error("drift escalated",
+				"drift_id", o.ID,
+				"subscription_id", o.SubscriptionID,
+				"from", o.Severity,
+				"to", to,
+				"open_for", now.Sub(o.DetectedAt).Round(time.Second).String(),
+			)
+			o.Severity, o.Since = to, now
+			stillOpen = append(stillOpen, o)
+			escalated++
+		case TimeoutClose:
+			if err := emitTimeoutClose(ctx, d.DB, o, rule); err != nil {
+				d.Logger.Warn("emit timeout close", "drift_id", o.ID, "error", err)
+				stillOpen = append(stillOpen, o)
+				continue
+			}
+			metrics.RecordDriftTimeout(string(o.Severity))
+			d.Logger.Warn("drift closed as timeout_unresolved",
+				"drift_id", o.ID,
+				"subscription_id", o.SubscriptionID,
+				"severity", o.Severity,
+				"open_for", now.Sub(o.DetectedAt).Round(time.Second).String(),
+			)
+			closed++
+		}
+	}
+
+	updateOpenDriftGauges(stillOpen, now)
+	return escalated, closed, nil
+}
+
+// updateOpenDriftGauges sets the gauges for every severity, zeroing those
+// with no open drift.
+func updateOpenDriftGauges(open []openDrift, now time.Time) {
+	counts := map[Severity]int{}
+	oldest := map[Severity]time.Time{}
+	for _, o := range open {
+		counts[o.Severity]++
+		if t, ok := oldest[o.Severity]; !ok || o.DetectedAt.Before(t) {
+			oldest[o.Severity] = o.DetectedAt
+		}
+	}
+	for _, sev := range []Severity{SeverityInformational, SeverityWarning, SeverityCritical, SeverityRegulatory} {
+		age := 0.0
+		if t, ok := oldest[sev]; ok {
+			age = now.Sub(t).Seconds()
+		}
+		metrics.UpdateDriftOpen(string(sev), counts[sev], age)
+	}
+}
+
+// timeoutDetails is the details jsonb on escalation and timeout rows.
+func timeoutDetails(o openDrift, to Severity, rule TimeoutRule) []byte {
+	t := map[string]any{
+		"from":          o.Severity,
+		"action":        rule.Action,
+		"after_seconds": int64(rule.After / time.Second),
+	}
+	if to != "" {
+		t["to"] = to
+	}
+	b, _ := json.Marshal(map[string]any{"timeout": t})
+	return b
+}
+
+// emitEscalation appends a drift_escalated row carrying the detection's
+// snapshots.
+func emitEscalation(ctx context.Context, db *sql.DB, o openDrift, to Severity, rule TimeoutRule) error {
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO subscription_reconciliation_events (
+			stripe_subscription_id, event_type, severity,
+			projection_id_at_detection, reducer_state, canonical_state,
+			detector_name, detector_version, reducer_version,
+			escalates_id, details
+		)
+		SELECT
+			d.stripe_subscription_id, $2, $3,
+			d.projection_id_at_detection, d.reducer_state, d.canonical_state,
+			$4, $5, d.reducer_version,
+			d.id, $6
+		FROM subscription_reconciliation_events d
+		WHERE d.id = $1::uuid
+	`, o.ID, string(EventDriftEscalated), string(to), DetectorName, DetectorVersion, timeoutDetails(o, to, rule))
+	return err
+}
+
+// emitTimeoutClose resolves o as timeout_unresolved.
+func emitTimeoutClose(ctx context.Context, db *sql.DB, o openDrift, rule TimeoutRule) error {
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO subscription_reconciliation_events (
+			stripe_subscription_id, event_type, severity,
+			projection_id_at_detection, reducer_state, canonical_state,
+			detector_name, detector_version, reducer_version,
+			resolves_id, resolution_mechanism, details
+		)
+		SELECT
+			d.stripe_subscription_id, $2, $3,
+			d.projection_id_at_detection, d.reducer_state, d.canonical_state,
+			$4, $5, d.reducer_version,
+			d.id, $6, $7
+		FROM subscription_reconciliation_events d
+		WHERE d.id = $1::uuid
+	`, o.ID, string(EventDriftResolved), string(SeverityInformational), DetectorName, DetectorVersion,
+		string(ResolutionTimeoutUnresolved), timeoutDetails(o, "", rule))
+	return err
+}
+
+// timedOutAtHead reports whether the subscription's latest drift was
+// closed as timeout_unresolved while the projection head was the same row
+// it is now. The sweep does not re-open such a drift: nothing on the
+// reducer side has changed since the policy gave up on it.
+func timedOutAtHead(ctx context.Context, db *sql.DB, subID string) (bool, error) {
+	var timedOut bool
+	err := db.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM subscription_reconciliation_events r
+			JOIN subscription_reconciliation_events d ON d.id = r.resolves_id
+			WHERE r.stripe_subscription_id = $1
+			  AND r.resolution_mechanism = 'timeout_unresolved'
+			  AND d.projection_id_at_detection = (
+				SELECT id FROM subscription_current_state WHERE stripe_subscription_id = $1
+			  )
+		)
+	`, subID).Scan(&timedOut)
+	return timedOut, err
+}
+
+// String renders p in ParseTimeoutPolicy's format, severities in rank
+// order.
+func (p TimeoutPolicy) String() string {
+	sevs := make([]Severity, 0, len(p))
+	for sev := range p {
+		sevs = append(sevs, sev)
+	}
+	sort.Slice(sevs, func(i, j int) bool { return severityRank(sevs[i]) < severityRank(sevs[j]) })
+	parts := make([]string, len(sevs))
+	for i, sev := range sevs {
+		parts[i] = fmt.Sprintf("%s=%s:%s", sev, p[sev].After, p[sev].Action)
+	}
+	return strings.Join(parts, ",")
+}
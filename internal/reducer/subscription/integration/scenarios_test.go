@@ -3,7 +3,10 @@ package integration
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 	"time"
@@ -11,6 +14,8 @@ import (
 	"payment-node/internal/reducer/subscription"
 	"payment-node/internal/reducer/subscription/authority"
 	"payment-node/internal/reducer/subscription/drift"
+	"payment-node/internal/reducer/subscription/manual"
+	"payment-node/internal/reducer/subscription/polling"
 )
 
 // stripeEventFixture builds a synthetic Stripe webhook payload for the
@@ -597,8 +602,539 @@ func TestSubstrate_ReplayEpochLineage(t *testing.T) {
 	}
 }
 
+// TestSubstrate_PollingSupersedesStaleWebhook drives the polling worker
+// against a fake Stripe API: the webhook-derived projection says active,
+// Stripe (and canonical) say past_due. The polled snapshot lands in the
+// ledger, the reducer applies it as the newest observation, and the
+// detector attributes the resolution to polling.
+func TestSubstrate_PollingSupersedesStaleWebhook(t *testing.T) {
+	db := SetupSubstrate(t)
+	ctx := context.Background()
+
+	wsID := InsertWorkspace(t, db)
+	webhookAt := time.Now().UTC().Add(-time.Hour)
+
+	InsertLedgerEvent(t, db, LedgerEvent{
+		StripeEventID: "evt_poll_001",
+		Payload:       stripeEventFixture("evt_poll_001", "customer.subscription.updated", "sub_poll_001", wsID, "active", webhookAt, false),
+		ReceivedAt:    webhookAt,
+	})
+	if _, _, err := subscription.ProcessPending(ctx, db); err != nil {
+		t.Fatalf("ProcessPending: %v", err)
+	}
+
+	periodStart := time.Unix(webhookAt.Unix(), 0).UTC()
+	periodEnd := time.Unix(webhookAt.Add(30*24*time.Hour).Unix(), 0).UTC()
+	InsertBillingSubscription(t, db, BillingSubscription{
+		WorkspaceID:          wsID,
+		StripeSubscriptionID: "sub_poll_001",
+		StripeCustomerID:     "cus_poll_001",
+		Status:               "past_due",
+		CurrentPeriodStart:   &periodStart,
+		CurrentPeriodEnd:     &periodEnd,
+	})
+	runDetectorSweep(t, db)
+	assertRowCount(t, db, `SELECT count(*)::int FROM subscription_reconciliation_events WHERE event_type = 'drift_major'`, 1)
+
+	stripeObject := stripeEventFixture("", "", "sub_poll_001", wsID, "past_due", webhookAt, false)["data"].(map[string]any)["object"]
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"object":   "list",
+			"data":     []any{stripeObject},
+			"has_more": false,
+		})
+	}))
+	defer srv.Close()
+
+	worker := &polling.Worker{DB: db, Client: polling.NewClient(srv.URL, "sk_test_fake")}
+	for i := 0; i < 2; i++ {
+		if _, err := worker.PollOnce(ctx); err != nil {
+			t.Fatalf("PollOnce: %v", err)
+		}
+	}
+	assertRowCount(t, db, `SELECT count(*)::int FROM stripe_event_ledger WHERE verify_outcome = 'polling'`, 1)
+
+	if _, _, err := subscription.ProcessPending(ctx, db); err != nil {
+		t.Fatalf("ProcessPending after poll: %v", err)
+	}
+	assertSubscriptionStatusInView(t, db, "sub_poll_001", "past_due")
+
+	runDetectorSweep(t, db)
+	var mechanism string
+	err := db.QueryRow(`
+		SELECT resolution_mechanism FROM subscription_reconciliation_events
+		WHERE stripe_subscription_id = $1 AND event_type = 'drift_resolved'
+	`, "sub_poll_001").Scan(&mechanism)
+	if err != nil {
+		t.Fatalf("query resolution: %v", err)
+	}
+	if mechanism != "polling_supersede" {
+		t.Errorf("expected polling_supersede; got %s", mechanism)
+	}
+}
+
+// TestSubstrate_ManualCorrectionResolvesDrift submits an operator
+// correction for a drifted subscription: the correction lands in the
+// ledger with provenance, the reducer applies it with manual authority,
+// and the detector attributes the resolution to the operator.
+func TestSubstrate_ManualCorrectionResolvesDrift(t *testing.T) {
+	db := SetupSubstrate(t)
+	ctx := context.Background()
+
+	wsID := InsertWorkspace(t, db)
+	webhookAt := time.Now().UTC().Add(-time.Hour)
+
+	InsertLedgerEvent(t, db, LedgerEvent{
+		StripeEventID: "evt_manual_001",
+		Payload:       stripeEventFixture("evt_manual_001", "customer.subscription.updated", "sub_manual_001", wsID, "active", webhookAt, false),
+		ReceivedAt:    webhookAt,
+	})
+	if _, _, err := subscription.ProcessPending(ctx, db); err != nil {
+		t.Fatalf("ProcessPending: %v", err)
+	}
+
+	periodStart := time.Unix(webhookAt.Unix(), 0).UTC()
+	periodEnd := time.Unix(webhookAt.Add(30*24*time.Hour).Unix(), 0).UTC()
+	InsertBillingSubscription(t, db, BillingSubscription{
+		WorkspaceID:          wsID,
+		StripeSubscriptionID: "sub_manual_001",
+		StripeCustomerID:     "cus_manual_001",
+		Status:               "past_due",
+		CurrentPeriodStart:   &periodStart,
+		CurrentPeriodEnd:     &periodEnd,
+	})
+	runDetectorSweep(t, db)
+	assertRowCount(t, db, `SELECT count(*)::int FROM subscription_reconciliation_events WHERE event_type = 'drift_major'`, 1)
+
+	if _, err := manual.Submit(ctx, db, subscription.ManualCorrection{
+		Subscription: "sub_unknown",
+		Fields:       map[string]json.RawMessage{"status": json.RawMessage(`"past_due"`)},
+		Operator:     "ops@example.com",
+		Reason:       "test",
+	}, ""); err != manual.ErrUnknownSubscription {
+		t.Fatalf("expected ErrUnknownSubscription; got %v", err)
+	}
+
+	// A key bound to another workspace cannot see the subscription.
+	const otherWorkspace = "00000000-0000-0000-0000-0000000000ff"
+	if _, err := manual.Submit(ctx, db, subscription.ManualCorrection{
+		Subscription: "sub_manual_001",
+		Fields:       map[string]json.RawMessage{"status": json.RawMessage(`"past_due"`)},
+		Operator:     "other-admin",
+		Reason:       "test",
+	}, otherWorkspace); err != manual.ErrUnknownSubscription {
+		t.Fatalf("expected ErrUnknownSubscription for another workspace; got %v", err)
+	}
+
+	receipt, err := manual.Submit(ctx, db, subscription.ManualCorrection{
+		Subscription: "sub_manual_001",
+		Fields:       map[string]json.RawMessage{"status": json.RawMessage(`"past_due"`)},
+		Operator:     "ops@example.com",
+		Reason:       "INC-42: customer.subscription.updated never delivered",
+	}, "")
+	if err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	if !strings.HasPrefix(receipt.SourceEventID, subscription.ManualEventIDPrefix) {
+		t.Errorf("source event id = %q", receipt.SourceEventID)
+	}
+	assertRowCount(t, db, `SELECT count(*)::int FROM stripe_event_ledger WHERE verify_outcome = 'manual'`, 1)
+
+	if _, _, err := subscription.ProcessPending(ctx, db); err != nil {
+		t.Fatalf("ProcessPending after correction: %v", err)
+	}
+	assertSubscriptionStatusInView(t, db, "sub_manual_001", "past_due")
+
+	var authority string
+	if err := db.QueryRow(`
+		SELECT field_authority->>'status' FROM subscription_current_state
+		WHERE stripe_subscription_id = $1 AND source_event_id = $2
+	`, "sub_manual_001", receipt.SourceEventID).Scan(&authority); err != nil {
+		t.Fatalf("query corrected head: %v", err)
+	}
+	if authority != "manual" {
+		t.Errorf("status authority = %s, want manual", authority)
+	}
+
+	records, err := manual.List(ctx, db, "sub_manual_001", "", 10)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(records) != 1 || records[0].Operator != "ops@example.com" || records[0].ProjectedAt == nil {
+		t.Fatalf("correction history = %+v", records)
+	}
+	if records, err := manual.List(ctx, db, "sub_manual_001", otherWorkspace, 10); err != nil || len(records) != 0 {
+		t.Fatalf("another workspace's correction history = %+v, %v", records, err)
+	}
+
+	runDetectorSweep(t, db)
+	var mechanism string
+	err = db.QueryRow(`
+		SELECT resolution_mechanism FROM subscription_reconciliation_events
+		WHERE stripe_subscription_id = $1 AND event_type = 'drift_resolved'
+	`, "sub_manual_001").Scan(&mechanism)
+	if err != nil {
+		t.Fatalf("query resolution: %v", err)
+	}
+	if mechanism != "manual_operator" {
+		t.Errorf("expected manual_operator; got %s", mechanism)
+	}
+}
+
+// TestSubstrate_QuorumOutvotesLaggingProvider verifies that with two
+// providers a single dissenting one does not produce a detection, that
+// drift is recorded once both dissent, and that the row's details name
+// the dissenting providers.
+func TestSubstrate_QuorumOutvotesLaggingProvider(t *testing.T) {
+	db := SetupSubstrate(t)
+	ctx := context.Background()
+
+	wsID := InsertWorkspace(t, db)
+	now := time.Now().UTC()
+	InsertLedgerEvent(t, db, LedgerEvent{
+		StripeEventID: "evt_quorum_001",
+		Payload:       stripeEventFixture("evt_quorum_001", "customer.subscription.updated", "sub_quorum_001", wsID, "active", now, false),
+		ReceivedAt:    now,
+	})
+	if _, _, err := subscription.ProcessPending(ctx, db); err != nil {
+		t.Fatalf("ProcessPending: %v", err)
+	}
+
+	periodStart := time.Unix(now.Unix(), 0).UTC()
+	periodEnd := time.Unix(now.Add(30*24*time.Hour).Unix(), 0).UTC()
+	InsertBillingSubscription(t, db, BillingSubscription{
+		WorkspaceID:          wsID,
+		StripeSubscriptionID: "sub_quorum_001",
+		StripeCustomerID:     "cus_quorum_001",
+		Status:               "canceled",
+		CurrentPeriodStart:   &periodStart,
+		CurrentPeriodEnd:     &periodEnd,
+	})
+	stripe := &staticProvider{states: []*authority.State{{
+		StripeSubscriptionID: "sub_quorum_001",
+		Status:               "active",
+		CurrentPeriodStart:   &periodStart,
+		CurrentPeriodEnd:     &periodEnd,
+		ProviderName:         "stripe_api",
+	}}}
+	sweep := func() {
+		d := &drift.Detector{DB: db, Providers: []authority.Provider{authority.NewBillingProvider(db), stripe}}
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		_ = d.Run(ctx)
+	}
+
+	sweep()
+	assertRowCount(t, db, `SELECT count(*) FROM subscription_reconciliation_events WHERE stripe_subscription_id = 'sub_quorum_001'`, 0)
+
+	stripe.states[0].Status = "canceled"
+	sweep()
+	var eventType, viewProvider string
+	var disagreed []byte
+	err := db.QueryRow(`
+		SELECT event_type, canonical_state->>'provider_name',
+			details->'quorum'->'fields'->'status'->'disagreed'
+		FROM subscription_reconciliation_events
+		WHERE stripe_subscription_id = $1
+	`, "sub_quorum_001").Scan(&eventType, &viewProvider, &disagreed)
+	if err != nil {
+		t.Fatalf("query detection: %v", err)
+	}
+	if eventType != "drift_major" || viewProvider != drift.QuorumProviderName {
+		t.Errorf("detection event_type=%s provider=%s", eventType, viewProvider)
+	}
+	if got := string(disagreed); got != `["billing_subscriptions", "stripe_api"]` {
+		t.Errorf("disagreed = %s", got)
+	}
+}
+
+// TestSubstrate_DriftTimeoutEscalatesThenCloses verifies the timeout
+// policy: a warning drift is escalated to critical with an escalation row
+// chained to the detection, a critical drift is closed as
+// timeout_unresolved, and the closed drift is not re-opened while the
+// projection head is unchanged.
+func TestSubstrate_DriftTimeoutEscalatesThenCloses(t *testing.T) {
+	db := SetupSubstrate(t)
+	ctx := context.Background()
+
+	wsID := InsertWorkspace(t, db)
+	now := time.Now().UTC()
+	for _, id := range []string{"sub_timeout_warn", "sub_timeout_crit"} {
+		InsertLedgerEvent(t, db, LedgerEvent{
+			StripeEventID: "evt_" + id,
+			Payload:       stripeEventFixture("evt_"+id, "customer.subscription.updated", id, wsID, "active", now, false),
+			ReceivedAt:    now,
+		})
+	}
+	if _, _, err := subscription.ProcessPending(ctx, db); err != nil {
+		t.Fatalf("ProcessPending: %v", err)
+	}
+
+	periodStart := time.Unix(now.Unix(), 0).UTC()
+	periodEnd := time.Unix(now.Add(30*24*time.Hour).Unix(), 0).UTC()
+	laterEnd := periodEnd.Add(24 * time.Hour)
+	// Period end only: warning severity.
+	InsertBillingSubscription(t, db, BillingSubscription{
+		WorkspaceID: wsID, StripeSubscriptionID: "sub_timeout_warn", StripeCustomerID: "cus_timeout_warn",
+		Status: "active", CurrentPeriodStart: &periodStart, CurrentPeriodEnd: &laterEnd,
+	})
+	// Status: critical severity.
+	InsertBillingSubscription(t, db, BillingSubscription{
+		WorkspaceID: wsID, StripeSubscriptionID: "sub_timeout_crit", StripeCustomerID: "cus_timeout_crit",
+		Status: "canceled", CurrentPeriodStart: &periodStart, CurrentPeriodEnd: &periodEnd,
+	})
+
+	policy := drift.TimeoutPolicy{
+		drift.SeverityWarning:  {After: time.Millisecond, Action: drift.TimeoutEscalate},
+		drift.SeverityCritical: {After: time.Hour, Action: drift.TimeoutClose},
+	}
+	sweep := func(p drift.TimeoutPolicy) {
+		d := &drift.Detector{DB: db, Provider: authority.NewBillingProvider(db), Timeouts: p}
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		_ = d.Run(ctx)
+	}
+
+	// First sweep detects both; the warning drift is already past its
+	// 1ms TTL when timeouts are enforced.
+	sweep(policy)
+	var escalatedSeverity, escalatesID, detectionID string
+	err := db.QueryRow(`
+		SELECT e.severity, e.escalates_id::text, d.id::text
+		FROM subscription_reconciliation_events e
+		JOIN subscription_reconciliation_events d ON d.id = e.escalates_id
+		WHERE e.stripe_subscription_id = $1 AND e.event_type = 'drift_escalated'
+	`, "sub_timeout_warn").Scan(&escalatedSeverity, &escalatesID, &detectionID)
+	if err != nil {
+		t.Fatalf("query escalation: %v", err)
+	}
+	if escalatedSeverity != "critical" || escalatesID != detectionID {
+		t.Errorf("escalation severity=%s escalates_id=%s detection=%s", escalatedSeverity, escalatesID, detectionID)
+	}
+	assertRowCount(t, db, `SELECT coalesce(sum(open_count), 0) FROM subscription_drift_open WHERE severity = 'critical'`, 2)
+	assertRowCount(t, db, `SELECT count(*) FROM subscription_reconciliation_events WHERE resolution_mechanism = 'timeout_unresolved'`, 0)
+
+	// Critical drifts (the escalated one included, whose TTL runs from
+	// the escalation) close once they outlive the critical TTL.
+	policy[drift.SeverityCritical] = drift.TimeoutRule{After: time.Millisecond, Action: drift.TimeoutClose}
+	time.Sleep(5 * time.Millisecond)
+	sweep(policy)
+	assertRowCount(t, db, `SELECT count(*) FROM subscription_reconciliation_events WHERE resolution_mechanism = 'timeout_unresolved'`, 2)
+	assertRowCount(t, db, `SELECT count(*) FROM subscription_drift_open`, 0)
+
+	// The disagreement persists, but the projection head has not moved:
+	// no new detection.
+	sweep(nil)
+	assertRowCount(t, db, `SELECT count(*) FROM subscription_reconciliation_events WHERE event_type IN ('drift_minor', 'drift_major')`, 2)
+}
+
+// TestSubstrate_ShadowReplayDiff replays the ledger into the shadow
+// projection table and diffs it against the live projections: a replay
+// of the same version agrees everywhere; once the live chain carries a
+// head the merge function would not have produced, the report names the
+// subscription and field.
+func TestSubstrate_ShadowReplayDiff(t *testing.T) {
+	db := SetupSubstrate(t)
+	ctx := context.Background()
+
+	wsID := InsertWorkspace(t, db)
+	now := time.Now().UTC().Truncate(time.Second)
+	for i, subID := range []string{"sub_shadow_a", "sub_shadow_b"} {
+		eventID := fmt.Sprintf("evt_shadow_%d", i)
+		InsertLedgerEvent(t, db, LedgerEvent{
+			StripeEventID: eventID,
+			Payload:       stripeEventFixture(eventID, "customer.subscription.updated", subID, wsID, "active", now, false),
+			ReceivedAt:    now.Add(time.Duration(i) * time.Second),
+		})
+	}
+	if _, _, err := subscription.ProcessPending(ctx, db); err != nil {
+		t.Fatalf("ProcessPending: %v", err)
+	}
+
+	report, err := subscription.ReplayShadow(ctx, db, subscription.ShadowOptions{})
+	if err != nil {
+		t.Fatalf("ReplayShadow: %v", err)
+	}
+	if report.BaselineVersion != subscription.ReducerVersion || report.Subscriptions != 2 || report.Unchanged != 2 || len(report.Diffs) != 0 {
+		t.Fatalf("same-version report = %+v", report)
+	}
+	assertRowCount(t, db, fmt.Sprintf(`SELECT count(*) FROM subscription_projection_shadow WHERE replay_epoch_id = '%s'`, report.EpochID), 2)
+	assertRowCount(t, db, fmt.Sprintf(`SELECT count(*) FROM replay_epochs WHERE id = '%s' AND reducer_name = 'subscription_shadow' AND completed_at IS NOT NULL`, report.EpochID), 1)
+	// The live projection chain is untouched.
+	assertRowCount(t, db, `SELECT count(*) FROM subscription_projection`, 2)
+
+	// A ledger row the baseline has not reduced yet is past the snapshot
+	// cursor, so the replay does not fold it either.
+	InsertLedgerEvent(t, db, LedgerEvent{
+		StripeEventID: "evt_shadow_pending",
+		Payload:       stripeEventFixture("evt_shadow_pending", "customer.subscription.updated", "sub_shadow_c", wsID, "active", now, false),
+		ReceivedAt:    now.Add(5 * time.Second),
+	})
+	report, err = subscription.ReplayShadow(ctx, db, subscription.ShadowOptions{})
+	if err != nil {
+		t.Fatalf("ReplayShadow: %v", err)
+	}
+	if report.Subscriptions != 2 || len(report.Diffs) != 0 {
+		t.Fatalf("pending-event report = %+v", report)
+	}
+
+	// Extend one live chain with a head no ledger event explains.
+	_, err = db.Exec(`
+		INSERT INTO subscription_projection (
+			stripe_subscription_id, workspace_id, status,
+			current_period_start, current_period_end, cancel_at_period_end,
+			field_authority, projection_version, reducer_version,
+			source_event_id, source_ingestion_version, replay_epoch_id,
+			supersedes_id, event_occurred_at, subscription_event_occurred_at
+		)
+		SELECT stripe_subscription_id, workspace_id, 'canceled',
+			current_period_start, current_period_end, cancel_at_period_end,
+			field_authority, projection_version, reducer_version,
+			'evt_shadow_unexplained', source_ingestion_version, replay_epoch_id,
+			id, event_occurred_at, subscription_event_occurred_at
+		FROM subscription_current_state
+		WHERE stripe_subscription_id = 'sub_shadow_a'
+	`)
+	if err != nil {
+		t.Fatalf("insert unexplained head: %v", err)
+	}
+
+	report, err = subscription.ReplayShadow(ctx, db, subscription.ShadowOptions{BaselineVersion: subscription.ReducerVersion})
+	if err != nil {
+		t.Fatalf("ReplayShadow: %v", err)
+	}
+	if len(report.Diffs) != 1 || report.Unchanged != 1 {
+		t.Fatalf("report = %+v, want one diff", report)
+	}
+	d := report.Diffs[0]
+	if d.StripeSubscriptionID != "sub_shadow_a" || d.Kind != subscription.DiffChanged ||
+		len(d.Fields) != 1 || d.Fields[0].Field != "status" ||
+		d.Fields[0].Baseline != "canceled" || d.Fields[0].Candidate != "active" {
+		t.Errorf("diff = %+v", d)
+	}
+	assertRowCount(t, db, fmt.Sprintf(`
+		SELECT count(*) FROM subscription_replay_diffs
+		WHERE replay_epoch_id = '%s' AND diff_kind = 'changed'
+		  AND fields->'status'->>'baseline' = 'canceled'
+	`, report.EpochID), 1)
+}
+
+// TestSubstrate_CheckoutBeforeSubscriptionEvent verifies that a checkout
+// event landing before any subscription event projects a NULL status, and
+// that the detector treats that head as not yet projected rather than as
+// an impossible status.
+func TestSubstrate_CheckoutBeforeSubscriptionEvent(t *testing.T) {
+	db := SetupSubstrate(t)
+	ctx := context.Background()
+
+	wsID := InsertWorkspace(t, db)
+	now := time.Now().UTC()
+	InsertLedgerEvent(t, db, LedgerEvent{
+		StripeEventID: "evt_checkout_001",
+		Payload: map[string]any{
+			"id":          "evt_checkout_001",
+			"object":      "event",
+			"type":        "checkout.session.completed",
+			"created":     now.Unix(),
+			"api_version": "2025-02-24.acacia",
+			"data": map[string]any{"object": map[string]any{
+				"id":                  "cs_checkout_001",
+				"object":              "checkout.session",
+				"mode":                "subscription",
+				"subscription":        "sub_checkout_001",
+				"client_reference_id": wsID,
+			}},
+		},
+		ReceivedAt: now,
+	})
+	if _, _, err := subscription.ProcessPending(ctx, db); err != nil {
+		t.Fatalf("ProcessPending: %v", err)
+	}
+	assertRowCount(t, db, `
+		SELECT count(*)::int FROM subscription_current_state
+		WHERE stripe_subscription_id = 'sub_checkout_001' AND status IS NULL
+	`, 1)
+
+	InsertBillingSubscription(t, db, BillingSubscription{
+		WorkspaceID:          wsID,
+		StripeSubscriptionID: "sub_checkout_001",
+		StripeCustomerID:     "cus_checkout_001",
+		Status:               "active",
+	})
+	runDetectorSweep(t, db)
+	assertRowCount(t, db, `SELECT count(*)::int FROM subscription_reconciliation_events`, 0)
+}
+
+// TestSubstrate_CurrentStateWaitsForActivation verifies that
+// subscription_current_state keeps serving the activated version while a
+// newer version's chain exists but has not been activated, and switches
+// once it is.
+func TestSubstrate_CurrentStateWaitsForActivation(t *testing.T) {
+	db := SetupSubstrate(t)
+	ctx := context.Background()
+
+	wsID := InsertWorkspace(t, db)
+	t0 := time.Now().UTC().Add(-time.Hour)
+	InsertLedgerEvent(t, db, LedgerEvent{
+		StripeEventID: "evt_activate_001",
+		Payload:       stripeEventFixture("evt_activate_001", "customer.subscription.updated", "sub_activate_001", wsID, "active", t0, false),
+		ReceivedAt:    t0,
+	})
+	if _, _, err := subscription.ProcessPending(ctx, db); err != nil {
+		t.Fatalf("ProcessPending: %v", err)
+	}
+	// Caught up: the reducer's own version is activated.
+	assertRowCount(t, db, fmt.Sprintf(`
+		SELECT count(*) FROM subscription_reducer_activations WHERE reducer_version = '%s'
+	`, subscription.ReducerVersion), 1)
+	assertSubscriptionStatusInView(t, db, "sub_activate_001", "active")
+
+	// A later version that is still replaying has a chain head of its own.
+	_, err := db.Exec(`
+		INSERT INTO subscription_projection (
+			stripe_subscription_id, workspace_id, status,
+			field_authority, projection_version, reducer_version,
+			source_event_id, source_ingestion_version,
+			replay_epoch_id, event_occurred_at
+		) VALUES ($1, $2::uuid, 'canceled', '{"status": "webhook"}'::jsonb, 'v99', 'v99',
+			'evt_activate_001', 'test',
+			(SELECT id FROM replay_epochs LIMIT 1), $3)
+	`, "sub_activate_001", wsID, t0)
+	if err != nil {
+		t.Fatalf("insert v99 projection: %v", err)
+	}
+	assertSubscriptionStatusInView(t, db, "sub_activate_001", "active")
+
+	if _, err := db.Exec(`
+		INSERT INTO subscription_reducer_activations (reducer_version, cursor_received_at)
+		VALUES ('v99', now())
+	`); err != nil {
+		t.Fatalf("activate v99: %v", err)
+	}
+	assertSubscriptionStatusInView(t, db, "sub_activate_001", "canceled")
+}
+
 // ---- helpers ----------------------------------------------------------
 
+// staticProvider is an authority provider serving fixed states.
+type staticProvider struct {
+	states []*authority.State
+}
+
+func (p *staticProvider) Name() string    { return "stripe_api" }
+func (p *staticProvider) Version() string { return "test" }
+func (p *staticProvider) FetchState(_ context.Context, id string) (*authority.State, error) {
+	for _, s := range p.states {
+		if s.StripeSubscriptionID == id {
+			return s, nil
+		}
+	}
+	return nil, nil
+}
+func (p *staticProvider) FetchAll(context.Context) ([]*authority.State, error) {
+	return p.states, nil
+}
+
 func runDetectorSweep(t *testing.T, db *sql.DB) {
 	t.Helper()
 	d := &drift.Detector{DB: db, Provider: authority.NewBillingProvider(db)}
@@ -0,0 +1,139 @@
+package subscription
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ManualCorrection is an operator's override of specific interpreted
+// fields of a subscription. It is the data.object of a ManualEventType
+// ledger event, so the correction and its provenance (operator, reason)
+// live in the ledger alongside the Stripe events it corrects.
+//
+// Fields maps a correctable field name to its JSON value: a string for
+// status and last_payment_status, a bool for cancel_at_period_end, a
+// non-negative integer for dunning_attempt_count, and an RFC 3339
+// timestamp or null for the timestamp fields.
+type ManualCorrection struct {
+	Subscription string                     `json:"subscription"`
+	Fields       map[string]json.RawMessage `json:"fields"`
+	Operator     string                     `json:"operator"`
+	Reason       string                     `json:"reason"`
+	Metadata     struct {
+		WorkspaceID string `json:"workspaceId,omitempty"`
+	} `json:"metadata"`
+}
+
+// manualFieldSetters is the closed set of correctable fields. Each field
+// here is written by every event of its group's authoritative source,
+// which is what lets mergeManual use the group watermark as "the last
+// time the authority reported this field". last_payment_failed_at and
+// the checkout fields are written by only some events of their group and
+// are therefore not correctable.
+var manualFieldSetters = map[string]func(s *State, raw json.RawMessage) error{
+	"status": func(s *State, raw json.RawMessage) error {
+		var v string
+		if err := json.Unmarshal(raw, &v); err != nil || v == "" {
+			return errors.New("must be a non-empty string")
+		}
+		s.Status = v
+		return nil
+	},
+	"current_period_start": manualTimeSetter(func(s *State) **time.Time { return &s.CurrentPeriodStart }),
+	"current_period_end":   manualTimeSetter(func(s *State) **time.Time { return &s.CurrentPeriodEnd }),
+	"cancel_at_period_end": func(s *State, raw json.RawMessage) error {
+		var v bool
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return errors.New("must be a boolean")
+		}
+		s.CancelAtPeriodEnd = v
+		return nil
+	},
+	"canceled_at": manualTimeSetter(func(s *State) **time.Time { return &s.CanceledAt }),
+	"trial_start": manualTimeSetter(func(s *State) **time.Time { return &s.TrialStart }),
+	"trial_end":   manualTimeSetter(func(s *State) **time.Time { return &s.TrialEnd }),
+	"last_payment_status": func(s *State, raw json.RawMessage) error {
+		var v string
+		_ = json.Unmarshal(raw, &v)
+		switch v {
+		case PaymentStatusSucceeded, PaymentStatusFailed, PaymentStatusActionRequired:
+			s.LastPaymentStatus = v
+			return nil
+		}
+		return fmt.Errorf("must be one of %s, %s, %s", PaymentStatusSucceeded, PaymentStatusFailed, PaymentStatusActionRequired)
+	},
+	"dunning_attempt_count": func(s *State, raw json.RawMessage) error {
+		var v int
+		if err := json.Unmarshal(raw, &v); err != nil || v < 0 {
+			return errors.New("must be a non-negative integer")
+		}
+		s.DunningAttemptCount = v
+		return nil
+	},
+}
+
+func manualTimeSetter(field func(s *State) **time.Time) func(s *State, raw json.RawMessage) error {
+	return func(s *State, raw json.RawMessage) error {
+		var v *time.Time
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return errors.New("must be an RFC 3339 timestamp or null")
+		}
+		if v != nil {
+			utc := v.UTC()
+			v = &utc
+		}
+		*field(s) = v
+		return nil
+	}
+}
+
+// CorrectableFields returns the names of the fields an operator may
+// correct, sorted.
+func CorrectableFields() []string {
+	fields := make([]string, 0, len(manualFieldSetters))
+	for field := range manualFieldSetters {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// Validate checks that the correction names a subscription, carries
+// provenance, and sets at least one correctable field to a well-typed
+// value.
+func (c *ManualCorrection) Validate() error {
+	switch {
+	case c.Subscription == "":
+		return errors.New("subscription is required")
+	case c.Operator == "":
+		return errors.New("operator is required")
+	case c.Reason == "":
+		return errors.New("reason is required")
+	case len(c.Fields) == 0:
+		return errors.New("at least one field is required")
+	}
+	var scratch State
+	for _, field := range c.fieldNames() {
+		set, ok := manualFieldSetters[field]
+		if !ok {
+			return fmt.Errorf("field %q is not correctable", field)
+		}
+		if err := set(&scratch, c.Fields[field]); err != nil {
+			return fmt.Errorf("field %q: %w", field, err)
+		}
+	}
+	return nil
+}
+
+// fieldNames returns the corrected field names in a stable order.
+func (c *ManualCorrection) fieldNames() []string {
+	fields := make([]string, 0, len(c.Fields))
+	for field := range c.Fields {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
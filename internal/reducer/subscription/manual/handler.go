@@ -0,0 +1,119 @@
+package manual
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"payment-node/internal/apikeys"
+	"payment-node/internal/reducer/subscription"
+)
+
+// maxRequestBytes bounds a correction request body.
+const maxRequestBytes = 64 << 10
+
+// Handler serves the corrections endpoint. Callers are responsible for
+// authentication and must put the caller's apikeys.Identity in the request
+// context; its key id is recorded as the correction's operator. A key bound
+// to a workspace corrects and lists only that workspace's subscriptions.
+//
+//	POST /api/v1/subscriptions/corrections
+//	  {"subscription": "sub_...", "fields": {"status": "past_due"},
+//	   "reason": "INC-123: missed webhook"}
+//	  → 202 with a Receipt
+//	GET  /api/v1/subscriptions/corrections?subscription=sub_...[&limit=N]
+//	  → 200 {"corrections": [...]}, newest first
+func Handler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			handleSubmit(w, r, db)
+		case http.MethodGet:
+			handleList(w, r, db)
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// submitRequest is the POST body. The operator and workspace are not part
+// of it: the operator is the authenticated key and the workspace comes from
+// the projection.
+type submitRequest struct {
+	Subscription string                     `json:"subscription"`
+	Fields       map[string]json.RawMessage `json:"fields"`
+	Reason       string                     `json:"reason"`
+}
+
+func handleSubmit(w http.ResponseWriter, r *http.Request, db *sql.DB) {
+	id := apikeys.FromContext(r.Context())
+	if id == nil || id.KeyID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req submitRequest
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		http.Error(w, "invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	c := subscription.ManualCorrection{
+		Subscription: req.Subscription,
+		Fields:       req.Fields,
+		Operator:     id.KeyID,
+		Reason:       req.Reason,
+	}
+	if err := Validate(&c); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	receipt, err := Submit(r.Context(), db, c, id.WorkspaceID)
+	switch {
+	case errors.Is(err, ErrUnknownSubscription):
+		http.Error(w, "no projection exists for subscription "+c.Subscription, http.StatusNotFound)
+		return
+	case errors.Is(err, ErrInvalid):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		http.Error(w, "failed to record correction", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(receipt)
+}
+
+func handleList(w http.ResponseWriter, r *http.Request, db *sql.DB) {
+	id := apikeys.FromContext(r.Context())
+	if id == nil || id.KeyID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	subID := r.URL.Query().Get("subscription")
+	if subID == "" {
+		http.Error(w, "subscription is required", http.StatusBadRequest)
+		return
+	}
+	limit := 50
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	records, err := List(r.Context(), db, subID, id.WorkspaceID, limit)
+	if err != nil {
+		http.Error(w, "failed to list corrections", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"corrections": records})
+}
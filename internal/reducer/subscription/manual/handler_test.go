@@ -0,0 +1,64 @@
+package manual
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"payment-node/internal/apikeys"
+)
+
+// The handler validates before touching the database, so every rejection
+// path runs with a nil *sql.DB. Acceptance is covered by the integration
+// scenario TestSubstrate_ManualCorrectionResolvesDrift.
+func TestHandlerRejectsInvalidCorrections(t *testing.T) {
+	h := Handler(nil)
+	cases := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		want   int
+		substr string
+	}{
+		{"method", http.MethodDelete, "/", "", http.StatusMethodNotAllowed, ""},
+		{"bad json", http.MethodPost, "/", `{`, http.StatusBadRequest, "invalid JSON"},
+		{"unknown key", http.MethodPost, "/", `{"subscription":"sub_1","value":"x"}`, http.StatusBadRequest, "invalid JSON"},
+		{"operator in body", http.MethodPost, "/", `{"subscription":"sub_1","fields":{"status":"active"},"operator":"o","reason":"r"}`, http.StatusBadRequest, "unknown field"},
+		{"workspace in body", http.MethodPost, "/", `{"subscription":"sub_1","fields":{"status":"active"},"metadata":{"workspaceId":"ws"},"reason":"r"}`, http.StatusBadRequest, "unknown field"},
+		{"no reason", http.MethodPost, "/", `{"subscription":"sub_1","fields":{"status":"active"}}`, http.StatusBadRequest, "reason is required"},
+		{"uncorrectable", http.MethodPost, "/", `{"subscription":"sub_1","fields":{"checkout_session_id":"cs_1"},"reason":"r"}`, http.StatusBadRequest, "not correctable"},
+		{"bad status", http.MethodPost, "/", `{"subscription":"sub_1","fields":{"status":"paused_forever"},"reason":"r"}`, http.StatusBadRequest, "not a Stripe subscription status"},
+		{"list without subscription", http.MethodGet, "/", "", http.StatusBadRequest, "subscription is required"},
+		{"list bad limit", http.MethodGet, "/?subscription=sub_1&limit=0", "", http.StatusBadRequest, "limit"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h(rec, withOperator(httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body))))
+			if rec.Code != tc.want {
+				t.Fatalf("status = %d, want %d (%s)", rec.Code, tc.want, strings.TrimSpace(rec.Body.String()))
+			}
+			if !strings.Contains(rec.Body.String(), tc.substr) {
+				t.Fatalf("body %q does not mention %q", rec.Body.String(), tc.substr)
+			}
+		})
+	}
+}
+
+func withOperator(r *http.Request) *http.Request {
+	id := &apikeys.Identity{KeyID: "ops-key", Scopes: []string{apikeys.ScopeAdmin}}
+	return r.WithContext(apikeys.WithIdentity(r.Context(), id))
+}
+
+// A correction is attributed to the authenticated key, so a request
+// without one is refused before the body is read.
+func TestHandlerRequiresIdentity(t *testing.T) {
+	rec := httptest.NewRecorder()
+	body := `{"subscription":"sub_1","fields":{"status":"active"},"reason":"r"}`
+	Handler(nil)(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want 401", rec.Code)
+	}
+}
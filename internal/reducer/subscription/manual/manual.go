@@ -0,0 +1,207 @@
+// Package manual records operator corrections to subscription projections.
+//
+// A correction is never written to subscription_projection directly. Submit
+// appends it to stripe_event_ledger as a synthetic
+// subscription.manual_correction event (verify_outcome = 'manual') and
+// records its provenance in subscription_manual_corrections; the reducer
+// then folds it through Merge as an EventSourceManual event, and the drift
+// detector attributes any drift it resolves to manual_operator. See
+// SUBSCRIPTION_REDUCER_CONTRACT.md.
+package manual
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"payment-node/internal/reducer/subscription"
+	"payment-node/internal/reducer/subscription/drift"
+)
+
+// IngestionVersion is the ingestion_version written on correction ledger
+// rows.
+const IngestionVersion = "manual-v1"
+
+var (
+	// ErrInvalid wraps every validation failure; handlers map it to 400.
+	ErrInvalid = errors.New("invalid correction")
+
+	// ErrUnknownSubscription means there is no projection to correct.
+	ErrUnknownSubscription = errors.New("unknown subscription")
+)
+
+// Receipt is returned for an accepted correction. The projection changes
+// once the reducer processes SourceEventID.
+type Receipt struct {
+	ID                   string    `json:"id"`
+	SourceEventID        string    `json:"source_event_id"`
+	StripeSubscriptionID string    `json:"stripe_subscription_id"`
+	SubmittedAt          time.Time `json:"submitted_at"`
+}
+
+// Record is one row of a subscription's correction history.
+type Record struct {
+	Receipt
+	WorkspaceID string                     `json:"workspace_id"`
+	Fields      map[string]json.RawMessage `json:"fields"`
+	Operator    string                     `json:"operator"`
+	Reason      string                     `json:"reason"`
+
+	// ProjectedAt is when the current reducer version folded the
+	// correction into a projection row; nil while pending, or when the
+	// correction was rejected as late for every field.
+	ProjectedAt *time.Time `json:"projected_at"`
+}
+
+// Validate checks c the way Submit does, without touching the database.
+func Validate(c *subscription.ManualCorrection) error {
+	if err := c.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	if raw, ok := c.Fields["status"]; ok {
+		var status string
+		_ = json.Unmarshal(raw, &status)
+		if !drift.ValidStripeStatuses[status] {
+			return fmt.Errorf("%w: field %q: %q is not a Stripe subscription status", ErrInvalid, "status", status)
+		}
+	}
+	return nil
+}
+
+// Submit validates c and appends it to the ledger. The correction is
+// attributed to the subscription's current workspace; subscriptions with
+// no projection cannot be corrected. A non-empty callerWorkspace confines
+// the caller to subscriptions currently in that workspace; others are
+// reported as ErrUnknownSubscription.
+func Submit(ctx context.Context, db *sql.DB, c subscription.ManualCorrection, callerWorkspace string) (*Receipt, error) {
+	if err := Validate(&c); err != nil {
+		return nil, err
+	}
+
+	var workspaceID string
+	err := db.QueryRowContext(ctx, `
+		SELECT workspace_id::text FROM subscription_current_state
+		WHERE stripe_subscription_id = $1
+	`, c.Subscription).Scan(&workspaceID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUnknownSubscription
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load projection: %w", err)
+	}
+	if callerWorkspace != "" && workspaceID != callerWorkspace {
+		return nil, ErrUnknownSubscription
+	}
+	c.Metadata.WorkspaceID = workspaceID
+
+	eventID, err := newEventID(c.Subscription)
+	if err != nil {
+		return nil, err
+	}
+	submittedAt := time.Now().UTC().Truncate(time.Second)
+	eventJSON, err := json.Marshal(map[string]any{
+		"id":      eventID,
+		"object":  "event",
+		"type":    subscription.ManualEventType,
+		"created": submittedAt.Unix(),
+		"data":    map[string]any{"object": c},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("marshal correction event: %w", err)
+	}
+	fieldsJSON, err := json.Marshal(c.Fields)
+	if err != nil {
+		return nil, fmt.Errorf("marshal fields: %w", err)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	var ledgerID string
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO stripe_event_ledger (
+			stripe_event_id, payload, payload_size_bytes, signature_header,
+			verify_outcome, payload_schema_version, ingestion_version
+		) VALUES ($1, $2, $3, NULL, $4, NULL, $5)
+		RETURNING id::text
+	`, eventID, string(eventJSON), len(eventJSON), subscription.ManualVerifyOutcome, IngestionVersion).Scan(&ledgerID)
+	if err != nil {
+		return nil, fmt.Errorf("insert ledger event: %w", err)
+	}
+
+	receipt := &Receipt{
+		SourceEventID:        eventID,
+		StripeSubscriptionID: c.Subscription,
+		SubmittedAt:          submittedAt,
+	}
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO subscription_manual_corrections (
+			stripe_subscription_id, workspace_id, ledger_event_id, source_event_id,
+			fields, operator, reason, submitted_at
+		) VALUES ($1, $2::uuid, $3::uuid, $4, $5, $6, $7, $8)
+		RETURNING id::text
+	`, c.Subscription, workspaceID, ledgerID, eventID, fieldsJSON, c.Operator, c.Reason, submittedAt).Scan(&receipt.ID)
+	if err != nil {
+		return nil, fmt.Errorf("insert correction: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit: %w", err)
+	}
+	return receipt, nil
+}
+
+// List returns a subscription's corrections, newest first. A non-empty
+// callerWorkspace returns only the corrections recorded in that workspace.
+func List(ctx context.Context, db *sql.DB, subID, callerWorkspace string, limit int) ([]Record, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT c.id::text, c.source_event_id, c.stripe_subscription_id, c.submitted_at,
+			c.workspace_id::text, c.fields, c.operator, c.reason,
+			(SELECT min(p.projected_at) FROM subscription_projection p
+			 WHERE p.stripe_subscription_id = c.stripe_subscription_id
+			   AND p.source_event_id = c.source_event_id
+			   AND p.reducer_version = $2)
+		FROM subscription_manual_corrections c
+		WHERE c.stripe_subscription_id = $1
+		  AND ($4 = '' OR c.workspace_id::text = $4)
+		ORDER BY c.submitted_at DESC, c.id DESC
+		LIMIT $3
+	`, subID, subscription.ReducerVersion, limit, callerWorkspace)
+	if err != nil {
+		return nil, fmt.Errorf("query corrections: %w", err)
+	}
+	defer rows.Close()
+
+	records := []Record{}
+	for rows.Next() {
+		var r Record
+		var fieldsJSON []byte
+		if err := rows.Scan(&r.ID, &r.SourceEventID, &r.StripeSubscriptionID, &r.SubmittedAt,
+			&r.WorkspaceID, &fieldsJSON, &r.Operator, &r.Reason, &r.ProjectedAt); err != nil {
+			return nil, fmt.Errorf("scan correction: %w", err)
+		}
+		if err := json.Unmarshal(fieldsJSON, &r.Fields); err != nil {
+			return nil, fmt.Errorf("decode fields: %w", err)
+		}
+		records = append(records, r)
+	}
+	return records, rows.Err()
+}
+
+// newEventID returns a manual_-prefixed event id. Unlike polled ids it is
+// random: two identical corrections are still two operator actions.
+func newEventID(subID string) (string, error) {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("generate event id: %w", err)
+	}
+	return subscription.ManualEventIDPrefix + subID + "_" + hex.EncodeToString(b[:]), nil
+}
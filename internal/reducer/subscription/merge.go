@@ -7,12 +7,12 @@ import (
 
 // ReducerVersion is the version tag written onto every projection row this
 // reducer produces. Bump on any merge-function behavior change.
-const ReducerVersion = "v1"
+const ReducerVersion = "v2"
 
 // ProjectionVersion is the schema version of the subscription_projection
 // table shape this reducer understands. Bump if the projection schema
 // changes in a way that affects how older rows should be interpreted.
-const ProjectionVersion = "v1"
+const ProjectionVersion = "v2"
 
 // MergeResult is the outcome of a single merge invocation. It contains
 // either a new State to be projected (Next is non-nil and Skipped is false),
@@ -59,6 +59,13 @@ const (
 // This function is pure: it does not perform I/O, does not consult external
 // state, does not read clocks beyond the event's own timestamps. That
 // purity is what makes the RAC permutation and determinism tests possible.
+//
+// Events are dispatched to one of three event groups — subscription,
+// invoice payment, checkout — by the object they carry. Each group owns a
+// disjoint set of fields and is ordered against its own watermark; fields
+// outside the event's group are carried forward unchanged. Operator
+// corrections may touch fields of any group and are arbitrated per field
+// (mergeManual).
 func Merge(current *State, event *Event, source EventSource) MergeResult {
 	// Step 1: validate ordering metadata is present and well-formed.
 	if event.OccurredAt.IsZero() {
@@ -74,106 +81,93 @@ func Merge(current *State, event *Event, source EventSource) MergeResult {
 		}
 	}
 
-	// Step 2: only process events that carry a subscription object.
-	if event.Subscription == nil {
+	// Step 2: only process events that carry an object about a
+	// subscription.
+	if event.SubscriptionID() == "" {
 		return MergeResult{Skipped: true}
 	}
 
-	// Step 3: ordering checks against current state (if any).
+	switch {
+	case event.Subscription != nil:
+		return mergeSubscription(current, event, source)
+	case event.Invoice != nil:
+		return mergeInvoice(current, event, source)
+	case event.Correction != nil:
+		return mergeManual(current, event)
+	default:
+		return mergeCheckout(current, event, source)
+	}
+}
+
+// mergeSubscription applies a customer.subscription.* event, delivered by
+// webhook or polled. The newest observation of the subscription wins
+// regardless of source and writes every subscription field.
+func mergeSubscription(current *State, event *Event, source EventSource) MergeResult {
+	// Step 3: ordering checks against the subscription watermark (if any).
 	var conflicts []Conflict
 	if current != nil {
-		if event.OccurredAt.Before(current.EventOccurredAt) {
-			// Late event. Do not supersede; record the observation.
-			return MergeResult{
-				Skipped: true,
-				Conflicts: []Conflict{{
-					Type: ConflictLateEventDetected,
-					Details: map[string]any{
-						"source_event_id":              event.SourceEventID,
-						"event_occurred_at":            event.OccurredAt,
-						"current_event_occurred_at":    current.EventOccurredAt,
-						"current_status":               current.Status,
-						"reason":                       "event_occurred_at older than current projection",
-					},
-				}},
+		if watermark := subscriptionWatermark(current); watermark != nil {
+			if event.OccurredAt.Before(*watermark) {
+				// Late event. Do not supersede; record the observation.
+				return MergeResult{
+					Skipped: true,
+					Conflicts: []Conflict{{
+						Type: ConflictLateEventDetected,
+						Details: map[string]any{
+							"source_event_id":           event.SourceEventID,
+							"event_occurred_at":         event.OccurredAt,
+							"current_event_occurred_at": *watermark,
+							"current_status":            current.Status,
+							"reason":                    "event_occurred_at older than current projection",
+						},
+					}},
+				}
+			}
+			if event.OccurredAt.Equal(*watermark) && event.SourceEventID != "" {
+				// Same-second events. Record the chronology conflict for
+				// review. A poll tied with a webhook adds nothing: polling
+				// only fills gaps, so the webhook wins in either order.
+				conflicts = append(conflicts, chronologyConflict(event))
+				if source == EventSourcePolling && current.SubscriptionEventOccurredAt != nil &&
+					current.SubscriptionEventOccurredAt.Equal(*watermark) {
+					return MergeResult{Skipped: true, Conflicts: conflicts}
+				}
 			}
-		}
-		if event.OccurredAt.Equal(current.EventOccurredAt) && event.SourceEventID != "" {
-			// Same-second events. Tie-break by source_event_id ordering
-			// (lexicographic) so the merge function is deterministic, but
-			// record the chronology conflict for review.
-			conflicts = append(conflicts, Conflict{
-				Type: ConflictChronologyConflict,
-				Details: map[string]any{
-					"source_event_id":         event.SourceEventID,
-					"event_occurred_at":       event.OccurredAt,
-					"reason":                  "tied timestamp with current projection",
-				},
-			})
 		}
 	}
 
-	// Step 4: build the new state by applying per-field authority rules.
+	// Step 4: build the new state. Every field of the group takes the
+	// event's value unless an operator correction newer than the event
+	// holds it (applyField).
 	authority := source.Authority()
-	next := &State{
-		StripeSubscriptionID: event.Subscription.ID,
-		WorkspaceID:          event.WorkspaceID,
-		EventOccurredAt:      event.OccurredAt,
-		FieldAuthority:       make(map[string]AuthoritySource, len(AuthorityPolicy)),
-	}
-
-	// Apply each field via its authority policy. Currently every field's
-	// authority is webhook (the v1 policy), so every event field is
-	// applied. When polling lands, fields with polling authority will
-	// require a different code path to consult the polling source.
-	applyField(next, "status", authority, func() {
+	next := carryForward(current, event)
+	occurredAt := event.OccurredAt
+	if source == EventSourcePolling {
+		next.PolledAt = &occurredAt
+	} else {
+		next.SubscriptionEventOccurredAt = &occurredAt
+	}
+
+	applyField(next, "status", authority, event.OccurredAt, func() {
 		next.Status = event.Subscription.Status
-	}, func() {
-		if current != nil {
-			next.Status = current.Status
-		}
 	})
-	applyField(next, "current_period_start", authority, func() {
+	applyField(next, "current_period_start", authority, event.OccurredAt, func() {
 		next.CurrentPeriodStart = epochToTime(event.Subscription.CurrentPeriodStart)
-	}, func() {
-		if current != nil {
-			next.CurrentPeriodStart = current.CurrentPeriodStart
-		}
 	})
-	applyField(next, "current_period_end", authority, func() {
+	applyField(next, "current_period_end", authority, event.OccurredAt, func() {
 		next.CurrentPeriodEnd = epochToTime(event.Subscription.CurrentPeriodEnd)
-	}, func() {
-		if current != nil {
-			next.CurrentPeriodEnd = current.CurrentPeriodEnd
-		}
 	})
-	applyField(next, "cancel_at_period_end", authority, func() {
+	applyField(next, "cancel_at_period_end", authority, event.OccurredAt, func() {
 		next.CancelAtPeriodEnd = event.Subscription.CancelAtPeriodEnd
-	}, func() {
-		if current != nil {
-			next.CancelAtPeriodEnd = current.CancelAtPeriodEnd
-		}
 	})
-	applyField(next, "canceled_at", authority, func() {
+	applyField(next, "canceled_at", authority, event.OccurredAt, func() {
 		next.CanceledAt = epochPtrToTime(event.Subscription.CanceledAt)
-	}, func() {
-		if current != nil {
-			next.CanceledAt = current.CanceledAt
-		}
 	})
-	applyField(next, "trial_start", authority, func() {
+	applyField(next, "trial_start", authority, event.OccurredAt, func() {
 		next.TrialStart = epochPtrToTime(event.Subscription.TrialStart)
-	}, func() {
-		if current != nil {
-			next.TrialStart = current.TrialStart
-		}
 	})
-	applyField(next, "trial_end", authority, func() {
+	applyField(next, "trial_end", authority, event.OccurredAt, func() {
 		next.TrialEnd = epochPtrToTime(event.Subscription.TrialEnd)
-	}, func() {
-		if current != nil {
-			next.TrialEnd = current.TrialEnd
-		}
 	})
 
 	// Step 5: detect merge invariant violations. The reducer's policy is
@@ -201,30 +195,284 @@ func Merge(current *State, event *Event, source EventSource) MergeResult {
 	}
 }
 
-// applyField encapsulates the per-field arbitration. If the event's source
-// matches the authority policy for this field, the apply function runs;
-// otherwise the fallback (carry-forward from current state) runs. Either
-// way the authority tag is recorded.
+// Payment statuses written to last_payment_status.
+const (
+	PaymentStatusSucceeded      = "succeeded"
+	PaymentStatusFailed         = "failed"
+	PaymentStatusActionRequired = "action_required"
+)
+
+// paymentStatusFor maps an invoice event type to the payment status it
+// reports. Returns "" for invoice events that say nothing about payment.
+func paymentStatusFor(eventType string) string {
+	switch eventType {
+	case "invoice.payment_succeeded", "invoice.paid":
+		return PaymentStatusSucceeded
+	case "invoice.payment_failed":
+		return PaymentStatusFailed
+	case "invoice.payment_action_required":
+		return PaymentStatusActionRequired
+	}
+	return ""
+}
+
+// mergeInvoice applies an invoice payment event.
+//
+// last_payment_status and dunning_attempt_count come from the newest
+// invoice event. last_payment_failed_at is the newest failure ever seen,
+// so a late payment_failed event still advances it when it is newer than
+// the recorded failure — otherwise the final value would depend on
+// arrival order.
+func mergeInvoice(current *State, event *Event, source EventSource) MergeResult {
+	status := paymentStatusFor(event.EventType)
+	if status == "" {
+		return MergeResult{Skipped: true}
+	}
+	authority := source.Authority()
+
+	var conflicts []Conflict
+	if current != nil && current.PaymentEventOccurredAt != nil {
+		watermark := *current.PaymentEventOccurredAt
+		if event.OccurredAt.Before(watermark) {
+			late := Conflict{
+				Type: ConflictLateEventDetected,
+				Details: map[string]any{
+					"source_event_id":           event.SourceEventID,
+					"event_occurred_at":         event.OccurredAt,
+					"current_event_occurred_at": watermark,
+					"current_payment_status":    current.LastPaymentStatus,
+					"reason":                    "event_occurred_at older than current payment outcome",
+				},
+			}
+			if status != PaymentStatusFailed || !after(event.OccurredAt, current.LastPaymentFailedAt) {
+				return MergeResult{Skipped: true, Conflicts: []Conflict{late}}
+			}
+			next := carryForward(current, event)
+			applyField(next, "last_payment_failed_at", authority, event.OccurredAt, func() {
+				next.LastPaymentFailedAt = timePtr(event.OccurredAt)
+			})
+			return MergeResult{Next: next, Conflicts: []Conflict{late}}
+		}
+		if event.OccurredAt.Equal(watermark) && event.SourceEventID != "" {
+			conflicts = append(conflicts, chronologyConflict(event))
+		}
+	}
+
+	next := carryForward(current, event)
+	next.PaymentEventOccurredAt = timePtr(event.OccurredAt)
+
+	applyField(next, "last_payment_status", authority, event.OccurredAt, func() {
+		next.LastPaymentStatus = status
+	})
+	applyField(next, "dunning_attempt_count", authority, event.OccurredAt, func() {
+		if status == PaymentStatusSucceeded {
+			next.DunningAttemptCount = 0
+		} else {
+			next.DunningAttemptCount = event.Invoice.AttemptCount
+		}
+	})
+	if status == PaymentStatusFailed {
+		applyField(next, "last_payment_failed_at", authority, event.OccurredAt, func() {
+			next.LastPaymentFailedAt = timePtr(event.OccurredAt)
+		})
+	}
+
+	return MergeResult{Next: next, Conflicts: conflicts}
+}
+
+// mergeCheckout applies a checkout.session.completed event, linking the
+// session to the subscription. The newest completed session wins.
+func mergeCheckout(current *State, event *Event, source EventSource) MergeResult {
+	var conflicts []Conflict
+	if current != nil && current.CheckoutCompletedAt != nil {
+		watermark := *current.CheckoutCompletedAt
+		if event.OccurredAt.Before(watermark) {
+			return MergeResult{
+				Skipped: true,
+				Conflicts: []Conflict{{
+					Type: ConflictLateEventDetected,
+					Details: map[string]any{
+						"source_event_id":             event.SourceEventID,
+						"event_occurred_at":           event.OccurredAt,
+						"current_event_occurred_at":   watermark,
+						"current_checkout_session_id": current.CheckoutSessionID,
+						"reason":                      "event_occurred_at older than current checkout link",
+					},
+				}},
+			}
+		}
+		if event.OccurredAt.Equal(watermark) && event.SourceEventID != "" {
+			conflicts = append(conflicts, chronologyConflict(event))
+		}
+	}
+
+	authority := source.Authority()
+	next := carryForward(current, event)
+	applyField(next, "checkout_session_id", authority, event.OccurredAt, func() {
+		next.CheckoutSessionID = event.CheckoutSession.ID
+	})
+	applyField(next, "checkout_completed_at", authority, event.OccurredAt, func() {
+		next.CheckoutCompletedAt = timePtr(event.OccurredAt)
+	})
+
+	return MergeResult{Next: next, Conflicts: conflicts}
+}
+
+// mergeManual applies an operator correction. Each corrected field is
+// arbitrated on its own: the correction applies only if it is at least as
+// new as the field's previous correction and as the newest report of the
+// field from any source. An older correction would otherwise undo newer
+// data depending on arrival order. Fields that do not apply are recorded
+// in a late_event_detected conflict; if none apply, the correction is
+// skipped.
 //
-// In v1, authority is always webhook and the reducer only ingests webhook
-// events, so apply always runs. The fallback exists to make the future
-// polling-authority case obvious: when status authority changes to polling
-// and a webhook event arrives, this function will run the fallback
-// (preserve current) and tag the field's authority as the LAST one that
-// actually wrote it — which becomes the audit trail.
-func applyField(next *State, field string, eventSource AuthoritySource, apply, fallback func()) {
-	policyAuthority := AuthorityFor(field)
-	if policyAuthority == eventSource {
-		apply()
-		next.FieldAuthority[field] = eventSource
-		return
-	}
-	fallback()
-	// On carry-forward, we don't have direct visibility into what
-	// authority originally produced the carried value. Tag with the
-	// policy authority — the projection chain provides the lineage for
-	// anyone who needs the precise history.
-	next.FieldAuthority[field] = policyAuthority
+// A corrected field is tagged manual and holds until a source reports
+// something newer than the correction (see applyField).
+func mergeManual(current *State, event *Event) MergeResult {
+	next := carryForward(current, event)
+	var late, invalid []string
+	for _, field := range event.Correction.fieldNames() {
+		set, ok := manualFieldSetters[field]
+		if !ok {
+			invalid = append(invalid, field)
+			continue
+		}
+		if !manualApplies(current, field, event.OccurredAt) {
+			late = append(late, field)
+			continue
+		}
+		if err := set(next, event.Correction.Fields[field]); err != nil {
+			invalid = append(invalid, field)
+			continue
+		}
+		next.FieldAuthority[field] = AuthorityManual
+		next.ManualOverrides[field] = event.OccurredAt
+	}
+
+	var conflicts []Conflict
+	if len(late) > 0 {
+		conflicts = append(conflicts, Conflict{
+			Type: ConflictLateEventDetected,
+			Details: map[string]any{
+				"source_event_id":   event.SourceEventID,
+				"event_occurred_at": event.OccurredAt,
+				"fields":            late,
+				"reason":            "manual correction older than the fields' current values",
+			},
+		})
+	}
+	if len(invalid) > 0 {
+		conflicts = append(conflicts, Conflict{
+			Type: ConflictMergeInvariantViolation,
+			Details: map[string]any{
+				"source_event_id": event.SourceEventID,
+				"fields":          invalid,
+				"reason":          "manual correction names an uncorrectable field or an ill-typed value",
+			},
+		})
+	}
+	if len(late)+len(invalid) == len(event.Correction.Fields) {
+		return MergeResult{Skipped: true, Conflicts: conflicts}
+	}
+	return MergeResult{Next: next, Conflicts: conflicts}
+}
+
+// manualApplies reports whether a correction submitted at correctedAt
+// may write field over current.
+func manualApplies(current *State, field string, correctedAt time.Time) bool {
+	if current == nil {
+		return true
+	}
+	if prior, ok := current.ManualOverrides[field]; ok && correctedAt.Before(prior) {
+		return false
+	}
+	watermark := fieldWatermark(current, field)
+	return watermark == nil || !correctedAt.Before(*watermark)
+}
+
+// fieldWatermark returns the time of the newest report of a correctable
+// field. Every event of the field's group writes the field (or is held
+// back by a newer correction), so the group watermark doubles as the
+// field's last report.
+func fieldWatermark(s *State, field string) *time.Time {
+	switch field {
+	case "last_payment_status", "dunning_attempt_count":
+		return s.PaymentEventOccurredAt
+	}
+	return subscriptionWatermark(s)
+}
+
+// subscriptionWatermark returns the time of the newest subscription
+// observation, webhook or polled; nil before the first one.
+func subscriptionWatermark(s *State) *time.Time {
+	if s.PolledAt != nil && (s.SubscriptionEventOccurredAt == nil || s.PolledAt.After(*s.SubscriptionEventOccurredAt)) {
+		return s.PolledAt
+	}
+	return s.SubscriptionEventOccurredAt
+}
+
+// carryForward starts the next State from a copy of current so fields
+// outside the event's group are preserved. WorkspaceID and
+// EventOccurredAt follow the newest event across all groups, which keeps
+// both independent of arrival order.
+func carryForward(current *State, event *Event) *State {
+	next := &State{}
+	if current != nil {
+		*next = *current
+	}
+	next.StripeSubscriptionID = event.SubscriptionID()
+	next.FieldAuthority = make(map[string]AuthoritySource)
+	next.ManualOverrides = make(map[string]time.Time)
+	if current != nil {
+		for field, src := range current.FieldAuthority {
+			next.FieldAuthority[field] = src
+		}
+		for field, at := range current.ManualOverrides {
+			next.ManualOverrides[field] = at
+		}
+	}
+	if current == nil || !event.OccurredAt.Before(current.EventOccurredAt) {
+		next.WorkspaceID = event.WorkspaceID
+		next.EventOccurredAt = event.OccurredAt
+	}
+	return next
+}
+
+func chronologyConflict(event *Event) Conflict {
+	return Conflict{
+		Type: ConflictChronologyConflict,
+		Details: map[string]any{
+			"source_event_id":         event.SourceEventID,
+			"event_occurred_at":       event.OccurredAt,
+			"reason":                  "tied timestamp with current projection",
+		},
+	}
+}
+
+func timePtr(t time.Time) *time.Time { return &t }
+
+// after reports whether t is strictly after ref, treating a nil ref as
+// the beginning of time.
+func after(t time.Time, ref *time.Time) bool {
+	return ref == nil || t.After(*ref)
+}
+
+// applyField writes one field of an event that won its group's ordering
+// check. A field held by an operator correction is written only by an
+// event newer than the correction, and that write releases the
+// correction; otherwise the field keeps its carried-forward value.
+//
+// The tag records the source that last wrote the value, which is the
+// audit trail.
+func applyField(next *State, field string, eventSource AuthoritySource, occurredAt time.Time, apply func()) {
+	if correctedAt, ok := next.ManualOverrides[field]; ok {
+		if !occurredAt.After(correctedAt) {
+			return
+		}
+		delete(next.ManualOverrides, field)
+	}
+	apply()
+	next.FieldAuthority[field] = eventSource
 }
 
 // isTerminalStatus identifies subscription statuses Stripe treats as end
@@ -0,0 +1,214 @@
+// Package polling implements the subscription polling reconciliation
+// worker. It periodically lists subscriptions from a Stripe-compatible
+// API and appends each changed subscription to stripe_event_ledger as a
+// synthetic customer.subscription.polled event (verify_outcome
+// 'polling'). The reducer folds those events with polling authority, so
+// a polled status supersedes stale webhook state.
+//
+// The worker never writes projections itself — the ledger stays the only
+// reducer input, which keeps replay epochs reproducible.
+//
+// See SUBSCRIPTION_REDUCER_CONTRACT.md for the authority policy.
+package polling
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// DefaultBaseURL is Stripe's API origin. Tests and staging point BaseURL
+// at a local fake instead.
+const DefaultBaseURL = "https://api.stripe.com"
+
+// DefaultPageSize is the list page size. 100 is Stripe's maximum.
+const DefaultPageSize = 100
+
+// Client reads subscriptions from a Stripe-compatible API. Only the
+// subset of the API the worker and the drift detector's Stripe provider
+// need is implemented; responses are kept as raw JSON so the ledger
+// stores exactly what the API returned.
+type Client struct {
+	BaseURL  string
+	APIKey   string
+	PageSize int
+	HTTP     *http.Client
+
+	// Limiter, when set, paces every request. Stripe's live-mode default
+	// is 100 read requests per second per account, shared with every
+	// other integration on the key.
+	Limiter *rate.Limiter
+
+	// MaxRetries bounds retries of rate-limited (429) requests.
+	// RetryBackoff is the first retry delay, doubled on each attempt;
+	// a Retry-After header on the response takes precedence.
+	MaxRetries   int
+	RetryBackoff time.Duration
+}
+
+// NewClient returns a Client for the given API key with a bounded
+// request timeout and retries on rate limiting. No Limiter is set.
+func NewClient(baseURL, apiKey string) *Client {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	return &Client{
+		BaseURL:      baseURL,
+		APIKey:       apiKey,
+		PageSize:     DefaultPageSize,
+		HTTP:         &http.Client{Timeout: 30 * time.Second},
+		MaxRetries:   3,
+		RetryBackoff: time.Second,
+	}
+}
+
+// StatusError is returned for a non-200 response that was not retried
+// (or ran out of retries).
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status %d", e.StatusCode)
+}
+
+// Subscription is one listed subscription: its id, the raw object, and
+// the API version (Stripe-Version response header) that shaped it.
+type Subscription struct {
+	ID         string
+	Raw        json.RawMessage
+	APIVersion string
+}
+
+// listPage mirrors Stripe's list envelope.
+type listPage struct {
+	Data    []json.RawMessage `json:"data"`
+	HasMore bool              `json:"has_more"`
+}
+
+// ListSubscriptions pages through GET /v1/subscriptions?status=all and
+// calls fn for every subscription in API order. An error from fn stops
+// iteration and is returned as-is.
+func (c *Client) ListSubscriptions(ctx context.Context, fn func(Subscription) error) error {
+	pageSize := c.PageSize
+	if pageSize <= 0 || pageSize > DefaultPageSize {
+		pageSize = DefaultPageSize
+	}
+	startingAfter := ""
+	for {
+		page, apiVersion, err := c.fetchPage(ctx, pageSize, startingAfter)
+		if err != nil {
+			return err
+		}
+		for _, raw := range page.Data {
+			var head struct {
+				ID string `json:"id"`
+			}
+			if err := json.Unmarshal(raw, &head); err != nil || head.ID == "" {
+				return fmt.Errorf("list subscriptions: object without id after %q", startingAfter)
+			}
+			if err := fn(Subscription{ID: head.ID, Raw: raw, APIVersion: apiVersion}); err != nil {
+				return err
+			}
+			startingAfter = head.ID
+		}
+		if !page.HasMore || len(page.Data) == 0 {
+			return nil
+		}
+	}
+}
+
+// GetSubscription retrieves one subscription by id. Returns (nil, nil)
+// if the API has no such subscription.
+func (c *Client) GetSubscription(ctx context.Context, id string) (*Subscription, error) {
+	var raw json.RawMessage
+	apiVersion, err := c.get(ctx, "/v1/subscriptions/"+url.PathEscape(id), nil, &raw)
+	var se *StatusError
+	if errors.As(err, &se) && se.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get subscription: %w", err)
+	}
+	return &Subscription{ID: id, Raw: raw, APIVersion: apiVersion}, nil
+}
+
+func (c *Client) fetchPage(ctx context.Context, pageSize int, startingAfter string) (*listPage, string, error) {
+	q := url.Values{}
+	q.Set("status", "all")
+	q.Set("limit", strconv.Itoa(pageSize))
+	if startingAfter != "" {
+		q.Set("starting_after", startingAfter)
+	}
+	var page listPage
+	apiVersion, err := c.get(ctx, "/v1/subscriptions", q, &page)
+	if err != nil {
+		return nil, "", fmt.Errorf("list subscriptions: %w", err)
+	}
+	return &page, apiVersion, nil
+}
+
+// get performs an authenticated GET, decodes a 200 response into out,
+// and returns the Stripe-Version response header. 429 responses are
+// retried up to MaxRetries times.
+func (c *Client) get(ctx context.Context, path string, q url.Values, out any) (string, error) {
+	target := c.BaseURL + path
+	if len(q) > 0 {
+		target += "?" + q.Encode()
+	}
+	httpClient := c.HTTP
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	backoff := c.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		if c.Limiter != nil {
+			if err := c.Limiter.Wait(ctx); err != nil {
+				return "", err
+			}
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+		if err != nil {
+			return "", fmt.Errorf("build request: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+c.APIKey)
+
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			return "", err
+		}
+		if resp.StatusCode == http.StatusOK {
+			defer resp.Body.Close()
+			if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+				return "", fmt.Errorf("decode: %w", err)
+			}
+			return resp.Header.Get("Stripe-Version"), nil
+		}
+
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusTooManyRequests || attempt >= c.MaxRetries {
+			return "", &StatusError{StatusCode: resp.StatusCode}
+		}
+
+		delay := backoff
+		if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs >= 0 {
+			delay = time.Duration(secs) * time.Second
+		}
+		backoff *= 2
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package polling
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"payment-node/internal/reducer/subscription"
+)
+
+// IngestionVersion is written to stripe_event_ledger.ingestion_version on
+// every polled row. Bump on any change to the synthetic event shape.
+const IngestionVersion = "poller-v2"
+
+// Worker polls the API on a fixed tick and appends changed subscriptions
+// to the ledger.
+type Worker struct {
+	DB     *sql.DB
+	Client *Client
+	Logger *slog.Logger
+
+	// Tick is how often the worker polls. Default 5m if zero.
+	Tick time.Duration
+
+	ledger ledgerStore
+	now    func() time.Time
+
+	// last maps subscription id -> the digest of the most recent polled
+	// event written for it. Populated lazily from the ledger so a restart
+	// does not rewrite every subscription.
+	last map[string]string
+}
+
+// Result summarizes one poll.
+type Result struct {
+	Fetched   int
+	Written   int
+	Unchanged int
+	Failed    int
+}
+
+// ledgerStore is the slice of stripe_event_ledger the worker touches.
+// Tests substitute an in-memory implementation.
+type ledgerStore interface {
+	LastPolledDigest(ctx context.Context, subID string) (string, error)
+	InsertPolledEvent(ctx context.Context, eventID string, eventJSON []byte, apiVersion string) error
+}
+
+// Run drives the worker until the context is canceled. A failed poll is
+// logged and retried on the next tick; the ledger only ever receives
+// whole subscriptions, so a partial poll is safe. Subscriptions that fail
+// to write are retried on the next tick as well.
+func (w *Worker) Run(ctx context.Context) error {
+	if w.Logger == nil {
+		w.Logger = slog.Default()
+	}
+	w.Logger = w.Logger.With("worker", "subscription_poller", "ingestion_version", IngestionVersion)
+	tick := w.Tick
+	if tick == 0 {
+		tick = 5 * time.Minute
+	}
+	w.Logger.Info("polling worker starting", "tick", tick.String())
+
+	for {
+		res, err := w.PollOnce(ctx)
+		if err != nil {
+			w.Logger.Error("poll failed", "error", err, "fetched", res.Fetched, "written", res.Written)
+		} else {
+			w.Logger.Info("poll complete", "fetched", res.Fetched, "written", res.Written, "unchanged", res.Unchanged, "failed", res.Failed)
+		}
+		select {
+		case <-ctx.Done():
+			w.Logger.Info("polling worker canceled")
+			return nil
+		case <-time.After(tick):
+		}
+	}
+}
+
+// PollOnce lists every subscription and writes a polled event for each
+// one whose API object changed since the last polled event for it. A
+// subscription that cannot be compared or written is logged and counted in
+// Result.Failed; the others are still polled. The error reports a failed
+// listing only.
+func (w *Worker) PollOnce(ctx context.Context) (Result, error) {
+	if w.ledger == nil {
+		w.ledger = &sqlLedger{db: w.DB}
+	}
+	if w.Logger == nil {
+		w.Logger = slog.Default()
+	}
+	if w.now == nil {
+		w.now = time.Now
+	}
+	if w.last == nil {
+		w.last = make(map[string]string)
+	}
+
+	var res Result
+	polledAt := w.now().UTC()
+
+	err := w.Client.ListSubscriptions(ctx, func(sub Subscription) error {
+		res.Fetched++
+		if err := w.pollSubscription(ctx, sub, polledAt, &res); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			res.Failed++
+			w.Logger.Error("poll subscription failed", "subscription", sub.ID, "error", err)
+		}
+		return nil
+	})
+	return res, err
+}
+
+// pollSubscription writes a polled event for sub unless its API object is
+// unchanged since the last one.
+func (w *Worker) pollSubscription(ctx context.Context, sub Subscription, polledAt time.Time, res *Result) error {
+	digest := snapshotDigest(sub.Raw)
+
+	last, ok := w.last[sub.ID]
+	if !ok {
+		var err error
+		last, err = w.ledger.LastPolledDigest(ctx, sub.ID)
+		if err != nil {
+			return fmt.Errorf("load last polled event: %w", err)
+		}
+	}
+	if last == digest {
+		w.last[sub.ID] = digest
+		res.Unchanged++
+		return nil
+	}
+
+	eventID := polledEventID(sub.ID, polledAt, digest)
+	payload, err := polledEventPayload(eventID, polledAt, sub)
+	if err != nil {
+		return fmt.Errorf("build polled event: %w", err)
+	}
+	if err := w.ledger.InsertPolledEvent(ctx, eventID, payload, sub.APIVersion); err != nil {
+		return fmt.Errorf("insert polled event: %w", err)
+	}
+	w.last[sub.ID] = digest
+	res.Written++
+	return nil
+}
+
+// snapshotDigest is a digest of the API object. Identical objects yield
+// identical digests, which is how the worker recognizes "nothing changed
+// since last poll".
+func snapshotDigest(raw json.RawMessage) string {
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:8])
+}
+
+// polledEventID derives the synthetic event id from the subscription id,
+// the poll time and the snapshot digest. The poll time keeps ids unique
+// when a subscription changes back to an earlier object (A→B→A); the
+// digest is the last segment, where polledEventDigest reads it back.
+func polledEventID(subID string, polledAt time.Time, digest string) string {
+	return subscription.PolledEventIDPrefix + subID + "_" + strconv.FormatInt(polledAt.UnixMilli(), 10) + "_" + digest
+}
+
+// polledEventDigest returns the snapshot digest of a polled event id. Ids
+// written before the poll time was added end in the digest as well.
+func polledEventDigest(eventID string) string {
+	if i := strings.LastIndexByte(eventID, '_'); i >= 0 {
+		return eventID[i+1:]
+	}
+	return ""
+}
+
+// polledEvent is the synthetic Stripe-shaped event the worker appends.
+// created is the poll time: a snapshot describes the subscription as of
+// when it was read, which is what the reducer orders polled events by.
+type polledEvent struct {
+	ID         string          `json:"id"`
+	Object     string          `json:"object"`
+	Type       string          `json:"type"`
+	Created    int64           `json:"created"`
+	APIVersion string          `json:"api_version,omitempty"`
+	Data       polledEventData `json:"data"`
+}
+
+type polledEventData struct {
+	Object json.RawMessage `json:"object"`
+}
+
+func polledEventPayload(eventID string, polledAt time.Time, sub Subscription) ([]byte, error) {
+	return json.Marshal(polledEvent{
+		ID:         eventID,
+		Object:     "event",
+		Type:       subscription.PolledEventType,
+		Created:    polledAt.Unix(),
+		APIVersion: sub.APIVersion,
+		Data:       polledEventData{Object: sub.Raw},
+	})
+}
+
+// sqlLedger implements ledgerStore against stripe_event_ledger.
+type sqlLedger struct {
+	db *sql.DB
+}
+
+func (l *sqlLedger) LastPolledDigest(ctx context.Context, subID string) (string, error) {
+	// '_' is a LIKE wildcard and appears in every Stripe id; escape it so
+	// the prefix match is exact.
+	prefix := strings.ReplaceAll(subscription.PolledEventIDPrefix+subID+"_", "_", `\_`)
+	var id string
+	err := l.db.QueryRowContext(ctx, `
+		SELECT stripe_event_id FROM stripe_event_ledger
+		WHERE verify_outcome = $1
+		  AND stripe_event_id LIKE $2
+		ORDER BY received_at DESC, id DESC
+		LIMIT 1
+	`, subscription.PollingVerifyOutcome, prefix+"%").Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return polledEventDigest(id), nil
+}
+
+func (l *sqlLedger) InsertPolledEvent(ctx context.Context, eventID string, eventJSON []byte, apiVersion string) error {
+	var schemaVersion sql.NullString
+	if apiVersion != "" {
+		schemaVersion = sql.NullString{String: apiVersion, Valid: true}
+	}
+	_, err := l.db.ExecContext(ctx, `
+		INSERT INTO stripe_event_ledger (
+			stripe_event_id, payload, payload_size_bytes, signature_header,
+			verify_outcome, payload_schema_version, ingestion_version
+		) VALUES ($1, $2, $3, NULL, $4, $5, $6)
+	`, eventID, string(eventJSON), len(eventJSON), subscription.PollingVerifyOutcome, schemaVersion, IngestionVersion)
+	return err
+}
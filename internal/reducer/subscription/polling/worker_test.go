@@ -0,0 +1,377 @@
+package polling
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"payment-node/internal/reducer/subscription"
+)
+
+// fakeStripe is a minimal Stripe-compatible subscription list and
+// retrieve endpoint. Subscriptions are served in insertion order with
+// starting_after pagination, like the real API. The next throttle
+// requests are answered 429.
+type fakeStripe struct {
+	mu       sync.Mutex
+	apiKey   string
+	subs     []map[string]any
+	requests int
+	throttle int
+}
+
+func (f *fakeStripe) set(id, status string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, s := range f.subs {
+		if s["id"] == id {
+			s["status"] = status
+			return
+		}
+	}
+	f.subs = append(f.subs, map[string]any{
+		"id":                   id,
+		"object":               "subscription",
+		"status":               status,
+		"current_period_start": 1767268800,
+		"current_period_end":   1769947200,
+		"cancel_at_period_end": false,
+		"customer":             "cus_" + id,
+	})
+}
+
+func (f *fakeStripe) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests++
+
+	if f.throttle > 0 {
+		f.throttle--
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(http.StatusTooManyRequests)
+		return
+	}
+	if r.Header.Get("Authorization") != "Bearer "+f.apiKey {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if id, ok := strings.CutPrefix(r.URL.Path, "/v1/subscriptions/"); ok {
+		for _, s := range f.subs {
+			if s["id"] == id {
+				_ = json.NewEncoder(w).Encode(s)
+				return
+			}
+		}
+		http.NotFound(w, r)
+		return
+	}
+	if r.URL.Path != "/v1/subscriptions" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.URL.Query().Get("status") != "all" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	start := 0
+	if after := r.URL.Query().Get("starting_after"); after != "" {
+		for i, s := range f.subs {
+			if s["id"] == after {
+				start = i + 1
+			}
+		}
+	}
+	end := start + limit
+	if end > len(f.subs) {
+		end = len(f.subs)
+	}
+	w.Header().Set("Stripe-Version", "2025-02-24.acacia")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"object":   "list",
+		"data":     f.subs[start:end],
+		"has_more": end < len(f.subs),
+	})
+}
+
+// memLedger is an in-memory ledgerStore. Inserts for subscriptions in
+// failFor return an error.
+type memLedger struct {
+	rows    []memRow
+	failFor map[string]bool
+}
+
+type memRow struct {
+	eventID    string
+	payload    []byte
+	apiVersion string
+}
+
+func (l *memLedger) LastPolledDigest(_ context.Context, subID string) (string, error) {
+	prefix := subscription.PolledEventIDPrefix + subID + "_"
+	for i := len(l.rows) - 1; i >= 0; i-- {
+		if strings.HasPrefix(l.rows[i].eventID, prefix) {
+			return polledEventDigest(l.rows[i].eventID), nil
+		}
+	}
+	return "", nil
+}
+
+func (l *memLedger) InsertPolledEvent(_ context.Context, eventID string, payload []byte, apiVersion string) error {
+	for subID := range l.failFor {
+		if strings.HasPrefix(eventID, subscription.PolledEventIDPrefix+subID+"_") {
+			return errors.New("connection reset")
+		}
+	}
+	l.rows = append(l.rows, memRow{eventID: eventID, payload: payload, apiVersion: apiVersion})
+	return nil
+}
+
+func newTestWorker(t *testing.T, fake *fakeStripe, ledger *memLedger) *Worker {
+	t.Helper()
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+	client := NewClient(srv.URL, fake.apiKey)
+	client.PageSize = 2
+	// Each poll is a minute after the previous one
+	polledAt := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC).Add(-time.Minute)
+	return &Worker{
+		Client: client,
+		ledger: ledger,
+		now: func() time.Time {
+			polledAt = polledAt.Add(time.Minute)
+			return polledAt
+		},
+	}
+}
+
+func TestClientPaginates(t *testing.T) {
+	fake := &fakeStripe{apiKey: "sk_test_fake"}
+	for i := 1; i <= 5; i++ {
+		fake.set(fmt.Sprintf("sub_%d", i), "active")
+	}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	client := NewClient(srv.URL, "sk_test_fake")
+	client.PageSize = 2
+
+	var ids []string
+	err := client.ListSubscriptions(context.Background(), func(s Subscription) error {
+		ids = append(ids, s.ID)
+		if s.APIVersion != "2025-02-24.acacia" {
+			t.Errorf("APIVersion = %q", s.APIVersion)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ListSubscriptions: %v", err)
+	}
+	if got := strings.Join(ids, ","); got != "sub_1,sub_2,sub_3,sub_4,sub_5" {
+		t.Fatalf("ids = %s", got)
+	}
+	if fake.requests != 3 {
+		t.Fatalf("expected 3 page requests; got %d", fake.requests)
+	}
+}
+
+func TestClientRejectsBadKey(t *testing.T) {
+	fake := &fakeStripe{apiKey: "sk_test_fake"}
+	fake.set("sub_1", "active")
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	err := NewClient(srv.URL, "sk_test_wrong").ListSubscriptions(context.Background(), func(Subscription) error { return nil })
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Fatalf("expected 401 error; got %v", err)
+	}
+}
+
+func TestClientGetSubscription(t *testing.T) {
+	fake := &fakeStripe{apiKey: "sk_test_fake"}
+	fake.set("sub_1", "past_due")
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+	client := NewClient(srv.URL, "sk_test_fake")
+
+	sub, err := client.GetSubscription(context.Background(), "sub_1")
+	if err != nil || sub == nil {
+		t.Fatalf("GetSubscription: %v, %v", sub, err)
+	}
+	var decoded subscription.StripeSubscription
+	if err := json.Unmarshal(sub.Raw, &decoded); err != nil || decoded.Status != "past_due" {
+		t.Fatalf("decoded %+v (%v)", decoded, err)
+	}
+
+	sub, err = client.GetSubscription(context.Background(), "sub_missing")
+	if err != nil || sub != nil {
+		t.Fatalf("unknown id: got %v, %v; want nil, nil", sub, err)
+	}
+}
+
+func TestClientRetriesRateLimited(t *testing.T) {
+	fake := &fakeStripe{apiKey: "sk_test_fake", throttle: 2}
+	fake.set("sub_1", "active")
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+	client := NewClient(srv.URL, "sk_test_fake")
+
+	n := 0
+	if err := client.ListSubscriptions(context.Background(), func(Subscription) error { n++; return nil }); err != nil {
+		t.Fatalf("ListSubscriptions: %v", err)
+	}
+	if n != 1 || fake.requests != 3 {
+		t.Fatalf("got %d subscriptions in %d requests; want 1 in 3", n, fake.requests)
+	}
+
+	fake.throttle = client.MaxRetries + 1
+	err := client.ListSubscriptions(context.Background(), func(Subscription) error { return nil })
+	var se *StatusError
+	if !errors.As(err, &se) || se.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 after exhausting retries; got %v", err)
+	}
+}
+
+// TestPollOnceWritesOnlyChanges verifies the worker appends a polled
+// event for new or changed subscriptions only — including a change back
+// to an earlier value.
+func TestPollOnceWritesOnlyChanges(t *testing.T) {
+	fake := &fakeStripe{apiKey: "sk_test_fake"}
+	fake.set("sub_a", "active")
+	fake.set("sub_b", "trialing")
+	fake.set("sub_c", "active")
+	ledger := &memLedger{}
+	w := newTestWorker(t, fake, ledger)
+	ctx := context.Background()
+
+	steps := []struct {
+		name    string
+		mutate  func()
+		written int
+	}{
+		{"first poll", func() {}, 3},
+		{"no change", func() {}, 0},
+		{"one change", func() { fake.set("sub_a", "past_due") }, 1},
+		{"change back", func() { fake.set("sub_a", "active") }, 1},
+		{"new subscription", func() { fake.set("sub_d", "incomplete") }, 1},
+	}
+	for _, step := range steps {
+		step.mutate()
+		res, err := w.PollOnce(ctx)
+		if err != nil {
+			t.Fatalf("%s: PollOnce: %v", step.name, err)
+		}
+		if res.Written != step.written || res.Fetched != res.Written+res.Unchanged {
+			t.Fatalf("%s: result %+v, want written=%d", step.name, res, step.written)
+		}
+	}
+	if len(ledger.rows) != 6 {
+		t.Fatalf("expected 6 ledger rows; got %d", len(ledger.rows))
+	}
+	// The change back to "active" is a new event, not a repeat of the first
+	seen := make(map[string]bool)
+	for _, row := range ledger.rows {
+		if seen[row.eventID] {
+			t.Fatalf("event id %s written twice", row.eventID)
+		}
+		seen[row.eventID] = true
+	}
+	if a1, a3 := ledger.rows[0].eventID, ledger.rows[4].eventID; polledEventDigest(a1) != polledEventDigest(a3) {
+		t.Errorf("same object, different digests: %s, %s", a1, a3)
+	}
+}
+
+// TestPollOnceContinuesAfterFailure verifies a subscription that cannot be
+// written does not stop the others, and is retried on the next poll.
+func TestPollOnceContinuesAfterFailure(t *testing.T) {
+	fake := &fakeStripe{apiKey: "sk_test_fake"}
+	fake.set("sub_a", "active")
+	fake.set("sub_b", "active")
+	fake.set("sub_c", "active")
+	ledger := &memLedger{failFor: map[string]bool{"sub_b": true}}
+	w := newTestWorker(t, fake, ledger)
+
+	res, err := w.PollOnce(context.Background())
+	if err != nil {
+		t.Fatalf("PollOnce: %v", err)
+	}
+	if res.Fetched != 3 || res.Written != 2 || res.Failed != 1 {
+		t.Fatalf("result %+v, want 2 written and 1 failed", res)
+	}
+
+	ledger.failFor = nil
+	res, err = w.PollOnce(context.Background())
+	if err != nil {
+		t.Fatalf("PollOnce: %v", err)
+	}
+	if res.Written != 1 || res.Unchanged != 2 || res.Failed != 0 {
+		t.Fatalf("retry result %+v, want sub_b written", res)
+	}
+}
+
+// TestPollOnceResumesFromLedger verifies a restarted worker consults the
+// ledger instead of rewriting every subscription.
+func TestPollOnceResumesFromLedger(t *testing.T) {
+	fake := &fakeStripe{apiKey: "sk_test_fake"}
+	fake.set("sub_a", "active")
+	ledger := &memLedger{}
+
+	if _, err := newTestWorker(t, fake, ledger).PollOnce(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	res, err := newTestWorker(t, fake, ledger).PollOnce(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Written != 0 || res.Unchanged != 1 {
+		t.Fatalf("restarted worker should find the subscription unchanged; got %+v", res)
+	}
+}
+
+// TestPolledEventShape verifies the synthetic event parses the way the
+// reducer reads ledger payloads.
+func TestPolledEventShape(t *testing.T) {
+	fake := &fakeStripe{apiKey: "sk_test_fake"}
+	fake.set("sub_shape", "past_due")
+	ledger := &memLedger{}
+	if _, err := newTestWorker(t, fake, ledger).PollOnce(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	row := ledger.rows[0]
+	var ev struct {
+		ID         string `json:"id"`
+		Type       string `json:"type"`
+		Created    int64  `json:"created"`
+		APIVersion string `json:"api_version"`
+		Data       struct {
+			Object subscription.StripeSubscription `json:"object"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(row.payload, &ev); err != nil {
+		t.Fatalf("payload does not parse: %v", err)
+	}
+	if ev.ID != row.eventID || !strings.HasPrefix(ev.ID, subscription.PolledEventIDPrefix+"sub_shape_") {
+		t.Errorf("event id = %q (row %q)", ev.ID, row.eventID)
+	}
+	if ev.Type != subscription.PolledEventType {
+		t.Errorf("type = %q", ev.Type)
+	}
+	if ev.Created != time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC).Unix() {
+		t.Errorf("created = %d, want poll time", ev.Created)
+	}
+	if ev.Data.Object.ID != "sub_shape" || ev.Data.Object.Status != "past_due" {
+		t.Errorf("data.object = %+v", ev.Data.Object)
+	}
+	if row.apiVersion != "2025-02-24.acacia" || ev.APIVersion != row.apiVersion {
+		t.Errorf("api version row=%q payload=%q", row.apiVersion, ev.APIVersion)
+	}
+}
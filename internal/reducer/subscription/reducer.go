@@ -116,6 +116,9 @@ func Run(ctx context.Context, db *sql.DB, logger *slog.Logger, mode Mode) error
 		// If nothing processed and we're in backfill mode, check whether
 		// we've caught up.
 		if scanned == 0 {
+			if err := activateVersion(ctx, db); err != nil {
+				logger.Warn("version activation failed", "error", err)
+			}
 			if mode == ModeBackfill {
 				logger.Info("backfill complete")
 				return completeEpoch(ctx, db, epochID)
@@ -190,6 +193,7 @@ func ProcessPending(ctx context.Context, db *sql.DB) (processedTotal, conflictsT
 			continue
 		}
 		// No more pending events.
+		_ = activateVersion(ctx, db)
 		_ = completeEpoch(ctx, db, epochID)
 		return processedTotal, conflictsTotal, nil
 	}
@@ -211,9 +215,35 @@ type Cursor struct {
 // batch, the cumulative conflict count, and any unrecoverable error.
 func processBatch(ctx context.Context, db *sql.DB, cursor Cursor, epochID string, batchSize int, upperBound *time.Time) (scanned int, projections int, lastAt time.Time, lastID sql.NullString, conflicts int, err error) {
 	// Read the next batch from the ledger. We filter to event types this
-	// reducer cares about. Only successfully-verified deliveries are
-	// processed (signature failures and malformed bodies are forensic
-	// only, not interpretable).
+	// reducer cares about. Only successfully-verified deliveries, polled
+	// snapshots, and operator corrections are processed (signature
+	// failures and malformed bodies are forensic only, not
+	// interpretable).
+	batch, err := scanLedger(ctx, db, cursor, upperBound, batchSize)
+	if err != nil {
+		return 0, 0, lastAt, lastID, 0, err
+	}
+
+	for _, row := range batch {
+		projected, ec, err := processSingleEvent(ctx, db, row, epochID)
+		if err != nil {
+			return scanned, projections, lastAt, lastID, conflicts, fmt.Errorf("process event %s: %w", row.LedgerID, err)
+		}
+		scanned++
+		if projected {
+			projections++
+		}
+		conflicts += ec
+		lastAt = row.ReceivedAt
+		lastID = sql.NullString{String: row.LedgerID, Valid: true}
+	}
+
+	return scanned, projections, lastAt, lastID, conflicts, nil
+}
+
+// scanLedger reads up to batchSize ledger rows after cursor, in
+// (received_at, id) order, and no later than upperBound when set.
+func scanLedger(ctx context.Context, db *sql.DB, cursor Cursor, upperBound *time.Time, batchSize int) ([]ledgerEventOnDisk, error) {
 	var upperBoundValue any
 	if upperBound != nil {
 		upperBoundValue = *upperBound
@@ -221,7 +251,7 @@ func processBatch(ctx context.Context, db *sql.DB, cursor Cursor, epochID string
 	rows, err := db.QueryContext(ctx, `
 		SELECT id::text, stripe_event_id, payload, ingestion_version, verify_outcome, received_at
 		FROM stripe_event_ledger
-		WHERE verify_outcome IN ('primary', 'fallback', 'connect', 'per_account')
+		WHERE verify_outcome IN ('primary', 'fallback', 'connect', 'per_account', 'polling', 'manual')
 		  AND (
 			received_at > $1
 			OR (
@@ -234,7 +264,7 @@ func processBatch(ctx context.Context, db *sql.DB, cursor Cursor, epochID string
 		LIMIT $4
 	`, cursor.ReceivedAt, cursor.EventID, upperBoundValue, batchSize)
 	if err != nil {
-		return 0, 0, lastAt, lastID, 0, fmt.Errorf("scan ledger: %w", err)
+		return nil, fmt.Errorf("scan ledger: %w", err)
 	}
 	defer rows.Close()
 
@@ -242,29 +272,14 @@ func processBatch(ctx context.Context, db *sql.DB, cursor Cursor, epochID string
 	for rows.Next() {
 		var row ledgerEventOnDisk
 		if err := rows.Scan(&row.LedgerID, &row.StripeEventID, &row.Payload, &row.IngestionVersion, &row.VerifyOutcome, &row.ReceivedAt); err != nil {
-			return 0, 0, lastAt, lastID, 0, fmt.Errorf("scan row: %w", err)
+			return nil, fmt.Errorf("scan row: %w", err)
 		}
 		batch = append(batch, row)
 	}
 	if err := rows.Err(); err != nil {
-		return 0, 0, lastAt, lastID, 0, fmt.Errorf("iterate ledger rows: %w", err)
-	}
-
-	for _, row := range batch {
-		projected, ec, err := processSingleEvent(ctx, db, row, epochID)
-		if err != nil {
-			return scanned, projections, lastAt, lastID, conflicts, fmt.Errorf("process event %s: %w", row.LedgerID, err)
-		}
-		scanned++
-		if projected {
-			projections++
-		}
-		conflicts += ec
-		lastAt = row.ReceivedAt
-		lastID = sql.NullString{String: row.LedgerID, Valid: true}
+		return nil, fmt.Errorf("iterate ledger rows: %w", err)
 	}
-
-	return scanned, projections, lastAt, lastID, conflicts, nil
+	return batch, nil
 }
 
 // processSingleEvent handles one ledger row inside its own transaction:
@@ -275,56 +290,40 @@ func processBatch(ctx context.Context, db *sql.DB, cursor Cursor, epochID string
 // the event was an unhandled type or had no resolvable workspace (in
 // which case the cursor still advances).
 func processSingleEvent(ctx context.Context, db *sql.DB, row ledgerEventOnDisk, epochID string) (projected bool, conflictCount int, err error) {
-	var parsed stripeEvent
-	if jerr := json.Unmarshal([]byte(row.Payload), &parsed); jerr != nil {
-		// Malformed payload shouldn't reach the reducer (ledger
-		// captures malformed entries with verify_outcome='malformed'
-		// which the SELECT filters out), but defend anyway.
+	event, object, ok := parseLedgerEvent(row)
+	if !ok {
 		return false, 0, advanceCursorOnly(ctx, db, row, epochID)
 	}
+	subID := event.SubscriptionID()
 
-	// Only handle subscription events in v1.
-	if !isSubscriptionEvent(parsed.Type) {
-		return false, 0, advanceCursorOnly(ctx, db, row, epochID)
-	}
-
-	var sub StripeSubscription
-	if jerr := json.Unmarshal(parsed.Data.Object, &sub); jerr != nil || sub.ID == "" {
-		return false, 0, advanceCursorOnly(ctx, db, row, epochID)
+	// Load current projection.
+	current, err := loadCurrentProjection(ctx, db, subID)
+	if err != nil {
+		return false, 0, fmt.Errorf("load current projection: %w", err)
 	}
 
-	// Resolve workspace_id via billing_customers (subscription.customer
+	// Resolve workspace_id via billing_customers (the object's customer
 	// is the Stripe customer id; billing_customers maps that to the
 	// internal workspace).
-	workspaceID, err := resolveWorkspaceForSubscription(ctx, db, parsed.Data.Object)
+	workspaceID, err := resolveWorkspace(ctx, db, object)
 	if err != nil {
 		return false, 0, fmt.Errorf("resolve workspace: %w", err)
 	}
+	if workspaceID == "" && current != nil {
+		// Invoices rarely carry workspace metadata; the subscription's
+		// existing projection already knows the workspace.
+		workspaceID = current.WorkspaceID
+	}
 	if workspaceID == "" {
 		// No workspace match — log and advance. This happens for
 		// historical events that predate our processor_connections
 		// rows.
 		return false, 0, advanceCursorOnly(ctx, db, row, epochID)
 	}
-
-	event := &Event{
-		LedgerEventID:          row.LedgerID,
-		SourceEventID:          parsed.ID,
-		SourceIngestionVersion: row.IngestionVersion,
-		EventType:              parsed.Type,
-		OccurredAt:             time.Unix(parsed.Created, 0).UTC(),
-		Subscription:           &sub,
-		WorkspaceID:            workspaceID,
-	}
-
-	// Load current projection.
-	current, err := loadCurrentProjection(ctx, db, sub.ID)
-	if err != nil {
-		return false, 0, fmt.Errorf("load current projection: %w", err)
-	}
+	event.WorkspaceID = workspaceID
 
 	// Apply merge function.
-	result := Merge(current, event, EventSourceWebhook)
+	result := Merge(current, event, EventSourceForOutcome(row.VerifyOutcome))
 
 	// Persist inside one transaction: projection (if any) +
 	// conflicts (if any) + cursor advance.
@@ -341,13 +340,14 @@ func processSingleEvent(ctx context.Context, db *sql.DB, row ledgerEventOnDisk,
 		err := tx.QueryRowContext(ctx, `
 			SELECT id::text FROM subscription_projection
 			WHERE stripe_subscription_id = $1
+			  AND reducer_version = $2
 			  AND NOT EXISTS (
 				SELECT 1 FROM subscription_projection newer
 				WHERE newer.supersedes_id = subscription_projection.id
 			  )
 			ORDER BY projected_at DESC
 			LIMIT 1
-		`, sub.ID).Scan(&id)
+		`, subID, ReducerVersion).Scan(&id)
 		if err == nil {
 			supersedesID = sql.NullString{String: id, Valid: true}
 		} else if !errors.Is(err, sql.ErrNoRows) {
@@ -357,34 +357,47 @@ func processSingleEvent(ctx context.Context, db *sql.DB, row ledgerEventOnDisk,
 
 	if result.Next != nil {
 		authorityJSON, _ := result.Next.MarshalFieldAuthority()
+		overridesJSON, _ := result.Next.MarshalManualOverrides()
 		res, err := tx.ExecContext(ctx, `
 			INSERT INTO subscription_projection (
 				stripe_subscription_id, workspace_id,
 				status, current_period_start, current_period_end,
 				cancel_at_period_end, canceled_at, trial_start, trial_end,
-				field_authority,
+				last_payment_status, last_payment_failed_at, dunning_attempt_count,
+				checkout_session_id, checkout_completed_at,
+				field_authority, manual_overrides,
 				projection_version, reducer_version, source_event_id, source_ingestion_version,
-				replay_epoch_id, supersedes_id, event_occurred_at
-			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
+				replay_epoch_id, supersedes_id, event_occurred_at,
+				subscription_event_occurred_at, payment_event_occurred_at, polled_at
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26)
 			ON CONFLICT (stripe_subscription_id, source_event_id, reducer_version) DO NOTHING
 		`,
 			result.Next.StripeSubscriptionID,
 			result.Next.WorkspaceID,
-			result.Next.Status,
+			nullIfEmpty(result.Next.Status),
 			result.Next.CurrentPeriodStart,
 			result.Next.CurrentPeriodEnd,
 			result.Next.CancelAtPeriodEnd,
 			result.Next.CanceledAt,
 			result.Next.TrialStart,
 			result.Next.TrialEnd,
+			nullIfEmpty(result.Next.LastPaymentStatus),
+			result.Next.LastPaymentFailedAt,
+			result.Next.DunningAttemptCount,
+			nullIfEmpty(result.Next.CheckoutSessionID),
+			result.Next.CheckoutCompletedAt,
 			authorityJSON,
+			overridesJSON,
 			ProjectionVersion,
 			ReducerVersion,
 			event.SourceEventID,
 			event.SourceIngestionVersion,
 			epochID,
 			supersedesID,
-			event.OccurredAt,
+			result.Next.EventOccurredAt,
+			result.Next.SubscriptionEventOccurredAt,
+			result.Next.PaymentEventOccurredAt,
+			result.Next.PolledAt,
 		)
 		if err != nil {
 			return false, 0, fmt.Errorf("insert projection: %w", err)
@@ -402,7 +415,7 @@ func processSingleEvent(ctx context.Context, db *sql.DB, row ledgerEventOnDisk,
 				details, reducer_version, replay_epoch_id
 			) VALUES ($1, $2, $3, $4, $5, $6, $7)
 		`,
-			sub.ID, string(c.Type), event.SourceEventID, supersedesID, details, ReducerVersion, epochID,
+			subID, string(c.Type), event.SourceEventID, supersedesID, details, ReducerVersion, epochID,
 		)
 		if err != nil {
 			return false, 0, fmt.Errorf("insert conflict: %w", err)
@@ -422,23 +435,91 @@ func processSingleEvent(ctx context.Context, db *sql.DB, row ledgerEventOnDisk,
 	return projected, conflictCount, nil
 }
 
+// parseLedgerEvent decodes a ledger row into the reducer's Event and the
+// raw data.object (for workspace resolution). Returns ok=false for rows
+// the reducer skips: malformed payloads, unhandled event types, objects
+// without an id, and events not about a subscription (one-off invoices,
+// payment-mode checkouts).
+func parseLedgerEvent(row ledgerEventOnDisk) (event *Event, object json.RawMessage, ok bool) {
+	var parsed stripeEvent
+	if jerr := json.Unmarshal([]byte(row.Payload), &parsed); jerr != nil {
+		// Malformed payload shouldn't reach the reducer (ledger
+		// captures malformed entries with verify_outcome='malformed'
+		// which the SELECT filters out), but defend anyway.
+		return nil, nil, false
+	}
+
+	event = &Event{
+		LedgerEventID:          row.LedgerID,
+		SourceEventID:          parsed.ID,
+		SourceIngestionVersion: row.IngestionVersion,
+		EventType:              parsed.Type,
+		OccurredAt:             time.Unix(parsed.Created, 0).UTC(),
+	}
+	switch {
+	case isSubscriptionEvent(parsed.Type):
+		var sub StripeSubscription
+		if jerr := json.Unmarshal(parsed.Data.Object, &sub); jerr != nil || sub.ID == "" {
+			return nil, nil, false
+		}
+		event.Subscription = &sub
+	case isInvoicePaymentEvent(parsed.Type):
+		var inv StripeInvoice
+		if jerr := json.Unmarshal(parsed.Data.Object, &inv); jerr != nil || inv.ID == "" {
+			return nil, nil, false
+		}
+		event.Invoice = &inv
+	case isCheckoutEvent(parsed.Type):
+		var cs StripeCheckoutSession
+		if jerr := json.Unmarshal(parsed.Data.Object, &cs); jerr != nil || cs.ID == "" {
+			return nil, nil, false
+		}
+		event.CheckoutSession = &cs
+	case parsed.Type == ManualEventType:
+		var mc ManualCorrection
+		if jerr := json.Unmarshal(parsed.Data.Object, &mc); jerr != nil || mc.Subscription == "" {
+			return nil, nil, false
+		}
+		event.Correction = &mc
+	default:
+		return nil, nil, false
+	}
+
+	// One-off invoices and payment-mode checkouts carry no subscription
+	// and have nothing to project.
+	if event.SubscriptionID() == "" {
+		return nil, nil, false
+	}
+	return event, parsed.Data.Object, true
+}
+
 func isSubscriptionEvent(eventType string) bool {
 	switch eventType {
 	case "customer.subscription.created",
 		"customer.subscription.updated",
-		"customer.subscription.deleted":
+		"customer.subscription.deleted",
+		PolledEventType:
 		return true
 	}
 	return false
 }
 
-// resolveWorkspaceForSubscription extracts the internal workspace id from
-// the subscription's Stripe customer. Returns "" if unresolvable, which
+func isInvoicePaymentEvent(eventType string) bool {
+	return paymentStatusFor(eventType) != ""
+}
+
+func isCheckoutEvent(eventType string) bool {
+	return eventType == "checkout.session.completed"
+}
+
+// resolveWorkspace extracts the internal workspace id from a subscription,
+// invoice, or checkout session object. Returns "" if unresolvable, which
 // is treated as a clean skip rather than an error.
-func resolveWorkspaceForSubscription(ctx context.Context, db *sql.DB, raw json.RawMessage) (string, error) {
+func resolveWorkspace(ctx context.Context, db *sql.DB, raw json.RawMessage) (string, error) {
 	var sub struct {
-		Customer string `json:"customer"`
-		Metadata struct {
+		Customer          string `json:"customer"`
+		ClientReferenceID string `json:"client_reference_id"`
+		Metadata          struct {
 			WorkspaceID string `json:"workspaceId"`
 		} `json:"metadata"`
 	}
@@ -446,13 +527,17 @@ func resolveWorkspaceForSubscription(ctx context.Context, db *sql.DB, raw json.R
 		return "", nil
 	}
 
-	// Prefer explicit workspace metadata if present (platform billing
-	// subscriptions carry this).
-	if sub.Metadata.WorkspaceID != "" {
+	// Prefer explicit workspace references if present: platform billing
+	// subscriptions carry metadata.workspaceId, and checkout sessions are
+	// opened with the workspace as client_reference_id.
+	for _, ref := range []string{sub.Metadata.WorkspaceID, sub.ClientReferenceID} {
+		if ref == "" {
+			continue
+		}
 		var id string
 		err := db.QueryRowContext(ctx,
 			`SELECT id::text FROM workspaces WHERE id::text = $1 OR clerk_org_id = $1 LIMIT 1`,
-			sub.Metadata.WorkspaceID,
+			ref,
 		).Scan(&id)
 		if err == nil {
 			return id, nil
@@ -481,42 +566,71 @@ func resolveWorkspaceForSubscription(ctx context.Context, db *sql.DB, raw json.R
 }
 
 // loadCurrentProjection reads the current (non-superseded) projection for
-// a subscription. Returns nil if no projection exists yet.
+// a subscription under this reducer version. Returns nil if no projection
+// exists yet. Chains are per reducer version: a new version replays the
+// ledger into its own chain rather than extending an older one.
 func loadCurrentProjection(ctx context.Context, db *sql.DB, subID string) (*State, error) {
-	var s State
-	var authorityJSON []byte
-	err := db.QueryRowContext(ctx, `
-		SELECT
-			stripe_subscription_id, workspace_id::text,
-			status, current_period_start, current_period_end,
-			cancel_at_period_end, canceled_at, trial_start, trial_end,
-			field_authority,
-			event_occurred_at
+	s, err := scanState(db.QueryRowContext(ctx, `
+		SELECT `+stateColumns+`
 		FROM subscription_projection
 		WHERE stripe_subscription_id = $1
+		  AND reducer_version = $2
 		  AND NOT EXISTS (
 			SELECT 1 FROM subscription_projection newer
 			WHERE newer.supersedes_id = subscription_projection.id
 		  )
 		ORDER BY projected_at DESC
 		LIMIT 1
-	`, subID).Scan(
-		&s.StripeSubscriptionID, &s.WorkspaceID,
-		&s.Status, &s.CurrentPeriodStart, &s.CurrentPeriodEnd,
-		&s.CancelAtPeriodEnd, &s.CanceledAt, &s.TrialStart, &s.TrialEnd,
-		&authorityJSON,
-		&s.EventOccurredAt,
-	)
+	`, subID, ReducerVersion))
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, nil
 	}
+	return s, err
+}
+
+// stateColumns lists the columns scanState reads, in order. The
+// projection and shadow projection tables share them.
+const stateColumns = `
+			stripe_subscription_id, workspace_id::text,
+			status, current_period_start, current_period_end,
+			cancel_at_period_end, canceled_at, trial_start, trial_end,
+			last_payment_status, last_payment_failed_at, dunning_attempt_count,
+			checkout_session_id, checkout_completed_at,
+			field_authority, manual_overrides,
+			event_occurred_at, subscription_event_occurred_at, payment_event_occurred_at,
+			polled_at`
+
+// scanState reads a State from a row selected with stateColumns.
+func scanState(row interface{ Scan(...any) error }) (*State, error) {
+	var s State
+	var authorityJSON, overridesJSON []byte
+	var status, lastPaymentStatus, checkoutSessionID sql.NullString
+	err := row.Scan(
+		&s.StripeSubscriptionID, &s.WorkspaceID,
+		&status, &s.CurrentPeriodStart, &s.CurrentPeriodEnd,
+		&s.CancelAtPeriodEnd, &s.CanceledAt, &s.TrialStart, &s.TrialEnd,
+		&lastPaymentStatus, &s.LastPaymentFailedAt, &s.DunningAttemptCount,
+		&checkoutSessionID, &s.CheckoutCompletedAt,
+		&authorityJSON, &overridesJSON,
+		&s.EventOccurredAt, &s.SubscriptionEventOccurredAt, &s.PaymentEventOccurredAt,
+		&s.PolledAt,
+	)
 	if err != nil {
 		return nil, err
 	}
+	s.Status = status.String
+	s.LastPaymentStatus = lastPaymentStatus.String
+	s.CheckoutSessionID = checkoutSessionID.String
 	_ = json.Unmarshal(authorityJSON, &s.FieldAuthority)
+	_ = json.Unmarshal(overridesJSON, &s.ManualOverrides)
 	return &s, nil
 }
 
+// nullIfEmpty stores "" as NULL for optional text columns.
+func nullIfEmpty(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
 // startEpoch begins a new replay epoch row.
 func startEpoch(ctx context.Context, db *sql.DB, mode Mode) (string, error) {
 	var id string
@@ -546,19 +660,28 @@ func completeEpoch(ctx context.Context, db *sql.DB, epochID string) error {
 				concat_ws('|',
 					stripe_subscription_id,
 					workspace_id::text,
-					status,
+					COALESCE(status, ''),
 					COALESCE(current_period_start::text, ''),
 					COALESCE(current_period_end::text, ''),
 					cancel_at_period_end::text,
 					COALESCE(canceled_at::text, ''),
 					COALESCE(trial_start::text, ''),
 					COALESCE(trial_end::text, ''),
+					COALESCE(last_payment_status, ''),
+					COALESCE(last_payment_failed_at::text, ''),
+					dunning_attempt_count::text,
+					COALESCE(checkout_session_id, ''),
+					COALESCE(checkout_completed_at::text, ''),
 					field_authority::text,
+					manual_overrides::text,
 					projection_version,
 					reducer_version,
 					source_event_id,
 					source_ingestion_version,
-					event_occurred_at::text
+					event_occurred_at::text,
+					COALESCE(subscription_event_occurred_at::text, ''),
+					COALESCE(payment_event_occurred_at::text, ''),
+					COALESCE(polled_at::text, '')
 				),
 				E'\n' ORDER BY stripe_subscription_id, event_occurred_at, source_event_id
 			), '')) AS value
@@ -628,6 +751,33 @@ func touchCursorHeartbeat(ctx context.Context, db *sql.DB, epochID string) error
 	return err
 }
 
+// activateVersion lets subscription_current_state serve this version once
+// its replay has caught up: the row is only inserted while no ledger row
+// the reducer reads lies past the version's cursor. Backfill can stop at
+// its upper bound short of that; tail mode activates on its first idle
+// batch. Idempotent.
+func activateVersion(ctx context.Context, db *sql.DB) error {
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO subscription_reducer_activations (reducer_version, cursor_received_at)
+		SELECT c.reducer_version, c.cursor_received_at
+		FROM reducer_cursors c
+		WHERE c.reducer_name = $1 AND c.reducer_version = $2
+		  AND NOT EXISTS (
+			SELECT 1 FROM stripe_event_ledger l
+			WHERE l.verify_outcome IN ('primary', 'fallback', 'connect', 'per_account', 'polling', 'manual')
+			  AND (
+				l.received_at > c.cursor_received_at
+				OR (
+					l.received_at = c.cursor_received_at
+					AND (c.cursor_event_id IS NULL OR l.id > c.cursor_event_id)
+				)
+			  )
+		  )
+		ON CONFLICT (reducer_version) DO NOTHING
+	`, ReducerName, ReducerVersion)
+	return err
+}
+
 // advanceCursorOnly is used when an event is skipped (unhandled type, no
 // workspace, etc.) — we still want the cursor to advance so we don't
 // reread the same skipped event forever.
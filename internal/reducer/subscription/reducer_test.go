@@ -1,22 +1,25 @@
 package subscription
 
 import (
+	"encoding/json"
 	"fmt"
 	"testing"
 	"time"
 
 	"payment-node/internal/reducer/rac"
+	"payment-node/internal/reducer/rac/gen"
 )
 
 // racAdapter wraps the package's pure merge function into the
-// rac.Reducer[State, *Event] interface. The adapter fixes the EventSource
-// to webhook (the only source the v1 reducer consumes) and drops conflicts
-// — RAC assertions concern the projection chain, not operational
-// observations. Conflict generation is tested separately below.
+// rac.Reducer[State, *Event] interface. The adapter derives the
+// EventSource from the event type (the reducer derives it from the ledger
+// row's verify_outcome) and drops conflicts — RAC assertions concern the
+// projection chain, not operational observations. Conflict generation is
+// tested separately below.
 type racAdapter struct{}
 
 func (racAdapter) Merge(current *State, event *Event) *State {
-	result := Merge(current, event, EventSourceWebhook)
+	result := Merge(current, event, racSource(event))
 	if result.Skipped {
 		return nil
 	}
@@ -41,17 +44,33 @@ func (racAdapter) Equal(a, b *State) bool {
 		a.CancelAtPeriodEnd == b.CancelAtPeriodEnd &&
 		timePtrEqual(a.CanceledAt, b.CanceledAt) &&
 		timePtrEqual(a.TrialStart, b.TrialStart) &&
-		timePtrEqual(a.TrialEnd, b.TrialEnd)
+		timePtrEqual(a.TrialEnd, b.TrialEnd) &&
+		a.LastPaymentStatus == b.LastPaymentStatus &&
+		timePtrEqual(a.LastPaymentFailedAt, b.LastPaymentFailedAt) &&
+		a.DunningAttemptCount == b.DunningAttemptCount &&
+		a.CheckoutSessionID == b.CheckoutSessionID &&
+		timePtrEqual(a.CheckoutCompletedAt, b.CheckoutCompletedAt)
 }
 
 func (racAdapter) Describe(s *State) string {
 	if s == nil {
 		return "<nil>"
 	}
-	return fmt.Sprintf("State{sub=%s status=%s period=[%v,%v] cancel_at_period_end=%v occurred=%v}",
-		s.StripeSubscriptionID, s.Status,
+	return fmt.Sprintf("State{sub=%s ws=%s status=%s period=[%v,%v] cancel_at_period_end=%v payment=%s failed_at=%v dunning=%d checkout=%s occurred=%v}",
+		s.StripeSubscriptionID, s.WorkspaceID, s.Status,
 		s.CurrentPeriodStart, s.CurrentPeriodEnd,
-		s.CancelAtPeriodEnd, s.EventOccurredAt)
+		s.CancelAtPeriodEnd, s.LastPaymentStatus, s.LastPaymentFailedAt,
+		s.DunningAttemptCount, s.CheckoutSessionID, s.EventOccurredAt)
+}
+
+func racSource(event *Event) EventSource {
+	switch event.EventType {
+	case PolledEventType:
+		return EventSourcePolling
+	case ManualEventType:
+		return EventSourceManual
+	}
+	return EventSourceWebhook
 }
 
 func timePtrEqual(a, b *time.Time) bool {
@@ -126,6 +145,171 @@ func happyPathEvents() []*Event {
 	}
 }
 
+// invoiceFixtureEvent builds an invoice payment event for a subscription.
+func invoiceFixtureEvent(subID, eventType string, occurredAt time.Time, attemptCount int) *Event {
+	return &Event{
+		LedgerEventID:          fmt.Sprintf("ledger_%s_%d", eventType, occurredAt.Unix()),
+		SourceEventID:          fmt.Sprintf("evt_%s_%d", eventType, occurredAt.Unix()),
+		SourceIngestionVersion: "test-ingestion",
+		EventType:              eventType,
+		OccurredAt:             occurredAt.UTC(),
+		WorkspaceID:            "ws-fixture",
+		Invoice: &StripeInvoice{
+			ID:           fmt.Sprintf("in_%d", occurredAt.Unix()),
+			Subscription: subID,
+			AttemptCount: attemptCount,
+		},
+	}
+}
+
+// checkoutFixtureEvent builds a checkout.session.completed event.
+func checkoutFixtureEvent(subID, sessionID string, occurredAt time.Time) *Event {
+	return &Event{
+		LedgerEventID:          fmt.Sprintf("ledger_checkout_%d", occurredAt.Unix()),
+		SourceEventID:          fmt.Sprintf("evt_checkout_%d", occurredAt.Unix()),
+		SourceIngestionVersion: "test-ingestion",
+		EventType:              "checkout.session.completed",
+		OccurredAt:             occurredAt.UTC(),
+		WorkspaceID:            "ws-fixture",
+		CheckoutSession: &StripeCheckoutSession{
+			ID:                sessionID,
+			Mode:              "subscription",
+			Subscription:      subID,
+			ClientReferenceID: "ws-fixture",
+		},
+	}
+}
+
+// dunningPathEvents interleaves subscription, invoice, and checkout events
+// for one subscription: checkout → trialing → active → two failed
+// payments (past_due) → recovery → a later failure that then needs
+// customer action.
+//
+// The newest event overall is a subscription event, while the newest
+// failure is older than the newest payment outcome — both shapes a
+// single row-level watermark would get wrong under permutation.
+func dunningPathEvents() []*Event {
+	subID := "sub_test_dunning"
+	base := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	periodEnd := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	return []*Event{
+		checkoutFixtureEvent(subID, "cs_test_1", base),
+		fixtureEvent(subID, "trialing", base.Add(time.Minute), periodEnd),
+		invoiceFixtureEvent(subID, "invoice.paid", base.Add(2*time.Minute), 1),
+		fixtureEvent(subID, "active", base.Add(24*time.Hour), periodEnd),
+		invoiceFixtureEvent(subID, "invoice.payment_failed", base.Add(48*time.Hour), 1),
+		fixtureEvent(subID, "past_due", base.Add(48*time.Hour+time.Minute), periodEnd),
+		invoiceFixtureEvent(subID, "invoice.payment_failed", base.Add(72*time.Hour), 2),
+		invoiceFixtureEvent(subID, "invoice.payment_action_required", base.Add(80*time.Hour), 3),
+		invoiceFixtureEvent(subID, "invoice.payment_succeeded", base.Add(96*time.Hour), 3),
+		fixtureEvent(subID, "active", base.Add(96*time.Hour+time.Minute), periodEnd),
+		checkoutFixtureEvent(subID, "cs_test_2", base.Add(100*time.Hour)),
+		invoiceFixtureEvent(subID, "invoice.payment_failed", base.Add(120*time.Hour), 1),
+		invoiceFixtureEvent(subID, "invoice.payment_action_required", base.Add(122*time.Hour), 2),
+		fixtureEvent(subID, "past_due", base.Add(123*time.Hour), periodEnd),
+	}
+}
+
+// polledFixtureEvent builds a synthetic polled snapshot, as the polling
+// worker writes it.
+func polledFixtureEvent(subID, status string, polledAt time.Time, periodEnd time.Time) *Event {
+	e := fixtureEvent(subID, status, polledAt, periodEnd)
+	e.EventType = PolledEventType
+	e.LedgerEventID = fmt.Sprintf("ledger_poll_%s_%d", status, polledAt.Unix())
+	e.SourceEventID = fmt.Sprintf("%s%s_%s_%d", PolledEventIDPrefix, subID, status, polledAt.Unix())
+	return e
+}
+
+// pollingPathEvents interleaves webhook deliveries with polled snapshots.
+// The webhook that moved the subscription to past_due was missed; the poll
+// at +60h fills it in, and the webhook at +72h is newer and wins. The
+// latest poll (+84h) is superseded by the cancellation webhook at +90h, so
+// the final row is the cancellation throughout: status, canceled_at and
+// cancel_at_period_end.
+func pollingPathEvents() []*Event {
+	subID := "sub_test_polling"
+	base := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	periodEnd := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	canceledAt := base.Add(90 * time.Hour).Unix()
+	webhookCancel := fixtureEvent(subID, "canceled", base.Add(90*time.Hour), periodEnd)
+	webhookCancel.Subscription.CanceledAt = &canceledAt
+
+	lastPoll := polledFixtureEvent(subID, "past_due", base.Add(84*time.Hour), periodEnd)
+	lastPoll.Subscription.CancelAtPeriodEnd = true
+
+	return []*Event{
+		fixtureEvent(subID, "trialing", base, periodEnd),
+		fixtureEvent(subID, "active", base.Add(24*time.Hour), periodEnd),
+		polledFixtureEvent(subID, "active", base.Add(36*time.Hour), periodEnd),
+		fixtureEvent(subID, "past_due", base.Add(48*time.Hour), periodEnd),
+		polledFixtureEvent(subID, "past_due", base.Add(60*time.Hour), periodEnd),
+		fixtureEvent(subID, "active", base.Add(72*time.Hour), periodEnd),
+		lastPoll,
+		webhookCancel,
+		invoiceFixtureEvent(subID, "invoice.payment_failed", base.Add(50*time.Hour), 2),
+	}
+}
+
+// manualFixtureEvent builds an operator correction submitted at
+// correctedAt. Values are marshaled to JSON as the corrections endpoint
+// would store them.
+func manualFixtureEvent(subID string, correctedAt time.Time, fields map[string]any) *Event {
+	raw := make(map[string]json.RawMessage, len(fields))
+	for field, v := range fields {
+		b, err := json.Marshal(v)
+		if err != nil {
+			panic(err)
+		}
+		raw[field] = b
+	}
+	return &Event{
+		LedgerEventID:          fmt.Sprintf("ledger_manual_%d", correctedAt.Unix()),
+		SourceEventID:          fmt.Sprintf("%s%s_%d", ManualEventIDPrefix, subID, correctedAt.Unix()),
+		SourceIngestionVersion: "test-manual",
+		EventType:              ManualEventType,
+		OccurredAt:             correctedAt.UTC(),
+		WorkspaceID:            "ws-fixture",
+		Correction: &ManualCorrection{
+			Subscription: subID,
+			Fields:       raw,
+			Operator:     "ops@example.com",
+			Reason:       "fixture",
+		},
+	}
+}
+
+// manualPathEvents mixes operator corrections into webhook, polling and
+// invoice input:
+//   - status is corrected twice; the newer correction (unpaid at +54h)
+//     holds because no subscription event is newer than it.
+//   - cancel_at_period_end is corrected at +30h, older than the poll at
+//     +36h, so the poll's value wins whichever arrives first.
+//   - trial_end is corrected at +44h and released by the webhook at +48h.
+//   - the payment correction at +70h holds against the older failure.
+func manualPathEvents() []*Event {
+	subID := "sub_test_manual"
+	base := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	periodEnd := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	return []*Event{
+		fixtureEvent(subID, "trialing", base, periodEnd),
+		fixtureEvent(subID, "active", base.Add(24*time.Hour), periodEnd),
+		manualFixtureEvent(subID, base.Add(30*time.Hour), map[string]any{"cancel_at_period_end": true}),
+		polledFixtureEvent(subID, "active", base.Add(36*time.Hour), periodEnd),
+		manualFixtureEvent(subID, base.Add(44*time.Hour), map[string]any{"trial_end": base.Add(100 * time.Hour)}),
+		fixtureEvent(subID, "active", base.Add(48*time.Hour), periodEnd),
+		invoiceFixtureEvent(subID, "invoice.payment_failed", base.Add(50*time.Hour), 2),
+		manualFixtureEvent(subID, base.Add(52*time.Hour), map[string]any{"status": "past_due"}),
+		manualFixtureEvent(subID, base.Add(54*time.Hour), map[string]any{"status": "unpaid"}),
+		manualFixtureEvent(subID, base.Add(70*time.Hour), map[string]any{
+			"last_payment_status":   PaymentStatusSucceeded,
+			"dunning_attempt_count": 0,
+		}),
+	}
+}
+
 // ---- RAC assertions on the subscription reducer -------------------------
 
 func TestRAC_PermutationInvariance(t *testing.T) {
@@ -144,8 +328,210 @@ func TestRAC_PartialReplayEquivalence(t *testing.T) {
 	rac.AssertPartialReplayEquivalence(t, racAdapter{}, happyPathEvents(), 3)
 }
 
-func TestRAC_FutureVersionCompatibilityPlaceholder(t *testing.T) {
-	rac.AssertFutureVersionCompatibility(t, racAdapter{}, happyPathEvents())
+func TestRAC_PaymentAndCheckout_PermutationInvariance(t *testing.T) {
+	rac.AssertPermutationInvariance(t, racAdapter{}, dunningPathEvents(), 25)
+}
+
+func TestRAC_PaymentAndCheckout_Idempotency(t *testing.T) {
+	rac.AssertIdempotency(t, racAdapter{}, dunningPathEvents())
+}
+
+func TestRAC_PaymentAndCheckout_Determinism(t *testing.T) {
+	rac.AssertDeterminism(t, racAdapter{}, dunningPathEvents(), 5)
+}
+
+func TestRAC_PaymentAndCheckout_PartialReplayEquivalence(t *testing.T) {
+	rac.AssertPartialReplayEquivalence(t, racAdapter{}, dunningPathEvents(), 5)
+}
+
+func TestRAC_Polling_PermutationInvariance(t *testing.T) {
+	rac.AssertPermutationInvariance(t, racAdapter{}, pollingPathEvents(), 25)
+}
+
+func TestRAC_Polling_Idempotency(t *testing.T) {
+	rac.AssertIdempotency(t, racAdapter{}, pollingPathEvents())
+}
+
+func TestRAC_Polling_Determinism(t *testing.T) {
+	rac.AssertDeterminism(t, racAdapter{}, pollingPathEvents(), 5)
+}
+
+func TestRAC_Polling_PartialReplayEquivalence(t *testing.T) {
+	rac.AssertPartialReplayEquivalence(t, racAdapter{}, pollingPathEvents(), 4)
+}
+
+func TestRAC_Manual_PermutationInvariance(t *testing.T) {
+	rac.AssertPermutationInvariance(t, racAdapter{}, manualPathEvents(), 25)
+}
+
+func TestRAC_Manual_Idempotency(t *testing.T) {
+	rac.AssertIdempotency(t, racAdapter{}, manualPathEvents())
+}
+
+func TestRAC_Manual_Determinism(t *testing.T) {
+	rac.AssertDeterminism(t, racAdapter{}, manualPathEvents(), 5)
+}
+
+func TestRAC_Manual_PartialReplayEquivalence(t *testing.T) {
+	rac.AssertPartialReplayEquivalence(t, racAdapter{}, manualPathEvents(), 5)
+}
+
+// genEvents parses generated deliveries exactly as the reducer parses
+// ledger rows, dropping the ones it would skip.
+func genEvents(deliveries []gen.Event) []*Event {
+	out := make([]*Event, 0, len(deliveries))
+	for i, d := range deliveries {
+		event, _, ok := parseLedgerEvent(ledgerEventOnDisk{
+			LedgerID:         fmt.Sprintf("ledger_gen_%d", i),
+			StripeEventID:    d.ID,
+			Payload:          string(d.JSON()),
+			IngestionVersion: "test-ingestion",
+			VerifyOutcome:    "primary",
+		})
+		if !ok {
+			continue
+		}
+		event.WorkspaceID = "ws-gen"
+		out = append(out, event)
+	}
+	return out
+}
+
+// TestRAC_GeneratedLifecycles checks every RAC property over generated
+// lifecycles with duplicate, late, and created-less deliveries. A
+// failure reports the seed and the shrunk event list.
+func TestRAC_GeneratedLifecycles(t *testing.T) {
+	cases := 300
+	if testing.Short() {
+		cases = 50
+	}
+	gen.Check(t, cases,
+		func(seed int64) []gen.Event { return gen.Lifecycle(seed, gen.DefaultOptions()) },
+		func(deliveries []gen.Event) error {
+			return rac.CheckAll[State, *Event](racAdapter{}, genEvents(deliveries))
+		})
+}
+
+// identityPolicy declares no changes: the policy for comparing the
+// current version against itself until its successor exists.
+var identityPolicy = rac.MigrationPolicy[State]{
+	Name:   ReducerVersion + " -> " + ReducerVersion,
+	Fields: racFields,
+}
+
+func TestRAC_FutureVersionCompatibility(t *testing.T) {
+	for name, events := range map[string][]*Event{
+		"happy":   happyPathEvents(),
+		"dunning": dunningPathEvents(),
+		"polling": pollingPathEvents(),
+		"manual":  manualPathEvents(),
+	} {
+		t.Run(name, func(t *testing.T) {
+			rac.AssertFutureVersionCompatibility[State, *Event](t, racAdapter{}, racAdapter{}, identityPolicy, events)
+		})
+	}
+}
+
+// racFields breaks a State into the projection's interpreted columns for
+// cross-version comparison. Timestamps are normalized to UTC strings.
+func racFields(s *State) map[string]any {
+	ts := func(t *time.Time) any {
+		if t == nil {
+			return nil
+		}
+		return t.UTC().Format(time.RFC3339Nano)
+	}
+	authority := map[string]string{}
+	for field, src := range s.FieldAuthority {
+		authority[field] = string(src)
+	}
+	overrides := map[string]string{}
+	for field, at := range s.ManualOverrides {
+		overrides[field] = at.UTC().Format(time.RFC3339Nano)
+	}
+	return map[string]any{
+		"workspace_id":           s.WorkspaceID,
+		"status":                 s.Status,
+		"current_period_start":   ts(s.CurrentPeriodStart),
+		"current_period_end":     ts(s.CurrentPeriodEnd),
+		"cancel_at_period_end":   s.CancelAtPeriodEnd,
+		"canceled_at":            ts(s.CanceledAt),
+		"trial_start":            ts(s.TrialStart),
+		"trial_end":              ts(s.TrialEnd),
+		"last_payment_status":    s.LastPaymentStatus,
+		"last_payment_failed_at": ts(s.LastPaymentFailedAt),
+		"dunning_attempt_count":  s.DunningAttemptCount,
+		"checkout_session_id":    s.CheckoutSessionID,
+		"checkout_completed_at":  ts(s.CheckoutCompletedAt),
+		"field_authority":        authority,
+		"manual_overrides":       overrides,
+	}
+}
+
+// unpaidAdapter stands in for a successor version that reports past_due
+// subscriptions as unpaid.
+type unpaidAdapter struct{ racAdapter }
+
+func (a unpaidAdapter) Merge(current *State, event *Event) *State {
+	next := a.racAdapter.Merge(current, event)
+	if next == nil || next.Status != "past_due" {
+		return next
+	}
+	renamed := *next
+	renamed.Status = "unpaid"
+	return &renamed
+}
+
+func TestRAC_FutureVersionCompatibilityPolicy(t *testing.T) {
+	events := dunningPathEvents()
+	compare := func(policy rac.MigrationPolicy[State]) []rac.VersionDiff {
+		t.Helper()
+		diffs, err := rac.CompareVersions[State, *Event](racAdapter{}, unpaidAdapter{}, policy, events)
+		if err != nil {
+			t.Fatalf("CompareVersions(%s): %v", policy.Name, err)
+		}
+		return diffs
+	}
+
+	diffs := compare(rac.MigrationPolicy[State]{Name: "undeclared", Fields: racFields})
+	if len(diffs) != 1 || diffs[0].Field != "status" || diffs[0].Prev != "past_due" || diffs[0].Next != "unpaid" {
+		t.Fatalf("undeclared change: diffs = %v", diffs)
+	}
+	if got := diffs[0].String(); got != `status: vN "past_due", vN+1 "unpaid"` {
+		t.Errorf("diff renders as %q", got)
+	}
+
+	if diffs := compare(rac.MigrationPolicy[State]{Name: "changed", Fields: racFields, Changed: []string{"status"}}); len(diffs) != 0 {
+		t.Errorf("declared change: diffs = %v", diffs)
+	}
+
+	rename := func(prev *State) any {
+		if prev.Status == "past_due" {
+			return "unpaid"
+		}
+		return prev.Status
+	}
+	if diffs := compare(rac.MigrationPolicy[State]{Name: "transform", Fields: racFields,
+		Transforms: map[string]func(*State) any{"status": rename}}); len(diffs) != 0 {
+		t.Errorf("matching transform: diffs = %v", diffs)
+	}
+
+	wrong := func(*State) any { return "canceled" }
+	diffs = compare(rac.MigrationPolicy[State]{Name: "wrong transform", Fields: racFields,
+		Transforms: map[string]func(*State) any{"status": wrong}})
+	if len(diffs) != 1 || !diffs[0].Transformed || diffs[0].Want != "canceled" {
+		t.Errorf("mismatched transform: diffs = %v", diffs)
+	}
+
+	for name, policy := range map[string]rac.MigrationPolicy[State]{
+		"no fields":     {Name: "no fields"},
+		"unknown field": {Name: "unknown", Fields: racFields, Changed: []string{"plan_id"}},
+		"both":          {Name: "both", Fields: racFields, Changed: []string{"status"}, Transforms: map[string]func(*State) any{"status": rename}},
+	} {
+		if _, err := rac.CompareVersions[State, *Event](racAdapter{}, unpaidAdapter{}, policy, events); err == nil {
+			t.Errorf("%s: expected a policy error", name)
+		}
+	}
 }
 
 // ---- Conflict-generation tests (not part of RAC; reducer-specific) ------
@@ -227,3 +613,427 @@ func TestMergeInvariantViolationFromTerminalState(t *testing.T) {
 		t.Fatalf("expected merge_invariant_violation conflict; got %v", result.Conflicts)
 	}
 }
+
+// ---- Payment and checkout interpretation --------------------------------
+
+// TestDunningPathFinalState pins the interpreted outcome of the dunning
+// fixture so the RAC properties above converge on the right answer, not
+// merely the same one.
+func TestDunningPathFinalState(t *testing.T) {
+	base := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	final := rac.FoldEvents[State, *Event](racAdapter{}, dunningPathEvents())
+	if final == nil {
+		t.Fatal("expected a final state")
+	}
+	if final.Status != "past_due" {
+		t.Errorf("status = %q, want past_due", final.Status)
+	}
+	if final.LastPaymentStatus != PaymentStatusActionRequired {
+		t.Errorf("last_payment_status = %q, want action_required", final.LastPaymentStatus)
+	}
+	if final.DunningAttemptCount != 2 {
+		t.Errorf("dunning_attempt_count = %d, want 2", final.DunningAttemptCount)
+	}
+	if want := base.Add(120 * time.Hour); !timePtrEqual(final.LastPaymentFailedAt, &want) {
+		t.Errorf("last_payment_failed_at = %v, want %v", final.LastPaymentFailedAt, want)
+	}
+	if final.CheckoutSessionID != "cs_test_2" {
+		t.Errorf("checkout_session_id = %q, want cs_test_2", final.CheckoutSessionID)
+	}
+	if want := base.Add(123 * time.Hour); !final.EventOccurredAt.Equal(want) {
+		t.Errorf("event_occurred_at = %v, want %v", final.EventOccurredAt, want)
+	}
+}
+
+// TestPaymentSucceededResetsDunning verifies a successful payment clears
+// the dunning count but keeps the last failure timestamp.
+func TestPaymentSucceededResetsDunning(t *testing.T) {
+	subID := "sub_recovered"
+	t0 := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	failed := Merge(nil, invoiceFixtureEvent(subID, "invoice.payment_failed", t0, 3), EventSourceWebhook).Next
+	if failed == nil || failed.DunningAttemptCount != 3 || failed.LastPaymentStatus != PaymentStatusFailed {
+		t.Fatalf("expected failed state with 3 attempts; got %+v", failed)
+	}
+
+	result := Merge(failed, invoiceFixtureEvent(subID, "invoice.payment_succeeded", t0.Add(time.Hour), 4), EventSourceWebhook)
+	if result.Skipped || result.Next == nil {
+		t.Fatal("payment_succeeded should produce a projection")
+	}
+	if result.Next.DunningAttemptCount != 0 || result.Next.LastPaymentStatus != PaymentStatusSucceeded {
+		t.Fatalf("expected succeeded with dunning reset; got %+v", result.Next)
+	}
+	if !timePtrEqual(result.Next.LastPaymentFailedAt, &t0) {
+		t.Fatalf("last_payment_failed_at = %v, want %v", result.Next.LastPaymentFailedAt, t0)
+	}
+	if result.Next.FieldAuthority["dunning_attempt_count"] != AuthorityWebhook {
+		t.Fatalf("expected webhook authority on dunning_attempt_count; got %v", result.Next.FieldAuthority)
+	}
+}
+
+// TestLatePaymentFailedAdvancesFailedAt verifies a late payment_failed
+// records the late-event conflict, leaves the newer outcome in place, and
+// still advances last_payment_failed_at.
+func TestLatePaymentFailedAdvancesFailedAt(t *testing.T) {
+	subID := "sub_late_failure"
+	t0 := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	current := Merge(nil, invoiceFixtureEvent(subID, "invoice.payment_succeeded", t0, 2), EventSourceWebhook).Next
+	late := invoiceFixtureEvent(subID, "invoice.payment_failed", t0.Add(-time.Hour), 1)
+	result := Merge(current, late, EventSourceWebhook)
+
+	if result.Next == nil {
+		t.Fatal("late failure newer than the recorded failure should produce a projection")
+	}
+	if len(result.Conflicts) == 0 || result.Conflicts[0].Type != ConflictLateEventDetected {
+		t.Fatalf("expected late_event_detected conflict; got %v", result.Conflicts)
+	}
+	if result.Next.LastPaymentStatus != PaymentStatusSucceeded || result.Next.DunningAttemptCount != 0 {
+		t.Fatalf("late failure must not override the newer outcome; got %+v", result.Next)
+	}
+	if want := t0.Add(-time.Hour); !timePtrEqual(result.Next.LastPaymentFailedAt, &want) {
+		t.Fatalf("last_payment_failed_at = %v, want %v", result.Next.LastPaymentFailedAt, want)
+	}
+
+	// Replaying the same late failure is now a clean skip.
+	again := Merge(result.Next, late, EventSourceWebhook)
+	if !again.Skipped {
+		t.Fatal("repeated late failure should be skipped")
+	}
+}
+
+// TestPaymentEventNotLateAgainstNewerSubscriptionEvent verifies event
+// groups are ordered independently: an invoice older than the newest
+// subscription event is still applied.
+func TestPaymentEventNotLateAgainstNewerSubscriptionEvent(t *testing.T) {
+	subID := "sub_groups"
+	t0 := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	current := Merge(nil, fixtureEvent(subID, "past_due", t0, t0.AddDate(0, 1, 0)), EventSourceWebhook).Next
+	result := Merge(current, invoiceFixtureEvent(subID, "invoice.payment_failed", t0.Add(-time.Minute), 2), EventSourceWebhook)
+
+	if result.Skipped || result.Next == nil {
+		t.Fatal("invoice event should not be late against a subscription event")
+	}
+	if len(result.Conflicts) != 0 {
+		t.Fatalf("expected no conflicts; got %v", result.Conflicts)
+	}
+	if result.Next.Status != "past_due" || result.Next.DunningAttemptCount != 2 {
+		t.Fatalf("expected carried status and applied dunning count; got %+v", result.Next)
+	}
+	if !result.Next.EventOccurredAt.Equal(t0) {
+		t.Fatalf("event_occurred_at should stay at the newest event %v; got %v", t0, result.Next.EventOccurredAt)
+	}
+}
+
+// TestEventsWithoutSubscriptionAreSkipped covers one-off invoices and
+// payment-mode checkout sessions.
+func TestEventsWithoutSubscriptionAreSkipped(t *testing.T) {
+	t0 := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	oneOff := invoiceFixtureEvent("", "invoice.payment_failed", t0, 1)
+	if result := Merge(nil, oneOff, EventSourceWebhook); !result.Skipped {
+		t.Fatal("one-off invoice should be skipped")
+	}
+
+	payment := checkoutFixtureEvent("", "cs_payment", t0)
+	payment.CheckoutSession.Mode = "payment"
+	if result := Merge(nil, payment, EventSourceWebhook); !result.Skipped {
+		t.Fatal("payment-mode checkout should be skipped")
+	}
+}
+
+// TestInvoiceSubscriptionFromParent verifies the newer invoice shape,
+// where the subscription reference lives under parent.subscription_details.
+func TestInvoiceSubscriptionFromParent(t *testing.T) {
+	var inv StripeInvoice
+	raw := `{"id":"in_1","attempt_count":2,"parent":{"subscription_details":{"subscription":"sub_parent"}}}`
+	if err := json.Unmarshal([]byte(raw), &inv); err != nil {
+		t.Fatal(err)
+	}
+	if got := inv.SubscriptionID(); got != "sub_parent" {
+		t.Fatalf("SubscriptionID() = %q, want sub_parent", got)
+	}
+}
+
+// ---- Polling arbitration ----------------------------------------------
+
+// TestPollingPathFinalState pins the arbitration outcome of the polling
+// fixture.
+func TestPollingPathFinalState(t *testing.T) {
+	base := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	final := rac.FoldEvents[State, *Event](racAdapter{}, pollingPathEvents())
+	if final == nil {
+		t.Fatal("expected a final state")
+	}
+	if final.Status != "canceled" || final.FieldAuthority["status"] != AuthorityWebhook {
+		t.Errorf("status = %q (%s), want canceled from webhook", final.Status, final.FieldAuthority["status"])
+	}
+	if final.CancelAtPeriodEnd || final.FieldAuthority["cancel_at_period_end"] != AuthorityWebhook {
+		t.Errorf("cancel_at_period_end = %v (%s), want false from the cancellation webhook",
+			final.CancelAtPeriodEnd, final.FieldAuthority["cancel_at_period_end"])
+	}
+	if want := base.Add(90 * time.Hour); !timePtrEqual(final.CanceledAt, &want) || final.FieldAuthority["canceled_at"] != AuthorityWebhook {
+		t.Errorf("canceled_at = %v (%s), want %v from webhook", final.CanceledAt, final.FieldAuthority["canceled_at"], want)
+	}
+	if want := base.Add(84 * time.Hour); !timePtrEqual(final.PolledAt, &want) {
+		t.Errorf("polled_at = %v, want %v", final.PolledAt, want)
+	}
+}
+
+// TestPollFillsMissedWebhook verifies a poll newer than the last webhook
+// replaces the whole subscription group, and that a webhook newer than
+// the poll replaces it in turn.
+func TestPollFillsMissedWebhook(t *testing.T) {
+	subID := "sub_missed"
+	t0 := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	periodEnd := t0.AddDate(0, 1, 0)
+
+	current := Merge(nil, fixtureEvent(subID, "active", t0, periodEnd), EventSourceWebhook).Next
+	polled := Merge(current, polledFixtureEvent(subID, "past_due", t0.Add(time.Minute), periodEnd), EventSourcePolling)
+	if polled.Skipped || polled.Next.Status != "past_due" || polled.Next.FieldAuthority["status"] != AuthorityPolling {
+		t.Fatalf("newer poll should fill in the missed webhook; got %+v", polled.Next)
+	}
+
+	newer := Merge(polled.Next, fixtureEvent(subID, "active", t0.Add(2*time.Minute), periodEnd), EventSourceWebhook)
+	if newer.Skipped || newer.Next.Status != "active" || newer.Next.FieldAuthority["status"] != AuthorityWebhook {
+		t.Fatalf("newer webhook should win over the poll; got %+v", newer.Next)
+	}
+}
+
+// TestWebhookAfterPoll verifies a webhook newer than the last poll writes
+// the whole subscription group, so the row stays consistent, and that a
+// webhook older than the poll is late.
+func TestWebhookAfterPoll(t *testing.T) {
+	subID := "sub_webhook_after_poll"
+	t0 := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	periodEnd := t0.AddDate(0, 1, 0)
+
+	current := Merge(nil, polledFixtureEvent(subID, "active", t0, periodEnd), EventSourcePolling).Next
+
+	canceledAt := t0.Add(time.Hour).Unix()
+	cancel := fixtureEvent(subID, "canceled", t0.Add(time.Hour), periodEnd)
+	cancel.Subscription.CanceledAt = &canceledAt
+	result := Merge(current, cancel, EventSourceWebhook)
+	if result.Skipped {
+		t.Fatal("webhook newer than the poll should apply")
+	}
+	next := result.Next
+	if next.Status != "canceled" || next.CanceledAt == nil || next.FieldAuthority["status"] != AuthorityWebhook {
+		t.Fatalf("got status=%q canceled_at=%v authority=%v; want canceled with canceled_at from webhook",
+			next.Status, next.CanceledAt, next.FieldAuthority)
+	}
+
+	stale := Merge(next, fixtureEvent(subID, "active", t0.Add(-time.Minute), periodEnd), EventSourceWebhook)
+	if !stale.Skipped || len(stale.Conflicts) == 0 || stale.Conflicts[0].Type != ConflictLateEventDetected {
+		t.Fatalf("webhook older than the newest observation should be late; got %+v", stale)
+	}
+	stalePoll := Merge(next, polledFixtureEvent(subID, "active", t0.Add(30*time.Minute), periodEnd), EventSourcePolling)
+	if !stalePoll.Skipped || stalePoll.Conflicts[0].Type != ConflictLateEventDetected {
+		t.Fatalf("poll older than the newest webhook should be late; got %+v", stalePoll)
+	}
+}
+
+// TestPollTiedWithWebhook verifies the webhook wins a tie in either order.
+func TestPollTiedWithWebhook(t *testing.T) {
+	subID := "sub_tie"
+	t0 := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	periodEnd := t0.AddDate(0, 1, 0)
+	webhook := fixtureEvent(subID, "past_due", t0, periodEnd)
+	poll := polledFixtureEvent(subID, "active", t0, periodEnd)
+
+	afterWebhook := Merge(Merge(nil, webhook, EventSourceWebhook).Next, poll, EventSourcePolling)
+	if !afterWebhook.Skipped || afterWebhook.Conflicts[0].Type != ConflictChronologyConflict {
+		t.Fatalf("poll tied with a webhook should be skipped with a chronology conflict; got %+v", afterWebhook)
+	}
+
+	afterPoll := Merge(Merge(nil, poll, EventSourcePolling).Next, webhook, EventSourceWebhook)
+	if afterPoll.Skipped || afterPoll.Next.Status != "past_due" {
+		t.Fatalf("webhook tied with a poll should apply; got %+v", afterPoll)
+	}
+}
+
+func TestEventSourceForOutcome(t *testing.T) {
+	if EventSourceForOutcome(PollingVerifyOutcome) != EventSourcePolling {
+		t.Error("polling outcome should map to EventSourcePolling")
+	}
+	if EventSourceForOutcome(ManualVerifyOutcome) != EventSourceManual {
+		t.Error("manual outcome should map to EventSourceManual")
+	}
+	for _, outcome := range []string{"primary", "fallback", "connect", "per_account"} {
+		if EventSourceForOutcome(outcome) != EventSourceWebhook {
+			t.Errorf("%s should map to EventSourceWebhook", outcome)
+		}
+	}
+}
+
+// ---- Manual operator corrections ----------------------------------------
+
+// TestManualPathFinalState pins the arbitration outcome of the manual
+// fixture.
+func TestManualPathFinalState(t *testing.T) {
+	base := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	final := rac.FoldEvents[State, *Event](racAdapter{}, manualPathEvents())
+	if final == nil {
+		t.Fatal("expected a final state")
+	}
+	if final.Status != "unpaid" || final.FieldAuthority["status"] != AuthorityManual {
+		t.Errorf("status = %q (%s), want unpaid from manual", final.Status, final.FieldAuthority["status"])
+	}
+	if want := base.Add(54 * time.Hour); !final.ManualOverrides["status"].Equal(want) {
+		t.Errorf("status override at %v, want %v", final.ManualOverrides["status"], want)
+	}
+	if final.CancelAtPeriodEnd || final.FieldAuthority["cancel_at_period_end"] != AuthorityWebhook {
+		t.Errorf("cancel_at_period_end = %v (%s), want false from the newest webhook", final.CancelAtPeriodEnd, final.FieldAuthority["cancel_at_period_end"])
+	}
+	if final.TrialEnd != nil || final.FieldAuthority["trial_end"] != AuthorityWebhook {
+		t.Errorf("trial_end = %v (%s), want released to webhook", final.TrialEnd, final.FieldAuthority["trial_end"])
+	}
+	if final.LastPaymentStatus != PaymentStatusSucceeded || final.DunningAttemptCount != 0 {
+		t.Errorf("payment = %s/%d, want corrected succeeded/0", final.LastPaymentStatus, final.DunningAttemptCount)
+	}
+	if len(final.ManualOverrides) != 3 {
+		t.Errorf("overrides = %v, want status, last_payment_status, dunning_attempt_count", final.ManualOverrides)
+	}
+}
+
+// TestManualCorrectionHoldsUntilNewerReport verifies a correction survives
+// reports older than it from either source, and is released by a newer
+// report.
+func TestManualCorrectionHoldsUntilNewerReport(t *testing.T) {
+	subID := "sub_manual_hold"
+	t0 := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	periodEnd := t0.AddDate(0, 1, 0)
+
+	current := Merge(nil, polledFixtureEvent(subID, "active", t0, periodEnd), EventSourcePolling).Next
+	corrected := Merge(current, manualFixtureEvent(subID, t0.Add(time.Hour), map[string]any{"status": "past_due"}), EventSourceManual)
+	if corrected.Skipped || corrected.Next.Status != "past_due" || corrected.Next.FieldAuthority["status"] != AuthorityManual {
+		t.Fatalf("correction should apply; got %+v", corrected)
+	}
+
+	olderWebhook := Merge(corrected.Next, fixtureEvent(subID, "active", t0.Add(30*time.Minute), periodEnd), EventSourceWebhook)
+	if olderWebhook.Next.Status != "past_due" || olderWebhook.Next.FieldAuthority["status"] != AuthorityManual {
+		t.Fatalf("webhook older than the correction must not release it; got %+v", olderWebhook.Next)
+	}
+
+	olderPoll := Merge(olderWebhook.Next, polledFixtureEvent(subID, "active", t0.Add(45*time.Minute), periodEnd), EventSourcePolling)
+	if olderPoll.Next.Status != "past_due" || olderPoll.Next.FieldAuthority["status"] != AuthorityManual {
+		t.Fatalf("poll older than the correction must not release it; got %+v", olderPoll.Next)
+	}
+
+	newerWebhook := Merge(olderPoll.Next, fixtureEvent(subID, "canceled", t0.Add(3*time.Hour), periodEnd), EventSourceWebhook)
+	if newerWebhook.Next.Status != "canceled" || newerWebhook.Next.FieldAuthority["status"] != AuthorityWebhook {
+		t.Fatalf("newer webhook should release the correction; got %+v", newerWebhook.Next)
+	}
+	if _, held := newerWebhook.Next.ManualOverrides["status"]; held {
+		t.Fatal("released correction should leave manual_overrides")
+	}
+}
+
+// TestManualCorrectionLateFieldsRejected verifies a correction older than
+// a field's last authoritative report is rejected for that field only.
+func TestManualCorrectionLateFieldsRejected(t *testing.T) {
+	subID := "sub_manual_late"
+	t0 := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	periodEnd := t0.AddDate(0, 1, 0)
+
+	current := Merge(nil, fixtureEvent(subID, "active", t0.Add(-time.Hour), periodEnd), EventSourceWebhook).Next
+	current = Merge(current, invoiceFixtureEvent(subID, "invoice.payment_failed", t0.Add(time.Hour), 1), EventSourceWebhook).Next
+
+	result := Merge(current, manualFixtureEvent(subID, t0, map[string]any{
+		"status":              "past_due",
+		"last_payment_status": PaymentStatusSucceeded,
+	}), EventSourceManual)
+	if result.Skipped {
+		t.Fatal("status correction should apply: it is newer than the last subscription event")
+	}
+	if result.Next.Status != "past_due" || result.Next.LastPaymentStatus != PaymentStatusFailed {
+		t.Fatalf("got status=%q payment=%q", result.Next.Status, result.Next.LastPaymentStatus)
+	}
+	if len(result.Conflicts) != 1 || result.Conflicts[0].Type != ConflictLateEventDetected {
+		t.Fatalf("expected one late_event_detected conflict; got %+v", result.Conflicts)
+	}
+	if fields := result.Conflicts[0].Details["fields"].([]string); len(fields) != 1 || fields[0] != "last_payment_status" {
+		t.Fatalf("late fields = %v", fields)
+	}
+
+	// trial_end was last reported by the webhook at t0-1h.
+	allLate := Merge(current, manualFixtureEvent(subID, t0.Add(-2*time.Hour), map[string]any{"trial_end": nil}), EventSourceManual)
+	if !allLate.Skipped {
+		t.Fatal("a correction with no applicable fields should be skipped")
+	}
+}
+
+func TestManualCorrectionValidate(t *testing.T) {
+	valid := manualFixtureEvent("sub_v", time.Now(), map[string]any{
+		"status":               "active",
+		"cancel_at_period_end": false,
+		"canceled_at":          nil,
+		"current_period_end":   "2026-07-01T00:00:00Z",
+	}).Correction
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("valid correction rejected: %v", err)
+	}
+
+	cases := map[string]func(c *ManualCorrection){
+		"no operator":      func(c *ManualCorrection) { c.Operator = "" },
+		"no reason":        func(c *ManualCorrection) { c.Reason = "" },
+		"no fields":        func(c *ManualCorrection) { c.Fields = nil },
+		"unknown field":    func(c *ManualCorrection) { c.Fields["workspace_id"] = json.RawMessage(`"ws"`) },
+		"uncorrectable":    func(c *ManualCorrection) { c.Fields["checkout_session_id"] = json.RawMessage(`"cs_1"`) },
+		"ill-typed bool":   func(c *ManualCorrection) { c.Fields["cancel_at_period_end"] = json.RawMessage(`"yes"`) },
+		"bad timestamp":    func(c *ManualCorrection) { c.Fields["trial_end"] = json.RawMessage(`"tomorrow"`) },
+		"negative dunning": func(c *ManualCorrection) { c.Fields["dunning_attempt_count"] = json.RawMessage(`-1`) },
+		"payment status":   func(c *ManualCorrection) { c.Fields["last_payment_status"] = json.RawMessage(`"paid"`) },
+	}
+	for name, mutate := range cases {
+		c := manualFixtureEvent("sub_v", time.Now(), map[string]any{"status": "active"}).Correction
+		mutate(c)
+		if err := c.Validate(); err == nil {
+			t.Errorf("%s: expected a validation error", name)
+		}
+	}
+}
+
+// ---- Shadow replay diff ------------------------------------------------
+
+func TestDiffStates(t *testing.T) {
+	baseline := rac.FoldEvents[State, *Event](racAdapter{}, dunningPathEvents())
+	if baseline == nil {
+		t.Fatal("fixture produced no state")
+	}
+
+	same := *baseline
+	localEnd := baseline.CurrentPeriodEnd.In(time.FixedZone("EST", -5*3600))
+	same.CurrentPeriodEnd = &localEnd
+	if diffs := DiffStates(baseline, &same); len(diffs) != 0 {
+		t.Fatalf("equal states diff: %+v", diffs)
+	}
+
+	candidate := *baseline
+	candidate.Status = "unpaid"
+	candidate.CheckoutCompletedAt = nil
+	candidate.FieldAuthority = map[string]AuthoritySource{}
+	for field, src := range baseline.FieldAuthority {
+		candidate.FieldAuthority[field] = src
+	}
+	candidate.FieldAuthority["status"] = AuthorityPolling
+
+	diffs := DiffStates(baseline, &candidate)
+	got := map[string]FieldDiff{}
+	for _, d := range diffs {
+		got[d.Field] = d
+	}
+	if len(diffs) != 3 {
+		t.Fatalf("diffs = %+v, want status, checkout_completed_at and field_authority", diffs)
+	}
+	if d := got["status"]; d.Baseline != baseline.Status || d.Candidate != "unpaid" {
+		t.Errorf("status diff = %+v", d)
+	}
+	if d := got["checkout_completed_at"]; d.Baseline == nil || d.Candidate != nil {
+		t.Errorf("checkout_completed_at diff = %+v", d)
+	}
+	if _, ok := got["field_authority"]; !ok {
+		t.Errorf("field_authority change not reported")
+	}
+}
@@ -0,0 +1,512 @@
+package subscription
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+)
+
+// ShadowReducerName keys shadow replay epochs in replay_epochs, so they
+// never share a cursor or epoch history with the live reducer.
+const ShadowReducerName = "subscription_shadow"
+
+// ShadowOptions configures ReplayShadow.
+type ShadowOptions struct {
+	// BaselineVersion is the reducer_version whose projections the
+	// replay is compared against. Empty means the newest activated version
+	// present in subscription_projection — the one
+	// subscription_current_state serves.
+	BaselineVersion string
+
+	Logger *slog.Logger
+}
+
+// DiffKind classifies a subscription in the shadow diff report.
+type DiffKind string
+
+const (
+	// DiffChanged: both versions project the subscription, with
+	// different field values.
+	DiffChanged DiffKind = "changed"
+
+	// DiffOnlyBaseline: the baseline projects the subscription and the
+	// candidate does not.
+	DiffOnlyBaseline DiffKind = "only_baseline"
+
+	// DiffOnlyCandidate: the candidate projects a subscription the
+	// baseline does not.
+	DiffOnlyCandidate DiffKind = "only_candidate"
+)
+
+// FieldDiff is one interpreted field the two versions disagree on.
+// Values are rendered as they are stored (RFC3339 timestamps, nil for
+// NULL).
+type FieldDiff struct {
+	Field     string `json:"field"`
+	Baseline  any    `json:"baseline"`
+	Candidate any    `json:"candidate"`
+}
+
+// SubscriptionDiff is one subscription's entry in the diff report.
+type SubscriptionDiff struct {
+	StripeSubscriptionID string      `json:"stripe_subscription_id"`
+	Kind                 DiffKind    `json:"kind"`
+	Fields               []FieldDiff `json:"fields,omitempty"`
+}
+
+// ShadowReport summarizes a shadow replay and lists every subscription
+// whose candidate projection differs from the baseline's.
+type ShadowReport struct {
+	EpochID          string    `json:"epoch_id"`
+	CandidateVersion string    `json:"candidate_version"`
+	BaselineVersion  string    `json:"baseline_version"`
+	LedgerBound      time.Time `json:"ledger_bound"`
+
+	EventsScanned int `json:"events_scanned"`
+	EventsApplied int `json:"events_applied"`
+	Conflicts     int `json:"conflicts"`
+
+	Subscriptions int              `json:"subscriptions"`
+	Unchanged     int              `json:"unchanged"`
+	ByKind        map[DiffKind]int `json:"by_kind"`
+	ByField       map[string]int   `json:"by_field"`
+
+	Diffs []SubscriptionDiff `json:"diffs"`
+}
+
+// ReplayShadow replays stripe_event_ledger through this binary's merge
+// function (the candidate, ReducerVersion) into
+// subscription_projection_shadow, then diffs each subscription's shadow
+// head against the baseline version's current projection and records
+// the result in subscription_replay_diffs.
+//
+// The replay runs under its own replay_epochs row (ShadowReducerName) and
+// never touches subscription_projection, the conflicts table, or the live
+// reducer's cursor. The baseline cursor and the baseline heads are read in
+// one snapshot at start, and the replay stops at that cursor, so both sides
+// have seen the same ledger prefix even while the baseline keeps tailing.
+func ReplayShadow(ctx context.Context, db *sql.DB, opts ShadowOptions) (*ShadowReport, error) {
+	logger := opts.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	baseline := opts.BaselineVersion
+	if baseline == "" {
+		err := db.QueryRowContext(ctx, `
+			SELECT p.reducer_version FROM subscription_projection p
+			JOIN subscription_reducer_activations a ON a.reducer_version = p.reducer_version
+			GROUP BY p.reducer_version
+			ORDER BY length(p.reducer_version) DESC, p.reducer_version DESC
+			LIMIT 1
+		`).Scan(&baseline)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("no projections to compare against; set a baseline version")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("find baseline version: %w", err)
+		}
+	}
+
+	bound, baselineHeads, err := loadBaseline(ctx, db, baseline)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ShadowReport{
+		CandidateVersion: ReducerVersion,
+		BaselineVersion:  baseline,
+		LedgerBound:      bound.ReceivedAt,
+		ByKind:           map[DiffKind]int{},
+		ByField:          map[string]int{},
+	}
+	logger = logger.With("reducer", ShadowReducerName, "candidate", ReducerVersion, "baseline", baseline)
+
+	err = db.QueryRowContext(ctx, `
+		INSERT INTO replay_epochs (reducer_name, reducer_version, started_at, ending_event_received_at)
+		VALUES ($1, $2, now(), $3) RETURNING id::text
+	`, ShadowReducerName, ReducerVersion, bound.ReceivedAt).Scan(&report.EpochID)
+	if err != nil {
+		return nil, fmt.Errorf("start shadow epoch: %w", err)
+	}
+	logger = logger.With("epoch", report.EpochID)
+	logger.Info("shadow replay started", "ledger_bound", bound.ReceivedAt)
+
+	if err := replayShadow(ctx, db, report, bound, baselineHeads, logger); err != nil {
+		_ = abortEpoch(ctx, db, report.EpochID, err.Error())
+		return nil, err
+	}
+	logger.Info("shadow replay complete",
+		"events_scanned", report.EventsScanned,
+		"events_applied", report.EventsApplied,
+		"subscriptions", report.Subscriptions,
+		"diffs", len(report.Diffs),
+	)
+	return report, nil
+}
+
+// loadBaseline reads the baseline cursor and the baseline's chain heads in
+// one repeatable-read snapshot. The live reducer advances its cursor in
+// the transaction that writes the projection, so the heads are exactly the
+// baseline's state at that cursor. Without a cursor the bound is now.
+func loadBaseline(ctx context.Context, db *sql.DB, baseline string) (Cursor, map[string]*State, error) {
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return Cursor{}, nil, fmt.Errorf("begin baseline snapshot: %w", err)
+	}
+	defer tx.Rollback()
+
+	bound := Cursor{ReceivedAt: time.Now().UTC()}
+	err = tx.QueryRowContext(ctx, `
+		SELECT cursor_received_at, cursor_event_id::text FROM reducer_cursors
+		WHERE reducer_name = $1 AND reducer_version = $2
+	`, ReducerName, baseline).Scan(&bound.ReceivedAt, &bound.EventID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return Cursor{}, nil, fmt.Errorf("load baseline cursor: %w", err)
+	}
+
+	heads, err := loadStates(ctx, tx, `
+		SELECT `+stateColumns+`
+		FROM subscription_projection
+		WHERE reducer_version = $1
+		  AND NOT EXISTS (
+			SELECT 1 FROM subscription_projection newer
+			WHERE newer.supersedes_id = subscription_projection.id
+		  )
+	`, baseline)
+	if err != nil {
+		return Cursor{}, nil, fmt.Errorf("load baseline projections: %w", err)
+	}
+	return bound, heads, tx.Commit()
+}
+
+// pastCursor reports whether row comes after bound in (received_at, id)
+// order. scanLedger bounds by received_at only; rows at the cursor's
+// received_at with a greater id have not been seen by the baseline.
+func pastCursor(row ledgerEventOnDisk, bound Cursor) bool {
+	if !row.ReceivedAt.Equal(bound.ReceivedAt) {
+		return row.ReceivedAt.After(bound.ReceivedAt)
+	}
+	return bound.EventID.Valid && row.LedgerID > bound.EventID.String
+}
+
+func replayShadow(ctx context.Context, db *sql.DB, report *ShadowReport, bound Cursor, baseline map[string]*State, logger *slog.Logger) error {
+	heads := map[string]*State{}
+	headEvents := map[string]string{}
+	cursor := Cursor{ReceivedAt: time.Unix(0, 0).UTC()}
+	const batchSize = 500
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		batch, err := scanLedger(ctx, db, cursor, &report.LedgerBound, batchSize)
+		if err != nil {
+			return err
+		}
+		for len(batch) > 0 && pastCursor(batch[len(batch)-1], bound) {
+			batch = batch[:len(batch)-1]
+		}
+		if len(batch) == 0 {
+			break
+		}
+		for _, row := range batch {
+			report.EventsScanned++
+			applied, conflicts, err := applyShadowEvent(ctx, db, heads, headEvents, row)
+			if err != nil {
+				return fmt.Errorf("process event %s: %w", row.LedgerID, err)
+			}
+			if applied {
+				report.EventsApplied++
+			}
+			report.Conflicts += conflicts
+		}
+		last := batch[len(batch)-1]
+		cursor = Cursor{ReceivedAt: last.ReceivedAt, EventID: sql.NullString{String: last.LedgerID, Valid: true}}
+		logger.Info("shadow batch processed", "events_scanned", report.EventsScanned, "cursor_at", cursor.ReceivedAt)
+	}
+
+	if err := writeShadowHeads(ctx, db, report.EpochID, heads, headEvents); err != nil {
+		return err
+	}
+	if err := diffShadow(ctx, db, report, baseline); err != nil {
+		return err
+	}
+	summary, _ := json.Marshal(map[string]any{
+		"baseline_version": report.BaselineVersion,
+		"subscriptions":    report.Subscriptions,
+		"unchanged":        report.Unchanged,
+		"by_kind":          report.ByKind,
+		"by_field":         report.ByField,
+	})
+	_, err := db.ExecContext(ctx, `
+		UPDATE replay_epochs
+		SET events_processed = $2,
+			projections_written = $3,
+			conflicts_emitted = $4,
+			drift_summary = $5,
+			completed_at = now()
+		WHERE id = $1::uuid
+	`, report.EpochID, report.EventsScanned, len(heads), report.Conflicts, summary)
+	if err != nil {
+		return fmt.Errorf("complete shadow epoch: %w", err)
+	}
+	return nil
+}
+
+// applyShadowEvent folds one ledger row into heads exactly as
+// processSingleEvent would into subscription_projection, minus the
+// writes. Returns whether the event produced a new head.
+func applyShadowEvent(ctx context.Context, db *sql.DB, heads map[string]*State, headEvents map[string]string, row ledgerEventOnDisk) (applied bool, conflicts int, err error) {
+	event, object, ok := parseLedgerEvent(row)
+	if !ok {
+		return false, 0, nil
+	}
+	subID := event.SubscriptionID()
+	current := heads[subID]
+
+	workspaceID, err := resolveWorkspace(ctx, db, object)
+	if err != nil {
+		return false, 0, fmt.Errorf("resolve workspace: %w", err)
+	}
+	if workspaceID == "" && current != nil {
+		workspaceID = current.WorkspaceID
+	}
+	if workspaceID == "" {
+		return false, 0, nil
+	}
+	event.WorkspaceID = workspaceID
+
+	result := Merge(current, event, EventSourceForOutcome(row.VerifyOutcome))
+	if result.Next != nil {
+		heads[subID] = result.Next
+		headEvents[subID] = event.SourceEventID
+		applied = true
+	}
+	return applied, len(result.Conflicts), nil
+}
+
+// writeShadowHeads persists every shadow head in one transaction.
+func writeShadowHeads(ctx context.Context, db *sql.DB, epochID string, heads map[string]*State, headEvents map[string]string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	for subID, s := range heads {
+		authorityJSON, _ := s.MarshalFieldAuthority()
+		overridesJSON, _ := s.MarshalManualOverrides()
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO subscription_projection_shadow (
+				replay_epoch_id, stripe_subscription_id, workspace_id,
+				status, current_period_start, current_period_end,
+				cancel_at_period_end, canceled_at, trial_start, trial_end,
+				last_payment_status, last_payment_failed_at, dunning_attempt_count,
+				checkout_session_id, checkout_completed_at,
+				field_authority, manual_overrides,
+				reducer_version, source_event_id, event_occurred_at,
+				subscription_event_occurred_at, payment_event_occurred_at, polled_at
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23)
+		`,
+			epochID, subID, s.WorkspaceID,
+			nullIfEmpty(s.Status), s.CurrentPeriodStart, s.CurrentPeriodEnd,
+			s.CancelAtPeriodEnd, s.CanceledAt, s.TrialStart, s.TrialEnd,
+			nullIfEmpty(s.LastPaymentStatus), s.LastPaymentFailedAt, s.DunningAttemptCount,
+			nullIfEmpty(s.CheckoutSessionID), s.CheckoutCompletedAt,
+			authorityJSON, overridesJSON,
+			ReducerVersion, headEvents[subID], s.EventOccurredAt,
+			s.SubscriptionEventOccurredAt, s.PaymentEventOccurredAt, s.PolledAt,
+		)
+		if err != nil {
+			return fmt.Errorf("insert shadow projection %s: %w", subID, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit shadow projections: %w", err)
+	}
+	return nil
+}
+
+// diffShadow loads the shadow heads back from the database — so
+// timestamps and jsonb round-trip as they did for the baseline heads —
+// diffs them against baseline and records every difference in
+// subscription_replay_diffs.
+func diffShadow(ctx context.Context, db *sql.DB, report *ShadowReport, baseline map[string]*State) error {
+	candidate, err := loadStates(ctx, db, `
+		SELECT `+stateColumns+`
+		FROM subscription_projection_shadow
+		WHERE replay_epoch_id = $1::uuid
+	`, report.EpochID)
+	if err != nil {
+		return fmt.Errorf("load shadow projections: %w", err)
+	}
+
+	ids := map[string]bool{}
+	for id := range baseline {
+		ids[id] = true
+	}
+	for id := range candidate {
+		ids[id] = true
+	}
+	sorted := make([]string, 0, len(ids))
+	for id := range ids {
+		sorted = append(sorted, id)
+	}
+	sort.Strings(sorted)
+	report.Subscriptions = len(sorted)
+
+	for _, id := range sorted {
+		b, c := baseline[id], candidate[id]
+		d := SubscriptionDiff{StripeSubscriptionID: id}
+		switch {
+		case c == nil:
+			d.Kind = DiffOnlyBaseline
+		case b == nil:
+			d.Kind = DiffOnlyCandidate
+		default:
+			d.Fields = DiffStates(b, c)
+			if len(d.Fields) == 0 {
+				report.Unchanged++
+				continue
+			}
+			d.Kind = DiffChanged
+		}
+		if err := recordDiff(ctx, db, report, d); err != nil {
+			return err
+		}
+		report.ByKind[d.Kind]++
+		for _, f := range d.Fields {
+			report.ByField[f.Field]++
+		}
+		report.Diffs = append(report.Diffs, d)
+	}
+	return nil
+}
+
+func loadStates(ctx context.Context, q interface {
+	QueryContext(context.Context, string, ...any) (*sql.Rows, error)
+}, query string, arg any) (map[string]*State, error) {
+	rows, err := q.QueryContext(ctx, query, arg)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[string]*State{}
+	for rows.Next() {
+		s, err := scanState(rows)
+		if err != nil {
+			return nil, err
+		}
+		out[s.StripeSubscriptionID] = s
+	}
+	return out, rows.Err()
+}
+
+func recordDiff(ctx context.Context, db *sql.DB, report *ShadowReport, d SubscriptionDiff) error {
+	fields := map[string]any{}
+	for _, f := range d.Fields {
+		fields[f.Field] = map[string]any{"baseline": f.Baseline, "candidate": f.Candidate}
+	}
+	fieldsJSON, _ := json.Marshal(fields)
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO subscription_replay_diffs (
+			replay_epoch_id, stripe_subscription_id, diff_kind,
+			baseline_version, candidate_version, fields
+		) VALUES ($1, $2, $3, $4, $5, $6)
+	`, report.EpochID, d.StripeSubscriptionID, string(d.Kind), report.BaselineVersion, report.CandidateVersion, fieldsJSON)
+	if err != nil {
+		return fmt.Errorf("record diff %s: %w", d.StripeSubscriptionID, err)
+	}
+	return nil
+}
+
+// DiffStates compares two interpretations of the same subscription field
+// by field, in projection column order. Ordering watermarks are
+// provenance and are not compared; field_authority and manual_overrides
+// are, since a version that reaches the same value through a different
+// source has still changed behavior.
+func DiffStates(baseline, candidate *State) []FieldDiff {
+	var out []FieldDiff
+	add := func(field string, b, c any, equal bool) {
+		if !equal {
+			out = append(out, FieldDiff{Field: field, Baseline: b, Candidate: c})
+		}
+	}
+	str := func(field, b, c string) {
+		add(field, nullString(b), nullString(c), b == c)
+	}
+	ts := func(field string, b, c *time.Time) {
+		add(field, timeValue(b), timeValue(c), timeEqual(b, c))
+	}
+
+	str("workspace_id", baseline.WorkspaceID, candidate.WorkspaceID)
+	str("status", baseline.Status, candidate.Status)
+	ts("current_period_start", baseline.CurrentPeriodStart, candidate.CurrentPeriodStart)
+	ts("current_period_end", baseline.CurrentPeriodEnd, candidate.CurrentPeriodEnd)
+	add("cancel_at_period_end", baseline.CancelAtPeriodEnd, candidate.CancelAtPeriodEnd,
+		baseline.CancelAtPeriodEnd == candidate.CancelAtPeriodEnd)
+	ts("canceled_at", baseline.CanceledAt, candidate.CanceledAt)
+	ts("trial_start", baseline.TrialStart, candidate.TrialStart)
+	ts("trial_end", baseline.TrialEnd, candidate.TrialEnd)
+	str("last_payment_status", baseline.LastPaymentStatus, candidate.LastPaymentStatus)
+	ts("last_payment_failed_at", baseline.LastPaymentFailedAt, candidate.LastPaymentFailedAt)
+	add("dunning_attempt_count", baseline.DunningAttemptCount, candidate.DunningAttemptCount,
+		baseline.DunningAttemptCount == candidate.DunningAttemptCount)
+	str("checkout_session_id", baseline.CheckoutSessionID, candidate.CheckoutSessionID)
+	ts("checkout_completed_at", baseline.CheckoutCompletedAt, candidate.CheckoutCompletedAt)
+	add("field_authority", baseline.FieldAuthority, candidate.FieldAuthority,
+		authorityEqual(baseline.FieldAuthority, candidate.FieldAuthority))
+	add("manual_overrides", baseline.ManualOverrides, candidate.ManualOverrides,
+		overridesEqual(baseline.ManualOverrides, candidate.ManualOverrides))
+	return out
+}
+
+func nullString(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+func timeValue(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+func timeEqual(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+func authorityEqual(a, b map[string]AuthoritySource) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if w, ok := b[k]; !ok || w != v {
+			return false
+		}
+	}
+	return true
+}
+
+func overridesEqual(a, b map[string]time.Time) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if w, ok := b[k]; !ok || !w.Equal(v) {
+			return false
+		}
+	}
+	return true
+}
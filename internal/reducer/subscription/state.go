@@ -1,6 +1,7 @@
 // Package subscription implements the first reducer in PayFlux's projection
-// substrate. It consumes Stripe subscription events from stripe_event_ledger
-// and writes interpretations to subscription_projection.
+// substrate. It consumes Stripe subscription, invoice payment, and checkout
+// session events from stripe_event_ledger and writes interpretations to
+// subscription_projection.
 //
 // See SUBSCRIPTION_REDUCER_CONTRACT.md for the full operational contract.
 //
@@ -25,6 +26,8 @@ type State struct {
 	StripeSubscriptionID string
 	WorkspaceID          string
 
+	// Status is "" (NULL in the projection) until the first subscription
+	// event is seen.
 	Status               string
 	CurrentPeriodStart   *time.Time
 	CurrentPeriodEnd     *time.Time
@@ -33,16 +36,50 @@ type State struct {
 	TrialStart           *time.Time
 	TrialEnd             *time.Time
 
+	// Payment outcome, interpreted from invoice.payment_* events.
+	// LastPaymentStatus is "" until the first invoice event is seen.
+	// DunningAttemptCount is the invoice's attempt_count while payment
+	// is outstanding and resets to 0 once an invoice is paid.
+	LastPaymentStatus   string
+	LastPaymentFailedAt *time.Time
+	DunningAttemptCount int
+
+	// Checkout link, interpreted from checkout.session.completed.
+	CheckoutSessionID   string
+	CheckoutCompletedAt *time.Time
+
 	// FieldAuthority records which authority source produced each
 	// interpreted field's value in this State. Keys correspond to the
 	// JSON tags of the interpreted fields above. Values are one of the
 	// AuthoritySource constants.
 	FieldAuthority map[string]AuthoritySource
 
-	// EventOccurredAt is the logical timestamp of the event that produced
-	// this State. Used by the merge function to detect late events and
-	// chronology conflicts.
+	// ManualOverrides records, for each field currently held by an
+	// operator correction, the time the correction was submitted. An
+	// entry exists exactly while FieldAuthority tags the field manual;
+	// it is removed when a newer report of the field supersedes the
+	// correction.
+	ManualOverrides map[string]time.Time
+
+	// EventOccurredAt is the logical timestamp of the newest event folded
+	// into this State, across all event groups.
 	EventOccurredAt time.Time
+
+	// Per-group ordering watermarks. Each event group (subscription,
+	// invoice payment, checkout) is ordered independently so that, e.g.,
+	// an invoice event is never rejected as late merely because a newer
+	// subscription event already landed. Nil until the group's first
+	// event is applied. Checkout uses CheckoutCompletedAt as its
+	// watermark.
+	//
+	// Subscription snapshots come from two sources: webhook deliveries
+	// advance SubscriptionEventOccurredAt, polled snapshots PolledAt. The
+	// group is ordered against the newer of the two (see
+	// subscriptionWatermark), so the newest observation wins regardless of
+	// source.
+	SubscriptionEventOccurredAt *time.Time
+	PaymentEventOccurredAt      *time.Time
+	PolledAt                    *time.Time
 }
 
 // AuthoritySource enumerates the categories of input the reducer can
@@ -74,7 +111,9 @@ type Event struct {
 
 	// EventType is event.type from the Stripe payload. The reducer only
 	// processes a subset (subscription.created, .updated, .deleted,
-	// invoice.payment_*, checkout.session.completed).
+	// invoice.payment_*, invoice.paid, checkout.session.completed), plus
+	// the synthetic PolledEventType written by the polling worker and
+	// ManualEventType written by the corrections endpoint.
 	EventType string
 
 	// OccurredAt is event.created from the Stripe payload, expressed as
@@ -88,12 +127,41 @@ type Event struct {
 	// (e.g., a stale event that's about a different entity).
 	Subscription *StripeSubscription
 
+	// Invoice is the parsed invoice object for invoice.payment_* and
+	// invoice.paid events. Nil otherwise.
+	Invoice *StripeInvoice
+
+	// CheckoutSession is the parsed session object for
+	// checkout.session.completed events. Nil otherwise.
+	CheckoutSession *StripeCheckoutSession
+
+	// Correction is the operator correction carried by a ManualEventType
+	// event. Nil otherwise.
+	Correction *ManualCorrection
+
 	// WorkspaceID is the resolved internal workspace id. The caller is
 	// responsible for resolving stripe_account_id or metadata.workspaceId
 	// to a workspace before passing the Event to the reducer.
 	WorkspaceID string
 }
 
+// PolledEventType is the event type of the synthetic ledger events the
+// polling worker writes. Stripe never emits it; the payload's data.object
+// is the subscription exactly as the API returned it.
+const PolledEventType = "customer.subscription.polled"
+
+// PolledEventIDPrefix prefixes the ids of synthetic polled events so they
+// can never collide with Stripe's evt_ ids.
+const PolledEventIDPrefix = "poll_"
+
+// ManualEventType is the event type of the synthetic ledger events an
+// operator correction writes. The payload's data.object is a
+// ManualCorrection.
+const ManualEventType = "subscription.manual_correction"
+
+// ManualEventIDPrefix prefixes the ids of operator correction events.
+const ManualEventIDPrefix = "manual_"
+
 // StripeSubscription is a minimal structured view of Stripe's subscription
 // object — only the fields the reducer cares about. Stripe's full object has
 // many more fields; we extract only what feeds the projection.
@@ -108,6 +176,61 @@ type StripeSubscription struct {
 	TrialEnd           *int64     `json:"trial_end"`
 }
 
+// StripeInvoice is a minimal structured view of Stripe's invoice object.
+// Newer API versions move the subscription reference under
+// parent.subscription_details; both shapes are accepted.
+type StripeInvoice struct {
+	ID           string `json:"id"`
+	Subscription string `json:"subscription"`
+	AttemptCount int    `json:"attempt_count"`
+	Parent       *struct {
+		SubscriptionDetails *struct {
+			Subscription string `json:"subscription"`
+		} `json:"subscription_details"`
+	} `json:"parent"`
+}
+
+// SubscriptionID returns the subscription the invoice bills, or "" for
+// one-off invoices.
+func (i *StripeInvoice) SubscriptionID() string {
+	if i.Subscription != "" {
+		return i.Subscription
+	}
+	if i.Parent != nil && i.Parent.SubscriptionDetails != nil {
+		return i.Parent.SubscriptionDetails.Subscription
+	}
+	return ""
+}
+
+// StripeCheckoutSession is a minimal structured view of Stripe's checkout
+// session object. Only mode=subscription sessions link to a subscription.
+type StripeCheckoutSession struct {
+	ID                string `json:"id"`
+	Mode              string `json:"mode"`
+	Subscription      string `json:"subscription"`
+	ClientReferenceID string `json:"client_reference_id"`
+}
+
+// SubscriptionID returns the id of the subscription the event concerns,
+// whichever object it carries. Returns "" when the event is not about a
+// subscription (one-off invoice, payment-mode checkout).
+func (e *Event) SubscriptionID() string {
+	switch {
+	case e.Subscription != nil:
+		return e.Subscription.ID
+	case e.Invoice != nil:
+		return e.Invoice.SubscriptionID()
+	case e.CheckoutSession != nil:
+		if e.CheckoutSession.Mode != "subscription" {
+			return ""
+		}
+		return e.CheckoutSession.Subscription
+	case e.Correction != nil:
+		return e.Correction.Subscription
+	}
+	return ""
+}
+
 // MarshalFieldAuthority serializes FieldAuthority for storage in jsonb.
 func (s *State) MarshalFieldAuthority() ([]byte, error) {
 	if s.FieldAuthority == nil {
@@ -116,6 +239,14 @@ func (s *State) MarshalFieldAuthority() ([]byte, error) {
 	return json.Marshal(s.FieldAuthority)
 }
 
+// MarshalManualOverrides serializes ManualOverrides for storage in jsonb.
+func (s *State) MarshalManualOverrides() ([]byte, error) {
+	if s.ManualOverrides == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(s.ManualOverrides)
+}
+
 // epochToTime converts a Stripe unix-seconds field to *time.Time. Zero
 // timestamps from Stripe become nil rather than time.Unix(0, 0) so the
 // projection row stores NULL.
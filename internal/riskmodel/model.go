@@ -0,0 +1,173 @@
+// Package riskmodel defines the versioned scoring model used by the processor
+// and merchant risk scorers: component weights, driver thresholds and band
+// cutoffs.
+//
+// A model is loaded from JSON or YAML (chosen by file extension), validated,
+// and swapped into the scorer atomically. Every score carries the Version of
+// the model that produced it so exports can be attributed to a model.
+package riskmodel
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.yaml.in/yaml/v2"
+)
+
+// DefaultVersion identifies the built-in model, used when no model file is
+// configured. Its values match the original hard-coded scorer.
+const DefaultVersion = "builtin-v1"
+
+// weightSumTolerance allows for rounding in hand-edited model files.
+const weightSumTolerance = 0.001
+
+// Components holds one value per risk component. It is used both for
+// weights and for driver thresholds.
+type Components struct {
+	FailRate      float64 `json:"fail_rate" yaml:"fail_rate"`
+	RetryPressure float64 `json:"retry_pressure" yaml:"retry_pressure"`
+	TimeoutMix    float64 `json:"timeout_mix" yaml:"timeout_mix"`
+	TrafficSpike  float64 `json:"traffic_spike" yaml:"traffic_spike"`
+	AuthFailMix   float64 `json:"auth_fail_mix" yaml:"auth_fail_mix"`
+	GeoEntropy    float64 `json:"geo_entropy" yaml:"geo_entropy"`
+}
+
+func (c Components) named() []struct {
+	name string
+	v    float64
+} {
+	return []struct {
+		name string
+		v    float64
+	}{
+		{"fail_rate", c.FailRate},
+		{"retry_pressure", c.RetryPressure},
+		{"timeout_mix", c.TimeoutMix},
+		{"traffic_spike", c.TrafficSpike},
+		{"auth_fail_mix", c.AuthFailMix},
+		{"geo_entropy", c.GeoEntropy},
+	}
+}
+
+// Bands holds the score cutoffs for the elevated, high and critical bands.
+type Bands struct {
+	Elevated float64 `json:"elevated" yaml:"elevated"`
+	High     float64 `json:"high" yaml:"high"`
+	Critical float64 `json:"critical" yaml:"critical"`
+}
+
+// Model is a complete, versioned scoring model.
+type Model struct {
+	Version          string     `json:"version" yaml:"version"`
+	Weights          Components `json:"weights" yaml:"weights"`
+	DriverThresholds Components `json:"driver_thresholds" yaml:"driver_thresholds"`
+	Bands            Bands      `json:"bands" yaml:"bands"`
+}
+
+// Default returns the built-in model with the given band cutoffs
+// (elevated, high, critical), as configured by PAYFLUX_RISK_SCORE_THRESHOLDS.
+func Default(thresholds [3]float64) *Model {
+	return &Model{
+		Version: DefaultVersion,
+		Weights: Components{
+			FailRate:      0.25,
+			RetryPressure: 0.20,
+			TimeoutMix:    0.15,
+			TrafficSpike:  0.15,
+			AuthFailMix:   0.10,
+			GeoEntropy:    0.15,
+		},
+		DriverThresholds: Components{
+			FailRate:      0.4,
+			RetryPressure: 0.5,
+			TimeoutMix:    0.5,
+			TrafficSpike:  0.5,
+			AuthFailMix:   0.5,
+			GeoEntropy:    0.5,
+		},
+		Bands: Bands{Elevated: thresholds[0], High: thresholds[1], Critical: thresholds[2]},
+	}
+}
+
+// Validate checks that the model is internally consistent. All problems are
+// reported together.
+func (m *Model) Validate() error {
+	var problems []string
+
+	if strings.TrimSpace(m.Version) == "" {
+		problems = append(problems, "version is required")
+	}
+
+	sum := 0.0
+	for _, w := range m.Weights.named() {
+		if w.v < 0 || w.v > 1 {
+			problems = append(problems, fmt.Sprintf("weights.%s=%.3f must be in [0, 1]", w.name, w.v))
+		}
+		sum += w.v
+	}
+	if math.Abs(sum-1) > weightSumTolerance {
+		problems = append(problems, fmt.Sprintf("weights must sum to 1.0, got %.3f", sum))
+	}
+
+	for _, t := range m.DriverThresholds.named() {
+		if t.v <= 0 {
+			problems = append(problems, fmt.Sprintf("driver_thresholds.%s=%.3f must be positive", t.name, t.v))
+		}
+	}
+
+	b := m.Bands
+	if b.Elevated <= 0 || b.Critical > 1 || b.Elevated >= b.High || b.High >= b.Critical {
+		problems = append(problems, fmt.Sprintf(
+			"bands must satisfy 0 < elevated < high < critical <= 1, got %.2f,%.2f,%.2f",
+			b.Elevated, b.High, b.Critical))
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Parse decodes a model from data. format is "yaml" or "json". Unknown
+// fields are rejected so typos do not silently fall back to zero weights.
+func Parse(data []byte, format string) (*Model, error) {
+	var m Model
+	switch format {
+	case "yaml":
+		if err := yaml.UnmarshalStrict(data, &m); err != nil {
+			return nil, fmt.Errorf("invalid YAML: %w", err)
+		}
+	case "json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&m); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported model format %q", format)
+	}
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// Load reads and validates the model at path. Files ending in .yaml or .yml
+// are parsed as YAML; everything else as JSON.
+func Load(path string) (*Model, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	format := "json"
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		format = "yaml"
+	}
+	return Parse(data, format)
+}
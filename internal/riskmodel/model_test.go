@@ -0,0 +1,116 @@
+package riskmodel
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const validJSON = `{
+  "version": "test-v2",
+  "weights": {"fail_rate": 0.5, "retry_pressure": 0.1, "timeout_mix": 0.1,
+              "traffic_spike": 0.1, "auth_fail_mix": 0.1, "geo_entropy": 0.1},
+  "driver_thresholds": {"fail_rate": 0.2, "retry_pressure": 0.5, "timeout_mix": 0.5,
+                        "traffic_spike": 0.5, "auth_fail_mix": 0.5, "geo_entropy": 0.5},
+  "bands": {"elevated": 0.2, "high": 0.5, "critical": 0.7}
+}`
+
+func TestDefault_IsValid(t *testing.T) {
+	if err := Default([3]float64{0.3, 0.6, 0.8}).Validate(); err != nil {
+		t.Fatalf("built-in model must validate: %v", err)
+	}
+}
+
+func TestLoad_ExampleFile(t *testing.T) {
+	m, err := Load("../../config/risk_model.example.yaml")
+	if err != nil {
+		t.Fatalf("example model must load: %v", err)
+	}
+	def := Default([3]float64{0.3, 0.6, 0.8})
+	if m.Weights != def.Weights || m.DriverThresholds != def.DriverThresholds || m.Bands != def.Bands {
+		t.Errorf("example model should reproduce the built-in values, got %+v", m)
+	}
+}
+
+func TestParse_JSONAndYAML(t *testing.T) {
+	m, err := Parse([]byte(validJSON), "json")
+	if err != nil {
+		t.Fatalf("json: %v", err)
+	}
+	if m.Version != "test-v2" || m.Weights.FailRate != 0.5 || m.Bands.Critical != 0.7 {
+		t.Errorf("unexpected model: %+v", m)
+	}
+
+	// JSON is valid YAML, so the same document must parse identically.
+	y, err := Parse([]byte(validJSON), "yaml")
+	if err != nil {
+		t.Fatalf("yaml: %v", err)
+	}
+	if *y != *m {
+		t.Errorf("yaml and json results differ: %+v vs %+v", y, m)
+	}
+}
+
+func TestParse_Rejects(t *testing.T) {
+	tests := []struct {
+		name, doc, want string
+	}{
+		{"unknown field", strings.Replace(validJSON, `"fail_rate": 0.5`, `"failrate": 0.5`, 1), "unknown field"},
+		{"missing version", strings.Replace(validJSON, `"test-v2"`, `""`, 1), "version is required"},
+		{"weights sum", strings.Replace(validJSON, `"fail_rate": 0.5`, `"fail_rate": 0.9`, 1), "sum to 1.0"},
+		{"zero threshold", strings.Replace(validJSON, `"fail_rate": 0.2`, `"fail_rate": 0`, 1), "driver_thresholds.fail_rate"},
+		{"bands order", strings.Replace(validJSON, `"high": 0.5`, `"high": 0.9`, 1), "bands must satisfy"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.doc), "json")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestWatcher_ReloadsAndKeepsPreviousOnError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "model.json")
+	if err := os.WriteFile(path, []byte(validJSON), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := make(chan *Model, 4)
+	failed := make(chan error, 4)
+	w := NewWatcher(path, 0, func(m *Model) { loaded <- m }, func(err error) { failed <- err })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.Run(ctx)
+
+	// Invalid edit is reported, not loaded
+	os.WriteFile(path, []byte(`{"version": "broken"}`), 0o644)
+	w.Trigger()
+	select {
+	case err := <-failed:
+		if !strings.Contains(err.Error(), "sum to 1.0") {
+			t.Errorf("unexpected error: %v", err)
+		}
+	case m := <-loaded:
+		t.Fatalf("invalid model must not load, got %+v", m)
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for reload error")
+	}
+
+	// Fixed file is loaded
+	os.WriteFile(path, []byte(strings.Replace(validJSON, "test-v2", "test-v3", 1)), 0o644)
+	w.Trigger()
+	select {
+	case m := <-loaded:
+		if m.Version != "test-v3" {
+			t.Errorf("expected test-v3, got %s", m.Version)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for reload")
+	}
+}
@@ -0,0 +1,98 @@
+package riskmodel
+
+import (
+	"context"
+	"os"
+	"time"
+)
+
+// Watcher reloads a model file when its modification time or size changes,
+// or when Trigger is called (e.g. on SIGHUP).
+//
+// An invalid file never replaces the active model: OnError is called and the
+// previous model stays in effect until the file is fixed.
+type Watcher struct {
+	Path     string
+	Interval time.Duration
+
+	// OnLoad receives each successfully loaded model.
+	OnLoad func(*Model)
+	// OnError receives load or validation failures.
+	OnError func(error)
+
+	trigger chan struct{}
+	modTime time.Time
+	size    int64
+}
+
+// NewWatcher creates a watcher for path. The file's current state is taken
+// as already loaded, so the first reload happens on the first change.
+func NewWatcher(path string, interval time.Duration, onLoad func(*Model), onError func(error)) *Watcher {
+	w := &Watcher{
+		Path:     path,
+		Interval: interval,
+		OnLoad:   onLoad,
+		OnError:  onError,
+		trigger:  make(chan struct{}, 1),
+	}
+	if info, err := os.Stat(path); err == nil {
+		w.modTime, w.size = info.ModTime(), info.Size()
+	}
+	return w
+}
+
+// Trigger requests an immediate reload regardless of file state.
+func (w *Watcher) Trigger() {
+	select {
+	case w.trigger <- struct{}{}:
+	default: // reload already pending
+	}
+}
+
+// Run polls until ctx is cancelled. Interval <= 0 disables polling; Trigger
+// still works.
+func (w *Watcher) Run(ctx context.Context) {
+	var tick <-chan time.Time
+	if w.Interval > 0 {
+		t := time.NewTicker(w.Interval)
+		defer t.Stop()
+		tick = t.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.trigger:
+			w.reload()
+		case <-tick:
+			if w.changed() {
+				w.reload()
+			}
+		}
+	}
+}
+
+func (w *Watcher) changed() bool {
+	info, err := os.Stat(w.Path)
+	if err != nil {
+		return false // reported on next successful change or Trigger
+	}
+	return !info.ModTime().Equal(w.modTime) || info.Size() != w.size
+}
+
+func (w *Watcher) reload() {
+	if info, err := os.Stat(w.Path); err == nil {
+		w.modTime, w.size = info.ModTime(), info.Size()
+	}
+	m, err := Load(w.Path)
+	if err != nil {
+		if w.OnError != nil {
+			w.OnError(err)
+		}
+		return
+	}
+	if w.OnLoad != nil {
+		w.OnLoad(m)
+	}
+}
@@ -19,7 +19,7 @@ func TierFromContext(ctx context.Context) string {
 	if ctx == nil {
 		return "baseline"
 	}
-	if v := ctx.Value("tier"); v != nil {
+	if v := ctx.Value(entitlements.TierContextKey); v != nil {
 		if s, ok := v.(string); ok && s != "" {
 			return s
 		}
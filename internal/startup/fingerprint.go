@@ -18,14 +18,24 @@ var configKeys = []string{
 	"HTTP_ADDR",
 	"PAYFLUX_API_KEY",
 	"PAYFLUX_API_KEYS",
+	"PAYFLUX_API_KEY_REGISTRY",
+	"PAYFLUX_API_KEY_REGISTRY_FILE",
+	"PAYFLUX_API_KEY_TIERS",
+	"PAYFLUX_API_KEY_WORKSPACES",
+	"PAYFLUX_AUDIT_LOG",
 	"PAYFLUX_BACKPRESSURE_THRESHOLD",
+	"PAYFLUX_CONSUMER_BATCH_SIZE",
 	"PAYFLUX_CONSUMER_NAME",
+	"PAYFLUX_CONSUMER_WORKERS",
+	"PAYFLUX_ENTITLEMENT_TIER",
 	"PAYFLUX_ENV",
 	"PAYFLUX_EXPORT_FILE",
 	"PAYFLUX_EXPORT_MODE",
+	"PAYFLUX_EXPORT_SINKS",
 	"PAYFLUX_INGEST_BURST",
 	"PAYFLUX_INGEST_ENABLED",
 	"PAYFLUX_INGEST_RPS",
+	"PAYFLUX_LEGACY_KEYS_ADMIN",
 	"PAYFLUX_OUTCOME_BURST",
 	"PAYFLUX_OUTCOME_RPS",
 	"PAYFLUX_PANIC_MODE",
@@ -33,14 +43,27 @@ var configKeys = []string{
 	"PAYFLUX_RATELIMIT_BURST",
 	"PAYFLUX_RATELIMIT_RPS",
 	"PAYFLUX_RAW_EVENT_TTL_DAYS",
+	"PAYFLUX_RETENTION_MODE",
 	"PAYFLUX_REVOKED_KEYS",
+	"PAYFLUX_RISK_MAX_MERCHANTS",
+	"PAYFLUX_RISK_MERCHANT_SCOPE",
+	"PAYFLUX_RISK_MODEL_FILE",
+	"PAYFLUX_RISK_MODEL_RELOAD_SEC",
 	"PAYFLUX_RISK_SCORE_ENABLED",
 	"PAYFLUX_RISK_SCORE_THRESHOLDS",
 	"PAYFLUX_RISK_SCORE_WINDOW_SEC",
+	"PAYFLUX_RISK_SHADOW_MODEL_FILE",
+	"PAYFLUX_RISK_SNAPSHOT",
+	"PAYFLUX_RISK_SNAPSHOT_FILE",
+	"PAYFLUX_RISK_SNAPSHOT_INTERVAL_SEC",
+	"PAYFLUX_RISK_SNAPSHOT_KEY",
 	"PAYFLUX_STREAM_MAXLEN",
 	"PAYFLUX_TIER",
 	"PAYFLUX_TIER2_ENABLED",
 	"PAYFLUX_WARNINGS_ENABLED",
+	"PAYFLUX_WARNING_STORE",
+	"PAYFLUX_WARNING_STORE_CAPACITY",
+	"PAYFLUX_WARNING_WEBHOOKS",
 	"REDIS_ADDR",
 	"STREAM_KEY",
 	"STRIPE_API_KEY",
@@ -120,10 +143,12 @@ func EnvSummary() map[string]string {
 	summary := make(map[string]string, len(configKeys))
 
 	secretKeys := map[string]bool{
-		"PAYFLUX_API_KEY":  true,
-		"PAYFLUX_API_KEYS": true,
-		"STRIPE_API_KEY":   true,
-		"PAYFLUX_REVOKED_KEYS": true,
+		"PAYFLUX_API_KEY":            true,
+		"PAYFLUX_API_KEYS":           true,
+		"STRIPE_API_KEY":             true,
+		"PAYFLUX_REVOKED_KEYS":       true,
+		"PAYFLUX_API_KEY_TIERS":      true,
+		"PAYFLUX_API_KEY_WORKSPACES": true,
 	}
 
 	for _, key := range configKeys {
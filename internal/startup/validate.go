@@ -11,6 +11,11 @@ import (
 	"os"
 	"strconv"
 	"strings"
+
+	"payment-node/internal/apikeys"
+	"payment-node/internal/exporter"
+	"payment-node/internal/riskmodel"
+	canonicaltier "payment-node/internal/tier"
 )
 
 // ConfigError collects every validation failure so operators see ALL problems
@@ -43,10 +48,14 @@ func ValidateConfig() error {
 	validateAuth(ce)
 	validateRedis(ce)
 	validateExport(ce)
+	validateExportSinks(ce)
+	validateWarningWebhooks(ce)
 	validateRiskScoring(ce)
 	validateTier(ce)
 	validateEnv(ce)
 	validatePanicMode(ce)
+	validateWarningStore(ce)
+	validateRetention(ce)
 	validateRateLimits(ce)
 	validateStreamConfig(ce)
 	validateStripe(ce)
@@ -61,10 +70,28 @@ func ValidateConfig() error {
 // --- Individual validators ---
 
 func validateAuth(ce *ConfigError) {
+	registry := os.Getenv("PAYFLUX_API_KEY_REGISTRY")
+	switch registry {
+	case "":
+	case "file":
+		path := envOr("PAYFLUX_API_KEY_REGISTRY_FILE", "config/api_keys.json")
+		if _, err := apikeys.NewFileRegistry(path); err != nil {
+			ce.addf("PAYFLUX_API_KEY_REGISTRY_FILE=%q: %v", path, err)
+		}
+	case "postgres":
+		if os.Getenv("DATABASE_URL") == "" {
+			ce.add("PAYFLUX_API_KEY_REGISTRY=postgres requires DATABASE_URL")
+		}
+	default:
+		ce.addf("PAYFLUX_API_KEY_REGISTRY=%q must be 'file' or 'postgres'", registry)
+	}
+
 	keys := os.Getenv("PAYFLUX_API_KEYS")
 	key := os.Getenv("PAYFLUX_API_KEY")
 	if keys == "" && key == "" {
-		ce.add("PAYFLUX_API_KEY or PAYFLUX_API_KEYS must be set")
+		if registry == "" {
+			ce.add("PAYFLUX_API_KEY or PAYFLUX_API_KEYS must be set")
+		}
 		return
 	}
 
@@ -119,6 +146,38 @@ func validateExport(ce *ConfigError) {
 	}
 }
 
+// validateExportSinks checks PAYFLUX_EXPORT_SINKS syntax, that every kind is
+// registered, and that each sink's required options are present. Sinks are
+// constructed and immediately closed; factories perform no network I/O.
+func validateExportSinks(ce *ConfigError) {
+	configs, err := exporter.ParseSinkSpecs(os.Getenv("PAYFLUX_EXPORT_SINKS"), os.Environ())
+	if err != nil {
+		ce.addf("PAYFLUX_EXPORT_SINKS: %v", err)
+		return
+	}
+	for _, c := range configs {
+		sink, err := exporter.NewSink(c)
+		if err != nil {
+			ce.addf("PAYFLUX_EXPORT_SINKS sink %q (%s): %v", c.Name, c.Kind, err)
+			continue
+		}
+		_ = sink.Close()
+	}
+}
+
+// validateWarningWebhooks checks PAYFLUX_WARNING_WEBHOOKS and each endpoint's
+// options, plus the entitlement tier that gates delivery.
+func validateWarningWebhooks(ce *ConfigError) {
+	if _, err := exporter.ParseWarningWebhookSpecs(os.Getenv("PAYFLUX_WARNING_WEBHOOKS"), os.Environ()); err != nil {
+		ce.addf("PAYFLUX_WARNING_WEBHOOKS: %v", err)
+	}
+	switch t := envOr("PAYFLUX_ENTITLEMENT_TIER", "baseline"); t {
+	case "baseline", "proof", "shield", "fortress":
+	default:
+		ce.addf("PAYFLUX_ENTITLEMENT_TIER=%q must be 'baseline', 'proof', 'shield' or 'fortress'", t)
+	}
+}
+
 func validateRiskScoring(ce *ConfigError) {
 	thresholds := envOr("PAYFLUX_RISK_SCORE_THRESHOLDS", "0.3,0.6,0.8")
 	parts := strings.Split(thresholds, ",")
@@ -153,6 +212,35 @@ func validateRiskScoring(ce *ConfigError) {
 	} else if w < 10 {
 		ce.addf("PAYFLUX_RISK_SCORE_WINDOW_SEC=%d must be >= 10", w)
 	}
+
+	scope := envOr("PAYFLUX_RISK_MERCHANT_SCOPE", "merchant")
+	if scope != "merchant" && scope != "processor_merchant" && scope != "off" {
+		ce.addf("PAYFLUX_RISK_MERCHANT_SCOPE=%q must be 'merchant', 'processor_merchant' or 'off'", scope)
+	}
+	checkPositiveInt(ce, "PAYFLUX_RISK_MAX_MERCHANTS", 10000)
+
+	if path := os.Getenv("PAYFLUX_RISK_MODEL_FILE"); path != "" {
+		if _, err := riskmodel.Load(path); err != nil {
+			ce.addf("PAYFLUX_RISK_MODEL_FILE=%q: %v", path, err)
+		}
+	}
+	if path := os.Getenv("PAYFLUX_RISK_SHADOW_MODEL_FILE"); path != "" {
+		if _, err := riskmodel.Load(path); err != nil {
+			ce.addf("PAYFLUX_RISK_SHADOW_MODEL_FILE=%q: %v", path, err)
+		}
+	}
+	checkNonNegativeInt(ce, "PAYFLUX_RISK_MODEL_RELOAD_SEC", 30)
+
+	switch snapshot := envOr("PAYFLUX_RISK_SNAPSHOT", "off"); snapshot {
+	case "off", "redis":
+	case "file":
+		if os.Getenv("PAYFLUX_RISK_SNAPSHOT_FILE") == "" {
+			ce.addf("PAYFLUX_RISK_SNAPSHOT_FILE is required when PAYFLUX_RISK_SNAPSHOT=file")
+		}
+	default:
+		ce.addf("PAYFLUX_RISK_SNAPSHOT=%q must be 'off', 'redis' or 'file'", snapshot)
+	}
+	checkPositiveInt(ce, "PAYFLUX_RISK_SNAPSHOT_INTERVAL_SEC", 30)
 }
 
 func validateTier(ce *ConfigError) {
@@ -160,6 +248,15 @@ func validateTier(ce *ConfigError) {
 	if tier != "tier1" && tier != "tier2" {
 		ce.addf("PAYFLUX_TIER=%q must be 'tier1' or 'tier2'", tier)
 	}
+	if _, err := canonicaltier.ParseKeyTiers(os.Getenv("PAYFLUX_API_KEY_TIERS")); err != nil {
+		ce.addf("PAYFLUX_API_KEY_TIERS: %v", err)
+	}
+	workspaces, err := canonicaltier.ParseKeyWorkspaces(os.Getenv("PAYFLUX_API_KEY_WORKSPACES"))
+	if err != nil {
+		ce.addf("PAYFLUX_API_KEY_WORKSPACES: %v", err)
+	} else if len(workspaces) > 0 && os.Getenv("DATABASE_URL") == "" {
+		ce.add("PAYFLUX_API_KEY_WORKSPACES requires DATABASE_URL")
+	}
 }
 
 func validateEnv(ce *ConfigError) {
@@ -176,6 +273,24 @@ func validatePanicMode(ce *ConfigError) {
 	}
 }
 
+func validateWarningStore(ce *ConfigError) {
+	backend := envOr("PAYFLUX_WARNING_STORE", "memory")
+	if backend != "memory" && backend != "redis" {
+		ce.addf("PAYFLUX_WARNING_STORE=%q must be 'memory' or 'redis'", backend)
+	}
+	checkPositiveInt(ce, "PAYFLUX_WARNING_STORE_CAPACITY", 1000)
+}
+
+func validateRetention(ce *ConfigError) {
+	mode := envOr("PAYFLUX_RETENTION_MODE", "dry_run")
+	if mode != "off" && mode != "dry_run" && mode != "enforce" {
+		ce.addf("PAYFLUX_RETENTION_MODE=%q must be 'off', 'dry_run' or 'enforce'", mode)
+	}
+}
+
+// validateRateLimits checks the backpressure threshold and the deprecated
+// rate limit overrides, which only apply to legacy keys. Other keys are
+// limited per entitlement tier (config/tier_entitlements.runtime.json).
 func validateRateLimits(ce *ConfigError) {
 	checkPositiveInt(ce, "PAYFLUX_RATELIMIT_RPS", 100)
 	checkPositiveInt(ce, "PAYFLUX_RATELIMIT_BURST", 500)
@@ -189,6 +304,8 @@ func validateRateLimits(ce *ConfigError) {
 func validateStreamConfig(ce *ConfigError) {
 	checkNonNegativeInt(ce, "PAYFLUX_STREAM_MAXLEN", 200000)
 	checkNonNegativeInt(ce, "PAYFLUX_RAW_EVENT_TTL_DAYS", 7)
+	checkPositiveInt(ce, "PAYFLUX_CONSUMER_WORKERS", 1)
+	checkPositiveInt(ce, "PAYFLUX_CONSUMER_BATCH_SIZE", 50)
 }
 
 func validateStripe(ce *ConfigError) {
@@ -217,8 +334,16 @@ func validateRuntimeConfigs(ce *ConfigError) {
 	})
 
 	validateJSONFile(ce, "config/tier_entitlements.runtime.json", func(data []byte) error {
+		type rateLimit struct {
+			Capacity        int `json:"capacity"`
+			RefillPerSecond int `json:"refill_per_second"`
+		}
 		var parsed struct {
-			Entitlements map[string]json.RawMessage `json:"entitlements"`
+			Entitlements map[string]struct {
+				RetentionDays    int       `json:"retention_days"`
+				IngestRateLimit  rateLimit `json:"ingest_rate_limit"`
+				OutcomeRateLimit rateLimit `json:"outcome_rate_limit"`
+			} `json:"entitlements"`
 		}
 		if err := json.Unmarshal(data, &parsed); err != nil {
 			return fmt.Errorf("invalid JSON structure: %w", err)
@@ -226,6 +351,15 @@ func validateRuntimeConfigs(ce *ConfigError) {
 		if len(parsed.Entitlements) == 0 {
 			return fmt.Errorf("no entitlements defined")
 		}
+		for name, ent := range parsed.Entitlements {
+			if ent.IngestRateLimit.Capacity < 1 || ent.IngestRateLimit.RefillPerSecond < 1 ||
+				ent.OutcomeRateLimit.Capacity < 1 || ent.OutcomeRateLimit.RefillPerSecond < 1 {
+				return fmt.Errorf("tier %s: ingest_rate_limit and outcome_rate_limit need a positive capacity and refill_per_second", name)
+			}
+			if ent.RetentionDays < 1 {
+				return fmt.Errorf("tier %s: retention_days must be positive", name)
+			}
+		}
 		return nil
 	})
 
@@ -102,6 +102,20 @@ func TestValidateConfig_InvalidExportMode(t *testing.T) {
 	}
 }
 
+func TestValidateConfig_InvalidRetentionMode(t *testing.T) {
+	env := validEnv()
+	env["PAYFLUX_RETENTION_MODE"] = "purge"
+	withEnv(t, env)
+
+	err := ValidateConfig()
+	if err == nil {
+		t.Fatal("expected error for invalid retention mode")
+	}
+	if !strings.Contains(err.Error(), "PAYFLUX_RETENTION_MODE") {
+		t.Errorf("error should mention PAYFLUX_RETENTION_MODE, got: %s", err.Error())
+	}
+}
+
 func TestValidateConfig_FileExportWithoutPath(t *testing.T) {
 	env := validEnv()
 	env["PAYFLUX_EXPORT_MODE"] = "file"
@@ -251,6 +265,31 @@ func TestValidateConfig_NonIntegerRPS(t *testing.T) {
 	}
 }
 
+func TestValidateConfig_NegativeBackpressure(t *testing.T) {
+	env := validEnv()
+	env["PAYFLUX_BACKPRESSURE_THRESHOLD"] = "-5"
+	withEnv(t, env)
+
+	err := ValidateConfig()
+	if err == nil {
+		t.Fatal("expected error for negative backpressure threshold")
+	}
+	if !strings.Contains(err.Error(), "PAYFLUX_BACKPRESSURE_THRESHOLD") {
+		t.Errorf("error should mention PAYFLUX_BACKPRESSURE_THRESHOLD, got: %s", err.Error())
+	}
+}
+
+func TestValidateConfig_NonIntegerWorkers(t *testing.T) {
+	env := validEnv()
+	env["PAYFLUX_CONSUMER_WORKERS"] = "abc"
+	withEnv(t, env)
+
+	err := ValidateConfig()
+	if err == nil {
+		t.Fatal("expected error for non-integer worker count")
+	}
+}
+
 func TestValidateConfig_MultipleErrors(t *testing.T) {
 	env := validEnv()
 	env["PAYFLUX_API_KEY"] = ""
@@ -296,3 +335,111 @@ func TestValidateConfig_NegativeStreamMaxLen(t *testing.T) {
 		t.Fatal("expected error for negative stream maxlen")
 	}
 }
+
+func TestValidateConfig_ExportSinkUnknownKind(t *testing.T) {
+	repoRoot(t)
+	env := validEnv()
+	env["PAYFLUX_EXPORT_SINKS"] = "warehouse=carrier_pigeon"
+	withEnv(t, env)
+
+	err := ValidateConfig()
+	if err == nil {
+		t.Fatal("expected error for unknown sink kind")
+	}
+	if !strings.Contains(err.Error(), "PAYFLUX_EXPORT_SINKS") {
+		t.Errorf("error should mention PAYFLUX_EXPORT_SINKS, got: %s", err.Error())
+	}
+}
+
+func TestValidateConfig_ExportSinkMissingOption(t *testing.T) {
+	repoRoot(t)
+	env := validEnv()
+	env["PAYFLUX_EXPORT_SINKS"] = "warehouse=webhook"
+	env["PAYFLUX_SINK_WAREHOUSE_URL"] = "https://example.invalid/hook"
+	env["PAYFLUX_SINK_WAREHOUSE_SECRET"] = ""
+	withEnv(t, env)
+
+	err := ValidateConfig()
+	if err == nil {
+		t.Fatal("expected error for webhook sink without SECRET")
+	}
+	if !strings.Contains(err.Error(), "SECRET") {
+		t.Errorf("error should mention SECRET, got: %s", err.Error())
+	}
+}
+
+func TestValidateConfig_ExportSinkValid(t *testing.T) {
+	repoRoot(t)
+	env := validEnv()
+	env["PAYFLUX_EXPORT_SINKS"] = "warehouse=webhook"
+	env["PAYFLUX_SINK_WAREHOUSE_URL"] = "https://example.invalid/hook"
+	env["PAYFLUX_SINK_WAREHOUSE_SECRET"] = "whsec_test"
+	withEnv(t, env)
+
+	if err := ValidateConfig(); err != nil {
+		t.Fatalf("expected no error with valid sink config, got: %v", err)
+	}
+}
+
+func TestValidateConfig_InvalidWarningStore(t *testing.T) {
+	repoRoot(t)
+	env := validEnv()
+	env["PAYFLUX_WARNING_STORE"] = "postgres"
+	withEnv(t, env)
+
+	err := ValidateConfig()
+	if err == nil {
+		t.Fatal("expected error for invalid warning store backend")
+	}
+	if !strings.Contains(err.Error(), "PAYFLUX_WARNING_STORE") {
+		t.Errorf("error should mention PAYFLUX_WARNING_STORE, got: %s", err.Error())
+	}
+}
+
+func TestValidateConfig_InvalidMerchantScope(t *testing.T) {
+	repoRoot(t)
+	env := validEnv()
+	env["PAYFLUX_RISK_MERCHANT_SCOPE"] = "per_merchant"
+	env["PAYFLUX_RISK_MAX_MERCHANTS"] = "0"
+	withEnv(t, env)
+
+	err := ValidateConfig()
+	if err == nil {
+		t.Fatal("expected error for invalid merchant scope")
+	}
+	for _, key := range []string{"PAYFLUX_RISK_MERCHANT_SCOPE", "PAYFLUX_RISK_MAX_MERCHANTS"} {
+		if !strings.Contains(err.Error(), key) {
+			t.Errorf("error should mention %s, got: %s", key, err.Error())
+		}
+	}
+}
+
+func TestValidateConfig_InvalidRiskModelFile(t *testing.T) {
+	repoRoot(t)
+	path := filepath.Join(t.TempDir(), "model.json")
+	if err := os.WriteFile(path, []byte(`{"version":"v1","weights":{"fail_rate":2}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	env := validEnv()
+	env["PAYFLUX_RISK_MODEL_FILE"] = path
+	withEnv(t, env)
+
+	err := ValidateConfig()
+	if err == nil {
+		t.Fatal("expected error for invalid risk model")
+	}
+	if !strings.Contains(err.Error(), "PAYFLUX_RISK_MODEL_FILE") || !strings.Contains(err.Error(), "weights.fail_rate") {
+		t.Errorf("error should name the model file and the bad weight, got: %s", err.Error())
+	}
+}
+
+func TestValidateConfig_ExampleRiskModelFile(t *testing.T) {
+	repoRoot(t)
+	env := validEnv()
+	env["PAYFLUX_RISK_MODEL_FILE"] = "config/risk_model.example.yaml"
+	withEnv(t, env)
+
+	if err := ValidateConfig(); err != nil {
+		t.Errorf("example model should validate: %v", err)
+	}
+}
@@ -10,3 +10,27 @@ const (
 	TierPro        CanonicalTier = "pro"
 	TierEnterprise CanonicalTier = "enterprise"
 )
+
+// Parse maps a canonical tier name ("free", "pro", "enterprise") to its
+// CanonicalTier. Legacy runtime values go through ResolveFromEnv instead.
+func Parse(s string) (CanonicalTier, bool) {
+	switch t := CanonicalTier(s); t {
+	case TierFree, TierPro, TierEnterprise:
+		return t, true
+	}
+	return "", false
+}
+
+// EntitlementTier maps t to the entitlement tier name used by
+// config/tier_entitlements.runtime.json and internal/entitlements, for
+// identities that do not name one explicitly.
+func (t CanonicalTier) EntitlementTier() string {
+	switch t {
+	case TierEnterprise:
+		return "fortress"
+	case TierPro:
+		return "shield"
+	default:
+		return "baseline"
+	}
+}
@@ -0,0 +1,89 @@
+package tier
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// Response headers describing how a response was gated.
+const (
+	HeaderTier     = "X-PayFlux-Tier"
+	HeaderWithheld = "X-PayFlux-Withheld-Features"
+)
+
+// Forbidden is the 403 body for a request whose tier lacks a feature.
+type Forbidden struct {
+	Error   string        `json:"error"`
+	Feature Feature       `json:"feature"`
+	Tier    CanonicalTier `json:"tier"`
+}
+
+// WriteForbidden responds 403 naming the missing feature.
+func WriteForbidden(w http.ResponseWriter, t CanonicalTier, f Feature) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set(HeaderTier, string(t))
+	w.WriteHeader(http.StatusForbidden)
+	json.NewEncoder(w).Encode(Forbidden{Error: "feature_not_available", Feature: f, Tier: t})
+}
+
+// Require wraps next so it only runs when the request's tier (see
+// FromContext) can access f. Mount it inside the middleware that resolves
+// the tier.
+func Require(f Feature, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		t := FromContext(r.Context())
+		if !CanAccess(t, f) {
+			WriteForbidden(w, t, f)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// Gate decides per-feature field stripping for one response and records
+// what it withheld.
+type Gate struct {
+	Tier     CanonicalTier
+	withheld []Feature
+}
+
+// NewGate returns a Gate for the request's tier.
+func NewGate(r *http.Request) *Gate {
+	return &Gate{Tier: FromContext(r.Context())}
+}
+
+// Allow reports whether fields behind f may be returned, recording f as
+// withheld when not.
+func (g *Gate) Allow(f Feature) bool {
+	if CanAccess(g.Tier, f) {
+		return true
+	}
+	for _, w := range g.withheld {
+		if w == f {
+			return false
+		}
+	}
+	g.withheld = append(g.withheld, f)
+	return false
+}
+
+// Withheld returns the features whose fields were stripped, in the order
+// first denied.
+func (g *Gate) Withheld() []Feature {
+	return g.withheld
+}
+
+// SetHeaders reports the tier and the withheld features on the response.
+// Call it before the body is written.
+func (g *Gate) SetHeaders(h http.Header) {
+	h.Set(HeaderTier, string(g.Tier))
+	if len(g.withheld) == 0 {
+		return
+	}
+	names := make([]string, len(g.withheld))
+	for i, f := range g.withheld {
+		names[i] = string(f)
+	}
+	h.Set(HeaderWithheld, strings.Join(names, ","))
+}
@@ -0,0 +1,119 @@
+package tier
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+type contextKey struct{}
+
+// WithTier returns a copy of ctx carrying the request's resolved tier.
+func WithTier(ctx context.Context, t CanonicalTier) context.Context {
+	return context.WithValue(ctx, contextKey{}, t)
+}
+
+// FromContext returns the tier resolved for the request. Requests that
+// never went through resolution are TierFree: gating fails closed.
+func FromContext(ctx context.Context) CanonicalTier {
+	if t, ok := ctx.Value(contextKey{}).(CanonicalTier); ok {
+		return t
+	}
+	return TierFree
+}
+
+// RequestResolver resolves the tier of an authenticated API key, in order:
+//
+//  1. a tier bound to the key itself (KeyTiers);
+//  2. the entitlement tier of the workspace the key is bound to
+//     (KeyWorkspaces, looked up through WorkspaceTier);
+//  3. Default, the deployment-wide tier from PAYFLUX_TIER.
+type RequestResolver struct {
+	Default       CanonicalTier
+	KeyTiers      map[string]CanonicalTier
+	KeyWorkspaces map[string]string
+
+	// WorkspaceTier looks up a workspace's entitlement tier. Nil when no
+	// workspace store is available; bound keys then resolve to Default.
+	WorkspaceTier func(ctx context.Context, workspaceID string) (CanonicalTier, error)
+}
+
+// Resolve returns the tier for apiKey. A failed workspace lookup returns
+// Default together with the error, so the caller can log it and still
+// serve the request.
+func (r *RequestResolver) Resolve(ctx context.Context, apiKey string) (CanonicalTier, error) {
+	if t, ok := r.KeyTiers[apiKey]; ok {
+		return t, nil
+	}
+	workspaceID, ok := r.KeyWorkspaces[apiKey]
+	if !ok {
+		return r.defaultTier(), nil
+	}
+	return r.ResolveWorkspace(ctx, workspaceID)
+}
+
+// ResolveWorkspace returns the entitlement tier of workspaceID, or Default
+// when there is no workspace store. Errors are handled as in Resolve.
+func (r *RequestResolver) ResolveWorkspace(ctx context.Context, workspaceID string) (CanonicalTier, error) {
+	if r.WorkspaceTier == nil {
+		return r.defaultTier(), nil
+	}
+	t, err := r.WorkspaceTier(ctx, workspaceID)
+	if err != nil {
+		return r.defaultTier(), fmt.Errorf("workspace %s: %w", workspaceID, err)
+	}
+	return t, nil
+}
+
+func (r *RequestResolver) defaultTier() CanonicalTier {
+	if r.Default == "" {
+		return TierFree
+	}
+	return r.Default
+}
+
+// ParseKeyTiers parses PAYFLUX_API_KEY_TIERS: comma-separated
+// "<api key>=<free|pro|enterprise>" bindings.
+func ParseKeyTiers(spec string) (map[string]CanonicalTier, error) {
+	bindings, err := parseBindings(spec)
+	if err != nil {
+		return nil, err
+	}
+	tiers := make(map[string]CanonicalTier, len(bindings))
+	for key, value := range bindings {
+		t, ok := Parse(value)
+		if !ok {
+			return nil, fmt.Errorf("unknown tier %q (want free, pro or enterprise)", value)
+		}
+		tiers[key] = t
+	}
+	return tiers, nil
+}
+
+// ParseKeyWorkspaces parses PAYFLUX_API_KEY_WORKSPACES: comma-separated
+// "<api key>=<workspace id>" bindings.
+func ParseKeyWorkspaces(spec string) (map[string]string, error) {
+	return parseBindings(spec)
+}
+
+// parseBindings splits "k=v,k=v". Errors never quote the key: it is a
+// secret.
+func parseBindings(spec string) (map[string]string, error) {
+	bindings := map[string]string{}
+	for i, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(entry, "=")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if !ok || key == "" || value == "" {
+			return nil, fmt.Errorf("entry %d: want <api key>=<value>", i+1)
+		}
+		if _, dup := bindings[key]; dup {
+			return nil, fmt.Errorf("entry %d: key bound twice", i+1)
+		}
+		bindings[key] = value
+	}
+	return bindings, nil
+}
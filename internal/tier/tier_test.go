@@ -0,0 +1,129 @@
+package tier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequestResolverOrder(t *testing.T) {
+	lookups := 0
+	r := &RequestResolver{
+		Default:       TierFree,
+		KeyTiers:      map[string]CanonicalTier{"key-ent": TierEnterprise, "key-both": TierPro},
+		KeyWorkspaces: map[string]string{"key-ws": "ws-1", "key-both": "ws-1", "key-broken": "ws-2"},
+		WorkspaceTier: func(_ context.Context, id string) (CanonicalTier, error) {
+			lookups++
+			if id == "ws-1" {
+				return TierEnterprise, nil
+			}
+			return "", errors.New("workspace not found")
+		},
+	}
+
+	tests := []struct {
+		key     string
+		want    CanonicalTier
+		wantErr bool
+	}{
+		{"key-ent", TierEnterprise, false},
+		{"key-both", TierPro, false}, // key binding wins over workspace
+		{"key-ws", TierEnterprise, false},
+		{"key-broken", TierFree, true},
+		{"key-unbound", TierFree, false},
+	}
+	for _, tt := range tests {
+		got, err := r.Resolve(context.Background(), tt.key)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("Resolve(%s) = %q, %v; want %q, error %v", tt.key, got, err, tt.want, tt.wantErr)
+		}
+	}
+	if lookups != 2 {
+		t.Errorf("workspace lookups = %d, want 2", lookups)
+	}
+}
+
+func TestParseKeyTiers(t *testing.T) {
+	got, err := ParseKeyTiers(" key-a=pro, key-b=enterprise ,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got["key-a"] != TierPro || got["key-b"] != TierEnterprise || len(got) != 2 {
+		t.Errorf("ParseKeyTiers = %v", got)
+	}
+
+	for _, spec := range []string{"key-a", "key-a=", "key-a=gold", "key-a=pro,key-a=free"} {
+		_, err := ParseKeyTiers(spec)
+		if err == nil {
+			t.Errorf("ParseKeyTiers(%q) accepted", spec)
+			continue
+		}
+		// Keys are secrets and must never appear in config errors.
+		if strings.Contains(err.Error(), "key-a") {
+			t.Errorf("ParseKeyTiers(%q) error leaks the key: %v", spec, err)
+		}
+	}
+}
+
+func TestRequire(t *testing.T) {
+	handler := Require(FeatureEvidenceExport, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	for _, tt := range []struct {
+		tier CanonicalTier
+		want int
+	}{
+		{"", http.StatusForbidden}, // unresolved requests fail closed
+		{TierFree, http.StatusForbidden},
+		{TierPro, http.StatusNoContent},
+		{TierEnterprise, http.StatusNoContent},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tt.tier != "" {
+			req = req.WithContext(WithTier(req.Context(), tt.tier))
+		}
+		w := httptest.NewRecorder()
+		handler(w, req)
+		if w.Code != tt.want {
+			t.Errorf("tier %q: status %d, want %d", tt.tier, w.Code, tt.want)
+			continue
+		}
+		if w.Code != http.StatusForbidden {
+			continue
+		}
+		var body Forbidden
+		if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+			t.Fatalf("decode 403 body: %v", err)
+		}
+		if body.Feature != FeatureEvidenceExport || body.Tier != TierFree {
+			t.Errorf("tier %q: 403 body = %+v", tt.tier, body)
+		}
+	}
+}
+
+func TestGate(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req = req.WithContext(WithTier(req.Context(), TierPro))
+	g := NewGate(req)
+
+	if !g.Allow(FeatureSlopeModeling) {
+		t.Error("pro denied slope_modeling")
+	}
+	g.Allow(FeatureSystemShockBlend)
+	g.Allow(FeatureBulkExport)
+	g.Allow(FeatureSystemShockBlend)
+
+	h := http.Header{}
+	g.SetHeaders(h)
+	if h.Get(HeaderTier) != "pro" {
+		t.Errorf("%s = %q", HeaderTier, h.Get(HeaderTier))
+	}
+	if got := h.Get(HeaderWithheld); got != "system_shock_blend,bulk_export" {
+		t.Errorf("%s = %q", HeaderWithheld, got)
+	}
+}
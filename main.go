@@ -24,10 +24,13 @@ import (
 	"syscall"
 	"time"
 
+	"payment-node/internal/apikeys"
+	"payment-node/internal/config"
 	"payment-node/internal/exporter"
 	"payment-node/internal/forecast"
 	"payment-node/internal/httpmw"
 	"payment-node/internal/ratelimit"
+	"payment-node/internal/reducer/subscription/manual"
 	"payment-node/internal/startup"
 	"payment-node/internal/tier"
 
@@ -38,7 +41,6 @@ import (
 	"github.com/redis/go-redis/v9"
 	"github.com/stripe/stripe-go/v74"
 	"github.com/stripe/stripe-go/v74/checkout/session"
-	"golang.org/x/time/rate"
 	"database/sql"
 	"payment-node/internal/api"
 	"payment-node/pg"
@@ -60,6 +62,10 @@ type Event struct {
 	Channel             string `json:"channel"`
 	RetryResult         string `json:"retry_result"`
 	FailureOrigin       string `json:"failure_origin"`
+
+	// Set at ingest from the caller's API key, never from the request
+	// body; retention (retention.go) prunes by it.
+	WorkspaceID string `json:"workspace_id,omitempty"`
 }
 
 type CheckoutRequest struct {
@@ -92,10 +98,6 @@ var (
 	streamMaxLen    int64
 	rawEventTTLDays int // PAYFLUX_RAW_EVENT_TTL_DAYS (default 7)
 
-	// Rate limit config (set in main)
-	rateLimitRPS   float64
-	rateLimitBurst int
-
 	// Panic mode config (set in main)
 	panicMode string // "crash" or "recover"
 
@@ -107,7 +109,8 @@ var (
 	exportMode         string   // "stdout", "file", "both"
 	exportFile         *os.File // file handle if file export enabled
 	exportWriter       *bufio.Writer
-	consumerNameGlobal string // for export metadata
+	exportSinks        []exporter.ConfiguredSink // PAYFLUX_EXPORT_SINKS (optional)
+	consumerNameGlobal string                    // for export metadata
 
 	// Export health tracking (atomic int64 Unix timestamps)
 	exportLastSuccessStdout int64 // atomic
@@ -123,28 +126,35 @@ var (
 	// Listed here for documentation: var exporterInstance *exporter.Exporter
 
 	// Risk Scorer (v0.2.1+)
-	riskScorer       *RiskScorer
-	riskScoreEnabled bool
-	riskScoreWindow  int
-	riskThresholds   [3]float64
+	riskScorer        *RiskScorer
+	riskScoreEnabled  bool
+	riskScoreWindow   int
+	riskThresholds    [3]float64
+	riskMerchantScope MerchantScope // PAYFLUX_RISK_MERCHANT_SCOPE (default merchant)
+	riskMaxMerchants  int           // PAYFLUX_RISK_MAX_MERCHANTS (default 10000)
+	riskModelFile     string        // PAYFLUX_RISK_MODEL_FILE (empty = built-in model)
+	riskModelReload   time.Duration // PAYFLUX_RISK_MODEL_RELOAD_SEC (default 30s, 0 = SIGHUP only)
+
+	// Shadow scoring (nil unless PAYFLUX_RISK_SHADOW_MODEL_FILE is set)
+	shadowScorer     *RiskScorer
+	shadowComparator *ShadowComparator
+	shadowModelFile  string
 
 	// Tier gating (v0.2.2+)
 	exportTier           string            // "tier1" or "tier2" (default tier1)
 	runtimeCanonicalTier tier.CanonicalTier // Resolved once at startup from exportTier
 
 	// Pilot mode (v0.2.3+)
-	pilotModeEnabled bool
-	warningStore     *WarningStore
+	pilotModeEnabled     bool
+	warningStore         WarningStorage
+	warningStoreBackend  string // PAYFLUX_WARNING_STORE: "memory" (default) or "redis"
+	warningStoreCapacity int    // PAYFLUX_WARNING_STORE_CAPACITY (default 1000)
 
 	// Operational Guardrails (v0.2.4+)
 	payfluxEnv            string // "dev" or "prod" (default dev)
 	ingestEnabled         bool   // PAYFLUX_INGEST_ENABLED (default true)
 	warningsEnabled       bool   // PAYFLUX_WARNINGS_ENABLED (default true)
 	tier2Enabled          bool   // PAYFLUX_TIER2_ENABLED (default true)
-	ingestRPS             float64
-	ingestBurst           int
-	outcomeRPS            float64
-	outcomeBurst          int
 	backpressureThreshold int64 // Stream depth threshold for warning logs
 
 	// Config fingerprint (computed at startup, immutable after)
@@ -154,14 +164,6 @@ var (
 	pgDB *sql.DB
 )
 
-// Rate limiter maps (per API key)
-var (
-	rateLimiters    = make(map[string]*rate.Limiter)
-	outcomeLimiters = make(map[string]*rate.Limiter) // Separate for outcome endpoint
-	rlMu            sync.RWMutex
-	outcomeRlMu     sync.RWMutex
-)
-
 // Prometheus metrics
 var (
 	ingestAccepted = prometheus.NewCounter(prometheus.CounterOpts{
@@ -176,6 +178,11 @@ var (
 		Name: "payflux_consumer_processed_total",
 		Help: "Total number of events processed by the consumer.",
 	})
+	consumerBatchSizeHist = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "payflux_consumer_batch_size",
+		Help:    "Messages per consumer batch (XAUTOCLAIM or XREADGROUP read).",
+		Buckets: []float64{1, 5, 10, 25, 50, 100, 250, 500, 1000},
+	})
 	consumerDlq = prometheus.NewCounter(prometheus.CounterOpts{
 		Name: "payflux_consumer_dlq_total",
 		Help: "Total number of events sent to the DLQ.",
@@ -197,6 +204,11 @@ var (
 		Help:    "Latency of HTTP ingest endpoint",
 		Buckets: []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1},
 	})
+	ingestBatchSize = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "payflux_ingest_batch_size",
+		Help:    "Number of items per batch ingest request",
+		Buckets: []float64{1, 10, 50, 100, 250, 500, 1000},
+	})
 	eventsByProcessor = prometheus.NewCounterVec(prometheus.CounterOpts{
 		Name: "payflux_events_by_processor_total",
 		Help: "Events ingested, labeled by processor",
@@ -223,6 +235,49 @@ var (
 		Name: "payflux_processor_risk_score_last",
 		Help: "Last computed risk score per processor",
 	}, []string{"processor"})
+	merchantRiskEventsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "payflux_merchant_risk_events_total",
+		Help: "Merchant-scored events grouped by merchant risk band (merchant hash is not a label)",
+	}, []string{"band"})
+	riskModelInfo = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "payflux_risk_model_info",
+		Help: "Active risk scoring model per role (primary, shadow); value 1 on the active version label",
+	}, []string{"role", "version"})
+	riskModelReloads = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "payflux_risk_model_reload_total",
+		Help: "Risk model hot-reload attempts by role and result (success, error)",
+	}, []string{"role", "result"})
+
+	// Shadow scoring (compares a candidate model against the primary)
+	shadowEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "payflux_risk_shadow_events_total",
+		Help: "Events scored by both the primary and shadow risk models",
+	}, []string{"processor"})
+	shadowBandDisagreements = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "payflux_risk_shadow_band_disagreements_total",
+		Help: "Events where the shadow model's processor band differs from the primary's",
+	}, []string{"processor", "primary_band", "shadow_band"})
+	shadowScoreDelta = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "payflux_risk_shadow_score_delta",
+		Help:    "Shadow minus primary processor risk score",
+		Buckets: []float64{-0.5, -0.25, -0.1, -0.05, -0.01, 0.01, 0.05, 0.1, 0.25, 0.5},
+	}, []string{"processor"})
+	shadowWarnings = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "payflux_risk_shadow_would_warn_total",
+		Help: "Events that would raise a pilot warning, by model role (primary, shadow)",
+	}, []string{"processor", "role"})
+	warningWebhookDeliveries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "payflux_warning_webhook_deliveries_total",
+		Help: "Warning webhook deliveries by endpoint and result (delivered, failed, dropped)",
+	}, []string{"endpoint", "result"})
+	riskSnapshotOps = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "payflux_risk_snapshot_total",
+		Help: "Risk window snapshot operations by op (save, restore) and result (success, error)",
+	}, []string{"op", "result"})
+	riskMerchantEvictions = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "payflux_risk_merchant_evictions_total",
+		Help: "Merchant risk windows evicted because PAYFLUX_RISK_MAX_MERCHANTS was reached",
+	})
 
 	// Tier 2 metrics (v0.2.2+)
 	tier2ContextEmitted = prometheus.NewCounter(prometheus.CounterOpts{
@@ -271,11 +326,11 @@ var (
 	// Phase 3B: Per-account rate limiting metrics
 	rateLimitAllowed = prometheus.NewCounter(prometheus.CounterOpts{
 		Name: "payflux_rate_limit_allowed_total",
-		Help: "Total number of allowed requests (per-account rate limiting)",
+		Help: "Total number of allowed requests (per-workspace rate limiting)",
 	})
 	rateLimitExceeded = prometheus.NewCounter(prometheus.CounterOpts{
 		Name: "payflux_rate_limit_exceeded_total",
-		Help: "Total number of rate limited requests (per-account)",
+		Help: "Total number of rate limited requests (per-workspace)",
 	})
 	rateLimitError = prometheus.NewCounterVec(prometheus.CounterOpts{
 		Name: "payflux_rate_limit_error_total",
@@ -296,6 +351,14 @@ var (
 		Name: "payflux_dlq_depth",
 		Help: "Current number of messages in the DLQ stream",
 	})
+	dlqReplayed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "payflux_dlq_replay_total",
+		Help: "DLQ replay attempts by result (replayed, replay_limit_reached, failed)",
+	}, []string{"result"})
+	retentionDeleted = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "payflux_retention_deleted_total",
+		Help: "Records deleted by per-workspace retention, by store (warnings, dlq, tpv_snapshots, export_file)",
+	}, []string{"store"})
 )
 
 // Helper: Setup logging
@@ -311,12 +374,21 @@ func loadConfiguration() (redisAddr, httpAddr string) {
 	redisAddr = env("REDIS_ADDR", "localhost:6379")
 	httpAddr = env("HTTP_ADDR", ":8080")
 
-	// Load API keys (multi-key support)
+	// Load API keys (multi-key support). With a key registry the legacy
+	// keys are optional.
 	validAPIKeys = loadAPIKeys()
-	if len(validAPIKeys) == 0 {
+	apiKeyRegistryMode = os.Getenv("PAYFLUX_API_KEY_REGISTRY")
+	if len(validAPIKeys) == 0 && apiKeyRegistryMode == "" {
 		log.Fatal("PAYFLUX_API_KEY or PAYFLUX_API_KEYS must be set")
 	}
-	slog.Info("api_keys_loaded", "count", len(validAPIKeys))
+	slog.Info("api_keys_loaded", "count", len(validAPIKeys), "registry", apiKeyRegistryMode)
+
+	// Legacy keys are limited to ingest and read; admin routes need a
+	// registry key with the admin scope unless explicitly opted in.
+	legacyKeysAdmin = os.Getenv("PAYFLUX_LEGACY_KEYS_ADMIN") == "true"
+	if legacyKeysAdmin && len(validAPIKeys) > 0 {
+		slog.Warn("legacy_keys_admin_enabled", "count", len(validAPIKeys))
+	}
 
 	// Load revoked keys (denylist)
 	revokedAPIKeys = loadRevokedAPIKeys()
@@ -324,11 +396,6 @@ func loadConfiguration() (redisAddr, httpAddr string) {
 		slog.Warn("revoked_keys_loaded", "count", len(revokedAPIKeys))
 	}
 
-	// Load rate limit config (legacy vars, still supported)
-	rateLimitRPS = float64(envInt("PAYFLUX_RATELIMIT_RPS", 100))
-	rateLimitBurst = envInt("PAYFLUX_RATELIMIT_BURST", 500)
-	slog.Info("rate_limit_configured", "rps", rateLimitRPS, "burst", rateLimitBurst)
-
 	// Load stream retention config
 	streamMaxLen = int64(envInt("PAYFLUX_STREAM_MAXLEN", 200000))
 	if streamMaxLen > 0 {
@@ -352,6 +419,13 @@ func loadConfiguration() (redisAddr, httpAddr string) {
 	}
 	slog.Info("panic_mode", "mode", panicMode)
 
+	// Consumer worker pool (see consumer_pool.go)
+	consumerWorkers = envInt("PAYFLUX_CONSUMER_WORKERS", defaultConsumerWorkers)
+	consumerBatchSize = envInt("PAYFLUX_CONSUMER_BATCH_SIZE", defaultConsumerBatchSize)
+	if consumerWorkers < 1 || consumerBatchSize < 1 {
+		log.Fatalf("PAYFLUX_CONSUMER_WORKERS and PAYFLUX_CONSUMER_BATCH_SIZE must be >= 1, got: %d, %d", consumerWorkers, consumerBatchSize)
+	}
+
 	loadRiskScoringConfig()
 	loadTierConfig()
 	loadPilotModeConfig()
@@ -374,9 +448,20 @@ func loadRiskScoringConfig() {
 			}
 		}
 	}
+	scope, ok := ParseMerchantScope(env("PAYFLUX_RISK_MERCHANT_SCOPE", string(MerchantScopeMerchant)))
+	if !ok {
+		log.Fatalf("PAYFLUX_RISK_MERCHANT_SCOPE must be 'merchant', 'processor_merchant' or 'off', got: %s", os.Getenv("PAYFLUX_RISK_MERCHANT_SCOPE"))
+	}
+	riskMerchantScope = scope
+	riskMaxMerchants = envInt("PAYFLUX_RISK_MAX_MERCHANTS", defaultMaxMerchants)
 	if riskScoreEnabled {
 		riskScorer = NewRiskScorer(riskScoreWindow, riskThresholds)
-		slog.Info("risk_scorer_initialized", "window_sec", riskScoreWindow, "thresholds", thresholdsStr)
+		riskScorer.SetMerchantScope(riskMerchantScope, riskMaxMerchants)
+		loadRiskModel()
+		loadShadowRiskModel()
+		slog.Info("risk_scorer_initialized", "window_sec", riskScoreWindow, "thresholds", thresholdsStr,
+			"merchant_scope", riskMerchantScope, "max_merchants", riskMaxMerchants,
+			"model_version", riskScorer.Model().Version)
 	}
 }
 
@@ -391,17 +476,39 @@ func loadTierConfig() {
 	if exportTier == "tier1" {
 		slog.Info("tier_hint", "msg", "Set PAYFLUX_TIER=tier2 to include playbook context and risk trajectory in exports")
 	}
+	loadRequestTierConfig()
 }
 
 // Helper: Load pilot mode configuration
 func loadPilotModeConfig() {
 	pilotModeEnabled = env("PAYFLUX_PILOT_MODE", "false") == "true"
+	warningStoreBackend = env("PAYFLUX_WARNING_STORE", "memory")
+	if warningStoreBackend != "memory" && warningStoreBackend != "redis" {
+		log.Fatalf("PAYFLUX_WARNING_STORE must be 'memory' or 'redis', got: %s", warningStoreBackend)
+	}
+	warningStoreCapacity = envInt("PAYFLUX_WARNING_STORE_CAPACITY", 1000)
 	if pilotModeEnabled {
-		warningStore = NewWarningStore(1000)
-		slog.Info("pilot_mode_enabled", "warning_store_capacity", 1000)
+		// The redis backend is constructed in setupWarningStore once rdb is connected.
+		if warningStoreBackend == "memory" {
+			warningStore = NewWarningStore(warningStoreCapacity)
+		}
+		slog.Info("pilot_mode_enabled",
+			"warning_store", warningStoreBackend,
+			"warning_store_capacity", warningStoreCapacity,
+		)
 	}
 }
 
+// setupWarningStore builds the Redis-backed warning store. Must run after
+// setupRedis; no-op unless pilot mode is enabled with PAYFLUX_WARNING_STORE=redis.
+func setupWarningStore() {
+	if !pilotModeEnabled || warningStoreBackend != "redis" {
+		return
+	}
+	warningStore = NewRedisWarningStore(rdb, "pilot:warnings", warningStoreCapacity)
+	slog.Info("warning_store_ready", "backend", "redis", "count", warningStore.Count())
+}
+
 // Helper: Load operational guardrails configuration
 func loadGuardrailsConfig() {
 	payfluxEnv = env("PAYFLUX_ENV", "dev")
@@ -414,11 +521,6 @@ func loadGuardrailsConfig() {
 	warningsEnabled = env("PAYFLUX_WARNINGS_ENABLED", "true") == "true"
 	tier2Enabled = env("PAYFLUX_TIER2_ENABLED", "true") == "true"
 
-	// Rate limit config (separate for ingest and outcome)
-	ingestRPS = float64(envInt("PAYFLUX_INGEST_RPS", 100))
-	ingestBurst = envInt("PAYFLUX_INGEST_BURST", 500)
-	outcomeRPS = float64(envInt("PAYFLUX_OUTCOME_RPS", 10))
-	outcomeBurst = envInt("PAYFLUX_OUTCOME_BURST", 20)
 	backpressureThreshold = int64(envInt("PAYFLUX_BACKPRESSURE_THRESHOLD", 10000))
 
 	// Loud startup logs for guardrail state
@@ -427,10 +529,6 @@ func loadGuardrailsConfig() {
 		"ingest_enabled", ingestEnabled,
 		"warnings_enabled", warningsEnabled,
 		"tier2_enabled", tier2Enabled,
-		"ingest_rps", ingestRPS,
-		"ingest_burst", ingestBurst,
-		"outcome_rps", outcomeRPS,
-		"outcome_burst", outcomeBurst,
 		"backpressure_threshold", backpressureThreshold,
 	)
 
@@ -465,9 +563,12 @@ func validateProduction() {
 // Helper: Initialize Prometheus metrics
 func initializePrometheus() {
 	prometheus.MustRegister(
-		ingestAccepted, ingestRejected, consumerProcessed, consumerDlq, ingestDuplicate,
-		streamLength, pendingCount, ingestLatency, eventsByProcessor, eventsExported,
+		ingestAccepted, ingestRejected, consumerProcessed, consumerBatchSizeHist, consumerDlq, ingestDuplicate,
+		streamLength, pendingCount, ingestLatency, ingestBatchSize, eventsByProcessor, eventsExported,
 		exportErrors, exportLastSuccess, riskEventsTotal, riskScoreLast,
+		merchantRiskEventsTotal, riskMerchantEvictions, riskModelInfo, riskModelReloads,
+		shadowEvents, shadowBandDisagreements, shadowScoreDelta, shadowWarnings, riskSnapshotOps,
+		warningWebhookDeliveries,
 		tier2ContextEmitted, tier2TrajectoryEmitted,
 		warningOutcomeSetTotal,
 		warningOutcomeLeadTime,
@@ -482,6 +583,8 @@ func initializePrometheus() {
 		exportDuration,
 		streamEvictions,
 		dlqDepth,
+		dlqReplayed,
+		retentionDeleted,
 	)
 }
 
@@ -528,6 +631,9 @@ func setupExportAndConsumer(ctx context.Context) {
 	if rawEventTTLDays > 0 {
 		go runStreamRetention(ctx)
 	}
+	if retentionMode != retentionOff {
+		go runRetentionWorker(ctx)
+	}
 }
 
 // Helper: Setup HTTP server and routes
@@ -536,15 +642,40 @@ func setupHTTPServer(httpAddr string) *http.Server {
 
 	// Apply auth and rate limit middleware
 	mux.HandleFunc("/v1/events/payment_exhaust",
-		authMiddleware(rateLimitMiddleware(handleEvent)))
+		scopedMiddleware(apikeys.ScopeIngest, rateLimitMiddleware(handleEvent)))
+	mux.HandleFunc("/v1/events/payment_exhaust:batch",
+		scopedMiddleware(apikeys.ScopeIngest, handleEventBatch)) // charges per item
 	mux.HandleFunc("/checkout",
-		authMiddleware(rateLimitMiddleware(handleCheckout)))
+		scopedMiddleware(apikeys.ScopeIngest, rateLimitMiddleware(handleCheckout)))
 
 	// Risk forecast endpoint
-	mux.HandleFunc("/api/v1/risk/forecast", authMiddleware(handleRiskForecast))
+	mux.HandleFunc("/api/v1/risk/forecast", scopedMiddleware(apikeys.ScopeRead, tier.Require(tier.FeatureBasicRiskScore, handleRiskForecast)))
+	mux.HandleFunc("/api/v1/risk/shadow", scopedMiddleware(apikeys.ScopeRead, handleRiskShadow))
+
+	// DLQ inspection and replay
+	mux.HandleFunc("/api/v1/dlq", adminMiddleware(handleDlqList))
+	mux.HandleFunc("/api/v1/dlq/replay", adminMiddleware(handleDlqReplay))
+	mux.HandleFunc("/api/v1/dlq/", adminMiddleware(handleDlqEntry))
+
+	// Per-workspace retention report and on-demand runs
+	mux.HandleFunc("/api/v1/retention", adminMiddleware(handleRetention))
+
+	// Warning webhook status and delivery log: endpoint URLs can carry
+	// secrets and the log spans every workspace.
+	mux.HandleFunc("/api/v1/warning-webhooks", deploymentAdminMiddleware(handleWarningWebhooks))
+	mux.HandleFunc("/api/v1/warning-webhooks/", deploymentAdminMiddleware(handleWarningWebhooks))
 
 	if pgDB != nil {
 		mux.HandleFunc("/api/v1/signals/evaluate", api.EvaluateFailureVelocityHandler(pgDB))
+
+		// Operator corrections to subscription projections
+		mux.HandleFunc("/api/v1/subscriptions/corrections", adminMiddleware(manual.Handler(pgDB)))
+	}
+
+	// API key lifecycle (postgres registry only)
+	if apiKeyStore != nil {
+		mux.HandleFunc("/api/v1/api-keys", adminMiddleware(apikeys.Handler(apiKeyStore)))
+		mux.HandleFunc("/api/v1/api-keys/", adminMiddleware(apikeys.Handler(apiKeyStore)))
 	}
 
 	// Health and metrics remain unauthenticated
@@ -571,11 +702,11 @@ func setupHTTPServer(httpAddr string) *http.Server {
 // Helper: Register pilot mode routes
 func registerPilotRoutes(mux *http.ServeMux) {
 	if pilotModeEnabled && warningStore != nil {
-		mux.HandleFunc("/pilot/dashboard", authMiddleware(pilotDashboardHandler(warningStore)))
-		mux.HandleFunc("/pilot/warnings", authMiddleware(pilotWarningsListHandler(warningStore)))
+		mux.HandleFunc("/pilot/dashboard", scopedMiddleware(apikeys.ScopeRead, pilotDashboardHandler(warningStore)))
+		mux.HandleFunc("/pilot/warnings", scopedMiddleware(apikeys.ScopeRead, tier.Require(tier.FeatureEvidenceExport, pilotWarningsListHandler(warningStore))))
 		mux.HandleFunc("/pilot/warnings/", authMiddleware(func(w http.ResponseWriter, r *http.Request) {
 			if strings.HasSuffix(r.URL.Path, "/outcome") {
-				outcomeRateLimitMiddleware(func(w http.ResponseWriter, r *http.Request) {
+				apikeys.RequireScope(apikeys.ScopeAdmin, outcomeRateLimitMiddleware(func(w http.ResponseWriter, r *http.Request) {
 					pilotOutcomeHandler(warningStore,
 						func(outcomeType, source string) {
 							warningOutcomeSetTotal.WithLabelValues(outcomeType, source).Inc()
@@ -583,9 +714,9 @@ func registerPilotRoutes(mux *http.ServeMux) {
 						func(seconds float64) {
 							warningOutcomeLeadTime.Observe(seconds)
 						})(w, r)
-				})(w, r)
+				}))(w, r)
 			} else {
-				pilotWarningGetHandler(warningStore)(w, r)
+				apikeys.RequireScope(apikeys.ScopeRead, tier.Require(tier.FeatureEvidenceExport, pilotWarningGetHandler(warningStore)))(w, r)
 			}
 		}))
 		slog.Info("pilot_routes_registered", "endpoints", []string{"/pilot/dashboard", "/pilot/warnings", "/pilot/warnings/{id}/outcome"})
@@ -594,13 +725,13 @@ func registerPilotRoutes(mux *http.ServeMux) {
 
 // Helper: Register evidence console routes
 func registerEvidenceRoutes(mux *http.ServeMux) {
-	mux.HandleFunc("/api/evidence", corsMiddleware(authMiddleware(handleEvidence)))
+	mux.HandleFunc("/api/evidence", corsMiddleware(scopedMiddleware(apikeys.ScopeRead, tier.Require(tier.FeatureBasicRiskScore, handleEvidence))))
 	// /api/evidence/health is a liveness probe (degraded counts, last-good timestamp,
 	// uptime) consumed by Fly's healthcheck — no auth so the platform can reach it.
 	mux.HandleFunc("/api/evidence/health", corsMiddleware(handleEvidenceHealth))
 
 	if payfluxEnv == "dev" {
-		mux.HandleFunc("/api/evidence/fixtures/", corsMiddleware(authMiddleware(handleEvidenceFixture)))
+		mux.HandleFunc("/api/evidence/fixtures/", corsMiddleware(scopedMiddleware(apikeys.ScopeRead, handleEvidenceFixture)))
 		slog.Info("dev_route_registered", "path", "/api/evidence/fixtures/")
 	}
 }
@@ -683,7 +814,14 @@ func main() {
 	defer appCancel()
 
 	initializePrometheus()
+	startRiskModelWatcher(appCtx)
 	setupRedis(redisAddr)
+	loadRateLimits()
+	loadRetentionConfig()
+	setupWarningStore()
+	setupRiskSnapshots(appCtx)
+	setupWarningWebhooks(appCtx)
+	defer saveRiskSnapshots() // final snapshot after shutdown so the next boot starts warm
 
 	dsn := os.Getenv("DATABASE_URL")
 	if dsn != "" {
@@ -695,6 +833,8 @@ func main() {
 			slog.Error("failed_to_connect_postgres", "error", err)
 		}
 	}
+	loadAPIKeyRegistry(appCtx, dsn)
+	defer config.CloseAuditLogger()
 
 	setupExportAndConsumer(appCtx)
 	defer cleanupExport()
@@ -764,14 +904,6 @@ func validateAPIKeysForProd(keys []string) error {
 	return nil
 }
 
-// safeAPIKeyID returns a safe identifier for logging (first 8 chars + ...)
-func safeAPIKeyID(key string) string {
-	if len(key) <= 8 {
-		return "***"
-	}
-	return key[:8] + "..."
-}
-
 // generateConsumerName creates a unique consumer name
 func generateConsumerName() string {
 	// Check for explicit name first
@@ -880,16 +1012,24 @@ func handleRiskForecast(w http.ResponseWriter, r *http.Request) {
 
 	sysReserve := forecast.ComputeSystemReserve(accountVol, procSuccess, procVol)
 
+	// Strip fields behind features the caller's tier lacks.
+	gate := tier.NewGate(r)
+	gatedProcs := make(map[string]gatedProcessorForecast, len(procForecasts))
+	for proc, pf := range procForecasts {
+		gatedProcs[proc] = gateProcessorForecast(gate, pf)
+	}
+
 	response := struct {
-		AccountForecast   forecast.AccountForecast              `json:"account_forecast"`
-		Processors        map[string]forecast.ProcessorForecast `json:"processor_forecasts"`
-		ReserveProjection forecast.ReserveProjection            `json:"reserve_projection"`
+		AccountForecast   gatedAccountForecast              `json:"account_forecast"`
+		Processors        map[string]gatedProcessorForecast `json:"processor_forecasts"`
+		ReserveProjection gatedReserveProjection            `json:"reserve_projection"`
 	}{
-		AccountForecast:   actForecast,
-		Processors:        procForecasts,
-		ReserveProjection: sysReserve,
+		AccountForecast:   gateAccountForecast(gate, actForecast),
+		Processors:        gatedProcs,
+		ReserveProjection: gateReserveProjection(gate, sysReserve),
 	}
 
+	gate.SetHeaders(w.Header())
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(response); err != nil {
 		slog.Error("risk_forecast_encode_error", "error", err)
@@ -903,7 +1043,9 @@ func consumeEvents(ctx context.Context) {
 	// Build the Exporter now that consumerNameGlobal is set and setupExport()
 	// has already configured exportMode / exportWriter (via setupExportAndConsumer).
 	exporterInstance = buildExporter()
-	slog.Info("consumer_started", "group", groupName, "stream", streamKey, "consumer", consumerName)
+	pool := newConsumerPool(consumerWorkers)
+	slog.Info("consumer_started", "group", groupName, "stream", streamKey, "consumer", consumerName,
+		"workers", pool.workers, "batch_size", consumerBatchSize)
 
 	defer func() {
 		if r := recover(); r != nil {
@@ -947,7 +1089,7 @@ func consumeEvents(ctx context.Context) {
 				Consumer: consumerName,
 				MinIdle:  minIdleToClaim,
 				Start:    claimStart,
-				Count:    50,
+				Count:    int64(consumerBatchSize),
 			}).Result()
 
 			if err != nil {
@@ -967,9 +1109,7 @@ func consumeEvents(ctx context.Context) {
 			}
 
 			lastConsume.Store(time.Now().Unix())
-			for _, msg := range msgs {
-				safeHandleMessage(ctx, msg)
-			}
+			pool.processBatch(ctx, msgs)
 		}
 
 		// 2) Normal consumption of new messages
@@ -977,7 +1117,7 @@ func consumeEvents(ctx context.Context) {
 			Group:    groupName,
 			Consumer: consumerName,
 			Streams:  []string{streamKey, ">"},
-			Count:    50,
+			Count:    int64(consumerBatchSize),
 			Block:    2 * time.Second,
 		}).Result()
 
@@ -997,9 +1137,7 @@ func consumeEvents(ctx context.Context) {
 
 		lastConsume.Store(time.Now().Unix())
 		for _, s := range streams {
-			for _, msg := range s.Messages {
-				safeHandleMessage(ctx, msg)
-			}
+			pool.processBatch(ctx, s.Messages)
 		}
 	}
 }
@@ -1025,22 +1163,40 @@ func authMiddleware(next http.HandlerFunc) http.HandlerFunc {
 			}
 		}
 
-		// Check against all valid keys using constant-time comparison
-		valid := false
+		// Check against all legacy keys using constant-time comparison,
+		// then the key registry
+		var id *apikeys.Identity
 		for _, key := range validAPIKeys {
 			if subtle.ConstantTimeCompare([]byte(token), []byte(key)) == 1 {
-				valid = true
+				id = apikeys.LegacyIdentity(token, legacyKeysAdmin)
 				break
 			}
 		}
+		if id == nil {
+			found, err := lookupRegistryKey(r, token)
+			switch {
+			case err == nil:
+				id = found
+			case errors.Is(err, errKeyExpired):
+				authDenied.WithLabelValues("expired_key").Inc()
+				slog.Warn("api_key_expired", "key_id", found.KeyID, "workspace_id", found.WorkspaceID)
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			case !errors.Is(err, apikeys.ErrUnknownKey):
+				authDenied.WithLabelValues("registry_error").Inc()
+				slog.Error("api_key_registry_error", "error", err)
+				http.Error(w, "key registry unavailable", http.StatusServiceUnavailable)
+				return
+			}
+		}
 
-		if !valid {
+		if id == nil {
 			authDenied.WithLabelValues("invalid_key").Inc()
 			http.Error(w, "unauthorized", http.StatusUnauthorized)
 			return
 		}
 
-		next(w, r)
+		next(w, withIdentity(r, token, id))
 	}
 }
 
@@ -1050,6 +1206,7 @@ func corsMiddleware(next http.HandlerFunc) http.HandlerFunc {
 		w.Header().Set("Access-Control-Allow-Origin", "http://localhost:3000")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
 		w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+		w.Header().Set("Access-Control-Expose-Headers", tier.HeaderTier+", "+tier.HeaderWithheld+", "+rateLimitExposeHeaders)
 
 		if r.Method == http.MethodOptions {
 			w.WriteHeader(http.StatusOK)
@@ -1060,171 +1217,6 @@ func corsMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
-// Rate limiting with configurable limits (ingest endpoint)
-func getRateLimiter(key string) *rate.Limiter {
-	rlMu.RLock()
-	limiter, exists := rateLimiters[key]
-	rlMu.RUnlock()
-
-	if exists {
-		return limiter
-	}
-
-	rlMu.Lock()
-	defer rlMu.Unlock()
-
-	// Double-check after acquiring write lock
-	if limiter, exists := rateLimiters[key]; exists {
-		return limiter
-	}
-
-	limiter = rate.NewLimiter(rate.Limit(ingestRPS), ingestBurst)
-	rateLimiters[key] = limiter
-	return limiter
-}
-
-// getOutcomeLimiter returns rate limiter for outcome endpoint (stricter)
-func getOutcomeLimiter(key string) *rate.Limiter {
-	outcomeRlMu.RLock()
-	limiter, exists := outcomeLimiters[key]
-	outcomeRlMu.RUnlock()
-
-	if exists {
-		return limiter
-	}
-
-	outcomeRlMu.Lock()
-	defer outcomeRlMu.Unlock()
-
-	if limiter, exists := outcomeLimiters[key]; exists {
-		return limiter
-	}
-
-	limiter = rate.NewLimiter(rate.Limit(outcomeRPS), outcomeBurst)
-	outcomeLimiters[key] = limiter
-	return limiter
-}
-
-// rateLimitMiddleware enforces per-account rate limiting using numeric config from headers
-// Headers are set by Dashboard boundary layer after tier resolution
-func rateLimitMiddleware(next http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		// 1. Parse numeric config from headers (set by Dashboard)
-		accountID := r.Header.Get("X-Payflux-Account-Id")
-		capacityStr := r.Header.Get("X-Payflux-Ingest-Capacity")
-		refillStr := r.Header.Get("X-Payflux-Ingest-Refill")
-		windowStr := r.Header.Get("X-Payflux-Ingest-Window")
-
-		// If headers are missing, fall back to global env-based rate limiting
-		if accountID == "" || capacityStr == "" || refillStr == "" || windowStr == "" {
-			// Legacy path: use global rate limiter (for backward compatibility)
-			auth := r.Header.Get("Authorization")
-			apiKey := strings.TrimPrefix(auth, "Bearer ")
-
-			limiter := getRateLimiter(apiKey)
-			if !limiter.Allow() {
-				ingestRateLimited.WithLabelValues(r.URL.Path).Inc()
-				slog.Warn("rate_limit_exceeded",
-					"endpoint", r.URL.Path,
-					"api_key_id", safeAPIKeyID(apiKey),
-					"limit_rps", rateLimitRPS,
-					"burst", rateLimitBurst,
-				)
-				w.Header().Set("Retry-After", "1")
-				http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
-				return
-			}
-
-			next(w, r)
-			return
-		}
-
-		// 2. Parse and validate numeric config
-		capacity, err := strconv.Atoi(capacityStr)
-		if err != nil || capacity <= 0 {
-			slog.Error("invalid_capacity_header", "value", capacityStr, "error", err)
-			http.Error(w, "Invalid capacity header", http.StatusBadRequest)
-			return
-		}
-
-		refillRate, err := strconv.Atoi(refillStr)
-		if err != nil || refillRate <= 0 {
-			slog.Error("invalid_refill_header", "value", refillStr, "error", err)
-			http.Error(w, "Invalid refill header", http.StatusBadRequest)
-			return
-		}
-
-		window, err := strconv.Atoi(windowStr)
-		if err != nil || window <= 0 {
-			slog.Error("invalid_window_header", "value", windowStr, "error", err)
-			http.Error(w, "Invalid window header", http.StatusBadRequest)
-			return
-		}
-
-		// 3. Check rate limit using Redis Lua (atomic, per-account)
-		cfg := ratelimit.Config{
-			Capacity:   capacity,
-			RefillRate: refillRate,
-			Window:     window,
-		}
-
-		result, err := globalRateLimiter.Check(r.Context(), accountID, cfg)
-		if err != nil {
-			// Fail closed on error
-			slog.Error("rate_limit_check_failed",
-				"account_id", accountID,
-				"error", err,
-			)
-			rateLimitError.WithLabelValues("redis_error").Inc()
-			w.Header().Set("Retry-After", "60")
-			http.Error(w, "Rate limit unavailable", http.StatusServiceUnavailable)
-			return
-		}
-
-		// 4. Set rate limit headers
-		w.Header().Set("X-Rate-Limit-Limit", strconv.Itoa(capacity))
-		w.Header().Set("X-Rate-Limit-Remaining", strconv.Itoa(result.Remaining))
-		w.Header().Set("X-Rate-Limit-Reset", strconv.FormatInt(result.Reset, 10))
-
-		if !result.Allowed {
-			slog.Warn("rate_limit_exceeded",
-				"account_id", accountID,
-				"limit", refillRate,
-			)
-			rateLimitExceeded.Inc()
-			http.Error(w, "Rate limit exceeded", http.StatusTooManyRequests)
-			return
-		}
-
-		rateLimitAllowed.Inc()
-		next(w, r)
-	}
-}
-
-// outcomeRateLimitMiddleware is a stricter limiter for pilot outcome endpoint
-func outcomeRateLimitMiddleware(next http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		auth := r.Header.Get("Authorization")
-		apiKey := strings.TrimPrefix(auth, "Bearer ")
-
-		limiter := getOutcomeLimiter(apiKey)
-		if !limiter.Allow() {
-			ingestRateLimited.WithLabelValues(r.URL.Path).Inc()
-			slog.Warn("rate_limit_exceeded",
-				"endpoint", r.URL.Path,
-				"api_key_id", safeAPIKeyID(apiKey),
-				"limit_rps", outcomeRPS,
-				"burst", outcomeBurst,
-			)
-			w.Header().Set("Retry-After", "1")
-			http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
-			return
-		}
-
-		next(w, r)
-	}
-}
-
 // Input validation
 func validateEvent(e *Event) error {
 	// UUID validation
@@ -1396,6 +1388,7 @@ func handleEvent(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, fmt.Sprintf("validation error: %v", err), http.StatusBadRequest)
 		return
 	}
+	e.WorkspaceID = requestWorkspaceID(r)
 
 	// Idempotency check
 	reqCtx := r.Context()
@@ -1538,131 +1531,6 @@ func ensureConsumerGroup(ctx context.Context, stream, group string) error {
 	return err
 }
 
-// DLQ retry budget
-func handleMessageWithDlq(ctx context.Context, msg redis.XMessage) {
-	// Check retry count via XPENDING
-	pending, err := rdb.XPendingExt(ctx, &redis.XPendingExtArgs{
-		Stream: streamKey,
-		Group:  groupName,
-		Start:  msg.ID,
-		End:    msg.ID,
-		Count:  1,
-	}).Result()
-
-	retryCount := int64(1)
-	if err == nil && len(pending) == 1 {
-		retryCount = pending[0].RetryCount
-	}
-
-	// Enforce retry budget
-	if retryCount > maxRetries {
-		log.Printf("dlq_max_retries id=%s retries=%d", msg.ID, retryCount)
-		_ = sendToDlq(ctx, msg, "max_retries_exceeded")
-		return
-	}
-
-	// Extract data
-	dataAny, ok := msg.Values["data"]
-	if !ok {
-		log.Printf("message_missing_data id=%s", msg.ID)
-		_ = sendToDlq(ctx, msg, "missing_data_field")
-		return
-	}
-
-	dataStr, ok := dataAny.(string)
-	if !ok {
-		log.Printf("message_data_not_string id=%s", msg.ID)
-		_ = sendToDlq(ctx, msg, "invalid_data_type")
-		return
-	}
-
-	// Validate JSON structure
-	var event Event
-	if err := json.Unmarshal([]byte(dataStr), &event); err != nil {
-		log.Printf("event_unmarshal_error id=%s err=%v", msg.ID, err)
-		_ = sendToDlq(ctx, msg, "unmarshal_failed")
-		return
-	}
-
-	// Process successfully
-	log.Printf("event_processed id=%s type=%s processor=%s",
-		msg.ID, event.EventType, event.Processor)
-	consumerProcessed.Inc()
-
-	// Idempotency latch — prevent duplicate exports on redelivery
-	latchKey := fmt.Sprintf("processed:%s:%s", streamKey, msg.ID)
-	wctx, wcancel := context.WithTimeout(ctx, redisWriteTimeout) //nolint:govet
-	wasSet, setErr := rdb.SetNX(wctx, latchKey, "1", idempotencyTTL).Result()
-	wcancel()
-	if setErr == nil && !wasSet {
-		log.Printf("duplicate_skipped message_id=%s", msg.ID)
-		// Already exported; safe to ACK and return
-		ackCtx, cancel := context.WithTimeout(ctx, ackTimeout)
-		var ackErr error
-		for i := 0; i < 3; i++ {
-			ackErr = rdb.XAck(ackCtx, streamKey, groupName, msg.ID).Err()
-			if ackErr == nil {
-				break
-			}
-			time.Sleep(50 * time.Millisecond)
-		}
-		cancel()
-		if ackErr != nil {
-			log.Printf("xack_error id=%s retries=3 err=%v", msg.ID, ackErr)
-		}
-		return
-	}
-	// If SetNX errors (network): fail-open, proceed with export to avoid data loss
-
-	// Export event — must succeed before ACK to prevent silent data loss
-	if err := exportEvent(event, msg.ID); err != nil {
-		log.Printf("export_failed event_id=%s reason=%v", event.EventID, err)
-		exportFailStreak.Add(1)
-		return // do not ACK — message remains pending for retry
-	}
-
-	exportFailStreak.Store(0)
-
-	// ACK only after confirmed export success
-	ackCtx, cancel := context.WithTimeout(ctx, ackTimeout)
-	var ackErr error
-	for i := 0; i < 3; i++ {
-		ackErr = rdb.XAck(ackCtx, streamKey, groupName, msg.ID).Err()
-		if ackErr == nil {
-			break
-		}
-		time.Sleep(50 * time.Millisecond)
-	}
-	cancel()
-	if ackErr != nil {
-		log.Printf("xack_error id=%s retries=3 err=%v", msg.ID, ackErr)
-		return
-	}
-
-}
-
-func safeHandleMessage(ctx context.Context, msg redis.XMessage) {
-	defer func() {
-		if r := recover(); r != nil {
-			log.Printf("consumer_panic_recovered message_id=%s panic=%v", msg.ID, r)
-
-			v, _ := poisonCounts.LoadOrStore(msg.ID, 0)
-			n := v.(int) + 1
-
-			if n >= 5 {
-				log.Printf("poison_message_detected message_id=%s failures=%d", msg.ID, n)
-				_ = sendToDlq(ctx, msg, "poison_message_repeated_panics")
-				poisonCounts.Delete(msg.ID)
-				return
-			}
-
-			poisonCounts.Store(msg.ID, n)
-		}
-	}()
-
-	handleMessageWithDlq(ctx, msg)
-}
-
 // Updated sendToDlq with reason and timestamp
 func sendToDlq(ctx context.Context, msg redis.XMessage, reason string) error {
 	consumerDlq.Inc()
@@ -1678,10 +1546,11 @@ func sendToDlq(ctx context.Context, msg redis.XMessage, reason string) error {
 	err := rdb.XAdd(wctx, &redis.XAddArgs{
 		Stream: dlqKey,
 		Values: map[string]any{
-			"data":        raw,
-			"original_id": msg.ID,
-			"reason":      reason,
-			"timestamp":   time.Now().Unix(),
+			"data":         raw,
+			"original_id":  msg.ID,
+			"reason":       reason,
+			"timestamp":    time.Now().Unix(),
+			"replay_count": replayCountOf(msg.Values), // carried across replays; see dlq.go
 		},
 	}).Err()
 	wcancel()
@@ -1774,6 +1643,27 @@ func handleExportHealth(w http.ResponseWriter, r *http.Request) {
 			}
 			response.Destinations["file"] = status
 		}
+
+		// Pluggable sink status (tracked by name in healthAdapter)
+		for _, cs := range exportSinks {
+			name := cs.Sink.Name()
+			status := ExportDestinationStatus{Enabled: true}
+			if v, ok := sinkHealth.Load(name); ok {
+				h := v.(*sinkHealthState)
+				if successTs := h.lastSuccess.Load(); successTs > 0 {
+					status.LastSuccessUnix = float64(successTs)
+					status.LastSuccessRFC3339 = time.Unix(successTs, 0).UTC().Format(time.RFC3339)
+				}
+				if errorTs := h.lastError.Load(); errorTs > 0 {
+					status.LastErrorUnix = float64(errorTs)
+					status.LastErrorRFC3339 = time.Unix(errorTs, 0).UTC().Format(time.RFC3339)
+					if reason, ok := exportLastErrorReason.Load(name); ok {
+						status.LastErrorReason = reason.(string)
+					}
+				}
+			}
+			response.Destinations[name] = status
+		}
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -1825,6 +1715,20 @@ func setupExport() error {
 		log.Printf("export_configured mode=%s", exportMode)
 	}
 
+	// Pluggable sinks are additive to the stdout/file mode
+	sinkConfigs, err := exporter.ParseSinkSpecs(os.Getenv("PAYFLUX_EXPORT_SINKS"), os.Environ())
+	if err != nil {
+		return fmt.Errorf("invalid PAYFLUX_EXPORT_SINKS: %w", err)
+	}
+	sinks, err := exporter.BuildSinks(sinkConfigs)
+	if err != nil {
+		return fmt.Errorf("failed to build export sinks: %w", err)
+	}
+	exportSinks = sinks
+	for _, c := range sinkConfigs {
+		log.Printf("export_sink_configured name=%s kind=%s best_effort=%t", c.Name, c.Kind, c.BestEffort)
+	}
+
 	return nil
 }
 
@@ -1836,6 +1740,11 @@ func cleanupExport() {
 	if exportFile != nil {
 		_ = exportFile.Close()
 	}
+	for _, cs := range exportSinks {
+		if err := cs.Sink.Close(); err != nil {
+			log.Printf("export_sink_close_error name=%s err=%v", cs.Sink.Name(), err)
+		}
+	}
 }
 
 // ExportedEvent type has moved to internal/exporter/transform.go (Step 2).
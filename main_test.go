@@ -12,7 +12,8 @@ import (
 	"time"
 
 	"github.com/redis/go-redis/v9"
-	"golang.org/x/time/rate"
+
+	"payment-node/internal/ratelimit"
 )
 
 // Test Redis client for testing
@@ -42,12 +43,9 @@ func setupTestRedis(t *testing.T) {
 	groupName = "payment_consumers"
 	dlqKey = "dlq_stream"
 
-	// Initialize rate limiters map to avoid nil panic
-	rateLimiters = make(map[string]*rate.Limiter)
-
-	// Set high rate limits for tests (use correct variable names)
-	ingestRPS = 1000
-	ingestBurst = 5000
+	// Per-workspace limits from the entitlements file
+	globalRateLimiter = ratelimit.NewLimiter(testRdb)
+	loadRateLimits()
 
 	// Create consumer group
 	_ = testRdb.XGroupCreateMkStream(testCtx, streamKey, groupName, "0").Err()
@@ -128,9 +126,6 @@ func TestAPIKeyRevocation(t *testing.T) {
 	validAPIKeys = []string{validKey, revokedKey}
 	revokedAPIKeys = []string{revokedKey}
 
-	// Set high rate limits for tests
-	ingestRPS = 1000
-	ingestBurst = 5000
 	ingestEnabled = true
 
 	testEvent := Event{
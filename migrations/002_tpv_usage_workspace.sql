@@ -0,0 +1,9 @@
+-- Workspace attribution for TPV snapshots
+-- Retention (retention.go) deletes each workspace's snapshots after its
+-- entitlement tier's retention_days. NULL: snapshots written before
+-- attribution, kept for the deployment tier's retention.
+
+ALTER TABLE tpv_usage_daily ADD COLUMN IF NOT EXISTS workspace_id TEXT;
+
+-- Index for retention scans by workspace and day
+CREATE INDEX IF NOT EXISTS idx_tpv_usage_workspace_day ON tpv_usage_daily(workspace_id, day);
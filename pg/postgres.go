@@ -13,6 +13,7 @@ import (
 // TPVSnapshot represents a daily TPV usage snapshot for a merchant.
 type TPVSnapshot struct {
 	MerchantIDHash    string
+	WorkspaceID       string // "" stores NULL
 	Day               time.Time
 	Currency          string
 	UsageCents        int64
@@ -41,14 +42,15 @@ func Connect(dsn string) (*sql.DB, error) {
 func UpsertDailySnapshot(ctx context.Context, db *sql.DB, snap TPVSnapshot) error {
 	query := `
 		INSERT INTO tpv_usage_daily 
-			(merchant_id_hash, day, currency, usage_cents, coverage_tier, monthly_limit_cents, created_at, updated_at)
+			(merchant_id_hash, day, currency, usage_cents, coverage_tier, monthly_limit_cents, workspace_id, created_at, updated_at)
 		VALUES 
-			($1, $2, $3, $4, $5, $6, NOW(), NOW())
+			($1, $2, $3, $4, $5, $6, NULLIF($7, ''), NOW(), NOW())
 		ON CONFLICT (merchant_id_hash, day, currency) 
 		DO UPDATE SET 
 			usage_cents = EXCLUDED.usage_cents,
 			coverage_tier = EXCLUDED.coverage_tier,
 			monthly_limit_cents = EXCLUDED.monthly_limit_cents,
+			workspace_id = COALESCE(EXCLUDED.workspace_id, tpv_usage_daily.workspace_id),
 			updated_at = NOW()
 	`
 
@@ -59,7 +61,46 @@ func UpsertDailySnapshot(ctx context.Context, db *sql.DB, snap TPVSnapshot) erro
 		snap.UsageCents,
 		snap.CoverageTier,
 		snap.MonthlyLimitCents,
+		snap.WorkspaceID,
 	)
 
 	return err
 }
+
+// SnapshotWorkspaces lists the workspaces with TPV snapshots; "" stands for
+// snapshots without a workspace.
+func SnapshotWorkspaces(ctx context.Context, db *sql.DB) ([]string, error) {
+	rows, err := db.QueryContext(ctx,
+		`SELECT DISTINCT COALESCE(workspace_id, '') FROM tpv_usage_daily`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var workspaces []string
+	for rows.Next() {
+		var ws string
+		if err := rows.Scan(&ws); err != nil {
+			return nil, err
+		}
+		workspaces = append(workspaces, ws)
+	}
+	return workspaces, rows.Err()
+}
+
+// DeleteSnapshotsBefore deletes a workspace's snapshots for days before
+// cutoff and returns how many rows matched. With dryRun it only counts them.
+func DeleteSnapshotsBefore(ctx context.Context, db *sql.DB, workspaceID string, cutoff time.Time, dryRun bool) (int64, error) {
+	const where = `COALESCE(workspace_id, '') = $1 AND day < $2`
+	if dryRun {
+		var n int64
+		err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM tpv_usage_daily WHERE `+where,
+			workspaceID, cutoff).Scan(&n)
+		return n, err
+	}
+	res, err := db.ExecContext(ctx, `DELETE FROM tpv_usage_daily WHERE `+where, workspaceID, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
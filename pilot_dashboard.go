@@ -9,6 +9,8 @@ import (
 	"os"
 	"strings"
 	"time"
+
+	"payment-node/internal/tier"
 )
 
 // PilotOutcomeAnnotation is the JSON structure emitted to stdout for proof capture
@@ -36,10 +38,17 @@ type OutcomeRequest struct {
 	Notes       string `json:"notes,omitempty"`
 }
 
+// warningVisibleTo reports whether the caller may see w: keys bound to a
+// workspace see only that workspace's warnings.
+func warningVisibleTo(w *Warning, r *http.Request) bool {
+	workspaceID := requestWorkspaceID(r)
+	return workspaceID == "" || w.WorkspaceID == workspaceID
+}
+
 // pilotDashboardHandler serves the minimal HTML dashboard
-func pilotDashboardHandler(store *WarningStore) http.HandlerFunc {
+func pilotDashboardHandler(store WarningStorage) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		warnings := store.List(100, "") // Last 100 warnings
+		warnings := store.List(100, "", requestWorkspaceID(r)) // Last 100 warnings
 
 		tmpl := template.Must(template.New("dashboard").Parse(pilotDashboardHTML))
 		w.Header().Set("Content-Type", "text/html; charset=utf-8")
@@ -51,36 +60,46 @@ func pilotDashboardHandler(store *WarningStore) http.HandlerFunc {
 }
 
 // pilotWarningsListHandler returns JSON list of warnings
-func pilotWarningsListHandler(store *WarningStore) http.HandlerFunc {
+func pilotWarningsListHandler(store WarningStorage) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		processor := r.URL.Query().Get("processor")
-		warnings := store.List(100, processor)
+		warnings := store.List(100, processor, requestWorkspaceID(r))
 
+		gate := tier.NewGate(r)
+		for i, warning := range warnings {
+			warnings[i] = gateWarning(gate, warning)
+		}
+
+		gate.SetHeaders(w.Header())
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(warnings)
 	}
 }
 
 // pilotWarningGetHandler returns a single warning by ID
-func pilotWarningGetHandler(store *WarningStore) http.HandlerFunc {
+func pilotWarningGetHandler(store WarningStorage) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Extract warning_id from path: /pilot/warnings/{warning_id}
 		path := strings.TrimPrefix(r.URL.Path, "/pilot/warnings/")
 		warningID := strings.TrimSuffix(path, "/outcome")
 
 		warning, found := store.Get(warningID)
-		if !found {
+		if !found || !warningVisibleTo(warning, r) {
 			http.Error(w, `{"error":"warning not found"}`, http.StatusNotFound)
 			return
 		}
 
+		gate := tier.NewGate(r)
+		warning = gateWarning(gate, warning)
+
+		gate.SetHeaders(w.Header())
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(warning)
 	}
 }
 
 // pilotOutcomeHandler handles POST /pilot/warnings/{warning_id}/outcome
-func pilotOutcomeHandler(store *WarningStore, outcomeSetCounter func(outcomeType, source string), leadTimeHist func(seconds float64)) http.HandlerFunc {
+func pilotOutcomeHandler(store WarningStorage, outcomeSetCounter func(outcomeType, source string), leadTimeHist func(seconds float64)) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			http.Error(w, `{"error":"method not allowed"}`, http.StatusMethodNotAllowed)
@@ -124,6 +143,12 @@ func pilotOutcomeHandler(store *WarningStore, outcomeSetCounter func(outcomeType
 			return
 		}
 
+		// Another workspace's warning reads as not found
+		if existing, found := store.Get(warningID); found && !warningVisibleTo(existing, r) {
+			http.Error(w, `{"error":"warning not found"}`, http.StatusNotFound)
+			return
+		}
+
 		// Set outcome
 		warning, found := store.SetOutcome(warningID, req.OutcomeType, observedAt, source, req.Notes)
 		if !found {
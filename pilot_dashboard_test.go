@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"payment-node/internal/apikeys"
+	"payment-node/internal/tier"
+)
+
+func TestPilotWarningsConfinedToWorkspace(t *testing.T) {
+	store := NewWarningStore(10)
+	store.Add(&Warning{WarningID: "w-acme", WorkspaceID: "ws-acme", Processor: "stripe"})
+	store.Add(&Warning{WarningID: "w-other", WorkspaceID: "ws-other", Processor: "stripe"})
+
+	asAcme := func(req *http.Request) *http.Request {
+		id := &apikeys.Identity{KeyID: "acme-admin", WorkspaceID: "ws-acme", Scopes: []string{apikeys.ScopeAdmin}}
+		ctx := tier.WithTier(apikeys.WithIdentity(req.Context(), id), tier.TierPro)
+		return req.WithContext(ctx)
+	}
+
+	w := httptest.NewRecorder()
+	pilotWarningsListHandler(store)(w, asAcme(httptest.NewRequest(http.MethodGet, "/pilot/warnings", nil)))
+	var list []Warning
+	if err := json.NewDecoder(w.Body).Decode(&list); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(list) != 1 || list[0].WarningID != "w-acme" {
+		t.Fatalf("list = %+v, want only w-acme", list)
+	}
+
+	w = httptest.NewRecorder()
+	pilotWarningGetHandler(store)(w, asAcme(httptest.NewRequest(http.MethodGet, "/pilot/warnings/w-other", nil)))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("get other workspace's warning: status %d, want 404", w.Code)
+	}
+
+	body := strings.NewReader(`{"outcome_type":"throttle"}`)
+	w = httptest.NewRecorder()
+	pilotOutcomeHandler(store, nil, nil)(w, asAcme(httptest.NewRequest(http.MethodPost, "/pilot/warnings/w-other/outcome", body)))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("outcome on other workspace's warning: status %d, want 404", w.Code)
+	}
+	if got, _ := store.Get("w-other"); got.OutcomeType != "" {
+		t.Errorf("other workspace's warning annotated: %q", got.OutcomeType)
+	}
+}
@@ -0,0 +1,204 @@
+package main
+
+// rate_limiting.go — per-workspace rate limits shared by all replicas.
+//
+// Ingest (events, batch, checkout) and pilot outcome requests spend tokens
+// from a Redis token bucket (internal/ratelimit), one per workspace and
+// route class: one token per request, and one per item for batches, so the limit holds however many replicas serve the
+// workspace. Bucket sizes are the ingest_rate_limit / outcome_rate_limit
+// of the caller's entitlement tier in config/tier_entitlements.runtime.json.
+// Keys without a workspace (legacy PAYFLUX_API_KEYS, file registry entries
+// without workspace_id) get a bucket of their own.
+//
+// Legacy PAYFLUX_API_KEYS keep the limits they had before tiers applied:
+// ingest 100/s with a burst of 500, outcome 10/s with a burst of 20. The
+// deprecated PAYFLUX_INGEST_RPS/BURST (or PAYFLUX_RATELIMIT_RPS/BURST) and
+// PAYFLUX_OUTCOME_RPS/BURST override them and log a warning at startup.
+
+import (
+	"fmt"
+	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"payment-node/internal/apikeys"
+	"payment-node/internal/entitlements"
+	"payment-node/internal/ratelimit"
+	"payment-node/internal/runtime/entitlementctx"
+)
+
+type rateLimitClass string
+
+const (
+	rateLimitIngest  rateLimitClass = "ingest"
+	rateLimitOutcome rateLimitClass = "outcome"
+)
+
+// rateLimitExposeHeaders are the rate limit headers browsers may read.
+const rateLimitExposeHeaders = "X-RateLimit-Limit, X-RateLimit-Remaining, X-RateLimit-Reset, Retry-After"
+
+var rateLimitRegistry entitlementctx.Registry // set by loadRateLimits
+
+// Limits for legacy keys, which have no entitlement tier of their own.
+var (
+	legacyIngestRateLimit  = entitlements.RateLimit{Capacity: 500, RefillPerSecond: 100}
+	legacyOutcomeRateLimit = entitlements.RateLimit{Capacity: 20, RefillPerSecond: 10}
+)
+
+// loadRateLimits loads the per-tier limits. Every tier must define both.
+func loadRateLimits() {
+	registry, err := entitlementctx.LoadRegistry(entitlementsConfigPath)
+	if err != nil {
+		log.Fatalf("rate_limit_entitlements_error err=%v", err)
+	}
+	for _, t := range []string{"baseline", "proof", "shield", "fortress"} {
+		ent, err := registry.GetEntitlements(t)
+		if err != nil || ent.IngestRateLimit.IsZero() || ent.OutcomeRateLimit.IsZero() {
+			log.Fatalf("%s: tier %s needs ingest_rate_limit and outcome_rate_limit", entitlementsConfigPath, t)
+		}
+		slog.Info("rate_limit_configured", "entitlement_tier", t,
+			"ingest_capacity", ent.IngestRateLimit.Capacity,
+			"ingest_refill_per_second", ent.IngestRateLimit.RefillPerSecond,
+			"outcome_capacity", ent.OutcomeRateLimit.Capacity,
+			"outcome_refill_per_second", ent.OutcomeRateLimit.RefillPerSecond,
+		)
+	}
+	rateLimitRegistry = registry
+
+	legacyIngestRateLimit = entitlements.RateLimit{
+		Capacity:        deprecatedRateLimitEnv(500, "PAYFLUX_INGEST_BURST", "PAYFLUX_RATELIMIT_BURST"),
+		RefillPerSecond: deprecatedRateLimitEnv(100, "PAYFLUX_INGEST_RPS", "PAYFLUX_RATELIMIT_RPS"),
+	}
+	legacyOutcomeRateLimit = entitlements.RateLimit{
+		Capacity:        deprecatedRateLimitEnv(20, "PAYFLUX_OUTCOME_BURST"),
+		RefillPerSecond: deprecatedRateLimitEnv(10, "PAYFLUX_OUTCOME_RPS"),
+	}
+	slog.Info("rate_limit_configured", "entitlement_tier", "legacy",
+		"ingest_capacity", legacyIngestRateLimit.Capacity,
+		"ingest_refill_per_second", legacyIngestRateLimit.RefillPerSecond,
+		"outcome_capacity", legacyOutcomeRateLimit.Capacity,
+		"outcome_refill_per_second", legacyOutcomeRateLimit.RefillPerSecond,
+	)
+}
+
+// deprecatedRateLimitEnv returns the first of keys that is set, warning that
+// it only applies to legacy keys, or fallback when none is.
+func deprecatedRateLimitEnv(fallback int, keys ...string) int {
+	for _, key := range keys {
+		if os.Getenv(key) == "" {
+			continue
+		}
+		slog.Warn("deprecated_config", "key", key,
+			"detail", "applies to legacy PAYFLUX_API_KEYS only; tiered keys use "+entitlementsConfigPath)
+		return envInt(key, fallback)
+	}
+	return fallback
+}
+
+// rateLimitFor returns the bucket and its size for the request's caller.
+func rateLimitFor(r *http.Request, class rateLimitClass) (string, ratelimit.Config) {
+	id := apikeys.FromContext(r.Context())
+	var rl entitlements.RateLimit
+	if id != nil && id.Legacy {
+		rl = legacyIngestRateLimit
+		if class == rateLimitOutcome {
+			rl = legacyOutcomeRateLimit
+		}
+	} else {
+		_, ent := entitlementctx.Resolve(r.Context(), rateLimitRegistry)
+		rl = ent.IngestRateLimit
+		if class == rateLimitOutcome {
+			rl = ent.OutcomeRateLimit
+		}
+	}
+
+	bucket := "key:" + requestKeyID(r)
+	if id != nil && id.WorkspaceID != "" {
+		bucket = "workspace:" + id.WorkspaceID
+	}
+	return bucket + ":" + string(class), bucketConfig(rl)
+}
+
+// bucketConfig converts an entitlement to a limiter config. Buckets expire
+// once they would have refilled completely: a fresh bucket is the same.
+func bucketConfig(rl entitlements.RateLimit) ratelimit.Config {
+	return ratelimit.Config{
+		Capacity:   rl.Capacity,
+		RefillRate: rl.RefillPerSecond,
+		Window:     (rl.Capacity+rl.RefillPerSecond-1)/rl.RefillPerSecond + 1,
+	}
+}
+
+// setRateLimitHeaders reports the bucket state; Retry-After only on denial.
+func setRateLimitHeaders(h http.Header, cfg ratelimit.Config, res *ratelimit.Result, now time.Time) {
+	h.Set("X-RateLimit-Limit", strconv.Itoa(cfg.Capacity))
+	h.Set("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	h.Set("X-RateLimit-Reset", strconv.FormatInt(res.Reset, 10))
+	if !res.Allowed {
+		h.Set("Retry-After", strconv.FormatInt(max(res.Reset-now.Unix(), 1), 10))
+	}
+}
+
+// rateLimitMiddleware limits ingest and checkout requests.
+func rateLimitMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return rateLimited(rateLimitIngest, next)
+}
+
+// outcomeRateLimitMiddleware limits pilot outcome annotations, which get a
+// much smaller bucket than ingest.
+func outcomeRateLimitMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return rateLimited(rateLimitOutcome, next)
+}
+
+// rateLimited must run inside authMiddleware, which resolves the caller's
+// workspace and entitlement tier. Each request costs one token.
+func rateLimited(class rateLimitClass, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if chargeRateLimit(w, r, class, 1) {
+			next(w, r)
+		}
+	}
+}
+
+// chargeRateLimit spends cost tokens from the caller's bucket and reports
+// whether the request may proceed; otherwise it has written the response.
+// A cost above the bucket's capacity could never be served and is rejected
+// with 413. Limiter errors fail closed.
+func chargeRateLimit(w http.ResponseWriter, r *http.Request, class rateLimitClass, cost int) bool {
+	bucket, cfg := rateLimitFor(r, class)
+	if cost > cfg.Capacity {
+		http.Error(w, fmt.Sprintf("request costs %d rate limit tokens, more than the bucket capacity of %d", cost, cfg.Capacity),
+			http.StatusRequestEntityTooLarge)
+		return false
+	}
+	result, err := globalRateLimiter.Check(r.Context(), bucket, cfg, cost)
+	if err != nil {
+		slog.Error("rate_limit_check_failed", "bucket", bucket, "error", err)
+		rateLimitError.WithLabelValues("redis_error").Inc()
+		w.Header().Set("Retry-After", "60")
+		http.Error(w, "Rate limit unavailable", http.StatusServiceUnavailable)
+		return false
+	}
+
+	setRateLimitHeaders(w.Header(), cfg, result, time.Now())
+	if !result.Allowed {
+		ingestRateLimited.WithLabelValues(r.URL.Path).Inc()
+		rateLimitExceeded.Inc()
+		slog.Warn("rate_limit_exceeded",
+			"endpoint", r.URL.Path,
+			"key_id", requestKeyID(r),
+			"bucket", bucket,
+			"cost", cost,
+			"capacity", cfg.Capacity,
+			"refill_per_second", cfg.RefillRate,
+		)
+		http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+		return false
+	}
+
+	rateLimitAllowed.Inc()
+	return true
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"payment-node/internal/apikeys"
+	"payment-node/internal/entitlements"
+	"payment-node/internal/ratelimit"
+	"payment-node/internal/tier"
+)
+
+func TestRateLimitFor(t *testing.T) {
+	loadRateLimits()
+	runtimeCanonicalTier = tier.TierFree
+
+	request := func(id *apikeys.Identity) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/v1/events/payment_exhaust", nil)
+		return withIdentity(r, "token", id)
+	}
+	acmeIngest := &apikeys.Identity{KeyID: "acme-ingest", WorkspaceID: "ws-acme", Tier: tier.TierEnterprise, Scopes: []string{apikeys.ScopeIngest}}
+	acmeOps := &apikeys.Identity{KeyID: "acme-ops", WorkspaceID: "ws-acme", Tier: tier.TierEnterprise, Scopes: []string{apikeys.ScopeAdmin}}
+
+	b1, cfg := rateLimitFor(request(acmeIngest), rateLimitIngest)
+	b2, _ := rateLimitFor(request(acmeOps), rateLimitIngest)
+	if b1 != "workspace:ws-acme:ingest" || b1 != b2 {
+		t.Errorf("keys of one workspace use buckets %q and %q", b1, b2)
+	}
+	if cfg.Capacity != 5000 || cfg.RefillRate != 1000 {
+		t.Errorf("fortress ingest config = %+v", cfg)
+	}
+	if b, _ := rateLimitFor(request(acmeIngest), rateLimitOutcome); b != "workspace:ws-acme:outcome" {
+		t.Errorf("outcome bucket = %q", b)
+	}
+
+	legacy := apikeys.LegacyIdentity("legacy-key", false)
+	b, cfg := rateLimitFor(request(legacy), rateLimitIngest)
+	if b != "key:"+legacy.KeyID+":ingest" {
+		t.Errorf("legacy bucket = %q", b)
+	}
+	if cfg.Capacity != 500 || cfg.RefillRate != 100 {
+		t.Errorf("legacy ingest config = %+v", cfg)
+	}
+	if _, cfg := rateLimitFor(request(legacy), rateLimitOutcome); cfg.Capacity != 20 || cfg.RefillRate != 10 {
+		t.Errorf("legacy outcome config = %+v", cfg)
+	}
+}
+
+func TestLegacyRateLimitEnvOverrides(t *testing.T) {
+	t.Cleanup(loadRateLimits) // runs after the variables are restored
+	t.Setenv("PAYFLUX_RATELIMIT_RPS", "50")
+	t.Setenv("PAYFLUX_RATELIMIT_BURST", "60")
+	t.Setenv("PAYFLUX_INGEST_BURST", "200")
+	t.Setenv("PAYFLUX_OUTCOME_RPS", "3")
+	loadRateLimits()
+
+	// PAYFLUX_INGEST_* wins over the older PAYFLUX_RATELIMIT_* name.
+	if legacyIngestRateLimit != (entitlements.RateLimit{Capacity: 200, RefillPerSecond: 50}) {
+		t.Errorf("legacy ingest limit = %+v", legacyIngestRateLimit)
+	}
+	if legacyOutcomeRateLimit != (entitlements.RateLimit{Capacity: 20, RefillPerSecond: 3}) {
+		t.Errorf("legacy outcome limit = %+v", legacyOutcomeRateLimit)
+	}
+}
+
+func TestBucketConfig(t *testing.T) {
+	cfg := bucketConfig(entitlements.RateLimit{Capacity: 25, RefillPerSecond: 10})
+	if cfg.Window != 4 {
+		t.Errorf("Window = %d, want 4 (3s to refill, plus 1)", cfg.Window)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSetRateLimitHeaders(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	cfg := ratelimit.Config{Capacity: 20, RefillRate: 10, Window: 3}
+
+	h := http.Header{}
+	setRateLimitHeaders(h, cfg, &ratelimit.Result{Allowed: true, Remaining: 7, Reset: now.Unix()}, now)
+	if h.Get("X-RateLimit-Limit") != "20" || h.Get("X-RateLimit-Remaining") != "7" || h.Get("Retry-After") != "" {
+		t.Errorf("allowed headers = %v", h)
+	}
+
+	h = http.Header{}
+	setRateLimitHeaders(h, cfg, &ratelimit.Result{Allowed: false, Remaining: 0, Reset: now.Unix() + 3}, now)
+	if h.Get("X-RateLimit-Reset") != "1700000003" || h.Get("Retry-After") != "3" {
+		t.Errorf("denied headers = %v", h)
+	}
+
+	h = http.Header{}
+	setRateLimitHeaders(h, cfg, &ratelimit.Result{Allowed: false, Reset: now.Unix()}, now)
+	if h.Get("Retry-After") != "1" {
+		t.Errorf("Retry-After = %q, want at least 1", h.Get("Retry-After"))
+	}
+}
+
+func TestRateLimitSharedByWorkspace(t *testing.T) {
+	setupTestRedis(t)
+	defer teardownTestRedis(t)
+
+	path := filepath.Join(t.TempDir(), "api_keys.json")
+	registryJSON := `{"keys": [
+		{"id": "a", "sha256": "` + apikeys.Hash("key-a") + `", "workspace_id": "ws-1", "tier": "free", "scopes": ["ingest"]},
+		{"id": "b", "sha256": "` + apikeys.Hash("key-b") + `", "workspace_id": "ws-1", "tier": "free", "scopes": ["ingest"]}
+	]}`
+	if err := os.WriteFile(path, []byte(registryJSON), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	reg, err := apikeys.NewFileRegistry(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	apiKeyRegistry = reg
+	defer func() { apiKeyRegistry = nil }()
+
+	handler := scopedMiddleware(apikeys.ScopeIngest, rateLimitMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	send := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/events/payment_exhaust", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w
+	}
+
+	// baseline: 20 tokens, split between the workspace's two keys
+	for i := 0; i < 20; i++ {
+		token := "key-a"
+		if i%2 == 1 {
+			token = "key-b"
+		}
+		if w := send(token); w.Code != http.StatusAccepted {
+			t.Fatalf("request %d: status %d", i, w.Code)
+		}
+	}
+	w := send("key-b")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("request over capacity: status %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" || w.Header().Get("X-RateLimit-Remaining") != "0" {
+		t.Errorf("429 headers = %v", w.Header())
+	}
+}
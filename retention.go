@@ -0,0 +1,595 @@
+package main
+
+// retention.go — per-workspace data retention.
+//
+// Every hour the retention worker deletes stored data older than the
+// retention_days of its workspace's entitlement tier
+// (config/tier_entitlements.runtime.json):
+//
+//	warnings       the warning store, by processed_at
+//	dlq            events_stream_dlq entries, by the time they failed
+//	tpv_snapshots  tpv_usage_daily rows, by day (needs DATABASE_URL)
+//	export_file    PAYFLUX_EXPORT_FILE lines, by processed_at
+//
+// Evidence artifacts have no pruner: /api/evidence builds them on demand
+// from the warning store and never stores them (Cache-Control: no-store),
+// so pruning warnings prunes the evidence derived from them.
+//
+// Ingest stamps every event with the caller's workspace (Event.WorkspaceID)
+// and warnings, DLQ payloads and export lines carry it from there. Data
+// without a workspace — keys without one, or written before attribution —
+// gets the deployment tier's retention (PAYFLUX_TIER). Workspace tiers
+// resolve as for requests (requestTierResolver), so a downgrade shortens
+// retention from the next run; a workspace whose tier cannot be resolved
+// keeps its data until one can.
+//
+// PAYFLUX_RETENTION_MODE selects what a run does: off, dry_run (the default:
+// report what would be deleted) or enforce. Each run logs a report and is
+// served by GET /api/v1/retention; POST runs one on demand. Admins of one
+// workspace only run retention on, and only see the report of, their own
+// workspace.
+//
+// The raw event stream keeps its own deployment-wide TTL
+// (PAYFLUX_RAW_EVENT_TTL_DAYS, runStreamRetention): it is unprocessed input,
+// not stored data.
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"payment-node/internal/entitlements"
+	"payment-node/internal/runtime/entitlementctx"
+	"payment-node/internal/tier"
+	"payment-node/pg"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Retention modes (PAYFLUX_RETENTION_MODE)
+const (
+	retentionOff     = "off"
+	retentionDryRun  = "dry_run"
+	retentionEnforce = "enforce"
+)
+
+const retentionInterval = time.Hour
+
+var (
+	retentionMode     = retentionDryRun       // set by loadRetentionConfig
+	retentionRegistry entitlementctx.Registry // set by loadRetentionConfig
+
+	retentionMu         sync.Mutex // one run at a time; guards lastRetentionReport
+	lastRetentionReport *RetentionReport
+)
+
+// RetentionReport describes one retention run: what was deleted from each
+// store or, in a dry run, what would have been.
+type RetentionReport struct {
+	StartedAt  time.Time              `json:"started_at"`
+	FinishedAt time.Time              `json:"finished_at"`
+	DryRun     bool                   `json:"dry_run"`
+	Workspaces []RetentionWorkspace   `json:"workspaces"`
+	Stores     []RetentionStoreReport `json:"stores"`
+}
+
+// RetentionWorkspace is the policy a run applied to one workspace. The empty
+// workspace ID stands for data without a workspace.
+type RetentionWorkspace struct {
+	WorkspaceID     string     `json:"workspace_id"`
+	EntitlementTier string     `json:"entitlement_tier,omitempty"`
+	RetentionDays   int        `json:"retention_days,omitempty"`
+	Cutoff          *time.Time `json:"cutoff,omitempty"`
+	Error           string     `json:"error,omitempty"` // set when nothing was deleted for the workspace
+}
+
+// RetentionStoreReport counts one store's deletions. Scanned is left unset
+// for stores pruned in SQL.
+type RetentionStoreReport struct {
+	Store              string         `json:"store"`
+	Scanned            int            `json:"scanned,omitempty"`
+	Deleted            int            `json:"deleted"`
+	DeletedByWorkspace map[string]int `json:"deleted_by_workspace,omitempty"`
+	Error              string         `json:"error,omitempty"`
+}
+
+func (r *RetentionStoreReport) deleted(workspaceID string, n int) {
+	if n == 0 {
+		return
+	}
+	if r.DeletedByWorkspace == nil {
+		r.DeletedByWorkspace = make(map[string]int)
+	}
+	r.Deleted += n
+	r.DeletedByWorkspace[workspaceID] += n
+}
+
+// loadRetentionConfig reads PAYFLUX_RETENTION_MODE and the per-tier
+// retention periods. Every tier must define retention_days.
+func loadRetentionConfig() {
+	retentionMode = env("PAYFLUX_RETENTION_MODE", retentionDryRun)
+	switch retentionMode {
+	case retentionOff, retentionDryRun, retentionEnforce:
+	default:
+		log.Fatalf("PAYFLUX_RETENTION_MODE must be off, dry_run or enforce, got: %s", retentionMode)
+	}
+
+	registry, err := entitlementctx.LoadRegistry(entitlementsConfigPath)
+	if err != nil {
+		log.Fatalf("retention_entitlements_error err=%v", err)
+	}
+	for _, t := range []string{"baseline", "proof", "shield", "fortress"} {
+		ent, err := registry.GetEntitlements(t)
+		if err != nil || ent.RetentionDays < 1 {
+			log.Fatalf("%s: tier %s needs retention_days", entitlementsConfigPath, t)
+		}
+	}
+	retentionRegistry = registry
+	slog.Info("retention_config", "mode", retentionMode, "interval", retentionInterval.String())
+}
+
+// runRetentionWorker applies retention at startup and then every
+// retentionInterval.
+func runRetentionWorker(ctx context.Context) {
+	dryRun := retentionMode != retentionEnforce
+	runRetention(ctx, dryRun, "")
+
+	ticker := time.NewTicker(retentionInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			runRetention(ctx, dryRun, "")
+		}
+	}
+}
+
+// retentionPolicy resolves and caches each workspace's retention for the
+// duration of one run.
+type retentionPolicy struct {
+	registry    entitlementctx.Registry
+	now         time.Time
+	workspaceID string // when set, the run only prunes this workspace's data
+	rules       map[string]RetentionWorkspace
+}
+
+func newRetentionPolicy(registry entitlementctx.Registry, now time.Time) *retentionPolicy {
+	return &retentionPolicy{registry: registry, now: now, rules: make(map[string]RetentionWorkspace)}
+}
+
+// covers reports whether the run prunes data of workspaceID.
+func (p *retentionPolicy) covers(workspaceID string) bool {
+	return p.workspaceID == "" || workspaceID == p.workspaceID
+}
+
+func (p *retentionPolicy) rule(ctx context.Context, workspaceID string) RetentionWorkspace {
+	if rule, ok := p.rules[workspaceID]; ok {
+		return rule
+	}
+	rule := RetentionWorkspace{WorkspaceID: workspaceID}
+
+	t := runtimeCanonicalTier
+	if workspaceID != "" && requestTierResolver != nil {
+		resolved, err := requestTierResolver.ResolveWorkspace(ctx, workspaceID)
+		if err != nil {
+			// The fallback tier may keep data for less time than the
+			// workspace pays for: delete nothing until the lookup works.
+			slog.Warn("retention_tier_resolution_failed", "workspace_id", workspaceID, "error", err)
+			rule.Error = err.Error()
+			p.rules[workspaceID] = rule
+			return rule
+		}
+		t = resolved
+	}
+	if t == "" {
+		t = tier.TierFree
+	}
+
+	rule.EntitlementTier = t.EntitlementTier()
+	ent, err := p.registry.GetEntitlements(rule.EntitlementTier)
+	if err != nil || ent.RetentionDays < 1 {
+		rule.Error = "no retention_days for entitlement tier " + rule.EntitlementTier
+	} else {
+		rule.RetentionDays = ent.RetentionDays
+		cutoff := p.now.Add(-time.Duration(ent.RetentionDays) * 24 * time.Hour).UTC()
+		rule.Cutoff = &cutoff
+	}
+	p.rules[workspaceID] = rule
+	return rule
+}
+
+// expired reports whether a record of workspaceID stored at ts is past
+// retention. Records without a timestamp, and records of workspaces the run
+// does not cover, are kept.
+func (p *retentionPolicy) expired(ctx context.Context, workspaceID string, ts time.Time) bool {
+	if !p.covers(workspaceID) {
+		return false
+	}
+	rule := p.rule(ctx, workspaceID)
+	if rule.Error != "" || ts.IsZero() {
+		return false
+	}
+	return !entitlements.CheckRetention(ts, rule.RetentionDays)
+}
+
+// applied lists the policies used so far, by workspace ID.
+func (p *retentionPolicy) applied() []RetentionWorkspace {
+	out := make([]RetentionWorkspace, 0, len(p.rules))
+	for _, rule := range p.rules {
+		out = append(out, rule)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].WorkspaceID < out[j].WorkspaceID })
+	return out
+}
+
+// retentionPruner prunes one store; it returns nil when the store is not
+// configured.
+type retentionPruner func(ctx context.Context, p *retentionPolicy, dryRun bool) *RetentionStoreReport
+
+var retentionPruners = []retentionPruner{
+	pruneWarnings,
+	pruneDlq,
+	pruneTPVSnapshots,
+	pruneExportFile,
+}
+
+// runRetention prunes every configured store. With a workspaceID it only
+// prunes that workspace's data; otherwise it prunes all data and records
+// the report for GET /api/v1/retention.
+func runRetention(ctx context.Context, dryRun bool, workspaceID string) *RetentionReport {
+	retentionMu.Lock()
+	defer retentionMu.Unlock()
+
+	p := newRetentionPolicy(retentionRegistry, time.Now())
+	p.workspaceID = workspaceID
+	report := &RetentionReport{StartedAt: p.now.UTC(), DryRun: dryRun, Stores: []RetentionStoreReport{}}
+	for _, prune := range retentionPruners {
+		rep := prune(ctx, p, dryRun)
+		if rep == nil {
+			continue
+		}
+		if rep.Error != "" {
+			slog.Error("retention_store_error", "store", rep.Store, "error", rep.Error)
+		}
+		if !dryRun {
+			retentionDeleted.WithLabelValues(rep.Store).Add(float64(rep.Deleted))
+		}
+		report.Stores = append(report.Stores, *rep)
+	}
+	report.Workspaces = p.applied()
+	report.FinishedAt = time.Now().UTC()
+
+	attrs := []any{"dry_run", dryRun, "scope", workspaceID, "workspaces", len(report.Workspaces),
+		"duration_ms", report.FinishedAt.Sub(report.StartedAt).Milliseconds()}
+	for _, rep := range report.Stores {
+		attrs = append(attrs, rep.Store+"_deleted", rep.Deleted)
+	}
+	slog.Info("retention_report", attrs...)
+
+	if workspaceID == "" {
+		lastRetentionReport = report
+	}
+	return report
+}
+
+// forWorkspace returns the part of the report about workspaceID.
+func (r *RetentionReport) forWorkspace(workspaceID string) *RetentionReport {
+	out := &RetentionReport{StartedAt: r.StartedAt, FinishedAt: r.FinishedAt, DryRun: r.DryRun,
+		Workspaces: []RetentionWorkspace{}, Stores: make([]RetentionStoreReport, 0, len(r.Stores))}
+	for _, ws := range r.Workspaces {
+		if ws.WorkspaceID == workspaceID {
+			out.Workspaces = append(out.Workspaces, ws)
+		}
+	}
+	for _, rep := range r.Stores {
+		scoped := RetentionStoreReport{Store: rep.Store, Error: rep.Error}
+		scoped.deleted(workspaceID, rep.DeletedByWorkspace[workspaceID])
+		out.Stores = append(out.Stores, scoped)
+	}
+	return out
+}
+
+// pruneWarnings removes expired warnings from the warning store.
+func pruneWarnings(ctx context.Context, p *retentionPolicy, dryRun bool) *RetentionStoreReport {
+	if warningStore == nil {
+		return nil
+	}
+	rep := &RetentionStoreReport{Store: "warnings"}
+	for _, w := range warningStore.List(warningStore.Count(), "", "") {
+		if !p.covers(w.WorkspaceID) {
+			continue
+		}
+		rep.Scanned++
+		if !p.expired(ctx, w.WorkspaceID, w.ProcessedAt) {
+			continue
+		}
+		if dryRun || warningStore.Remove(w.WarningID) {
+			rep.deleted(w.WorkspaceID, 1)
+		}
+	}
+	return rep
+}
+
+// pruneDlq removes expired DLQ entries. The workspace comes from the failed
+// event's payload; entries whose payload is not an event have none.
+func pruneDlq(ctx context.Context, p *retentionPolicy, dryRun bool) *RetentionStoreReport {
+	if rdb == nil {
+		return nil
+	}
+	rep := &RetentionStoreReport{Store: "dlq"}
+	cursor := ""
+	for {
+		msgs, next, err := scanDlq(ctx, dlqFilter{}, cursor, dlqMaxLimit)
+		if err != nil {
+			rep.Error = err.Error()
+			return rep
+		}
+
+		var ids []string
+		byWorkspace := make(map[string]int)
+		for _, msg := range msgs {
+			workspaceID := dlqWorkspaceID(msg)
+			if !p.covers(workspaceID) {
+				continue
+			}
+			rep.Scanned++
+			if p.expired(ctx, workspaceID, dlqEntryFromMessage(msg).Timestamp) {
+				ids = append(ids, msg.ID)
+				byWorkspace[workspaceID]++
+			}
+		}
+		if len(ids) > 0 && !dryRun {
+			if err := rdb.XDel(ctx, dlqKey, ids...).Err(); err != nil {
+				rep.Error = err.Error()
+				return rep
+			}
+		}
+		for workspaceID, n := range byWorkspace {
+			rep.deleted(workspaceID, n)
+		}
+
+		if next == "" {
+			return rep
+		}
+		cursor = next
+	}
+}
+
+func dlqWorkspaceID(msg redis.XMessage) string {
+	raw, _ := msg.Values["data"].(string)
+	var e Event
+	if json.Unmarshal([]byte(raw), &e) != nil {
+		return ""
+	}
+	return e.WorkspaceID
+}
+
+// pruneTPVSnapshots deletes each workspace's daily TPV snapshots older than
+// its retention.
+func pruneTPVSnapshots(ctx context.Context, p *retentionPolicy, dryRun bool) *RetentionStoreReport {
+	if pgDB == nil {
+		return nil
+	}
+	rep := &RetentionStoreReport{Store: "tpv_snapshots"}
+	workspaces, err := pg.SnapshotWorkspaces(ctx, pgDB)
+	if err != nil {
+		rep.Error = err.Error()
+		return rep
+	}
+	for _, workspaceID := range workspaces {
+		if !p.covers(workspaceID) {
+			continue
+		}
+		rule := p.rule(ctx, workspaceID)
+		if rule.Error != "" {
+			continue
+		}
+		n, err := pg.DeleteSnapshotsBefore(ctx, pgDB, workspaceID, *rule.Cutoff, dryRun)
+		if err != nil {
+			rep.Error = err.Error()
+			return rep
+		}
+		rep.deleted(workspaceID, int(n))
+	}
+	return rep
+}
+
+// exportLine holds the fields of an export record that retention reads.
+type exportLine struct {
+	WorkspaceID string    `json:"workspace_id"`
+	ProcessedAt time.Time `json:"processed_at"`
+}
+
+// pruneExportFile rewrites PAYFLUX_EXPORT_FILE without its expired lines.
+// The kept lines go to a temporary file next to it that then replaces it,
+// and the export writer moves to the new file. Export writes wait for the
+// rewrite. Lines that are not export records are kept.
+func pruneExportFile(ctx context.Context, p *retentionPolicy, dryRun bool) *RetentionStoreReport {
+	exportFileMu.Lock()
+	defer exportFileMu.Unlock()
+
+	if exportFile == nil {
+		return nil
+	}
+	rep := &RetentionStoreReport{Store: "export_file"}
+	if err := exportWriter.Flush(); err != nil {
+		rep.Error = err.Error()
+		return rep
+	}
+
+	path := exportFile.Name()
+	var kept *os.File
+	if !dryRun {
+		info, err := exportFile.Stat()
+		if err == nil {
+			kept, err = os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".retention-*")
+		}
+		if err == nil {
+			err = kept.Chmod(info.Mode().Perm())
+		}
+		if err != nil {
+			if kept != nil {
+				discardTemp(kept)
+			}
+			rep.Error = err.Error()
+			return rep
+		}
+	}
+
+	if err := filterExportFile(ctx, p, path, kept, rep); err != nil {
+		if kept != nil {
+			discardTemp(kept)
+		}
+		rep.Error = err.Error()
+		return rep
+	}
+	if kept == nil {
+		return rep
+	}
+	if rep.Deleted == 0 {
+		discardTemp(kept)
+		return rep
+	}
+
+	if err := replaceExportFile(kept, path); err != nil {
+		rep.Error = err.Error()
+	}
+	return rep
+}
+
+// filterExportFile reads path line by line, counting expired lines in rep
+// and copying the rest to kept (when non-nil).
+func filterExportFile(ctx context.Context, p *retentionPolicy, path string, kept *os.File, rep *RetentionStoreReport) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	r := bufio.NewReader(src)
+	var w *bufio.Writer
+	if kept != nil {
+		w = bufio.NewWriter(kept)
+	}
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(line) > 0 {
+			var rec exportLine
+			parsed := json.Unmarshal(line, &rec) == nil
+			if p.covers(rec.WorkspaceID) {
+				rep.Scanned++
+			}
+			if parsed && p.expired(ctx, rec.WorkspaceID, rec.ProcessedAt) {
+				rep.deleted(rec.WorkspaceID, 1)
+			} else if w != nil {
+				if _, err := w.Write(line); err != nil {
+					return err
+				}
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if w != nil {
+		return w.Flush()
+	}
+	return nil
+}
+
+// replaceExportFile moves kept over path and points the export writer at
+// it. Callers hold exportFileMu.
+func replaceExportFile(kept *os.File, path string) error {
+	if err := kept.Sync(); err != nil {
+		discardTemp(kept)
+		return err
+	}
+	if err := kept.Close(); err != nil {
+		_ = os.Remove(kept.Name())
+		return err
+	}
+	if err := os.Rename(kept.Name(), path); err != nil {
+		_ = os.Remove(kept.Name())
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		// Writes keep going to the old, now unlinked, file.
+		return err
+	}
+	_ = exportFile.Close()
+	exportFile = f
+	exportWriter.Reset(f)
+	return nil
+}
+
+func discardTemp(f *os.File) {
+	_ = f.Close()
+	_ = os.Remove(f.Name())
+}
+
+// RetentionRunRequest is the optional body of POST /api/v1/retention.
+// dry_run can only make a run more conservative than PAYFLUX_RETENTION_MODE:
+// a run deletes only in enforce mode and without dry_run.
+type RetentionRunRequest struct {
+	DryRun *bool `json:"dry_run,omitempty"`
+}
+
+// handleRetention serves /api/v1/retention: GET returns the last report,
+// POST runs retention now and returns its report. Callers whose key belongs
+// to a workspace see and prune only that workspace's data.
+func handleRetention(w http.ResponseWriter, r *http.Request) {
+	workspaceID := requestWorkspaceID(r)
+	var report *RetentionReport
+	switch r.Method {
+	case http.MethodGet:
+		retentionMu.Lock()
+		report = lastRetentionReport
+		retentionMu.Unlock()
+		if report == nil {
+			writeRetentionError(w, http.StatusNotFound, "retention has not run yet")
+			return
+		}
+		if workspaceID != "" {
+			report = report.forWorkspace(workspaceID)
+		}
+	case http.MethodPost:
+		var req RetentionRunRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			writeRetentionError(w, http.StatusBadRequest, "invalid JSON body")
+			return
+		}
+		dryRun := retentionMode != retentionEnforce || (req.DryRun != nil && *req.DryRun)
+		slog.Info("retention_run_requested", "key_id", requestKeyID(r), "workspace_id", workspaceID, "dry_run", dryRun)
+		report = runRetention(r.Context(), dryRun, workspaceID)
+	default:
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(report)
+}
+
+func writeRetentionError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
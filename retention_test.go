@@ -0,0 +1,310 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"payment-node/internal/apikeys"
+	"payment-node/internal/tier"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// setupRetentionTest loads the runtime entitlements and resolves "ws-ent"
+// to enterprise (365 days) and "ws-down" to a failed lookup. Data without a
+// workspace gets the free deployment tier (baseline, 7 days).
+func setupRetentionTest(t *testing.T) {
+	t.Helper()
+	loadRetentionConfig()
+	runtimeCanonicalTier = tier.TierFree
+	requestTierResolver = &tier.RequestResolver{
+		Default: tier.TierFree,
+		WorkspaceTier: func(_ context.Context, workspaceID string) (tier.CanonicalTier, error) {
+			if workspaceID == "ws-ent" {
+				return tier.TierEnterprise, nil
+			}
+			return "", errors.New("postgres unavailable")
+		},
+	}
+	t.Cleanup(func() { requestTierResolver = nil })
+}
+
+func daysAgo(n int) time.Time {
+	return time.Now().UTC().Add(-time.Duration(n) * 24 * time.Hour)
+}
+
+func TestRetentionPolicy(t *testing.T) {
+	setupRetentionTest(t)
+	ctx := context.Background()
+	p := newRetentionPolicy(retentionRegistry, time.Now())
+
+	if rule := p.rule(ctx, ""); rule.EntitlementTier != "baseline" || rule.RetentionDays != 7 {
+		t.Errorf("unattributed rule = %+v", rule)
+	}
+	if rule := p.rule(ctx, "ws-ent"); rule.EntitlementTier != "fortress" || rule.RetentionDays != 365 {
+		t.Errorf("enterprise rule = %+v", rule)
+	}
+	if rule := p.rule(ctx, "ws-down"); rule.Error == "" || rule.Cutoff != nil {
+		t.Errorf("failed lookup rule = %+v, want an error and no cutoff", rule)
+	}
+
+	for _, tt := range []struct {
+		workspace string
+		ts        time.Time
+		want      bool
+	}{
+		{"", daysAgo(8), true},
+		{"", daysAgo(6), false},
+		{"ws-ent", daysAgo(8), false},
+		{"ws-ent", daysAgo(366), true},
+		{"ws-down", daysAgo(1000), false}, // unresolved: keep
+		{"", time.Time{}, false},          // no timestamp: keep
+	} {
+		if got := p.expired(ctx, tt.workspace, tt.ts); got != tt.want {
+			t.Errorf("expired(%q, %s) = %t, want %t", tt.workspace, tt.ts.Format(time.DateOnly), got, tt.want)
+		}
+	}
+
+	if ws := p.applied(); len(ws) != 3 || ws[0].WorkspaceID != "" || ws[1].WorkspaceID != "ws-down" {
+		t.Errorf("applied = %+v", ws)
+	}
+}
+
+func TestPruneWarnings(t *testing.T) {
+	setupRetentionTest(t)
+	prev := warningStore
+	defer func() { warningStore = prev }()
+
+	store := NewWarningStore(10)
+	store.Add(&Warning{WarningID: "old", ProcessedAt: daysAgo(10)})
+	store.Add(&Warning{WarningID: "old-ent", WorkspaceID: "ws-ent", ProcessedAt: daysAgo(10)})
+	store.Add(&Warning{WarningID: "fresh", ProcessedAt: daysAgo(1)})
+	warningStore = store
+
+	rep := pruneWarnings(context.Background(), newRetentionPolicy(retentionRegistry, time.Now()), true)
+	if rep.Scanned != 3 || rep.Deleted != 1 || rep.DeletedByWorkspace[""] != 1 {
+		t.Errorf("dry run report = %+v", rep)
+	}
+	if store.Count() != 3 {
+		t.Fatalf("dry run deleted warnings: count=%d", store.Count())
+	}
+
+	pruneWarnings(context.Background(), newRetentionPolicy(retentionRegistry, time.Now()), false)
+	if _, ok := store.Get("old"); ok || store.Count() != 2 {
+		t.Errorf("expired warning kept, count=%d", store.Count())
+	}
+}
+
+func TestPruneExportFile(t *testing.T) {
+	setupRetentionTest(t)
+	prevFile, prevWriter := exportFile, exportWriter
+	defer func() { exportFile, exportWriter = prevFile, prevWriter }()
+
+	path := filepath.Join(t.TempDir(), "export.jsonl")
+	line := func(workspace string, ts time.Time) string {
+		b, _ := json.Marshal(exportLine{WorkspaceID: workspace, ProcessedAt: ts.Truncate(time.Second)})
+		return string(b) + "\n"
+	}
+	kept := line("", daysAgo(1)) + line("ws-ent", daysAgo(30)) + "not json\n"
+	content := line("", daysAgo(30)) + kept
+	if err := os.WriteFile(path, []byte(content), 0o640); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exportFile, exportWriter = f, bufio.NewWriter(f)
+	defer func() { _ = exportFile.Close() }()
+
+	rep := pruneExportFile(context.Background(), newRetentionPolicy(retentionRegistry, time.Now()), true)
+	if rep.Scanned != 4 || rep.Deleted != 1 || rep.Error != "" {
+		t.Errorf("dry run report = %+v", rep)
+	}
+	if got, _ := os.ReadFile(path); string(got) != content {
+		t.Fatalf("dry run rewrote the file:\n%s", got)
+	}
+
+	rep = pruneExportFile(context.Background(), newRetentionPolicy(retentionRegistry, time.Now()), false)
+	if rep.Deleted != 1 || rep.Error != "" {
+		t.Fatalf("report = %+v", rep)
+	}
+	if got, _ := os.ReadFile(path); string(got) != kept {
+		t.Errorf("file after retention:\n%s\nwant:\n%s", got, kept)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0o640 {
+		t.Errorf("mode = %v, want 0640", info.Mode().Perm())
+	}
+
+	// Exports after the rewrite land in the new file
+	(&exportWriterAdapter{writer: exportWriter}).WriteFile([]byte("next\n"))
+	if got, _ := os.ReadFile(path); string(got) != kept+"next\n" {
+		t.Errorf("export after rewrite went elsewhere:\n%s", got)
+	}
+	if matches, _ := filepath.Glob(path + ".retention-*"); len(matches) != 0 {
+		t.Errorf("temporary files left: %v", matches)
+	}
+}
+
+func TestHandleRetention(t *testing.T) {
+	setupRetentionTest(t)
+	prev := warningStore
+	defer func() { warningStore, retentionMode = prev, retentionDryRun }()
+	warningStore = NewWarningStore(10)
+	warningStore.Add(&Warning{WarningID: "old", ProcessedAt: daysAgo(10)})
+
+	retentionMu.Lock()
+	lastRetentionReport = nil
+	retentionMu.Unlock()
+
+	rec := httptest.NewRecorder()
+	handleRetention(rec, httptest.NewRequest(http.MethodGet, "/api/v1/retention", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("GET before any run: status %d", rec.Code)
+	}
+
+	// dry_run is the default mode: an empty POST deletes nothing, and the
+	// body cannot turn the dry run into an enforced one
+	for _, body := range []string{"", `{"dry_run":false}`} {
+		rec = httptest.NewRecorder()
+		handleRetention(rec, httptest.NewRequest(http.MethodPost, "/api/v1/retention", strings.NewReader(body)))
+		var report RetentionReport
+		if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+			t.Fatalf("status %d: %v", rec.Code, err)
+		}
+		if !report.DryRun || len(report.Stores) != 1 || report.Stores[0].Deleted != 1 || warningStore.Count() != 1 {
+			t.Errorf("body %q: dry run report = %+v", body, report)
+		}
+	}
+
+	retentionMode = retentionOff
+	rec = httptest.NewRecorder()
+	handleRetention(rec, httptest.NewRequest(http.MethodPost, "/api/v1/retention", strings.NewReader(`{"dry_run":false}`)))
+	if warningStore.Count() != 1 {
+		t.Fatalf("mode off: POST deleted warnings")
+	}
+
+	retentionMode = retentionEnforce
+	rec = httptest.NewRecorder()
+	handleRetention(rec, httptest.NewRequest(http.MethodPost, "/api/v1/retention", strings.NewReader(`{"dry_run":true}`)))
+	if rec.Code != http.StatusOK || warningStore.Count() != 1 {
+		t.Fatalf("enforce mode with dry_run: status %d, %d warnings left", rec.Code, warningStore.Count())
+	}
+
+	rec = httptest.NewRecorder()
+	handleRetention(rec, httptest.NewRequest(http.MethodPost, "/api/v1/retention", nil))
+	if rec.Code != http.StatusOK || warningStore.Count() != 0 {
+		t.Fatalf("enforced run: status %d, %d warnings left", rec.Code, warningStore.Count())
+	}
+
+	rec = httptest.NewRecorder()
+	handleRetention(rec, httptest.NewRequest(http.MethodGet, "/api/v1/retention", nil))
+	var report RetentionReport
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil || report.DryRun {
+		t.Errorf("GET returned %s", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	handleRetention(rec, httptest.NewRequest(http.MethodPost, "/api/v1/retention", strings.NewReader(`{`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid body: status %d", rec.Code)
+	}
+}
+
+func TestHandleRetentionScopedAdmin(t *testing.T) {
+	setupRetentionTest(t)
+	prev := warningStore
+	defer func() { warningStore, retentionMode = prev, retentionDryRun }()
+	retentionMode = retentionEnforce
+	warningStore = NewWarningStore(10)
+	warningStore.Add(&Warning{WarningID: "old", ProcessedAt: daysAgo(10)})
+	warningStore.Add(&Warning{WarningID: "old-ent", WorkspaceID: "ws-ent", ProcessedAt: daysAgo(400)})
+	warningStore.Add(&Warning{WarningID: "old-other", WorkspaceID: "ws-other", ProcessedAt: daysAgo(400)})
+
+	// A global run records the report of every workspace
+	full := runRetention(context.Background(), true, "")
+	if len(full.Workspaces) != 3 {
+		t.Fatalf("global run workspaces = %+v", full.Workspaces)
+	}
+
+	scoped := func(method string) *http.Request {
+		req := httptest.NewRequest(method, "/api/v1/retention", nil)
+		return req.WithContext(apikeys.WithIdentity(req.Context(),
+			&apikeys.Identity{KeyID: "ent-admin", WorkspaceID: "ws-ent", Scopes: []string{apikeys.ScopeAdmin}}))
+	}
+
+	rec := httptest.NewRecorder()
+	handleRetention(rec, scoped(http.MethodGet))
+	var report RetentionReport
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatalf("status %d: %v", rec.Code, err)
+	}
+	if len(report.Workspaces) != 1 || report.Workspaces[0].WorkspaceID != "ws-ent" {
+		t.Errorf("scoped GET workspaces = %+v", report.Workspaces)
+	}
+	if rep := report.Stores[0]; rep.Deleted != 1 || len(rep.DeletedByWorkspace) != 1 || rep.Scanned != 0 {
+		t.Errorf("scoped GET store report = %+v", rep)
+	}
+
+	rec = httptest.NewRecorder()
+	handleRetention(rec, scoped(http.MethodPost))
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatalf("status %d: %v", rec.Code, err)
+	}
+	if len(report.Workspaces) != 1 || report.Stores[0].Scanned != 1 || report.Stores[0].Deleted != 1 {
+		t.Errorf("scoped POST report = %+v", report)
+	}
+	for id, want := range map[string]bool{"old": true, "old-ent": false, "old-other": true} {
+		if _, ok := warningStore.Get(id); ok != want {
+			t.Errorf("warning %s kept=%t, want %t", id, ok, want)
+		}
+	}
+
+	retentionMu.Lock()
+	last := lastRetentionReport
+	retentionMu.Unlock()
+	if last != full {
+		t.Error("scoped run replaced the global report")
+	}
+}
+
+func TestPruneDlq(t *testing.T) {
+	setupTestRedis(t)
+	defer teardownTestRedis(t)
+	setupRetentionTest(t)
+
+	add := func(data string, failedAt time.Time) string {
+		id, err := testRdb.XAdd(testCtx, &redis.XAddArgs{
+			Stream: dlqKey,
+			Values: map[string]any{"data": data, "reason": "unmarshal_failed", "timestamp": failedAt.Unix()},
+		}).Result()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+	old := add(`{"event_id":"e1"}`, daysAgo(10))
+	oldEnt := add(`{"event_id":"e2","workspace_id":"ws-ent"}`, daysAgo(10))
+	garbage := add(`not json`, daysAgo(10))
+	fresh := add(`{"event_id":"e3"}`, daysAgo(1))
+
+	rep := pruneDlq(testCtx, newRetentionPolicy(retentionRegistry, time.Now()), false)
+	if rep.Scanned != 4 || rep.Deleted != 2 || rep.Error != "" {
+		t.Fatalf("report = %+v", rep)
+	}
+	for id, want := range map[string]bool{old: false, oldEnt: true, garbage: false, fresh: true} {
+		msgs, _ := testRdb.XRange(testCtx, dlqKey, id, id).Result()
+		if (len(msgs) == 1) != want {
+			t.Errorf("entry %s kept=%t, want %t", id, len(msgs) == 1, want)
+		}
+	}
+}
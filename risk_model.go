@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"payment-node/internal/riskmodel"
+)
+
+// Model roles, used as the "role" label on risk model metrics.
+const (
+	riskModelRolePrimary = "primary"
+	riskModelRoleShadow  = "shadow"
+)
+
+// loadRiskModel applies PAYFLUX_RISK_MODEL_FILE to riskScorer. Without a
+// file the scorer keeps the built-in model, whose band cutoffs come from
+// PAYFLUX_RISK_SCORE_THRESHOLDS. With a file, the file's bands win.
+//
+// startup.ValidateConfig has already loaded and validated the file, so a
+// failure here means it changed in between; treat it like any other config
+// error and refuse to start.
+func loadRiskModel() {
+	riskModelFile = os.Getenv("PAYFLUX_RISK_MODEL_FILE")
+	riskModelReload = time.Duration(envInt("PAYFLUX_RISK_MODEL_RELOAD_SEC", 30)) * time.Second
+	if riskModelFile == "" {
+		return
+	}
+
+	m, err := riskmodel.Load(riskModelFile)
+	if err != nil {
+		log.Fatalf("PAYFLUX_RISK_MODEL_FILE=%s: %v", riskModelFile, err)
+	}
+	riskScorer.SetModel(m)
+	slog.Info("risk_model_loaded", "path", riskModelFile, "version", m.Version)
+}
+
+// loadShadowRiskModel builds shadowScorer from PAYFLUX_RISK_SHADOW_MODEL_FILE.
+// The shadow scorer mirrors the primary's window and merchant scope so the
+// only difference between the two is the model.
+func loadShadowRiskModel() {
+	shadowModelFile = os.Getenv("PAYFLUX_RISK_SHADOW_MODEL_FILE")
+	if shadowModelFile == "" {
+		return
+	}
+
+	m, err := riskmodel.Load(shadowModelFile)
+	if err != nil {
+		log.Fatalf("PAYFLUX_RISK_SHADOW_MODEL_FILE=%s: %v", shadowModelFile, err)
+	}
+	shadowScorer = NewRiskScorer(riskScoreWindow, riskThresholds)
+	shadowScorer.SetMerchantScope(riskMerchantScope, riskMaxMerchants)
+	shadowScorer.SetModel(m)
+	shadowComparator = NewShadowComparator()
+	slog.Info("risk_shadow_model_loaded", "path", shadowModelFile, "version", m.Version)
+}
+
+// startRiskModelWatcher publishes the active model versions and, for each
+// configured model file, reloads it on change (polled every
+// PAYFLUX_RISK_MODEL_RELOAD_SEC) or on SIGHUP. An invalid file is logged and
+// counted; the previous model stays active.
+func startRiskModelWatcher(ctx context.Context) {
+	if riskScorer == nil {
+		return
+	}
+
+	var watchers []*riskmodel.Watcher
+	for _, t := range []struct {
+		role   string
+		path   string
+		scorer *RiskScorer
+	}{
+		{riskModelRolePrimary, riskModelFile, riskScorer},
+		{riskModelRoleShadow, shadowModelFile, shadowScorer},
+	} {
+		if t.scorer == nil {
+			continue
+		}
+		riskModelInfo.WithLabelValues(t.role, t.scorer.Model().Version).Set(1)
+		if t.path != "" {
+			watchers = append(watchers, newRiskModelWatcher(t.role, t.path, t.scorer))
+		}
+	}
+	if len(watchers) == 0 {
+		return
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(hup)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				for _, w := range watchers {
+					w.Trigger()
+				}
+			}
+		}
+	}()
+	for _, w := range watchers {
+		go w.Run(ctx)
+	}
+}
+
+func newRiskModelWatcher(role, path string, scorer *RiskScorer) *riskmodel.Watcher {
+	return riskmodel.NewWatcher(path, riskModelReload,
+		func(m *riskmodel.Model) {
+			prev := scorer.Model().Version
+			scorer.SetModel(m)
+			riskModelReloads.WithLabelValues(role, "success").Inc()
+			riskModelInfo.DeleteLabelValues(role, prev)
+			riskModelInfo.WithLabelValues(role, m.Version).Set(1)
+			slog.Info("risk_model_reloaded", "role", role, "path", path, "previous_version", prev, "version", m.Version)
+		},
+		func(err error) {
+			riskModelReloads.WithLabelValues(role, "error").Inc()
+			slog.Error("risk_model_reload_failed", "role", role, "path", path, "error", err.Error(),
+				"active_version", scorer.Model().Version)
+		})
+}
@@ -1,10 +1,13 @@
 package main
 
 import (
+	"container/list"
 	"math"
 	"strings"
 	"sync"
 	"time"
+
+	"payment-node/internal/riskmodel"
 )
 
 // RiskResult contains the output of the scoring algorithm
@@ -19,6 +22,42 @@ type RiskResult struct {
 	TrajectoryWindowSec  int     `json:"-"` // window size in seconds
 	CurrentFailureRate   float64 `json:"-"` // current bucket failure rate
 	BaselineFailureRate  float64 `json:"-"` // baseline (other buckets) failure rate
+
+	// ModelVersion identifies the scoring model that produced this result.
+	ModelVersion string `json:"-"`
+
+	// Merchant-level result, computed from the event's MerchantIDHash window
+	// (see MerchantScope). MerchantScored is false when merchant scoring is
+	// off or the event carries no merchant hash.
+	MerchantScored  bool     `json:"-"`
+	MerchantScore   float64  `json:"merchant_risk_score,omitempty"`
+	MerchantBand    string   `json:"merchant_risk_band,omitempty"`
+	MerchantDrivers []string `json:"merchant_risk_drivers,omitempty"`
+}
+
+// MerchantScope selects how merchant windows are keyed.
+type MerchantScope string
+
+const (
+	// MerchantScopeOff disables merchant-level scoring.
+	MerchantScopeOff MerchantScope = "off"
+	// MerchantScopeMerchant keeps one window per MerchantIDHash across all processors.
+	MerchantScopeMerchant MerchantScope = "merchant"
+	// MerchantScopeProcessorMerchant keeps one window per processor×MerchantIDHash.
+	MerchantScopeProcessorMerchant MerchantScope = "processor_merchant"
+)
+
+// defaultMaxMerchants bounds how many merchant windows are tracked before the
+// least recently updated one is evicted.
+const defaultMaxMerchants = 10000
+
+// ParseMerchantScope validates a PAYFLUX_RISK_MERCHANT_SCOPE value.
+func ParseMerchantScope(v string) (MerchantScope, bool) {
+	switch MerchantScope(v) {
+	case MerchantScopeOff, MerchantScopeMerchant, MerchantScopeProcessorMerchant:
+		return MerchantScope(v), true
+	}
+	return "", false
 }
 
 // ProcessorMetrics tracks counters for a specific time bucket
@@ -42,10 +81,17 @@ type RiskScorer struct {
 	// processor -> bucketIndex -> metrics
 	history map[string][]ProcessorMetrics
 
-	// Thresholds for bands
-	elevated float64
-	high     float64
-	critical float64
+	// Merchant windows: key -> element in merchantOrder (Front = most
+	// recently updated). Bounded by maxMerchants; the least recently
+	// updated merchant is evicted when a new one arrives at capacity.
+	merchantScope MerchantScope
+	maxMerchants  int
+	merchants     map[string]*list.Element
+	merchantOrder *list.List
+
+	// Active scoring model (weights, driver thresholds, band cutoffs).
+	// Replaced atomically under mu by SetModel on hot reload.
+	model *riskmodel.Model
 
 	// nowFunc returns current unix timestamp (useful for testing)
 	nowFunc func() int64
@@ -63,13 +109,57 @@ func NewRiskScorer(windowSec int, thresholds [3]float64) *RiskScorer {
 		bucketSizeSec: bucketSize,
 		numBuckets:    numBuckets,
 		history:       make(map[string][]ProcessorMetrics),
-		elevated:      thresholds[0],
-		high:          thresholds[1],
-		critical:      thresholds[2],
+		merchantScope: MerchantScopeMerchant,
+		maxMerchants:  defaultMaxMerchants,
+		merchants:     make(map[string]*list.Element),
+		merchantOrder: list.New(),
+		model:         riskmodel.Default(thresholds),
 		nowFunc:       func() int64 { return time.Now().Unix() },
 	}
 }
 
+// merchantWindow is the sliding window for one merchant key.
+type merchantWindow struct {
+	key  string
+	hist []ProcessorMetrics
+}
+
+// SetMerchantScope configures merchant-level scoring. Must be called before
+// the scorer is shared; maxMerchants <= 0 keeps the default bound.
+func (s *RiskScorer) SetMerchantScope(scope MerchantScope, maxMerchants int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.merchantScope = scope
+	if maxMerchants > 0 {
+		s.maxMerchants = maxMerchants
+	}
+	s.merchants = make(map[string]*list.Element)
+	s.merchantOrder = list.New()
+}
+
+// SetModel replaces the active scoring model. Windows are kept, so the next
+// score for every processor and merchant uses the new model immediately.
+func (s *RiskScorer) SetModel(m *riskmodel.Model) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.model = m
+}
+
+// Model returns the active scoring model.
+func (s *RiskScorer) Model() *riskmodel.Model {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.model
+}
+
+// MerchantCount returns the number of merchant windows currently tracked.
+func (s *RiskScorer) MerchantCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.merchantOrder.Len()
+}
+
 func (s *RiskScorer) RecordEvent(event Event) RiskResult {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -78,7 +168,25 @@ func (s *RiskScorer) RecordEvent(event Event) RiskResult {
 	if processor == "" {
 		processor = "unknown"
 	}
+	now := s.nowFunc()
+
+	res := s.recordProcessor(processor, event, now)
+	res.ModelVersion = s.model.Version
+
+	if hist := s.merchantHistory(processor, event.MerchantIDHash); hist != nil {
+		s.recordBucket(hist, event, now)
+		m := s.scoreHistory(hist)
+		res.MerchantScored = true
+		res.MerchantScore = m.Score
+		res.MerchantBand = m.Band
+		res.MerchantDrivers = m.Drivers
+	}
 
+	return res
+}
+
+// recordProcessor updates and scores the processor-level window.
+func (s *RiskScorer) recordProcessor(processor string, event Event, now int64) RiskResult {
 	// Initialize history for processor
 	if _, exists := s.history[processor]; !exists {
 		// Cap cardinality to 100 processors to avoid memory growth
@@ -88,11 +196,45 @@ func (s *RiskScorer) RecordEvent(event Event) RiskResult {
 		s.history[processor] = make([]ProcessorMetrics, s.numBuckets)
 	}
 
-	// Update current bucket
-	now := s.nowFunc()
+	s.recordBucket(s.history[processor], event, now)
+	return s.computeScore(processor)
+}
+
+// merchantHistory returns the window for the event's merchant key, creating
+// it (and evicting the least recently updated merchant at capacity) when
+// needed. Returns nil when merchant scoring does not apply to the event.
+func (s *RiskScorer) merchantHistory(processor, merchantIDHash string) []ProcessorMetrics {
+	if s.merchantScope == MerchantScopeOff || merchantIDHash == "" {
+		return nil
+	}
+	key := merchantIDHash
+	if s.merchantScope == MerchantScopeProcessorMerchant {
+		key = processor + "|" + merchantIDHash
+	}
+
+	if elem, ok := s.merchants[key]; ok {
+		s.merchantOrder.MoveToFront(elem)
+		return elem.Value.(*merchantWindow).hist
+	}
+
+	if s.merchantOrder.Len() >= s.maxMerchants {
+		if oldest := s.merchantOrder.Back(); oldest != nil {
+			delete(s.merchants, oldest.Value.(*merchantWindow).key)
+			s.merchantOrder.Remove(oldest)
+			riskMerchantEvictions.Inc()
+		}
+	}
+
+	w := &merchantWindow{key: key, hist: make([]ProcessorMetrics, s.numBuckets)}
+	s.merchants[key] = s.merchantOrder.PushFront(w)
+	return w.hist
+}
+
+// recordBucket adds the event to the current bucket of hist.
+func (s *RiskScorer) recordBucket(hist []ProcessorMetrics, event Event, now int64) {
 	bucketIdx := int((now / int64(s.bucketSizeSec)) % int64(s.numBuckets))
 
-	bucket := &s.history[processor][bucketIdx]
+	bucket := &hist[bucketIdx]
 
 	// If bucket is older than one bucketSizeSec, it's stale (left over from previous window cycle)
 	if now-bucket.LastUpdate >= int64(s.bucketSizeSec) {
@@ -125,8 +267,6 @@ func (s *RiskScorer) RecordEvent(event Event) RiskResult {
 	if len(bucket.GeoBuckets) < 50 {
 		bucket.GeoBuckets[event.GeoBucket] = struct{}{}
 	}
-
-	return s.computeScore(processor)
 }
 
 type riskStats struct {
@@ -202,45 +342,45 @@ func (s *RiskScorer) computeComponentScores(stats riskStats, currentBucket Proce
 	return c
 }
 
-func calculateWeightedScore(c riskComponents) float64 {
-	score := (0.25 * c.FailRate) +
-		(0.20 * clamp(c.RetryPressure)) +
-		(0.15 * clamp(c.TimeoutMix)) +
-		(0.15 * clamp(c.TrafficSpike)) +
-		(0.10 * clamp(c.AuthFailMix)) +
-		(0.15 * clamp(c.GeoEntropy))
+func calculateWeightedScore(c riskComponents, w riskmodel.Components) float64 {
+	score := (w.FailRate * c.FailRate) +
+		(w.RetryPressure * clamp(c.RetryPressure)) +
+		(w.TimeoutMix * clamp(c.TimeoutMix)) +
+		(w.TrafficSpike * clamp(c.TrafficSpike)) +
+		(w.AuthFailMix * clamp(c.AuthFailMix)) +
+		(w.GeoEntropy * clamp(c.GeoEntropy))
 	return clamp(score)
 }
 
-func (s *RiskScorer) mapScoreToBand(score float64) string {
-	if score >= s.critical {
+func mapScoreToBand(score float64, b riskmodel.Bands) string {
+	if score >= b.Critical {
 		return "critical"
-	} else if score >= s.high {
+	} else if score >= b.High {
 		return "high"
-	} else if score >= s.elevated {
+	} else if score >= b.Elevated {
 		return "elevated"
 	}
 	return "low"
 }
 
-func identifyRiskDrivers(c riskComponents) []string {
+func identifyRiskDrivers(c riskComponents, t riskmodel.Components) []string {
 	drivers := []string{}
-	if c.FailRate > 0.4 {
+	if c.FailRate > t.FailRate {
 		drivers = append(drivers, "high_failure_rate")
 	}
-	if c.RetryPressure > 0.5 {
+	if c.RetryPressure > t.RetryPressure {
 		drivers = append(drivers, "retry_pressure_spike")
 	}
-	if c.TimeoutMix > 0.5 {
+	if c.TimeoutMix > t.TimeoutMix {
 		drivers = append(drivers, "timeout_clustering")
 	}
-	if c.TrafficSpike > 0.5 {
+	if c.TrafficSpike > t.TrafficSpike {
 		drivers = append(drivers, "traffic_volatility")
 	}
-	if c.AuthFailMix > 0.5 {
+	if c.AuthFailMix > t.AuthFailMix {
 		drivers = append(drivers, "auth_failure_cluster")
 	}
-	if c.GeoEntropy > 0.5 {
+	if c.GeoEntropy > t.GeoEntropy {
 		drivers = append(drivers, "geo_entropy_increase")
 	}
 	if len(drivers) == 0 {
@@ -286,24 +426,29 @@ func (s *RiskScorer) calculateTrajectory(hist []ProcessorMetrics, currentIdx int
 }
 
 func (s *RiskScorer) computeScore(processor string) RiskResult {
-	hist := s.history[processor]
+	return s.scoreHistory(s.history[processor])
+}
+
+// scoreHistory scores a single window; shared by processor and merchant scopes.
+func (s *RiskScorer) scoreHistory(hist []ProcessorMetrics) RiskResult {
 	stats := aggregateHistory(hist)
 
 	if stats.TotalEvents < 5 {
-		return RiskResult{Score: 0, Band: "low", Drivers: []string{"insufficient_data"}}
+		return RiskResult{Score: 0, Band: "low", Drivers: []string{"insufficient_data"}, ModelVersion: s.model.Version}
 	}
 
 	// Calculate scores and metadata
 	now := s.nowFunc()
 	currentIdx := int((now / int64(s.bucketSizeSec)) % int64(s.numBuckets))
 	components := s.computeComponentScores(stats, hist[currentIdx])
-	score := calculateWeightedScore(components)
+	score := calculateWeightedScore(components, s.model.Weights)
 	multiplier, direction, curFR, baseFR := s.calculateTrajectory(hist, currentIdx)
 
 	return RiskResult{
 		Score:                math.Round(score*100) / 100,
-		Band:                 s.mapScoreToBand(score),
-		Drivers:              identifyRiskDrivers(components),
+		Band:                 mapScoreToBand(score, s.model.Bands),
+		Drivers:              identifyRiskDrivers(components, s.model.DriverThresholds),
+		ModelVersion:         s.model.Version,
 		TrajectoryMultiplier: multiplier,
 		TrajectoryDirection:  direction,
 		TrajectoryWindowSec:  s.windowSec,
@@ -4,6 +4,8 @@ import (
 	"math"
 	"reflect"
 	"testing"
+
+	"payment-node/internal/riskmodel"
 )
 
 const floatTolerance = 0.001
@@ -150,3 +152,120 @@ func TestRiskScorer_ScoringLogic_Deterministic(t *testing.T) {
 		})
 	}
 }
+
+func TestRiskScorer_MerchantScoping(t *testing.T) {
+	fixedNow := int64(1000000200)
+	s := NewRiskScorer(300, [3]float64{0.3, 0.6, 0.8})
+	s.nowFunc = func() int64 { return fixedNow }
+
+	// Healthy merchant traffic across several buckets
+	for i := 0; i < 5; i++ {
+		now := fixedNow - int64(i*10)
+		s.nowFunc = func() int64 { return now }
+		for j := 0; j < 10; j++ {
+			s.RecordEvent(Event{Processor: "stripe", MerchantIDHash: "healthy", GeoBucket: "US"})
+		}
+	}
+	s.nowFunc = func() int64 { return fixedNow }
+
+	// One noisy merchant: every event a retried timeout from a new geo
+	var noisy RiskResult
+	for i := 0; i < 10; i++ {
+		noisy = s.RecordEvent(Event{
+			Processor:       "stripe",
+			MerchantIDHash:  "noisy",
+			FailureCategory: "processor_timeout",
+			RetryCount:      3,
+			GeoBucket:       string(rune('A' + i)),
+		})
+	}
+
+	if !noisy.MerchantScored {
+		t.Fatal("expected merchant score for event with merchant hash")
+	}
+	if noisy.MerchantScore <= noisy.Score {
+		t.Errorf("noisy merchant score %.2f should exceed processor score %.2f", noisy.MerchantScore, noisy.Score)
+	}
+	if noisy.MerchantBand == "low" {
+		t.Errorf("noisy merchant should be above low, got %s", noisy.MerchantBand)
+	}
+
+	healthy := s.RecordEvent(Event{Processor: "stripe", MerchantIDHash: "healthy", GeoBucket: "US"})
+	if healthy.MerchantBand != "low" {
+		t.Errorf("healthy merchant should stay low, got %s (%.2f)", healthy.MerchantBand, healthy.MerchantScore)
+	}
+
+	anon := s.RecordEvent(Event{Processor: "stripe"})
+	if anon.MerchantScored {
+		t.Error("event without merchant hash should not be merchant-scored")
+	}
+}
+
+func TestRiskScorer_MerchantEviction(t *testing.T) {
+	s := NewRiskScorer(300, [3]float64{0.3, 0.6, 0.8})
+	s.SetMerchantScope(MerchantScopeMerchant, 2)
+
+	s.RecordEvent(Event{Processor: "stripe", MerchantIDHash: "m1"})
+	s.RecordEvent(Event{Processor: "stripe", MerchantIDHash: "m2"})
+	s.RecordEvent(Event{Processor: "stripe", MerchantIDHash: "m1"}) // m1 most recent
+	s.RecordEvent(Event{Processor: "stripe", MerchantIDHash: "m3"}) // evicts m2
+
+	if got := s.MerchantCount(); got != 2 {
+		t.Fatalf("expected 2 tracked merchants, got %d", got)
+	}
+	if _, ok := s.merchants["m2"]; ok {
+		t.Error("least recently updated merchant m2 should have been evicted")
+	}
+	if _, ok := s.merchants["m1"]; !ok {
+		t.Error("m1 should have survived eviction")
+	}
+}
+
+func TestRiskScorer_ProcessorMerchantScope(t *testing.T) {
+	s := NewRiskScorer(300, [3]float64{0.3, 0.6, 0.8})
+	s.SetMerchantScope(MerchantScopeProcessorMerchant, 0)
+
+	s.RecordEvent(Event{Processor: "stripe", MerchantIDHash: "m1"})
+	s.RecordEvent(Event{Processor: "adyen", MerchantIDHash: "m1"})
+	if got := s.MerchantCount(); got != 2 {
+		t.Errorf("expected separate windows per processor×merchant, got %d", got)
+	}
+
+	s.SetMerchantScope(MerchantScopeOff, 0)
+	if res := s.RecordEvent(Event{Processor: "stripe", MerchantIDHash: "m1"}); res.MerchantScored {
+		t.Error("merchant scoring should be disabled when scope is off")
+	}
+}
+
+func TestRiskScorer_SetModel(t *testing.T) {
+	fixedNow := int64(1000000200)
+	s := NewRiskScorer(300, [3]float64{0.3, 0.6, 0.8})
+	s.nowFunc = func() int64 { return fixedNow }
+
+	for i := 0; i < 10; i++ {
+		s.RecordEvent(Event{Processor: "stripe", FailureCategory: "card_declined", GeoBucket: "US"})
+	}
+	before := s.computeScore("stripe")
+	if before.ModelVersion != riskmodel.DefaultVersion {
+		t.Errorf("expected built-in model version, got %q", before.ModelVersion)
+	}
+
+	// All weight on failure rate, low driver threshold: every event failed,
+	// so the score becomes 1.0 and high_failure_rate is the only driver.
+	m := riskmodel.Default([3]float64{0.3, 0.6, 0.8})
+	m.Version = "fail-only"
+	m.Weights = riskmodel.Components{FailRate: 1}
+	m.DriverThresholds = riskmodel.Components{FailRate: 0.1, RetryPressure: 1, TimeoutMix: 1, TrafficSpike: 100, AuthFailMix: 1, GeoEntropy: 1}
+	s.SetModel(m)
+
+	after := s.RecordEvent(Event{Processor: "stripe", FailureCategory: "card_declined", GeoBucket: "US"})
+	if after.ModelVersion != "fail-only" {
+		t.Errorf("expected new model version, got %q", after.ModelVersion)
+	}
+	if after.Score != 1 || after.Band != "critical" {
+		t.Errorf("expected score 1/critical under fail-only model, got %.2f/%s", after.Score, after.Band)
+	}
+	if !reflect.DeepEqual(after.Drivers, []string{"high_failure_rate"}) {
+		t.Errorf("unexpected drivers: %v", after.Drivers)
+	}
+}
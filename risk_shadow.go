@@ -0,0 +1,184 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Shadow scoring runs a second RiskScorer (loaded from
+// PAYFLUX_RISK_SHADOW_MODEL_FILE) on every event next to the primary one.
+// Only the primary result reaches exports and warnings; the shadow result is
+// compared against it and the differences are published as Prometheus
+// metrics and through GET /api/v1/risk/shadow.
+
+// maxShadowProcessors caps per-processor report entries, matching the
+// processor cardinality cap in RiskScorer.
+const maxShadowProcessors = 100
+
+// ShadowProcessorStats accumulates primary vs shadow differences for one processor.
+type ShadowProcessorStats struct {
+	Events        int64 `json:"events"`
+	Disagreements int64 `json:"band_disagreements"`
+
+	// Transitions counts primary band -> shadow band for disagreeing events.
+	Transitions map[string]map[string]int64 `json:"band_transitions"`
+
+	MeanDelta    float64 `json:"mean_score_delta"`     // shadow - primary
+	MeanAbsDelta float64 `json:"mean_abs_score_delta"` // |shadow - primary|
+	MaxAbsDelta  float64 `json:"max_abs_score_delta"`
+
+	// Events that would raise a pilot warning under each model (band above
+	// "low" at processor or merchant level, the same rule as addWarnings).
+	PrimaryWarnings int64 `json:"primary_would_warn"`
+	ShadowWarnings  int64 `json:"shadow_would_warn"`
+
+	deltaSum    float64
+	absDeltaSum float64
+}
+
+// ShadowReport is the response body of GET /api/v1/risk/shadow.
+type ShadowReport struct {
+	PrimaryModelVersion string                           `json:"primary_model_version"`
+	ShadowModelVersion  string                           `json:"shadow_model_version"`
+	Since               time.Time                        `json:"since"`
+	Events              int64                            `json:"events"`
+	Disagreements       int64                            `json:"band_disagreements"`
+	PrimaryWarnings     int64                            `json:"primary_would_warn"`
+	ShadowWarnings      int64                            `json:"shadow_would_warn"`
+	Processors          map[string]*ShadowProcessorStats `json:"processors"`
+}
+
+// ShadowComparator records primary/shadow result pairs.
+type ShadowComparator struct {
+	mu         sync.Mutex
+	since      time.Time
+	processors map[string]*ShadowProcessorStats
+}
+
+// NewShadowComparator creates an empty comparator.
+func NewShadowComparator() *ShadowComparator {
+	return &ShadowComparator{
+		since:      time.Now().UTC(),
+		processors: make(map[string]*ShadowProcessorStats),
+	}
+}
+
+func wouldWarn(res RiskResult) bool {
+	return res.Band != "low" || (res.MerchantScored && res.MerchantBand != "low")
+}
+
+// Observe records one event's primary and shadow results.
+func (c *ShadowComparator) Observe(processor string, primary, shadow RiskResult) {
+	if processor == "" {
+		processor = "unknown"
+	}
+	delta := shadow.Score - primary.Score
+
+	shadowEvents.WithLabelValues(processor).Inc()
+	shadowScoreDelta.WithLabelValues(processor).Observe(delta)
+	if primary.Band != shadow.Band {
+		shadowBandDisagreements.WithLabelValues(processor, primary.Band, shadow.Band).Inc()
+	}
+	primaryWarn, shadowWarn := wouldWarn(primary), wouldWarn(shadow)
+	if primaryWarn {
+		shadowWarnings.WithLabelValues(processor, riskModelRolePrimary).Inc()
+	}
+	if shadowWarn {
+		shadowWarnings.WithLabelValues(processor, riskModelRoleShadow).Inc()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	st, ok := c.processors[processor]
+	if !ok {
+		if len(c.processors) >= maxShadowProcessors {
+			return
+		}
+		st = &ShadowProcessorStats{Transitions: make(map[string]map[string]int64)}
+		c.processors[processor] = st
+	}
+
+	st.Events++
+	st.deltaSum += delta
+	st.absDeltaSum += math.Abs(delta)
+	if math.Abs(delta) > st.MaxAbsDelta {
+		st.MaxAbsDelta = math.Abs(delta)
+	}
+	if primary.Band != shadow.Band {
+		st.Disagreements++
+		if st.Transitions[primary.Band] == nil {
+			st.Transitions[primary.Band] = make(map[string]int64)
+		}
+		st.Transitions[primary.Band][shadow.Band]++
+	}
+	if primaryWarn {
+		st.PrimaryWarnings++
+	}
+	if shadowWarn {
+		st.ShadowWarnings++
+	}
+}
+
+// Report returns a copy of the accumulated statistics.
+func (c *ShadowComparator) Report() ShadowReport {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	rep := ShadowReport{
+		Since:      c.since,
+		Processors: make(map[string]*ShadowProcessorStats, len(c.processors)),
+	}
+	names := make([]string, 0, len(c.processors))
+	for name := range c.processors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		st := c.processors[name]
+		cp := *st
+		cp.Transitions = make(map[string]map[string]int64, len(st.Transitions))
+		for from, tos := range st.Transitions {
+			cp.Transitions[from] = make(map[string]int64, len(tos))
+			for to, n := range tos {
+				cp.Transitions[from][to] = n
+			}
+		}
+		if st.Events > 0 {
+			cp.MeanDelta = math.Round(st.deltaSum/float64(st.Events)*1000) / 1000
+			cp.MeanAbsDelta = math.Round(st.absDeltaSum/float64(st.Events)*1000) / 1000
+		}
+		cp.MaxAbsDelta = math.Round(st.MaxAbsDelta*1000) / 1000
+		rep.Processors[name] = &cp
+
+		rep.Events += st.Events
+		rep.Disagreements += st.Disagreements
+		rep.PrimaryWarnings += st.PrimaryWarnings
+		rep.ShadowWarnings += st.ShadowWarnings
+	}
+	return rep
+}
+
+// handleRiskShadow serves GET /api/v1/risk/shadow.
+func handleRiskShadow(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if riskScorer == nil || shadowScorer == nil || shadowComparator == nil {
+		http.Error(w, "Shadow scoring is disabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	rep := shadowComparator.Report()
+	rep.PrimaryModelVersion = riskScorer.Model().Version
+	rep.ShadowModelVersion = shadowScorer.Model().Version
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rep)
+}
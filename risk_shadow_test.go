@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"payment-node/internal/riskmodel"
+)
+
+func TestShadowComparator_LowerThresholdsProduceMoreWarnings(t *testing.T) {
+	fixedNow := int64(1000000200)
+	primary := NewRiskScorer(300, [3]float64{0.3, 0.6, 0.8})
+	shadow := NewRiskScorer(300, [3]float64{0.3, 0.6, 0.8})
+	primary.nowFunc = func() int64 { return fixedNow }
+	shadow.nowFunc = func() int64 { return fixedNow }
+
+	candidate := riskmodel.Default([3]float64{0.05, 0.1, 0.15})
+	candidate.Version = "low-cutoffs"
+	shadow.SetModel(candidate)
+
+	cmp := NewShadowComparator()
+	// Half the events fail: scores land just under the primary elevated cutoff
+	for i := 0; i < 10; i++ {
+		ev := Event{Processor: "stripe", GeoBucket: "US"}
+		if i%2 == 0 {
+			ev.FailureCategory = "card_declined"
+		}
+		cmp.Observe(ev.Processor, primary.RecordEvent(ev), shadow.RecordEvent(ev))
+	}
+
+	rep := cmp.Report()
+	st := rep.Processors["stripe"]
+	if st == nil || st.Events != 10 || rep.Events != 10 {
+		t.Fatalf("expected 10 stripe events, got %+v", rep)
+	}
+	if st.ShadowWarnings <= st.PrimaryWarnings {
+		t.Errorf("lower cutoffs should warn more: primary=%d shadow=%d", st.PrimaryWarnings, st.ShadowWarnings)
+	}
+	if st.Disagreements == 0 || len(st.Transitions["low"]) == 0 {
+		t.Errorf("expected low -> higher band transitions, got %+v", st.Transitions)
+	}
+	if st.MeanDelta != 0 {
+		t.Errorf("same weights should give identical scores, mean delta %.3f", st.MeanDelta)
+	}
+}
+
+func TestHandleRiskShadow(t *testing.T) {
+	origPrimary, origShadow, origCmp := riskScorer, shadowScorer, shadowComparator
+	defer func() { riskScorer, shadowScorer, shadowComparator = origPrimary, origShadow, origCmp }()
+
+	riskScorer, shadowScorer, shadowComparator = NewRiskScorer(300, [3]float64{0.3, 0.6, 0.8}), nil, nil
+	rr := httptest.NewRecorder()
+	handleRiskShadow(rr, httptest.NewRequest(http.MethodGet, "/api/v1/risk/shadow", nil))
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 when shadow disabled, got %d", rr.Code)
+	}
+
+	m := riskmodel.Default([3]float64{0.2, 0.5, 0.7})
+	m.Version = "candidate"
+	shadowScorer = NewRiskScorer(300, [3]float64{0.3, 0.6, 0.8})
+	shadowScorer.SetModel(m)
+	shadowComparator = NewShadowComparator()
+	shadowComparator.Observe("adyen", RiskResult{Score: 0.25, Band: "low"}, RiskResult{Score: 0.25, Band: "elevated"})
+
+	rr = httptest.NewRecorder()
+	handleRiskShadow(rr, httptest.NewRequest(http.MethodGet, "/api/v1/risk/shadow", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	var rep ShadowReport
+	if err := json.Unmarshal(rr.Body.Bytes(), &rep); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if rep.PrimaryModelVersion != riskmodel.DefaultVersion || rep.ShadowModelVersion != "candidate" {
+		t.Errorf("unexpected model versions: %s / %s", rep.PrimaryModelVersion, rep.ShadowModelVersion)
+	}
+	if rep.Processors["adyen"].Transitions["low"]["elevated"] != 1 || rep.ShadowWarnings != 1 || rep.PrimaryWarnings != 0 {
+		t.Errorf("unexpected report: %s", rr.Body.String())
+	}
+}
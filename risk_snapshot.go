@@ -0,0 +1,389 @@
+package main
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RiskScorer window persistence.
+//
+// The scorer's buckets are snapshotted periodically (and once on shutdown)
+// to Redis or a local file. On boot the snapshot is restored before the
+// consumer starts, so scoring continues across rolling restarts instead of
+// reporting insufficient_data for a whole window.
+//
+// Restore re-derives each bucket's slot from its LastUpdate timestamp, so a
+// snapshot taken with a different PAYFLUX_RISK_SCORE_WINDOW_SEC still loads;
+// buckets that have aged out of the current window are discarded.
+
+// riskSnapshotFormat is bumped on incompatible snapshot layout changes.
+const riskSnapshotFormat = 1
+
+// RiskSnapshot is the serialized form of a RiskScorer's windows.
+type RiskSnapshot struct {
+	Format        int                         `json:"format"`
+	TakenAt       int64                       `json:"taken_at"`
+	BucketSizeSec int                         `json:"bucket_size_sec"`
+	Processors    map[string][]BucketSnapshot `json:"processors"`
+	// Merchants are ordered least to most recently updated so Restore
+	// rebuilds the same eviction order.
+	Merchants []MerchantSnapshot `json:"merchants,omitempty"`
+}
+
+// MerchantSnapshot is one merchant window.
+type MerchantSnapshot struct {
+	Key     string           `json:"key"`
+	Buckets []BucketSnapshot `json:"buckets"`
+}
+
+// BucketSnapshot is one non-empty ProcessorMetrics bucket.
+type BucketSnapshot struct {
+	TotalEvents int      `json:"total"`
+	Failures    int      `json:"failures"`
+	Timeouts    int      `json:"timeouts"`
+	AuthFails   int      `json:"auth_fails"`
+	RetrySum    int      `json:"retry_sum"`
+	GeoBuckets  []string `json:"geos,omitempty"`
+	LastUpdate  int64    `json:"last_update"`
+}
+
+func snapshotBuckets(hist []ProcessorMetrics) []BucketSnapshot {
+	out := make([]BucketSnapshot, 0, len(hist))
+	for _, b := range hist {
+		if b.TotalEvents == 0 {
+			continue
+		}
+		geos := make([]string, 0, len(b.GeoBuckets))
+		for g := range b.GeoBuckets {
+			geos = append(geos, g)
+		}
+		out = append(out, BucketSnapshot{
+			TotalEvents: b.TotalEvents,
+			Failures:    b.Failures,
+			Timeouts:    b.Timeouts,
+			AuthFails:   b.AuthFails,
+			RetrySum:    b.RetrySum,
+			GeoBuckets:  geos,
+			LastUpdate:  b.LastUpdate,
+		})
+	}
+	return out
+}
+
+// Snapshot captures every non-empty bucket.
+func (s *RiskScorer) Snapshot() RiskSnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snap := RiskSnapshot{
+		Format:        riskSnapshotFormat,
+		TakenAt:       s.nowFunc(),
+		BucketSizeSec: s.bucketSizeSec,
+		Processors:    make(map[string][]BucketSnapshot, len(s.history)),
+	}
+	for processor, hist := range s.history {
+		if buckets := snapshotBuckets(hist); len(buckets) > 0 {
+			snap.Processors[processor] = buckets
+		}
+	}
+	for e := s.merchantOrder.Back(); e != nil; e = e.Prev() {
+		w := e.Value.(*merchantWindow)
+		if buckets := snapshotBuckets(w.hist); len(buckets) > 0 {
+			snap.Merchants = append(snap.Merchants, MerchantSnapshot{Key: w.key, Buckets: buckets})
+		}
+	}
+	return snap
+}
+
+// restoreBuckets places each still-fresh bucket into hist at the slot its
+// LastUpdate maps to. Returns how many buckets were kept and discarded.
+func (s *RiskScorer) restoreBuckets(hist []ProcessorMetrics, buckets []BucketSnapshot, now int64) (kept, discarded int) {
+	for _, b := range buckets {
+		if now-b.LastUpdate >= int64(s.windowSec) || b.LastUpdate > now {
+			discarded++
+			continue
+		}
+		idx := int((b.LastUpdate / int64(s.bucketSizeSec)) % int64(s.numBuckets))
+		geos := make(map[string]struct{}, len(b.GeoBuckets))
+		for _, g := range b.GeoBuckets {
+			geos[g] = struct{}{}
+		}
+		hist[idx] = ProcessorMetrics{
+			TotalEvents: b.TotalEvents,
+			Failures:    b.Failures,
+			Timeouts:    b.Timeouts,
+			AuthFails:   b.AuthFails,
+			RetrySum:    b.RetrySum,
+			GeoBuckets:  geos,
+			LastUpdate:  b.LastUpdate,
+		}
+		kept++
+	}
+	return kept, discarded
+}
+
+// Restore replaces the scorer's windows with snap, discarding buckets older
+// than windowSec. Merchant windows are only restored under the current
+// merchant scope's capacity; the least recently updated are dropped first.
+func (s *RiskScorer) Restore(snap RiskSnapshot) (kept, discarded int, err error) {
+	if snap.Format != riskSnapshotFormat {
+		return 0, 0, fmt.Errorf("unsupported snapshot format %d", snap.Format)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if snap.BucketSizeSec != s.bucketSizeSec {
+		return 0, 0, fmt.Errorf("snapshot bucket size %ds does not match scorer bucket size %ds", snap.BucketSizeSec, s.bucketSizeSec)
+	}
+	now := s.nowFunc()
+
+	s.history = make(map[string][]ProcessorMetrics)
+	for processor, buckets := range snap.Processors {
+		if len(s.history) >= 100 {
+			discarded += len(buckets)
+			continue
+		}
+		hist := make([]ProcessorMetrics, s.numBuckets)
+		k, d := s.restoreBuckets(hist, buckets, now)
+		kept, discarded = kept+k, discarded+d
+		if k > 0 {
+			s.history[processor] = hist
+		}
+	}
+
+	s.merchants = make(map[string]*list.Element)
+	s.merchantOrder = list.New()
+	if s.merchantScope != MerchantScopeOff {
+		merchants := snap.Merchants
+		if len(merchants) > s.maxMerchants {
+			for _, m := range merchants[:len(merchants)-s.maxMerchants] {
+				discarded += len(m.Buckets)
+			}
+			merchants = merchants[len(merchants)-s.maxMerchants:]
+		}
+		for _, m := range merchants {
+			w := &merchantWindow{key: m.Key, hist: make([]ProcessorMetrics, s.numBuckets)}
+			k, d := s.restoreBuckets(w.hist, m.Buckets, now)
+			kept, discarded = kept+k, discarded+d
+			if k > 0 {
+				s.merchants[m.Key] = s.merchantOrder.PushFront(w)
+			}
+		}
+	}
+	return kept, discarded, nil
+}
+
+// ── Snapshot stores ──────────────────────────────────────────────────────────
+
+// RiskSnapshotStore persists serialized snapshots. Load returns
+// errRiskSnapshotNotFound when nothing has been saved yet.
+type RiskSnapshotStore interface {
+	Save(ctx context.Context, data []byte) error
+	Load(ctx context.Context) ([]byte, error)
+}
+
+var errRiskSnapshotNotFound = errors.New("risk snapshot not found")
+
+// redisRiskSnapshotStore keeps the snapshot in a single string key. The TTL
+// is a multiple of the window: an older snapshot could not contribute any
+// bucket anyway.
+type redisRiskSnapshotStore struct {
+	rdb *redis.Client
+	key string
+	ttl time.Duration
+}
+
+func (r *redisRiskSnapshotStore) Save(ctx context.Context, data []byte) error {
+	return r.rdb.Set(ctx, r.key, data, r.ttl).Err()
+}
+
+func (r *redisRiskSnapshotStore) Load(ctx context.Context) ([]byte, error) {
+	data, err := r.rdb.Get(ctx, r.key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, errRiskSnapshotNotFound
+	}
+	return data, err
+}
+
+// fileRiskSnapshotStore writes via a temp file and rename so a crash mid-write
+// never leaves a truncated snapshot behind.
+type fileRiskSnapshotStore struct {
+	path string
+}
+
+func (f *fileRiskSnapshotStore) Save(_ context.Context, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), f.path)
+}
+
+func (f *fileRiskSnapshotStore) Load(_ context.Context) ([]byte, error) {
+	data, err := os.ReadFile(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, errRiskSnapshotNotFound
+	}
+	return data, err
+}
+
+// ── Wiring ───────────────────────────────────────────────────────────────────
+
+// riskSnapshotTarget pairs a scorer with where its snapshot lives.
+type riskSnapshotTarget struct {
+	role   string
+	scorer *RiskScorer
+	store  RiskSnapshotStore
+}
+
+var riskSnapshotTargets []riskSnapshotTarget
+
+// riskSnapshotInstance names this replica in its Redis snapshot key. Each
+// replica scores only the events its consumer reads, so replicas must not
+// overwrite each other's windows. The name must survive a restart for the
+// warm start to find it: PAYFLUX_CONSUMER_NAME when set, else the hostname.
+func riskSnapshotInstance() string {
+	if name := env("PAYFLUX_CONSUMER_NAME", ""); name != "" {
+		return name
+	}
+	hostname, _ := os.Hostname()
+	if hostname == "" {
+		hostname = "unknown"
+	}
+	slog.Warn("risk_snapshot_instance_unstable", "instance", hostname,
+		"detail", "PAYFLUX_CONSUMER_NAME is unset; the snapshot is keyed by hostname and is not found after a restart that changes it")
+	return hostname
+}
+
+// newRiskSnapshotStore builds the store for role from PAYFLUX_RISK_SNAPSHOT
+// (off|redis|file). Redis keys are suffixed with the instance name. The
+// shadow scorer's snapshot lives next to the primary's with a ":shadow" key
+// suffix or ".shadow" file suffix.
+func newRiskSnapshotStore(backend, role string) RiskSnapshotStore {
+	switch backend {
+	case "redis":
+		key := env("PAYFLUX_RISK_SNAPSHOT_KEY", "payflux:risk:snapshot") + ":" + riskSnapshotInstance()
+		if role != riskModelRolePrimary {
+			key += ":" + role
+		}
+		return &redisRiskSnapshotStore{rdb: rdb, key: key, ttl: 2 * time.Duration(riskScoreWindow) * time.Second}
+	case "file":
+		path := os.Getenv("PAYFLUX_RISK_SNAPSHOT_FILE")
+		if role != riskModelRolePrimary {
+			path += "." + role
+		}
+		return &fileRiskSnapshotStore{path: path}
+	}
+	return nil
+}
+
+// setupRiskSnapshots warm-starts the scorers from their last snapshot and
+// starts periodic snapshotting. Must run after setupRedis and before the
+// consumer starts so no event is scored against an empty window.
+func setupRiskSnapshots(ctx context.Context) {
+	backend := env("PAYFLUX_RISK_SNAPSHOT", "off")
+	if riskScorer == nil || backend == "off" {
+		return
+	}
+	if backend != "redis" && backend != "file" {
+		log.Fatalf("PAYFLUX_RISK_SNAPSHOT must be 'off', 'redis' or 'file', got: %s", backend)
+	}
+
+	riskSnapshotTargets = []riskSnapshotTarget{{riskModelRolePrimary, riskScorer, newRiskSnapshotStore(backend, riskModelRolePrimary)}}
+	if shadowScorer != nil {
+		riskSnapshotTargets = append(riskSnapshotTargets,
+			riskSnapshotTarget{riskModelRoleShadow, shadowScorer, newRiskSnapshotStore(backend, riskModelRoleShadow)})
+	}
+
+	for _, t := range riskSnapshotTargets {
+		restoreRiskSnapshot(ctx, t)
+	}
+
+	interval := time.Duration(envInt("PAYFLUX_RISK_SNAPSHOT_INTERVAL_SEC", 30)) * time.Second
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				saveRiskSnapshots()
+			}
+		}
+	}()
+	slog.Info("risk_snapshots_enabled", "backend", backend, "interval_sec", int(interval.Seconds()))
+}
+
+func restoreRiskSnapshot(ctx context.Context, t riskSnapshotTarget) {
+	lctx, cancel := context.WithTimeout(ctx, redisWriteTimeout)
+	defer cancel()
+
+	data, err := t.store.Load(lctx)
+	if errors.Is(err, errRiskSnapshotNotFound) {
+		slog.Info("risk_snapshot_not_found", "role", t.role)
+		return
+	}
+	if err != nil {
+		riskSnapshotOps.WithLabelValues("restore", "error").Inc()
+		slog.Error("risk_snapshot_load_failed", "role", t.role, "error", err.Error())
+		return
+	}
+
+	var snap RiskSnapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		riskSnapshotOps.WithLabelValues("restore", "error").Inc()
+		slog.Error("risk_snapshot_decode_failed", "role", t.role, "error", err.Error())
+		return
+	}
+	kept, discarded, err := t.scorer.Restore(snap)
+	if err != nil {
+		riskSnapshotOps.WithLabelValues("restore", "error").Inc()
+		slog.Error("risk_snapshot_restore_failed", "role", t.role, "error", err.Error())
+		return
+	}
+	riskSnapshotOps.WithLabelValues("restore", "success").Inc()
+	slog.Info("risk_snapshot_restored", "role", t.role,
+		"age_sec", t.scorer.nowFunc()-snap.TakenAt, "buckets_kept", kept, "buckets_discarded", discarded)
+}
+
+// saveRiskSnapshots writes a snapshot for every configured scorer. Called
+// periodically and once during shutdown.
+func saveRiskSnapshots() {
+	for _, t := range riskSnapshotTargets {
+		data, err := json.Marshal(t.scorer.Snapshot())
+		if err != nil {
+			riskSnapshotOps.WithLabelValues("save", "error").Inc()
+			log.Printf("risk_snapshot_marshal_error role=%s err=%v", t.role, err)
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), redisWriteTimeout)
+		err = t.store.Save(ctx, data)
+		cancel()
+		if err != nil {
+			riskSnapshotOps.WithLabelValues("save", "error").Inc()
+			log.Printf("risk_snapshot_save_error role=%s err=%v", t.role, err)
+			continue
+		}
+		riskSnapshotOps.WithLabelValues("save", "success").Inc()
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestRiskScorer_SnapshotRoundTrip(t *testing.T) {
+	now := int64(1000000200)
+	src := NewRiskScorer(300, [3]float64{0.3, 0.6, 0.8})
+	for i := 0; i < 5; i++ {
+		ts := now - int64(i*10)
+		src.nowFunc = func() int64 { return ts }
+		for j := 0; j < 4; j++ {
+			src.RecordEvent(Event{Processor: "stripe", MerchantIDHash: "m1", FailureCategory: "processor_timeout", RetryCount: 2, GeoBucket: "US"})
+			src.RecordEvent(Event{Processor: "stripe", MerchantIDHash: "m2", GeoBucket: "DE"})
+		}
+	}
+	src.nowFunc = func() int64 { return now }
+
+	data, err := json.Marshal(src.Snapshot())
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var snap RiskSnapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	dst := NewRiskScorer(300, [3]float64{0.3, 0.6, 0.8})
+	dst.nowFunc = func() int64 { return now }
+	kept, discarded, err := dst.Restore(snap)
+	if err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	if kept != 15 || discarded != 0 {
+		t.Errorf("expected 15 kept / 0 discarded buckets, got %d / %d", kept, discarded)
+	}
+	if got := dst.MerchantCount(); got != 2 {
+		t.Errorf("expected 2 merchant windows restored, got %d", got)
+	}
+
+	ev := Event{Processor: "stripe", MerchantIDHash: "m1", GeoBucket: "US"}
+	want, got := src.RecordEvent(ev), dst.RecordEvent(ev)
+	if got.Score != want.Score || got.Band != want.Band || got.MerchantScore != want.MerchantScore {
+		t.Errorf("restored scorer diverged: got %.2f/%s/%.2f, want %.2f/%s/%.2f",
+			got.Score, got.Band, got.MerchantScore, want.Score, want.Band, want.MerchantScore)
+	}
+
+	// LRU order survives: m2 was updated last in src before the probe above,
+	// so adding a third merchant at capacity 2 must evict m2 (m1 was just touched).
+	dst.SetMerchantScope(MerchantScopeMerchant, 2)
+	dst.RecordEvent(Event{Processor: "stripe", MerchantIDHash: "m3"})
+	if _, ok := dst.merchants["m2"]; ok {
+		t.Error("expected m2 to be evicted as least recently updated")
+	}
+}
+
+func TestRiskScorer_RestoreDiscardsStaleBuckets(t *testing.T) {
+	now := int64(1000000200)
+	src := NewRiskScorer(300, [3]float64{0.3, 0.6, 0.8})
+	// Ages chosen so no two land in the same ring slot: the snapshot holds all
+	// five buckets and restore must drop the two outside the 300s window.
+	for _, age := range []int64{455, 305, 290, 100, 0} {
+		ts := now - age
+		src.nowFunc = func() int64 { return ts }
+		src.RecordEvent(Event{Processor: "stripe"})
+	}
+	snap := src.Snapshot()
+	if got := len(snap.Processors["stripe"]); got != 5 {
+		t.Fatalf("expected 5 buckets in snapshot, got %d", got)
+	}
+
+	dst := NewRiskScorer(300, [3]float64{0.3, 0.6, 0.8})
+	dst.nowFunc = func() int64 { return now }
+	kept, discarded, err := dst.Restore(snap)
+	if err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	if kept != 3 || discarded != 2 {
+		t.Errorf("expected 3 kept / 2 discarded buckets, got %d / %d", kept, discarded)
+	}
+
+	// Restoring long after the window has passed leaves nothing.
+	late := NewRiskScorer(300, [3]float64{0.3, 0.6, 0.8})
+	late.nowFunc = func() int64 { return now + 3600 }
+	kept, discarded, err = late.Restore(snap)
+	if err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	if kept != 0 || discarded == 0 {
+		t.Errorf("expected all buckets discarded, got %d kept / %d discarded", kept, discarded)
+	}
+	if res := late.RecordEvent(Event{Processor: "stripe"}); res.Band != "low" {
+		t.Errorf("expected empty window after stale restore, got band %s", res.Band)
+	}
+}
+
+func TestRiskScorer_RestoreRejectsMismatchedFormat(t *testing.T) {
+	s := NewRiskScorer(300, [3]float64{0.3, 0.6, 0.8})
+	if _, _, err := s.Restore(RiskSnapshot{Format: riskSnapshotFormat + 1, BucketSizeSec: 10}); err == nil {
+		t.Error("expected error for unknown snapshot format")
+	}
+	if _, _, err := s.Restore(RiskSnapshot{Format: riskSnapshotFormat, BucketSizeSec: 60}); err == nil {
+		t.Error("expected error for mismatched bucket size")
+	}
+}
+
+func TestRiskSnapshotStores(t *testing.T) {
+	setupTestRedis(t)
+	ctx := context.Background()
+
+	stores := map[string]RiskSnapshotStore{
+		"redis": &redisRiskSnapshotStore{rdb: testRdb, key: "payflux:risk:snapshot:test", ttl: 0},
+		"file":  &fileRiskSnapshotStore{path: filepath.Join(t.TempDir(), "risk.snapshot")},
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			if _, err := store.Load(ctx); !errors.Is(err, errRiskSnapshotNotFound) {
+				t.Fatalf("expected errRiskSnapshotNotFound before first save, got %v", err)
+			}
+			for _, payload := range []string{`{"format":1}`, `{"format":1,"taken_at":2}`} {
+				if err := store.Save(ctx, []byte(payload)); err != nil {
+					t.Fatalf("save: %v", err)
+				}
+				got, err := store.Load(ctx)
+				if err != nil {
+					t.Fatalf("load: %v", err)
+				}
+				if string(got) != payload {
+					t.Errorf("got %s, want %s", got, payload)
+				}
+			}
+		})
+	}
+}
+
+func TestRiskSnapshotKeyPerInstance(t *testing.T) {
+	t.Setenv("PAYFLUX_RISK_SNAPSHOT_KEY", "payflux:risk:snapshot")
+	t.Setenv("PAYFLUX_CONSUMER_NAME", "replica-a")
+
+	for role, want := range map[string]string{
+		riskModelRolePrimary: "payflux:risk:snapshot:replica-a",
+		riskModelRoleShadow:  "payflux:risk:snapshot:replica-a:" + riskModelRoleShadow,
+	} {
+		store := newRiskSnapshotStore("redis", role).(*redisRiskSnapshotStore)
+		if store.key != want {
+			t.Errorf("%s key = %q, want %q", role, store.key, want)
+		}
+	}
+}
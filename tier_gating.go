@@ -0,0 +1,192 @@
+package main
+
+// tier_gating.go — request-level tier resolution and per-feature response
+// gating.
+//
+// authMiddleware resolves each authenticated request's tier.CanonicalTier
+// (see resolveIdentityTier and loadRequestTierConfig for the sources) and
+// stores it in the request context. Handlers then either refuse the
+// request outright with tier.Require (403 naming the missing feature) or
+// strip the fields behind features the tier lacks, reporting them in
+// X-PayFlux-Withheld-Features.
+// tier.CanAccess is the only place feature access is decided.
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"payment-node/internal/forecast"
+	"payment-node/internal/tier"
+)
+
+// workspaceTierTTL bounds how long a workspace's entitlement tier is served
+// from cache after it changes.
+const workspaceTierTTL = time.Minute
+
+var requestTierResolver *tier.RequestResolver // nil until loadRequestTierConfig
+
+// loadRequestTierConfig builds the per-request resolver:
+//
+//	PAYFLUX_API_KEY_TIERS       key=tier bindings (free|pro|enterprise)
+//	PAYFLUX_API_KEY_WORKSPACES  key=workspace id bindings; the tier is the
+//	                            workspace's entitlement_tier (needs DATABASE_URL)
+//
+// Registry keys without a tier or workspace, and legacy keys without a
+// binding, get the deployment tier from PAYFLUX_TIER.
+func loadRequestTierConfig() {
+	keyTiers, err := tier.ParseKeyTiers(os.Getenv("PAYFLUX_API_KEY_TIERS"))
+	if err != nil {
+		log.Fatalf("invalid PAYFLUX_API_KEY_TIERS: %v", err)
+	}
+	keyWorkspaces, err := tier.ParseKeyWorkspaces(os.Getenv("PAYFLUX_API_KEY_WORKSPACES"))
+	if err != nil {
+		log.Fatalf("invalid PAYFLUX_API_KEY_WORKSPACES: %v", err)
+	}
+	if len(keyWorkspaces) > 0 && os.Getenv("DATABASE_URL") == "" {
+		log.Fatal("PAYFLUX_API_KEY_WORKSPACES requires DATABASE_URL to look up workspace tiers")
+	}
+
+	requestTierResolver = &tier.RequestResolver{
+		Default:       runtimeCanonicalTier,
+		KeyTiers:      keyTiers,
+		KeyWorkspaces: keyWorkspaces,
+	}
+	if os.Getenv("DATABASE_URL") != "" {
+		requestTierResolver.WorkspaceTier = newWorkspaceTierCache(workspaceTierTTL).lookup
+	}
+	slog.Info("request_tier_config", "default", string(runtimeCanonicalTier),
+		"key_bindings", len(keyTiers), "workspace_bindings", len(keyWorkspaces))
+}
+
+// workspaceTierCache reads workspaces.entitlement_tier, caching successful
+// lookups for ttl. Failures are not cached.
+type workspaceTierCache struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]workspaceTierEntry
+}
+
+type workspaceTierEntry struct {
+	tier    tier.CanonicalTier
+	expires time.Time
+}
+
+func newWorkspaceTierCache(ttl time.Duration) *workspaceTierCache {
+	return &workspaceTierCache{ttl: ttl, entries: make(map[string]workspaceTierEntry)}
+}
+
+func (c *workspaceTierCache) lookup(ctx context.Context, workspaceID string) (tier.CanonicalTier, error) {
+	now := time.Now()
+	c.mu.Lock()
+	e, ok := c.entries[workspaceID]
+	c.mu.Unlock()
+	if ok && now.Before(e.expires) {
+		return e.tier, nil
+	}
+
+	if pgDB == nil {
+		return "", errors.New("postgres unavailable")
+	}
+	var raw string
+	err := pgDB.QueryRowContext(ctx,
+		`SELECT entitlement_tier FROM workspaces WHERE id = $1 AND deleted_at IS NULL`,
+		workspaceID).Scan(&raw)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", errors.New("workspace not found")
+	}
+	if err != nil {
+		return "", err
+	}
+	t, ok := tier.Parse(raw)
+	if !ok {
+		return "", errors.New("unknown entitlement_tier " + raw)
+	}
+
+	c.mu.Lock()
+	c.entries[workspaceID] = workspaceTierEntry{tier: t, expires: now.Add(c.ttl)}
+	c.mu.Unlock()
+	return t, nil
+}
+
+// Gated forecast response. Field names match the forecast package's JSON
+// (untagged Go names); fields behind a feature the tier lacks are omitted.
+
+type gatedAccountForecast struct {
+	ExpectedVolume  float64
+	GrowthTrend     *float64 `json:",omitempty"` // slope_modeling
+	RiskProbability float64
+	Confidence      float64
+}
+
+type gatedProcessorForecast struct {
+	ExpectedLatency float64
+	ExpectedSuccess float64
+	FailureTrend    *float64 `json:",omitempty"` // slope_modeling
+	Confidence      float64
+}
+
+type gatedReserveProjection struct {
+	ShockProbability       *float64 `json:",omitempty"` // system_shock_blend
+	ExpectedReservePercent *float64 `json:",omitempty"` // reserve_projection
+	ProjectedReserveHold   *float64 `json:",omitempty"` // reserve_projection
+	Acceleration           *float64 `json:",omitempty"` // acceleration_modeling
+	InstabilityIndex       *float64 `json:",omitempty"` // instability_index
+	ConfidenceLow          *float64 `json:",omitempty"` // confidence_bands
+	ConfidenceHigh         *float64 `json:",omitempty"` // confidence_bands
+}
+
+// allowed returns &v when g allows f, else nil.
+func allowed(g *tier.Gate, f tier.Feature, v float64) *float64 {
+	if !g.Allow(f) {
+		return nil
+	}
+	return &v
+}
+
+func gateAccountForecast(g *tier.Gate, f forecast.AccountForecast) gatedAccountForecast {
+	return gatedAccountForecast{
+		ExpectedVolume:  f.ExpectedVolume,
+		GrowthTrend:     allowed(g, tier.FeatureSlopeModeling, f.GrowthTrend),
+		RiskProbability: f.RiskProbability,
+		Confidence:      f.Confidence,
+	}
+}
+
+func gateProcessorForecast(g *tier.Gate, f forecast.ProcessorForecast) gatedProcessorForecast {
+	return gatedProcessorForecast{
+		ExpectedLatency: f.ExpectedLatency,
+		ExpectedSuccess: f.ExpectedSuccess,
+		FailureTrend:    allowed(g, tier.FeatureSlopeModeling, f.FailureTrend),
+		Confidence:      f.Confidence,
+	}
+}
+
+func gateReserveProjection(g *tier.Gate, p forecast.ReserveProjection) gatedReserveProjection {
+	return gatedReserveProjection{
+		ShockProbability:       allowed(g, tier.FeatureSystemShockBlend, p.ShockProbability),
+		ExpectedReservePercent: allowed(g, tier.FeatureReserveProjection, p.ExpectedReservePercent),
+		ProjectedReserveHold:   allowed(g, tier.FeatureReserveProjection, p.ProjectedReserveHold),
+		Acceleration:           allowed(g, tier.FeatureAcceleration, p.Acceleration),
+		InstabilityIndex:       allowed(g, tier.FeatureInstabilityIndex, p.InstabilityIndex),
+		ConfidenceLow:          allowed(g, tier.FeatureConfidenceBands, p.ConfidenceLow),
+		ConfidenceHigh:         allowed(g, tier.FeatureConfidenceBands, p.ConfidenceHigh),
+	}
+}
+
+// gateWarning returns w as the request's tier may see it: the risk
+// trajectory is slope modeling output. w itself is never modified; it is
+// shared with the warning store.
+func gateWarning(g *tier.Gate, w *Warning) *Warning {
+	if w.RiskTrajectory == "" || g.Allow(tier.FeatureSlopeModeling) {
+		return w
+	}
+	gated := *w
+	gated.RiskTrajectory = ""
+	return &gated
+}
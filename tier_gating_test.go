@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"payment-node/internal/tier"
+)
+
+func TestRiskForecastTierGating(t *testing.T) {
+	validAPIKeys = []string{"key-free", "key-pro", "key-enterprise"}
+	revokedAPIKeys = nil
+	runtimeCanonicalTier = tier.TierFree
+	requestTierResolver = &tier.RequestResolver{
+		Default: tier.TierFree,
+		KeyTiers: map[string]tier.CanonicalTier{
+			"key-pro":        tier.TierPro,
+			"key-enterprise": tier.TierEnterprise,
+		},
+	}
+	prevScorer := riskScorer
+	riskScorer = NewRiskScorer(300, [3]float64{0.3, 0.6, 0.8})
+	defer func() {
+		riskScorer = prevScorer
+		requestTierResolver = nil
+	}()
+
+	handler := authMiddleware(tier.Require(tier.FeatureBasicRiskScore, handleRiskForecast))
+
+	tests := []struct {
+		key          string
+		withheld     string
+		wantReserve  []string
+		absentFields []string
+	}{
+		{
+			key:          "key-free",
+			withheld:     "slope_modeling,system_shock_blend,reserve_projection,acceleration_modeling,instability_index,confidence_bands",
+			absentFields: []string{"ShockProbability", "ExpectedReservePercent", "Acceleration", "InstabilityIndex", "ConfidenceLow"},
+		},
+		{
+			key:          "key-pro",
+			withheld:     "system_shock_blend",
+			wantReserve:  []string{"ExpectedReservePercent", "ProjectedReserveHold", "Acceleration", "InstabilityIndex", "ConfidenceLow", "ConfidenceHigh"},
+			absentFields: []string{"ShockProbability"},
+		},
+		{
+			key:         "key-enterprise",
+			wantReserve: []string{"ShockProbability", "ExpectedReservePercent", "Acceleration", "InstabilityIndex", "ConfidenceHigh"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/risk/forecast", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.key)
+			w := httptest.NewRecorder()
+			handler(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("status %d: %s", w.Code, w.Body)
+			}
+			if got := w.Header().Get(tier.HeaderWithheld); got != tt.withheld {
+				t.Errorf("withheld = %q, want %q", got, tt.withheld)
+			}
+
+			var body struct {
+				Account map[string]any `json:"account_forecast"`
+				Reserve map[string]any `json:"reserve_projection"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if _, ok := body.Account["RiskProbability"]; !ok {
+				t.Error("basic RiskProbability missing")
+			}
+			_, hasTrend := body.Account["GrowthTrend"]
+			if hasTrend != (tt.key != "key-free") {
+				t.Errorf("GrowthTrend present = %v", hasTrend)
+			}
+			for _, f := range tt.wantReserve {
+				if _, ok := body.Reserve[f]; !ok {
+					t.Errorf("reserve_projection.%s missing", f)
+				}
+			}
+			for _, f := range tt.absentFields {
+				if _, ok := body.Reserve[f]; ok {
+					t.Errorf("reserve_projection.%s returned to %s", f, tt.key)
+				}
+			}
+		})
+	}
+}
+
+func TestPilotWarningExportTierGating(t *testing.T) {
+	store := NewWarningStore(10)
+	store.Add(&Warning{WarningID: "w1", Processor: "stripe", RiskBand: "high", RiskTrajectory: "Pattern accelerating"})
+	handler := tier.Require(tier.FeatureEvidenceExport, pilotWarningGetHandler(store))
+
+	req := httptest.NewRequest(http.MethodGet, "/pilot/warnings/w1", nil)
+	w := httptest.NewRecorder()
+	handler(w, req.WithContext(tier.WithTier(req.Context(), tier.TierFree)))
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("free tier: status %d, want 403", w.Code)
+	}
+	var denied tier.Forbidden
+	if err := json.NewDecoder(w.Body).Decode(&denied); err != nil || denied.Feature != tier.FeatureEvidenceExport {
+		t.Errorf("403 body = %+v, %v", denied, err)
+	}
+
+	w = httptest.NewRecorder()
+	handler(w, req.WithContext(tier.WithTier(req.Context(), tier.TierPro)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("pro tier: status %d", w.Code)
+	}
+	var got Warning
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got.RiskTrajectory != "Pattern accelerating" {
+		t.Errorf("risk_trajectory = %q", got.RiskTrajectory)
+	}
+}
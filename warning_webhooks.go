@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"payment-node/internal/exporter"
+	"payment-node/internal/runtime/entitlementctx"
+)
+
+// Warning webhooks push each new pilot warning to the endpoints configured in
+// PAYFLUX_WARNING_WEBHOOKS (see internal/exporter/warning_webhook.go), so
+// receivers no longer have to poll /pilot/warnings.
+//
+// Delivery is gated by the alert_routing_enabled entitlement of
+// PAYFLUX_ENTITLEMENT_TIER in config/tier_entitlements.runtime.json. Warnings
+// only exist in pilot mode, so the dispatcher is not started without it.
+
+const entitlementsConfigPath = "config/tier_entitlements.runtime.json"
+
+var warningDispatcher *exporter.WarningDispatcher // nil when push delivery is off
+
+// setupWarningWebhooks builds and starts the dispatcher. Must run before
+// setupExportAndConsumer so buildExporter picks it up.
+func setupWarningWebhooks(ctx context.Context) {
+	configs, err := exporter.ParseWarningWebhookSpecs(os.Getenv("PAYFLUX_WARNING_WEBHOOKS"), os.Environ())
+	if err != nil {
+		log.Fatalf("invalid PAYFLUX_WARNING_WEBHOOKS: %v", err)
+	}
+	if len(configs) == 0 {
+		return
+	}
+	if !pilotModeEnabled {
+		slog.Warn("warning_webhooks_disabled", "reason", "pilot_mode_off",
+			"msg", "PAYFLUX_WARNING_WEBHOOKS is set but warnings are only created with PAYFLUX_PILOT_MODE=true")
+		return
+	}
+
+	registry, err := entitlementctx.LoadRegistry(entitlementsConfigPath)
+	if err != nil {
+		log.Fatalf("warning_webhooks_entitlements_error err=%v", err)
+	}
+	entTier := env("PAYFLUX_ENTITLEMENT_TIER", "baseline")
+	ent, err := registry.GetEntitlements(entTier)
+	if err != nil || !ent.AlertRoutingEnabled {
+		slog.Warn("warning_webhooks_disabled", "reason", "not_entitled", "entitlement_tier", entTier,
+			"msg", "alert_routing_enabled is false for this tier; warnings remain available at /pilot/warnings")
+		return
+	}
+
+	warningDispatcher = exporter.NewWarningDispatcher(configs)
+	warningDispatcher.OnResult = func(endpoint, result string) {
+		warningWebhookDeliveries.WithLabelValues(endpoint, result).Inc()
+	}
+	go warningDispatcher.Run(ctx)
+
+	for _, c := range configs {
+		slog.Info("warning_webhook_configured", "name", c.Name, "min_band", c.MinBand)
+	}
+}
+
+// handleWarningWebhooks serves:
+//
+//	GET /api/v1/warning-webhooks                        endpoint status
+//	GET /api/v1/warning-webhooks/{name}/deliveries?limit= delivery log, newest first
+func handleWarningWebhooks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if warningDispatcher == nil {
+		http.Error(w, "Warning webhooks are disabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/warning-webhooks"), "/")
+	if rest == "" {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"endpoints": warningDispatcher.Endpoints()})
+		return
+	}
+
+	name, ok := strings.CutSuffix(rest, "/deliveries")
+	if !ok || name == "" || strings.Contains(name, "/") {
+		http.NotFound(w, r)
+		return
+	}
+	limit := 50
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	records, ok := warningDispatcher.Deliveries(name, limit)
+	if !ok {
+		http.Error(w, "Unknown webhook endpoint", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"endpoint": name, "deliveries": records})
+}
@@ -32,6 +32,7 @@ type Warning struct {
 	EventID        string    `json:"event_id"`
 	Processor      string    `json:"processor"`
 	MerchantIDHash string    `json:"merchant_id_hash,omitempty"`
+	WorkspaceID    string    `json:"workspace_id,omitempty"`
 	ProcessedAt    time.Time `json:"processed_at"`
 	EventTimestamp time.Time `json:"event_timestamp"` // Upstream event time for latency measurement
 
@@ -40,6 +41,14 @@ type Warning struct {
 	RiskBand    string   `json:"processor_risk_band"`
 	RiskDrivers []string `json:"processor_risk_drivers"`
 
+	// Merchant risk data (when merchant scoring is enabled)
+	MerchantRiskScore   float64  `json:"merchant_risk_score,omitempty"`
+	MerchantRiskBand    string   `json:"merchant_risk_band,omitempty"`
+	MerchantRiskDrivers []string `json:"merchant_risk_drivers,omitempty"`
+
+	// Scoring model that produced the risk fields above
+	RiskModelVersion string `json:"risk_model_version,omitempty"`
+
 	// Tier 2 context (if present)
 	PlaybookContext string `json:"processor_playbook_context,omitempty"`
 	RiskTrajectory  string `json:"risk_trajectory,omitempty"`
@@ -53,6 +62,43 @@ type Warning struct {
 	OutcomeUpdatedAt time.Time `json:"outcome_updated_at,omitempty"`
 }
 
+// matches reports whether w passes List's filters; empty filters match all.
+func (w *Warning) matches(processor, workspaceID string) bool {
+	return (processor == "" || w.Processor == processor) &&
+		(workspaceID == "" || w.WorkspaceID == workspaceID)
+}
+
+// WarningStorage is the storage surface used by the exporter, pilot routes and
+// evidence handler. Implementations:
+//
+//	*WarningStore      in-memory LRU (default, lost on restart)
+//	*RedisWarningStore durable hash + sorted set, shared across replicas
+//
+// Methods do not return errors: storage failures are logged by the
+// implementation and surface as "not found" / empty results, matching the
+// fail-open behavior of the export pipeline.
+type WarningStorage interface {
+	// Add inserts or replaces a warning and marks it most recently used.
+	Add(w *Warning)
+	// Get retrieves a warning by ID.
+	Get(warningID string) (*Warning, bool)
+	// SetOutcome annotates a warning with an observed outcome.
+	SetOutcome(warningID, outcomeType, outcomeTimestamp, outcomeSource, outcomeNotes string) (*Warning, bool)
+	// List returns up to limit warnings, newest first, optionally filtered by
+	// processor and by workspace.
+	List(limit int, processor, workspaceID string) []*Warning
+	// Count returns the number of stored warnings.
+	Count() int
+	// Remove deletes a warning, reporting whether it was stored.
+	Remove(warningID string) bool
+}
+
+// Compile-time interface checks
+var (
+	_ WarningStorage = (*WarningStore)(nil)
+	_ WarningStorage = (*RedisWarningStore)(nil)
+)
+
 // WarningStore is a thread-safe in-memory LRU cache for warnings
 type WarningStore struct {
 	mu       sync.RWMutex
@@ -143,7 +189,8 @@ func (s *WarningStore) SetOutcome(warningID, outcomeType, outcomeTimestamp, outc
 }
 
 // List returns recent warnings (newest first), optionally filtered by processor
-func (s *WarningStore) List(limit int, processor string) []*Warning {
+// and workspace
+func (s *WarningStore) List(limit int, processor, workspaceID string) []*Warning {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -152,7 +199,7 @@ func (s *WarningStore) List(limit int, processor string) []*Warning {
 
 	for elem := s.order.Front(); elem != nil && count < limit; elem = elem.Next() {
 		w := elem.Value.(*warningEntry).warning
-		if processor == "" || w.Processor == processor {
+		if w.matches(processor, workspaceID) {
 			result = append(result, w)
 			count++
 		}
@@ -168,6 +215,20 @@ func (s *WarningStore) Count() int {
 	return s.order.Len()
 }
 
+// Remove deletes a warning from the store
+func (s *WarningStore) Remove(warningID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elem, exists := s.warnings[warningID]
+	if !exists {
+		return false
+	}
+	s.order.Remove(elem)
+	delete(s.warnings, warningID)
+	return true
+}
+
 // ValidOutcomeType checks if the outcome type is valid
 func ValidOutcomeType(t string) bool {
 	switch t {
@@ -0,0 +1,313 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisWarningStore is a durable WarningStorage backed by Redis, so pilot
+// warnings and operator outcome annotations survive restarts and are shared
+// by every PayFlux replica pointing at the same Redis.
+//
+// Layout (prefix defaults to "pilot:warnings"):
+//
+//	<prefix>:data     HASH  warning_id -> JSON-encoded Warning
+//	<prefix>:outcome  HASH  warning_id -> JSON-encoded outcome fields
+//	<prefix>:recent   ZSET  warning_id scored by last-touched unix micros
+//
+// The sorted set provides the same recency ordering as the in-memory LRU:
+// Add and SetOutcome bump the score, and entries beyond capacity are evicted
+// from the low end of the set together with their hash fields. Outcomes are
+// kept apart from the warning so SetOutcome is a single atomic write and
+// reads overlay them on the warning.
+type RedisWarningStore struct {
+	rdb        *redis.Client
+	capacity   int
+	dataKey    string
+	outcomeKey string
+	recentKey  string
+	timeout    time.Duration
+	nowFunc    func() time.Time
+}
+
+// warningOutcome is the value stored under <prefix>:outcome. Its JSON keys
+// are Warning's, without omitempty, so decoding it over a Warning replaces
+// every outcome field.
+type warningOutcome struct {
+	OutcomeObserved  bool      `json:"outcome_observed"`
+	OutcomeType      string    `json:"outcome_type"`
+	OutcomeTimestamp string    `json:"outcome_timestamp"`
+	OutcomeSource    string    `json:"outcome_source"`
+	OutcomeNotes     string    `json:"outcome_notes"`
+	OutcomeUpdatedAt time.Time `json:"outcome_updated_at"`
+}
+
+// setOutcomeScript stores the outcome and bumps recency only if the warning
+// still exists, and returns the stored warning.
+//
+//	KEYS: data, outcome, recent   ARGV: warning_id, outcome JSON, score
+const setOutcomeScript = `
+	local raw = redis.call('HGET', KEYS[1], ARGV[1])
+	if not raw then
+		return false
+	end
+	redis.call('HSET', KEYS[2], ARGV[1], ARGV[2])
+	redis.call('ZADD', KEYS[3], ARGV[3], ARGV[1])
+	return raw
+`
+
+// NewRedisWarningStore creates a Redis-backed warning store.
+func NewRedisWarningStore(client *redis.Client, prefix string, capacity int) *RedisWarningStore {
+	if prefix == "" {
+		prefix = "pilot:warnings"
+	}
+	return &RedisWarningStore{
+		rdb:        client,
+		capacity:   capacity,
+		dataKey:    prefix + ":data",
+		outcomeKey: prefix + ":outcome",
+		recentKey:  prefix + ":recent",
+		timeout:    redisWriteTimeout,
+		nowFunc:    time.Now,
+	}
+}
+
+func (s *RedisWarningStore) ctx() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), s.timeout)
+}
+
+func (s *RedisWarningStore) score() float64 {
+	return float64(s.nowFunc().UnixMicro())
+}
+
+// decodeWarning reads a stored warning and overlays its outcome, if one was set.
+func decodeWarning(raw string, outcome any) (*Warning, error) {
+	var w Warning
+	if err := json.Unmarshal([]byte(raw), &w); err != nil {
+		return nil, err
+	}
+	if str, ok := outcome.(string); ok {
+		if err := json.Unmarshal([]byte(str), &w); err != nil {
+			return nil, err
+		}
+	}
+	return &w, nil
+}
+
+// Add stores w, replacing any earlier outcome, marks it most recently used
+// and evicts the oldest entries beyond capacity.
+func (s *RedisWarningStore) Add(w *Warning) {
+	data, err := json.Marshal(w)
+	if err != nil {
+		log.Printf("warning_store_marshal_error warning_id=%s err=%v", w.WarningID, err)
+		return
+	}
+
+	ctx, cancel := s.ctx()
+	defer cancel()
+
+	_, err = s.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, s.dataKey, w.WarningID, data)
+		pipe.HDel(ctx, s.outcomeKey, w.WarningID)
+		pipe.ZAdd(ctx, s.recentKey, redis.Z{Score: s.score(), Member: w.WarningID})
+		return nil
+	})
+	if err != nil {
+		log.Printf("warning_store_add_error warning_id=%s err=%v", w.WarningID, err)
+		return
+	}
+
+	s.evict(ctx)
+}
+
+// evict trims the store back to capacity, oldest first.
+func (s *RedisWarningStore) evict(ctx context.Context) {
+	if s.capacity <= 0 {
+		return
+	}
+	excess, err := s.rdb.ZCard(ctx, s.recentKey).Result()
+	if err != nil || excess <= int64(s.capacity) {
+		return
+	}
+	oldest, err := s.rdb.ZRange(ctx, s.recentKey, 0, excess-int64(s.capacity)-1).Result()
+	if err != nil || len(oldest) == 0 {
+		return
+	}
+	members := make([]any, len(oldest))
+	for i, id := range oldest {
+		members[i] = id
+	}
+	_, err = s.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HDel(ctx, s.dataKey, oldest...)
+		pipe.HDel(ctx, s.outcomeKey, oldest...)
+		pipe.ZRem(ctx, s.recentKey, members...)
+		return nil
+	})
+	if err != nil {
+		log.Printf("warning_store_evict_error count=%d err=%v", len(oldest), err)
+	}
+}
+
+// Get retrieves a warning by ID.
+func (s *RedisWarningStore) Get(warningID string) (*Warning, bool) {
+	ctx, cancel := s.ctx()
+	defer cancel()
+
+	var raw, outcome *redis.StringCmd
+	_, err := s.rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		raw = pipe.HGet(ctx, s.dataKey, warningID)
+		outcome = pipe.HGet(ctx, s.outcomeKey, warningID)
+		return nil
+	})
+	if err != nil && !errors.Is(err, redis.Nil) {
+		log.Printf("warning_store_get_error warning_id=%s err=%v", warningID, err)
+		return nil, false
+	}
+	if raw.Err() != nil {
+		return nil, false
+	}
+	var outcomeRaw any
+	if outcome.Err() == nil {
+		outcomeRaw = outcome.Val()
+	}
+	w, err := decodeWarning(raw.Val(), outcomeRaw)
+	if err != nil {
+		log.Printf("warning_store_unmarshal_error warning_id=%s err=%v", warningID, err)
+		return nil, false
+	}
+	return w, true
+}
+
+// SetOutcome updates the outcome fields for a warning. One script checks
+// the warning exists and writes the outcome, so concurrent annotations from
+// other replicas neither conflict nor resurrect an evicted warning.
+func (s *RedisWarningStore) SetOutcome(warningID, outcomeType, outcomeTimestamp, outcomeSource, outcomeNotes string) (*Warning, bool) {
+	ctx, cancel := s.ctx()
+	defer cancel()
+
+	outcome, err := json.Marshal(warningOutcome{
+		OutcomeObserved:  outcomeType != OutcomeNone,
+		OutcomeType:      outcomeType,
+		OutcomeTimestamp: outcomeTimestamp,
+		OutcomeSource:    outcomeSource,
+		OutcomeNotes:     outcomeNotes,
+		OutcomeUpdatedAt: s.nowFunc().UTC(),
+	})
+	if err != nil {
+		log.Printf("warning_store_marshal_error warning_id=%s err=%v", warningID, err)
+		return nil, false
+	}
+
+	raw, err := s.rdb.Eval(ctx, setOutcomeScript,
+		[]string{s.dataKey, s.outcomeKey, s.recentKey},
+		warningID, outcome, s.score(),
+	).Text()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			log.Printf("warning_store_set_outcome_error warning_id=%s err=%v", warningID, err)
+		}
+		return nil, false
+	}
+	w, err := decodeWarning(raw, string(outcome))
+	if err != nil {
+		log.Printf("warning_store_unmarshal_error warning_id=%s err=%v", warningID, err)
+		return nil, false
+	}
+	return w, true
+}
+
+// List returns recent warnings (newest first), optionally filtered by processor
+// and workspace. Reads the recency index in pages so a filter can look past
+// other processors' or workspaces' warnings without loading the whole store.
+func (s *RedisWarningStore) List(limit int, processor, workspaceID string) []*Warning {
+	result := make([]*Warning, 0, limit)
+	if limit <= 0 {
+		return result
+	}
+
+	ctx, cancel := s.ctx()
+	defer cancel()
+
+	pageSize := int64(limit)
+	if (processor != "" || workspaceID != "") && pageSize < 100 {
+		pageSize = 100
+	}
+
+	for start := int64(0); len(result) < limit; start += pageSize {
+		ids, err := s.rdb.ZRevRange(ctx, s.recentKey, start, start+pageSize-1).Result()
+		if err != nil {
+			log.Printf("warning_store_list_error err=%v", err)
+			return result
+		}
+		if len(ids) == 0 {
+			return result
+		}
+		var raws, outcomes *redis.SliceCmd
+		_, err = s.rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			raws = pipe.HMGet(ctx, s.dataKey, ids...)
+			outcomes = pipe.HMGet(ctx, s.outcomeKey, ids...)
+			return nil
+		})
+		if err != nil {
+			log.Printf("warning_store_list_error err=%v", err)
+			return result
+		}
+		for i, raw := range raws.Val() {
+			str, ok := raw.(string)
+			if !ok {
+				continue // evicted between ZREVRANGE and HMGET
+			}
+			w, err := decodeWarning(str, outcomes.Val()[i])
+			if err != nil {
+				continue
+			}
+			if w.matches(processor, workspaceID) {
+				result = append(result, w)
+				if len(result) >= limit {
+					break
+				}
+			}
+		}
+		if int64(len(ids)) < pageSize {
+			return result
+		}
+	}
+	return result
+}
+
+// Count returns the number of warnings in the store.
+func (s *RedisWarningStore) Count() int {
+	ctx, cancel := s.ctx()
+	defer cancel()
+
+	n, err := s.rdb.ZCard(ctx, s.recentKey).Result()
+	if err != nil {
+		log.Printf("warning_store_count_error err=%v", err)
+		return 0
+	}
+	return int(n)
+}
+
+// Remove deletes a warning and its recency entry.
+func (s *RedisWarningStore) Remove(warningID string) bool {
+	ctx, cancel := s.ctx()
+	defer cancel()
+
+	var removed *redis.IntCmd
+	_, err := s.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		removed = pipe.HDel(ctx, s.dataKey, warningID)
+		pipe.HDel(ctx, s.outcomeKey, warningID)
+		pipe.ZRem(ctx, s.recentKey, warningID)
+		return nil
+	})
+	if err != nil {
+		log.Printf("warning_store_remove_error warning_id=%s err=%v", warningID, err)
+		return false
+	}
+	return removed.Val() > 0
+}
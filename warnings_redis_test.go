@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// exerciseWarningStorage runs the same behavioral checks against any
+// WarningStorage implementation so the Redis store stays a drop-in
+// replacement for the in-memory LRU.
+func exerciseWarningStorage(t *testing.T, store WarningStorage) {
+	t.Helper()
+
+	for i := 0; i < 4; i++ {
+		processor := "stripe"
+		if i%2 == 1 {
+			processor = "adyen"
+		}
+		store.Add(&Warning{
+			WarningID:   fmt.Sprintf("w%d", i),
+			EventID:     fmt.Sprintf("e%d", i),
+			Processor:   processor,
+			ProcessedAt: time.Now().UTC(),
+			RiskBand:    "high",
+		})
+		time.Sleep(time.Millisecond) // distinct recency scores
+	}
+
+	// Capacity is 3: w0 must have been evicted
+	if got := store.Count(); got != 3 {
+		t.Fatalf("expected 3 warnings after eviction, got %d", got)
+	}
+	if _, ok := store.Get("w0"); ok {
+		t.Error("w0 should have been evicted")
+	}
+
+	list := store.List(10, "", "")
+	if len(list) != 3 || list[0].WarningID != "w3" || list[2].WarningID != "w1" {
+		t.Fatalf("unexpected list order: %+v", warningIDs(list))
+	}
+
+	stripeOnly := store.List(10, "stripe", "")
+	if len(stripeOnly) != 1 || stripeOnly[0].WarningID != "w2" {
+		t.Fatalf("unexpected processor filter result: %+v", warningIDs(stripeOnly))
+	}
+
+	updated, ok := store.SetOutcome("w1", OutcomeThrottle, "2026-01-01T00:00:00Z", OutcomeSourceManual, "note")
+	if !ok {
+		t.Fatal("SetOutcome should find w1")
+	}
+	if !updated.OutcomeObserved || updated.OutcomeType != OutcomeThrottle {
+		t.Errorf("outcome not applied: %+v", updated)
+	}
+
+	got, ok := store.Get("w1")
+	if !ok || got.OutcomeNotes != "note" {
+		t.Errorf("outcome not persisted: %+v", got)
+	}
+
+	// SetOutcome bumps recency
+	if list := store.List(1, "", ""); len(list) != 1 || list[0].WarningID != "w1" {
+		t.Errorf("expected w1 to be most recent after SetOutcome, got %v", warningIDs(list))
+	}
+
+	if _, ok := store.SetOutcome("missing", OutcomeNone, "", OutcomeSourceManual, ""); ok {
+		t.Error("SetOutcome on missing warning should return false")
+	}
+
+	if !store.Remove("w2") || store.Remove("w2") {
+		t.Error("Remove should report w2 once")
+	}
+	if _, ok := store.Get("w2"); ok || store.Count() != 2 {
+		t.Errorf("w2 still stored, count=%d", store.Count())
+	}
+}
+
+func warningIDs(ws []*Warning) []string {
+	ids := make([]string, len(ws))
+	for i, w := range ws {
+		ids[i] = w.WarningID
+	}
+	return ids
+}
+
+func TestWarningStore_Memory(t *testing.T) {
+	exerciseWarningStorage(t, NewWarningStore(3))
+}
+
+func TestWarningStore_Redis(t *testing.T) {
+	setupTestRedis(t)
+	defer teardownTestRedis(t)
+
+	exerciseWarningStorage(t, NewRedisWarningStore(testRdb, "test:pilot:warnings", 3))
+
+	// A second instance (another replica) sees the same data
+	other := NewRedisWarningStore(testRdb, "test:pilot:warnings", 3)
+	if got, ok := other.Get("w1"); !ok || got.OutcomeType != OutcomeThrottle {
+		t.Errorf("second store instance should see persisted outcome, got %+v", got)
+	}
+
+	// Replicas annotating different warnings at once never conflict.
+	store := NewRedisWarningStore(testRdb, "test:pilot:concurrent", 100)
+	for i := 0; i < 50; i++ {
+		store.Add(&Warning{WarningID: fmt.Sprintf("c%d", i), Processor: "stripe"})
+	}
+	var wg sync.WaitGroup
+	var failed atomic.Int32
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			replica := NewRedisWarningStore(testRdb, "test:pilot:concurrent", 100)
+			if _, ok := replica.SetOutcome(id, OutcomeThrottle, "", OutcomeSourceManual, id); !ok {
+				failed.Add(1)
+			}
+		}(fmt.Sprintf("c%d", i))
+	}
+	wg.Wait()
+	if n := failed.Load(); n != 0 {
+		t.Errorf("%d concurrent SetOutcome calls failed", n)
+	}
+	for _, w := range store.List(100, "", "") {
+		if w.OutcomeNotes != w.WarningID {
+			t.Errorf("outcome of %s = %q", w.WarningID, w.OutcomeNotes)
+		}
+	}
+}